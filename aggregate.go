@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/feedhenry/fh-system-dump-tool/dumpformat"
+)
+
+// A FleetReport merges the inventories and findings of the dumps of several
+// clusters, for an overview of a fleet of RHMAP installations.
+type FleetReport struct {
+	Dumps []FleetDump `json:"dumps"`
+	// Components lists the images each component runs across the fleet,
+	// sorted by component name.
+	Components []FleetComponent `json:"components"`
+	// Findings are the checks that found issues, in the most dumps first.
+	Findings []FleetFinding `json:"findings"`
+}
+
+// A FleetDump sums up one of the dumps of a FleetReport.
+type FleetDump struct {
+	Path        string    `json:"path"`
+	Created     time.Time `json:"created"`
+	ToolVersion string    `json:"toolVersion"`
+	Criticals   int       `json:"criticals"`
+	Warnings    int       `json:"warnings"`
+	HealthScore int       `json:"healthScore"`
+}
+
+// A FleetComponent lists the images a component runs across the fleet.
+type FleetComponent struct {
+	Name   string       `json:"name"`
+	Images []FleetImage `json:"images"`
+}
+
+// A FleetImage is an image run by a component in some of the dumps.
+type FleetImage struct {
+	Image string   `json:"image"`
+	Dumps []string `json:"dumps"`
+}
+
+// A FleetFinding is a check that found an issue in some of the dumps.
+type FleetFinding struct {
+	CheckID   string `json:"checkId"`
+	CheckName string `json:"checkName"`
+	// Status is the most severe status of the check across the dumps.
+	Status int      `json:"status"`
+	Dumps  []string `json:"dumps"`
+}
+
+// aggregateCommand merges the inventories and findings of dumps of several
+// clusters into a fleet report.
+func aggregateCommand(args []string) error {
+	fs := flag.NewFlagSet("aggregate", flag.ExitOnError)
+	format := fs.String("format", "text", "output format: text or json")
+	fs.Parse(args)
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q, must be one of: text, json", *format)
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: aggregate [-format json] dump.tar.gz...")
+	}
+	var (
+		summaries []DumpSummary
+		metadata  []dumpformat.Metadata
+	)
+	for _, path := range fs.Args() {
+		s, m, err := loadFleetDump(path)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		summaries, metadata = append(summaries, s), append(metadata, m)
+	}
+	report := AggregateDumps(summaries, metadata)
+	if *format == "json" {
+		output, err := json.MarshalIndent(report, "", "    ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(os.Stdout, "%s\n", output)
+		return err
+	}
+	return report.WriteText(os.Stdout)
+}
+
+// loadFleetDump reads the summary and metadata of the dump archive at path.
+func loadFleetDump(path string) (DumpSummary, dumpformat.Metadata, error) {
+	var metadata dumpformat.Metadata
+	f, err := os.Open(path)
+	if err != nil {
+		return DumpSummary{}, metadata, err
+	}
+	defer f.Close()
+	files, err := ReadTgz(f, func(name string) bool { return name == "metadata.json" || isSummaryFile(name) })
+	if err != nil {
+		return DumpSummary{}, metadata, err
+	}
+	if content, ok := files["metadata.json"]; ok {
+		if err := json.Unmarshal(content, &metadata); err != nil {
+			return DumpSummary{}, metadata, err
+		}
+	}
+	if err := dumpformat.CheckLayoutVersion(metadata.LayoutVersion); err != nil {
+		return DumpSummary{}, metadata, fmt.Errorf("%s: %v", path, err)
+	}
+	summary, err := parseDumpSummary(path, files)
+	return summary, metadata, err
+}
+
+// AggregateDumps returns the fleet report of the dumps with the given summaries
+// and metadata, in the same order.
+func AggregateDumps(summaries []DumpSummary, metadata []dumpformat.Metadata) FleetReport {
+	report := FleetReport{Dumps: []FleetDump{}, Components: []FleetComponent{}, Findings: []FleetFinding{}}
+	images := make(map[string]map[string][]string)
+	findings := make(map[string]*FleetFinding)
+	for i, s := range summaries {
+		d := FleetDump{Path: s.Path, Criticals: s.CountFindings(StatusCritical), Warnings: s.CountFindings(StatusWarning), HealthScore: s.HealthScore()}
+		if i < len(metadata) {
+			d.Created, d.ToolVersion = metadata[i].Created, metadata[i].ToolVersion
+		}
+		report.Dumps = append(report.Dumps, d)
+
+		for _, c := range s.Inventory.Components {
+			if images[c.Name] == nil {
+				images[c.Name] = make(map[string][]string)
+			}
+			for _, image := range c.Images {
+				if dumps := images[c.Name][image]; len(dumps) == 0 || dumps[len(dumps)-1] != s.Path {
+					images[c.Name][image] = append(dumps, s.Path)
+				}
+			}
+		}
+		for _, f := range s.Findings() {
+			ff, ok := findings[f.Result.CheckID]
+			if !ok {
+				ff = &FleetFinding{CheckID: f.Result.CheckID, CheckName: f.Result.CheckName}
+				findings[f.Result.CheckID] = ff
+			}
+			if f.Result.Status > ff.Status {
+				ff.Status = f.Result.Status
+			}
+			if len(ff.Dumps) == 0 || ff.Dumps[len(ff.Dumps)-1] != s.Path {
+				ff.Dumps = append(ff.Dumps, s.Path)
+			}
+		}
+	}
+
+	for name, byImage := range images {
+		c := FleetComponent{Name: name, Images: []FleetImage{}}
+		for image, dumps := range byImage {
+			c.Images = append(c.Images, FleetImage{Image: image, Dumps: dumps})
+		}
+		sort.Slice(c.Images, func(i, j int) bool { return c.Images[i].Image < c.Images[j].Image })
+		report.Components = append(report.Components, c)
+	}
+	sort.Slice(report.Components, func(i, j int) bool { return report.Components[i].Name < report.Components[j].Name })
+	for _, f := range findings {
+		report.Findings = append(report.Findings, *f)
+	}
+	sort.Slice(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if len(a.Dumps) != len(b.Dumps) {
+			return len(a.Dumps) > len(b.Dumps)
+		}
+		if a.Status != b.Status {
+			return a.Status > b.Status
+		}
+		return a.CheckID < b.CheckID
+	})
+	return report
+}
+
+// WriteText writes r as tables to w.
+func (r FleetReport) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "DUMP\tCREATED\tTOOL VERSION\tCRITICAL\tWARNING\tHEALTH")
+	for _, d := range r.Dumps {
+		created := "-"
+		if !d.Created.IsZero() {
+			created = d.Created.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%d\n", d.Path, created, orDash(d.ToolVersion), d.Criticals, d.Warnings, d.HealthScore)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "COMPONENT\tIMAGE\tDUMPS")
+	for _, c := range r.Components {
+		for _, image := range c.Images {
+			fmt.Fprintf(tw, "%s\t%s\t%d/%d\n", c.Name, image.Image, len(image.Dumps), len(r.Dumps))
+		}
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "CHECK\tSEVERITY\tDUMPS")
+	for _, f := range r.Findings {
+		fmt.Fprintf(tw, "%s\t%s\t%d/%d\n", f.CheckName, strings.ToUpper(statusName(f.Status)), len(f.Dumps), len(r.Dumps))
+	}
+	return tw.Flush()
+}
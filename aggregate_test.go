@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/feedhenry/fh-system-dump-tool/dumpformat"
+)
+
+func TestAggregateDumps(t *testing.T) {
+	backups := Result{CheckID: "mongo-backups", CheckName: "check mongodb backups", Status: StatusWarning}
+	pull := Result{CheckID: "image-pull", CheckName: "check image pulls", Status: StatusCritical}
+	summaries := []DumpSummary{
+		{
+			Path:      "a.tar.gz",
+			Inventory: Inventory{Components: []Component{{Project: "core", Name: "millicore", Images: []string{"rhmap/millicore:4.5"}}}},
+			Results:   map[string][]Result{"core": {backups, pull}, "mbaas": {backups}},
+		},
+		{
+			Path:      "b.tar.gz",
+			Inventory: Inventory{Components: []Component{{Project: "core", Name: "millicore", Images: []string{"rhmap/millicore:4.6"}}}},
+			Results:   map[string][]Result{"core": {backups}},
+		},
+	}
+	report := AggregateDumps(summaries, []dumpformat.Metadata{{ToolVersion: "0.1.0"}, {}})
+
+	if len(report.Dumps) != 2 || report.Dumps[0].ToolVersion != "0.1.0" || report.Dumps[0].Criticals != 1 || report.Dumps[1].Warnings != 1 {
+		t.Errorf("Dumps = %+v", report.Dumps)
+	}
+	wantComponents := []FleetComponent{{Name: "millicore", Images: []FleetImage{
+		{Image: "rhmap/millicore:4.5", Dumps: []string{"a.tar.gz"}},
+		{Image: "rhmap/millicore:4.6", Dumps: []string{"b.tar.gz"}},
+	}}}
+	if !reflect.DeepEqual(report.Components, wantComponents) {
+		t.Errorf("Components = %+v, want %+v", report.Components, wantComponents)
+	}
+	wantFindings := []FleetFinding{
+		{CheckID: "mongo-backups", CheckName: "check mongodb backups", Status: StatusWarning, Dumps: []string{"a.tar.gz", "b.tar.gz"}},
+		{CheckID: "image-pull", CheckName: "check image pulls", Status: StatusCritical, Dumps: []string{"a.tar.gz"}},
+	}
+	if !reflect.DeepEqual(report.Findings, wantFindings) {
+		t.Errorf("Findings = %+v, want %+v", report.Findings, wantFindings)
+	}
+
+	var b bytes.Buffer
+	if err := report.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "check mongodb backups  WARNING   2/2") {
+		t.Errorf("WriteText() = %s, want the backup check found in both dumps", b.String())
+	}
+}
@@ -2,11 +2,17 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"reflect"
+	"runtime"
+	"sort"
 	"strings"
+	"time"
 )
 
 type Info struct {
@@ -17,7 +23,16 @@ type Info struct {
 	Message   string
 }
 
+// Result statuses, in increasing order of severity.
+const (
+	StatusOK       = 0
+	StatusWarning  = 1
+	StatusCritical = 2
+)
+
 type Result struct {
+	// CheckID is a short, stable identifier of the check.
+	CheckID       string `json:"checkId" yaml:"checkId"`
 	CheckName     string `json:"checkName" yaml:"checkName"`
 	Status        int    `json:"status" yaml:"status"`
 	StatusMessage string `json:"statusMessage" yaml:"statusMessage"`
@@ -27,9 +42,12 @@ type Result struct {
 type Events struct {
 	Items []struct {
 		Kind           string `json:"kind"`
+		Type           string `json:"type"`
 		InvolvedObject struct {
+			Kind      string `json:"kind"`
 			Namespace string `json:"namespace"`
 			Name      string `json:"name"`
+			FieldPath string `json:"fieldPath"`
 		} `json:"involvedObject"`
 		Reason  string `json:"reason"`
 		Message string `json:"message"`
@@ -37,20 +55,176 @@ type Events struct {
 	} `json:"items"`
 }
 
+type DeploymentConfig struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Spec struct {
+		Replicas int `json:"replicas"`
+		Template struct {
+			Metadata struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+			Spec PodSpec `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+	Status struct {
+		AvailableReplicas int `json:"availableReplicas"`
+	} `json:"status"`
+}
+
 type DeploymentConfigs struct {
-	Items []struct {
-		Kind     string `json:"kind"`
-		Metadata struct {
-			Name      string `json:"name"`
-			Namespace string `json:"namespace"`
-		} `json:"metadata"`
-		Spec struct {
-			Replicas int `json:"replicas"`
-		} `json:"spec"`
-	} `json:"items"`
+	Items []DeploymentConfig `json:"items"`
+}
+
+type EnvVar struct {
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+	ValueFrom *struct {
+		SecretKeyRef *struct {
+			Name string `json:"name"`
+		} `json:"secretKeyRef"`
+	} `json:"valueFrom"`
+}
+
+type Probe struct {
+	InitialDelaySeconds int `json:"initialDelaySeconds"`
+	TimeoutSeconds      int `json:"timeoutSeconds"`
+	PeriodSeconds       int `json:"periodSeconds"`
+	FailureThreshold    int `json:"failureThreshold"`
+}
+
+type Container struct {
+	Name           string        `json:"name"`
+	Image          string        `json:"image"`
+	Env            []EnvVar      `json:"env"`
+	VolumeMounts   []VolumeMount `json:"volumeMounts"`
+	ReadinessProbe *Probe        `json:"readinessProbe"`
+	LivenessProbe  *Probe        `json:"livenessProbe"`
+	Resources      struct {
+		Limits map[string]string `json:"limits"`
+	} `json:"resources"`
+}
+
+type VolumeMount struct {
+	Name      string `json:"name"`
+	MountPath string `json:"mountPath"`
 }
 
-type CheckTask func(string, io.Writer) (Result, error)
+type Volume struct {
+	Name                  string `json:"name"`
+	PersistentVolumeClaim *struct {
+		ClaimName string `json:"claimName"`
+	} `json:"persistentVolumeClaim"`
+	Secret *struct {
+		SecretName string `json:"secretName"`
+	} `json:"secret"`
+}
+
+type PodSpec struct {
+	NodeName         string      `json:"nodeName"`
+	InitContainers   []Container `json:"initContainers"`
+	Containers       []Container `json:"containers"`
+	Volumes          []Volume    `json:"volumes"`
+	ImagePullSecrets []struct {
+		Name string `json:"name"`
+	} `json:"imagePullSecrets"`
+	Affinity *Affinity `json:"affinity"`
+}
+
+type ContainerStatus struct {
+	Name         string `json:"name"`
+	Ready        bool   `json:"ready"`
+	RestartCount int    `json:"restartCount"`
+	Image        string `json:"image"`
+	ImageID      string `json:"imageID"`
+	State        struct {
+		Running *struct {
+			StartedAt time.Time `json:"startedAt"`
+		} `json:"running"`
+	} `json:"state"`
+}
+
+type PodCondition struct {
+	Type               string    `json:"type"`
+	Status             string    `json:"status"`
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
+}
+
+type Pod struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string            `json:"name"`
+		Namespace string            `json:"namespace"`
+		Labels    map[string]string `json:"labels"`
+	} `json:"metadata"`
+	Spec   PodSpec `json:"spec"`
+	Status struct {
+		Phase             string            `json:"phase"`
+		Conditions        []PodCondition    `json:"conditions"`
+		ContainerStatuses []ContainerStatus `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+type Pods struct {
+	Items []Pod `json:"items"`
+}
+
+type Job struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name            string `json:"name"`
+		Namespace       string `json:"namespace"`
+		OwnerReferences []struct {
+			Kind string `json:"kind"`
+			Name string `json:"name"`
+		} `json:"ownerReferences"`
+	} `json:"metadata"`
+	Status struct {
+		CompletionTime time.Time `json:"completionTime"`
+		StartTime      time.Time `json:"startTime"`
+		Succeeded      int       `json:"succeeded"`
+		Failed         int       `json:"failed"`
+	} `json:"status"`
+}
+
+type Jobs struct {
+	Items []Job `json:"items"`
+}
+
+type CronJob struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Spec struct {
+		Schedule    string `json:"schedule"`
+		Suspend     bool   `json:"suspend"`
+		JobTemplate struct {
+			Spec struct {
+				Template struct {
+					Spec PodSpec `json:"spec"`
+				} `json:"template"`
+			} `json:"spec"`
+		} `json:"jobTemplate"`
+	} `json:"spec"`
+	Status struct {
+		Active []struct {
+			Name string `json:"name"`
+		} `json:"active"`
+		LastScheduleTime   time.Time `json:"lastScheduleTime"`
+		LastSuccessfulTime time.Time `json:"lastSuccessfulTime"`
+	} `json:"status"`
+}
+
+type CronJobs struct {
+	Items []CronJob `json:"items"`
+}
+
+type CheckTask func(context.Context, string, io.Writer) (Result, error)
 
 // ResourceDefinitions is a task factory for tasks that fetch the JSON resource
 // definition for all given types in project. For each resource type, the task
@@ -58,7 +232,17 @@ type CheckTask func(string, io.Writer) (Result, error)
 // output and any eventual error message.
 func CheckTasks(project string, outFor, errOutFor projectResourceWriterCloserFactory) Task {
 	return checkTasks(func() []CheckTask {
-		return []CheckTask{CheckImagePullBackOff, CheckDeployConfigsReplicasNotZero}
+		checks := []CheckTask{CheckImagePullBackOff, CheckDeployConfigsReplicasNotZero, CheckMongoBackups, CheckWeakCredentials, CheckAdminRoutesExposed, CheckStudioURL, CheckNagiosPresent, CheckFailedScheduling, CheckProbeTimeouts, CheckStickySessions, CheckOrphanedResources, CheckProjectTerminating, CheckLimitRangeDefaults, CheckSupportMatrix, CheckNodeVersions, CheckObjectSizes, CheckDrainReadiness}
+		if *networkStats {
+			checks = append(checks, CheckConntrackExhaustion)
+		}
+		if *routerStats {
+			checks = append(checks, CheckRouter503Rate)
+		}
+		for _, c := range config.CustomChecks {
+			checks = append(checks, c.CheckTask())
+		}
+		return checks
 	}, project, outFor, errOutFor)
 }
 
@@ -74,7 +258,7 @@ type CheckResults struct {
 // The results of the checks are combined into a single JSON object and written to the writer return from outFor, any
 // errors that occur during the test are written to the writer returned from errOutFor.
 func checkTasks(checkFactory getProjectCheckFactory, project string, outFor, errOutFor projectResourceWriterCloserFactory) Task {
-	return func() error {
+	return func(ctx context.Context) error {
 		stdOut, stdOutCloser, err := outFor(project, "analysis")
 		if err != nil {
 			return err
@@ -92,7 +276,7 @@ func checkTasks(checkFactory getProjectCheckFactory, project string, outFor, err
 
 		var errors errorList
 		for _, check := range checks {
-			res, err := check(project, stdErr)
+			res, err := runCheck(ctx, check, project, stdErr, *checkTimeout)
 			if err != nil {
 				errors = append(errors, err)
 			}
@@ -114,9 +298,69 @@ func checkTasks(checkFactory getProjectCheckFactory, project string, outFor, err
 	}
 }
 
+// runCheck runs check against project, recovering from panics and cancelling
+// it after timeout. A check that panics or times out is reported as a finding
+// with the check-crashed ID, so that the remaining checks still run.
+func runCheck(ctx context.Context, check CheckTask, project string, stdErr io.Writer, timeout time.Duration) (Result, error) {
+	type outcome struct {
+		result  Result
+		err     error
+		crashed bool
+	}
+	name := checkFuncName(check)
+	// The check writes to its own buffer, since it may still be running
+	// after a timeout.
+	var errOut bytes.Buffer
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: fmt.Errorf("check %s panicked: %v", name, r), crashed: true}
+			}
+		}()
+		res, err := check(ctx, project, &errOut)
+		done <- outcome{result: res, err: err}
+	}()
+
+	var o outcome
+	select {
+	case o = <-done:
+		stdErr.Write(errOut.Bytes())
+		if !o.crashed {
+			return o.result, o.err
+		}
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			o.err = fmt.Errorf("check %s timed out after %v", name, timeout)
+		} else {
+			o.err = fmt.Errorf("check %s: %v", name, ctx.Err())
+		}
+	}
+	// Only panics and timeouts get here, the check didn't produce a
+	// result.
+	stdErr.Write([]byte(o.err.Error()))
+	return Result{
+		Status:        StatusWarning,
+		StatusMessage: o.err.Error(),
+		CheckID:       "check-crashed",
+		CheckName:     "check " + name + " did not complete",
+	}, o.err
+}
+
+// checkFuncName returns the name of the function implementing check.
+func checkFuncName(check CheckTask) string {
+	name := runtime.FuncForPC(reflect.ValueOf(check).Pointer()).Name()
+	return strings.TrimPrefix(name, "main.")
+}
+
 // getResourceStruct will retrieve the requested resource in the supplied project from the platform and parse the JSON
-// into the supplied interface.
-func getResourceStruct(project, resource string, dest interface{}) error {
+// into the supplied interface. The definitions collected in the project, if kept for its checks, are read instead.
+func getResourceStruct(ctx context.Context, project, resource string, dest interface{}) error {
+	if content, ok := collectedDefinitions.get(project, resource); ok {
+		return json.Unmarshal(content, dest)
+	}
 	stdOut := bytes.NewBuffer([]byte{})
 	stdErr := bytes.NewBuffer([]byte{})
 	outFor := func(project, resource string) (io.Writer, io.Closer, error) {
@@ -127,7 +371,7 @@ func getResourceStruct(project, resource string, dest interface{}) error {
 	}
 	task := ResourceDefinitions(project, []string{resource}, outFor, errOutFor)
 
-	err := task()
+	err := task(ctx)
 	if err != nil {
 		return err
 	}
@@ -150,10 +394,10 @@ func getResourceStruct(project, resource string, dest interface{}) error {
 // CheckImagePullBackOff will check all events in the supplied project and if any are exhibiting signs that they have
 // experience an ImagePullBackOff recently this will be reflected in the returned Result data. Any errors are written
 // to the supplied stdErr writer
-func CheckImagePullBackOff(project string, stdErr io.Writer) (Result, error) {
-	result := Result{Status: 0, StatusMessage: "this issue was not detected", CheckName: "check deploys for ImagePullBackOff error"}
+func CheckImagePullBackOff(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+	result := Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: "image-pull-backoff", CheckName: "check deploys for ImagePullBackOff error"}
 	events := Events{}
-	err := getResourceStruct(project, "events", &events)
+	err := getResourceStruct(ctx, project, "events", &events)
 	if err != nil {
 		stdErr.Write([]byte(err.Error()))
 		return result, err
@@ -162,7 +406,7 @@ func CheckImagePullBackOff(project string, stdErr io.Writer) (Result, error) {
 	for _, event := range events.Items {
 		if event.Reason == "FailedSync" && strings.Contains(event.Message, "ImagePullBackOff") {
 			info := Info{Name: event.InvolvedObject.Name, Namespace: event.InvolvedObject.Namespace, Kind: event.Kind, Count: event.Count, Message: event.Message}
-			result.Status = 1
+			result.Status = StatusWarning
 			result.StatusMessage = "'ImagePullBackOff' error detected"
 			result.Info = append(result.Info, info)
 		}
@@ -173,10 +417,10 @@ func CheckImagePullBackOff(project string, stdErr io.Writer) (Result, error) {
 
 // CheckDeployConfigsReplicasNotZero will check all deployconfigs in the supplied project and if any have replicas set
 // to zero this will be reflected in the returned Result data. Any errors are written to the supplied stdErr writer
-func CheckDeployConfigsReplicasNotZero(project string, stdErr io.Writer) (Result, error) {
-	result := Result{Status: 0, StatusMessage: "this issue was not detected", CheckName: "check deployconfig replicas not 0"}
+func CheckDeployConfigsReplicasNotZero(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+	result := Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: "dc-replicas-zero", CheckName: "check deployconfig replicas not 0"}
 	deploymentConfigs := DeploymentConfigs{}
-	err := getResourceStruct(project, "dc", &deploymentConfigs)
+	err := getResourceStruct(ctx, project, "dc", &deploymentConfigs)
 	if err != nil {
 		stdErr.Write([]byte(err.Error()))
 		return result, err
@@ -185,7 +429,7 @@ func CheckDeployConfigsReplicasNotZero(project string, stdErr io.Writer) (Result
 	for _, deploymentConfig := range deploymentConfigs.Items {
 		if deploymentConfig.Spec.Replicas == 0 {
 			info := Info{Name: deploymentConfig.Metadata.Name, Namespace: deploymentConfig.Metadata.Namespace, Kind: deploymentConfig.Kind, Count: 1, Message: "the replica parameter is set to 0, this should be greater than 0"}
-			result.Status = 1
+			result.Status = StatusWarning
 			result.StatusMessage = "one or more deployConfig replicas are set to 0"
 			result.Info = append(result.Info, info)
 		}
@@ -193,3 +437,281 @@ func CheckDeployConfigsReplicasNotZero(project string, stdErr io.Writer) (Result
 
 	return result, nil
 }
+
+// CheckMongoBackups will check all cron jobs and jobs in the supplied project that look like MongoDB backups and if
+// the last successful backup is older than the configured threshold, a backup was not scheduled on time, recent
+// backups have failed, the claim of the backup volume is not bound or the backup volume holds no file modified
+// within the threshold, this will be reflected in the returned Result data. The files of a backup volume are listed
+// in a running pod mounting it, usually that of a backup in progress; the Result notes when no pod could list them.
+// Clusters that do not support cron jobs are checked for jobs only. Any errors are written to the supplied stdErr
+// writer
+func CheckMongoBackups(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+	result := Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: "mongodb-backups", CheckName: "check mongodb backups ran recently"}
+	var (
+		cronJobs CronJobs
+		jobs     Jobs
+		claims   PersistentVolumeClaims
+	)
+	for _, r := range []struct {
+		resource string
+		dest     interface{}
+	}{
+		{"cronjobs", &cronJobs},
+		{"jobs", &jobs},
+		{"pvc", &claims},
+	} {
+		err := getResourceStruct(ctx, project, r.resource, r.dest)
+		if err != nil && r.resource == "cronjobs" && isUnknownResourceType(err) {
+			continue
+		}
+		if err != nil {
+			stdErr.Write([]byte(err.Error()))
+			return result, err
+		}
+	}
+	result = checkMongoBackups(result, cronJobs, jobs, claims, time.Now(), *backupMaxAge)
+
+	claimNames := backupClaims(cronJobs)
+	if len(claimNames) == 0 {
+		return result, nil
+	}
+	var pods Pods
+	if err := getResourceStruct(ctx, project, "pods", &pods); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	mounts := backupVolumeMounts(claimNames, pods)
+	recent := make(map[string]bool)
+	for _, m := range mounts {
+		var out bytes.Buffer
+		cmd := ocCommand("-n", project, "exec", m.Pod, "-c", m.Container, "--",
+			"find", m.Path, "-type", "f", "-mmin", fmt.Sprintf("-%d", backupMaxAgeMinutes(*backupMaxAge)))
+		if err := runCmdCaptureOutput(ctx, cmd, &out, nil); err != nil {
+			// The volume is then reported as not listed.
+			stdErr.Write([]byte(err.Error()))
+			continue
+		}
+		recent[m.Claim] = strings.TrimSpace(out.String()) != ""
+	}
+	return checkBackupFiles(result, claimNames, mounts, recent, *backupMaxAge), nil
+}
+
+// backupClaims returns the names of the claims of the volumes of the backup
+// cron jobs, mapped to a cron job using each.
+func backupClaims(cronJobs CronJobs) map[string]CronJob {
+	claims := make(map[string]CronJob)
+	for _, cronJob := range cronJobs.Items {
+		if !isBackup(cronJob.Metadata.Name) {
+			continue
+		}
+		for _, v := range cronJob.Spec.JobTemplate.Spec.Template.Spec.Volumes {
+			if v.PersistentVolumeClaim != nil {
+				claims[v.PersistentVolumeClaim.ClaimName] = cronJob
+			}
+		}
+	}
+	return claims
+}
+
+// A backupVolumeMount is a backup volume mounted in a running pod, in which its
+// files can be listed.
+type backupVolumeMount struct {
+	Claim     string
+	Pod       string
+	Container string
+	Path      string
+}
+
+// backupVolumeMounts returns a running pod mounting each of the given claims,
+// if any, sorted by claim.
+func backupVolumeMounts(claims map[string]CronJob, pods Pods) []backupVolumeMount {
+	found := make(map[string]backupVolumeMount)
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != "Running" {
+			continue
+		}
+		for _, v := range pod.Spec.Volumes {
+			if v.PersistentVolumeClaim == nil {
+				continue
+			}
+			claim := v.PersistentVolumeClaim.ClaimName
+			if _, ok := claims[claim]; !ok {
+				continue
+			}
+			if _, ok := found[claim]; ok {
+				continue
+			}
+			for _, c := range pod.Spec.Containers {
+				for _, m := range c.VolumeMounts {
+					if m.Name == v.Name && m.MountPath != "" {
+						found[claim] = backupVolumeMount{Claim: claim, Pod: pod.Metadata.Name, Container: c.Name, Path: m.MountPath}
+					}
+				}
+			}
+		}
+	}
+	var mounts []backupVolumeMount
+	for _, m := range found {
+		mounts = append(mounts, m)
+	}
+	sort.Slice(mounts, func(i, j int) bool { return mounts[i].Claim < mounts[j].Claim })
+	return mounts
+}
+
+// backupMaxAgeMinutes returns maxAge in whole minutes, at least one, as find
+// -mmin expects.
+func backupMaxAgeMinutes(maxAge time.Duration) int {
+	if minutes := int((maxAge + time.Minute - 1) / time.Minute); minutes > 1 {
+		return minutes
+	}
+	return 1
+}
+
+// checkBackupFiles reports in result the backup volumes, of the given claims,
+// whose files were listed in mounts and hold no file modified within maxAge,
+// as recorded in recent by claim. If the files of some volumes could not be
+// listed and no issue was found, the status message of result says so.
+func checkBackupFiles(result Result, claims map[string]CronJob, mounts []backupVolumeMount, recent map[string]bool, maxAge time.Duration) Result {
+	for _, m := range mounts {
+		if ok, listed := recent[m.Claim]; !listed || ok {
+			continue
+		}
+		result.Status = StatusWarning
+		result.StatusMessage = "one or more mongodb backup volumes hold no recent backup"
+		cronJob := claims[m.Claim]
+		result.Info = append(result.Info, Info{Name: m.Claim, Namespace: cronJob.Metadata.Namespace, Kind: "PersistentVolumeClaim", Count: 1,
+			Message: fmt.Sprintf("the backup volume of the cron job %s has no file modified within %v", cronJob.Metadata.Name, maxAge)})
+	}
+	if len(recent) < len(claims) && result.Status == StatusOK {
+		result.StatusMessage = "this issue was not detected, but the files of one or more backup volumes were not listed, as no running pod mounts them"
+	}
+	return result
+}
+
+func isBackup(name string) bool {
+	return strings.Contains(strings.ToLower(name), "backup")
+}
+
+// isUnknownResourceType reports whether err is the error of oc for a resource
+// type the cluster does not support.
+func isUnknownResourceType(err error) bool {
+	return strings.Contains(err.Error(), "doesn't have a resource type")
+}
+
+// lastCronJobSuccess returns the last time a job of cronJob succeeded, taken
+// from its status or, on clusters older than Kubernetes 1.21 that do not
+// record it, from the jobs it owns. ok is false if neither tells whether a job
+// of cronJob ever succeeded.
+func lastCronJobSuccess(cronJob CronJob, jobs Jobs) (last time.Time, ok bool) {
+	last = cronJob.Status.LastSuccessfulTime
+	ok = !last.IsZero()
+	for _, job := range jobs.Items {
+		owned := false
+		for _, owner := range job.Metadata.OwnerReferences {
+			if owner.Kind == "CronJob" && owner.Name == cronJob.Metadata.Name {
+				owned = true
+			}
+		}
+		if !owned {
+			continue
+		}
+		ok = true
+		if job.Status.Succeeded > 0 && job.Status.CompletionTime.After(last) {
+			last = job.Status.CompletionTime
+		}
+	}
+	return last, ok
+}
+
+func checkMongoBackups(result Result, cronJobs CronJobs, jobs Jobs, claims PersistentVolumeClaims, now time.Time, maxAge time.Duration) Result {
+	var (
+		lastGood time.Time
+		backups  int
+		// known counts the backups telling whether they ever
+		// succeeded.
+		known int
+	)
+	warn := func(info Info, message string) {
+		result.Status = StatusWarning
+		result.StatusMessage = message
+		result.Info = append(result.Info, info)
+	}
+
+	for _, job := range jobs.Items {
+		if !isBackup(job.Metadata.Name) {
+			continue
+		}
+		backups++
+		known++
+		if job.Status.Succeeded > 0 && job.Status.CompletionTime.After(lastGood) {
+			lastGood = job.Status.CompletionTime
+		}
+	}
+	for _, cronJob := range cronJobs.Items {
+		if !isBackup(cronJob.Metadata.Name) {
+			continue
+		}
+		backups++
+		last, ok := lastCronJobSuccess(cronJob, jobs)
+		if ok {
+			known++
+		}
+		if last.After(lastGood) {
+			lastGood = last
+		}
+	}
+	if backups == 0 {
+		return result
+	}
+
+	for _, job := range jobs.Items {
+		if !isBackup(job.Metadata.Name) {
+			continue
+		}
+		if job.Status.Failed > 0 && job.Status.StartTime.After(lastGood) {
+			info := Info{Name: job.Metadata.Name, Namespace: job.Metadata.Namespace, Kind: job.Kind, Count: job.Status.Failed, Message: "the backup job failed after the last successful backup"}
+			warn(info, "one or more mongodb backups failed")
+		}
+	}
+
+	bound := make(map[string]bool)
+	for _, claim := range claims.Items {
+		bound[claim.Metadata.Name] = claim.Status.Phase == "Bound"
+	}
+	for _, cronJob := range cronJobs.Items {
+		if !isBackup(cronJob.Metadata.Name) {
+			continue
+		}
+		info := Info{Name: cronJob.Metadata.Name, Namespace: cronJob.Metadata.Namespace, Kind: cronJob.Kind, Count: 1}
+		status := cronJob.Status
+		lastSuccess, known := lastCronJobSuccess(cronJob, jobs)
+		switch {
+		case cronJob.Spec.Suspend:
+			info.Message = "the backup cron job is suspended"
+			warn(info, "one or more mongodb backups are not scheduled")
+		case status.LastScheduleTime.IsZero() || now.Sub(status.LastScheduleTime) > maxAge:
+			info.Message = fmt.Sprintf("the backup cron job with schedule %q was not scheduled within %v", cronJob.Spec.Schedule, maxAge)
+			warn(info, "one or more mongodb backups missed their schedule")
+		case len(status.Active) == 0 && known && status.LastScheduleTime.After(lastSuccess):
+			info.Message = "the last backup scheduled by the cron job did not succeed"
+			warn(info, "one or more mongodb backups failed")
+		}
+		for _, v := range cronJob.Spec.JobTemplate.Spec.Template.Spec.Volumes {
+			if v.PersistentVolumeClaim == nil || bound[v.PersistentVolumeClaim.ClaimName] {
+				continue
+			}
+			info := Info{Name: v.PersistentVolumeClaim.ClaimName, Namespace: cronJob.Metadata.Namespace, Kind: "PersistentVolumeClaim", Count: 1, Message: fmt.Sprintf("the backup volume %q of the cron job %s is missing or not bound", v.Name, cronJob.Metadata.Name)}
+			warn(info, "one or more mongodb backup volumes are not available")
+		}
+	}
+
+	if lastGood.IsZero() && known > 0 {
+		result.Status = StatusWarning
+		result.StatusMessage = "no successful mongodb backup was found"
+	} else if age := now.Sub(lastGood); !lastGood.IsZero() && age > maxAge {
+		result.Status = StatusWarning
+		result.StatusMessage = fmt.Sprintf("the last successful mongodb backup completed %v ago, which is older than %v", age/time.Minute*time.Minute, maxAge)
+	}
+
+	return result
+}
@@ -2,10 +2,17 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"io/ioutil"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/feedhenry/fh-system-dump-tool/cmdrunner"
 )
 
 var b *bytes.Buffer
@@ -21,13 +28,13 @@ func mockCheckFactoryOnePass() []CheckTask {
 	return []CheckTask{mockTestOne}
 }
 
-func mockTestOne(project string, stdErr io.Writer) (Result, error) {
+func mockTestOne(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
 	result := Result{StatusMessage: "Called mockTestOne"}
 	return result, nil
 
 }
 
-func mockTestTwo(project string, stdErr io.Writer) (Result, error) {
+func mockTestTwo(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
 	result := Result{StatusMessage: "Called mockTestTwo"}
 	return result, errors.New("FAIL")
 }
@@ -35,7 +42,7 @@ func mockTestTwo(project string, stdErr io.Writer) (Result, error) {
 func TestCheckTasks(t *testing.T) {
 	b = bytes.NewBuffer([]byte{})
 	task := checkTasks(mockCheckFactoryOnePassOneFail, "MockProject", mockOutFor, mockOutFor)
-	err := task()
+	err := task(context.Background())
 
 	if err == nil {
 		t.Fatal("Expected error")
@@ -48,7 +55,7 @@ func TestCheckTasks(t *testing.T) {
 
 	b = bytes.NewBuffer([]byte{})
 	task = checkTasks(mockCheckFactoryOnePass, "MockProject", mockOutFor, mockOutFor)
-	err = task()
+	err = task(context.Background())
 
 	if err != nil {
 		t.Fatal("Expected no errors")
@@ -60,3 +67,292 @@ func TestCheckTasks(t *testing.T) {
 	}
 
 }
+
+func TestRunCheck(t *testing.T) {
+	panics := func(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+		var pods *Pods
+		return Result{CheckID: "panics"}, errors.New(pods.Items[0].Metadata.Name)
+	}
+	hangs := func(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+		time.Sleep(time.Second)
+		return Result{CheckID: "hangs"}, nil
+	}
+	for _, check := range []CheckTask{panics, hangs} {
+		var stdErr bytes.Buffer
+		res, err := runCheck(context.Background(), check, "MockProject", &stdErr, 10*time.Millisecond)
+		if err == nil {
+			t.Errorf("runCheck() didn't return an error")
+		}
+		if res.CheckID != "check-crashed" || res.Status != StatusWarning {
+			t.Errorf("runCheck() = %+v, want a check-crashed warning", res)
+		}
+	}
+
+	res, err := runCheck(context.Background(), mockTestTwo, "MockProject", ioutil.Discard, time.Second)
+	if err == nil || res.StatusMessage != "Called mockTestTwo" {
+		t.Errorf("runCheck(mockTestTwo) = %+v, %v, want the result and error of the check", res, err)
+	}
+}
+
+func TestCheckMongoBackups(t *testing.T) {
+	now := time.Date(2016, 9, 1, 12, 0, 0, 0, time.UTC)
+	job := func(name string, start, completion time.Time, succeeded, failed int) Job {
+		var j Job
+		j.Kind = "Job"
+		j.Metadata.Name = name
+		j.Status.StartTime = start
+		j.Status.CompletionTime = completion
+		j.Status.Succeeded = succeeded
+		j.Status.Failed = failed
+		return j
+	}
+	tests := []struct {
+		jobs       []Job
+		wantStatus int
+		wantInfo   int
+	}{
+		{jobs: nil, wantStatus: StatusOK},
+		{jobs: []Job{job("unrelated", now.Add(-72*time.Hour), time.Time{}, 0, 1)}, wantStatus: StatusOK},
+		{jobs: []Job{job("mongodb-backup-1", now.Add(-2*time.Hour), now.Add(-1*time.Hour), 1, 0)}, wantStatus: StatusOK},
+		{jobs: []Job{job("mongodb-backup-1", now.Add(-49*time.Hour), now.Add(-48*time.Hour), 1, 0)}, wantStatus: StatusWarning},
+		{jobs: []Job{job("mongodb-backup-1", now.Add(-2*time.Hour), time.Time{}, 0, 1)}, wantStatus: StatusWarning, wantInfo: 1},
+		{
+			jobs: []Job{
+				job("mongodb-backup-1", now.Add(-26*time.Hour), now.Add(-25*time.Hour), 1, 0),
+				job("mongodb-backup-2", now.Add(-2*time.Hour), time.Time{}, 0, 3),
+			},
+			wantStatus: StatusWarning,
+			wantInfo:   1,
+		},
+	}
+	for i, tt := range tests {
+		got := checkMongoBackups(Result{Status: StatusOK}, CronJobs{}, Jobs{Items: tt.jobs}, PersistentVolumeClaims{}, now, 24*time.Hour)
+		if got.Status != tt.wantStatus {
+			t.Errorf("%d: Status = %d, want %d (%s)", i, got.Status, tt.wantStatus, got.StatusMessage)
+		}
+		if len(got.Info) != tt.wantInfo {
+			t.Errorf("%d: len(Info) = %d, want %d", i, len(got.Info), tt.wantInfo)
+		}
+	}
+}
+
+func TestCheckMongoBackupsCronJobs(t *testing.T) {
+	now := time.Date(2016, 9, 1, 12, 0, 0, 0, time.UTC)
+	claims := PersistentVolumeClaims{}
+	if err := json.Unmarshal([]byte(`{"items": [{"metadata": {"name": "mongodb-backup"}, "status": {"phase": "Bound"}}, {"metadata": {"name": "pending"}, "status": {"phase": "Pending"}}]}`), &claims); err != nil {
+		t.Fatal(err)
+	}
+	cronJob := func(lastSchedule, lastSuccess time.Time, claim string) CronJob {
+		var c CronJob
+		c.Kind = "CronJob"
+		c.Metadata.Name = "mongodb-backup"
+		c.Spec.Schedule = "0 1 * * *"
+		c.Spec.JobTemplate.Spec.Template.Spec.Volumes = []Volume{{Name: "backups"}}
+		c.Spec.JobTemplate.Spec.Template.Spec.Volumes[0].PersistentVolumeClaim = &struct {
+			ClaimName string `json:"claimName"`
+		}{ClaimName: claim}
+		c.Status.LastScheduleTime = lastSchedule
+		c.Status.LastSuccessfulTime = lastSuccess
+		return c
+	}
+	suspended := cronJob(now.Add(-2*time.Hour), now.Add(-2*time.Hour), "mongodb-backup")
+	suspended.Spec.Suspend = true
+	running := cronJob(now.Add(-1*time.Hour), now.Add(-25*time.Hour), "mongodb-backup")
+	running.Status.Active = append(running.Status.Active, struct {
+		Name string `json:"name"`
+	}{Name: "mongodb-backup-2"})
+	tests := []struct {
+		description string
+		cronJob     CronJob
+		wantStatus  int
+		wantInfo    []string
+	}{
+		{
+			description: "recent successful backup",
+			cronJob:     cronJob(now.Add(-2*time.Hour), now.Add(-2*time.Hour), "mongodb-backup"),
+			wantStatus:  StatusOK,
+		},
+		{
+			description: "missed schedule",
+			cronJob:     cronJob(now.Add(-30*time.Hour), now.Add(-30*time.Hour), "mongodb-backup"),
+			wantStatus:  StatusWarning,
+			wantInfo:    []string{"mongodb-backup"},
+		},
+		{
+			description: "failed last run",
+			cronJob:     cronJob(now.Add(-1*time.Hour), now.Add(-5*time.Hour), "mongodb-backup"),
+			wantStatus:  StatusWarning,
+			wantInfo:    []string{"mongodb-backup"},
+		},
+		{
+			description: "last run still active",
+			cronJob:     running,
+			wantStatus:  StatusWarning,
+		},
+		{
+			description: "suspended",
+			cronJob:     suspended,
+			wantStatus:  StatusWarning,
+			wantInfo:    []string{"mongodb-backup"},
+		},
+		{
+			description: "backup volume not bound",
+			cronJob:     cronJob(now.Add(-2*time.Hour), now.Add(-2*time.Hour), "pending"),
+			wantStatus:  StatusWarning,
+			wantInfo:    []string{"pending"},
+		},
+		{
+			description: "backup volume missing",
+			cronJob:     cronJob(now.Add(-2*time.Hour), now.Add(-2*time.Hour), "missing"),
+			wantStatus:  StatusWarning,
+			wantInfo:    []string{"missing"},
+		},
+	}
+	for _, tt := range tests {
+		got := checkMongoBackups(Result{Status: StatusOK}, CronJobs{Items: []CronJob{tt.cronJob}}, Jobs{}, claims, now, 24*time.Hour)
+		if got.Status != tt.wantStatus {
+			t.Errorf("%s: Status = %d, want %d (%s)", tt.description, got.Status, tt.wantStatus, got.StatusMessage)
+		}
+		var names []string
+		for _, info := range got.Info {
+			names = append(names, info.Name)
+		}
+		if !reflect.DeepEqual(names, tt.wantInfo) {
+			t.Errorf("%s: Info names = %v, want %v", tt.description, names, tt.wantInfo)
+		}
+	}
+}
+
+// Clusters older than Kubernetes 1.21 do not record the last successful time of
+// cron jobs, which is then taken from the jobs they own.
+func TestCheckMongoBackupsCronJobsWithoutLastSuccessfulTime(t *testing.T) {
+	now := time.Date(2016, 9, 1, 12, 0, 0, 0, time.UTC)
+	cronJob := func() CronJob {
+		var c CronJob
+		c.Kind = "CronJob"
+		c.Metadata.Name = "mongodb-backup"
+		c.Spec.Schedule = "0 1 * * *"
+		c.Status.LastScheduleTime = now.Add(-2 * time.Hour)
+		return c
+	}
+	job := func(succeeded, failed int) Job {
+		var j Job
+		data := `{"kind": "Job", "metadata": {"name": "mongodb-backup-1472688000", "ownerReferences": [{"kind": "CronJob", "name": "mongodb-backup"}]}}`
+		if err := json.Unmarshal([]byte(data), &j); err != nil {
+			t.Fatal(err)
+		}
+		j.Status.StartTime = now.Add(-2 * time.Hour)
+		if succeeded > 0 {
+			j.Status.CompletionTime = now.Add(-1 * time.Hour)
+		}
+		j.Status.Succeeded = succeeded
+		j.Status.Failed = failed
+		return j
+	}
+	tests := []struct {
+		description string
+		jobs        []Job
+		wantStatus  int
+		wantInfo    []string
+	}{
+		{
+			description: "no jobs left",
+			wantStatus:  StatusOK,
+		},
+		{
+			description: "job succeeded",
+			jobs:        []Job{job(1, 0)},
+			wantStatus:  StatusOK,
+		},
+		{
+			description: "job failed",
+			jobs:        []Job{job(0, 1)},
+			wantStatus:  StatusWarning,
+			wantInfo:    []string{"mongodb-backup-1472688000", "mongodb-backup"},
+		},
+	}
+	for _, tt := range tests {
+		got := checkMongoBackups(Result{Status: StatusOK}, CronJobs{Items: []CronJob{cronJob()}}, Jobs{Items: tt.jobs}, PersistentVolumeClaims{}, now, 24*time.Hour)
+		if got.Status != tt.wantStatus {
+			t.Errorf("%s: Status = %d, want %d (%s)", tt.description, got.Status, tt.wantStatus, got.StatusMessage)
+		}
+		var names []string
+		for _, info := range got.Info {
+			names = append(names, info.Name)
+		}
+		if !reflect.DeepEqual(names, tt.wantInfo) {
+			t.Errorf("%s: Info names = %v, want %v", tt.description, names, tt.wantInfo)
+		}
+	}
+}
+
+func TestCheckBackupFiles(t *testing.T) {
+	var cronJobs CronJobs
+	if err := json.Unmarshal([]byte(`{"items": [{"metadata": {"name": "mongodb-backup", "namespace": "core"}, "spec": {"jobTemplate": {"spec": {"template": {"spec": {"volumes": [{"name": "backups", "persistentVolumeClaim": {"claimName": "mongodb-backup"}}]}}}}}}]}`), &cronJobs); err != nil {
+		t.Fatal(err)
+	}
+	var pods Pods
+	if err := json.Unmarshal([]byte(`{"items": [
+		{"metadata": {"name": "mongodb-backup-1-done"}, "spec": {"volumes": [{"name": "backups", "persistentVolumeClaim": {"claimName": "mongodb-backup"}}], "containers": [{"name": "backup", "volumeMounts": [{"name": "backups", "mountPath": "/old"}]}]}, "status": {"phase": "Succeeded"}},
+		{"metadata": {"name": "mongodb-backup-2-run"}, "spec": {"volumes": [{"name": "data", "persistentVolumeClaim": {"claimName": "mongodb-backup"}}], "containers": [{"name": "backup", "volumeMounts": [{"name": "data", "mountPath": "/backups"}]}]}, "status": {"phase": "Running"}}
+	]}`), &pods); err != nil {
+		t.Fatal(err)
+	}
+	claims := backupClaims(cronJobs)
+	mounts := backupVolumeMounts(claims, pods)
+	if want := []backupVolumeMount{{Claim: "mongodb-backup", Pod: "mongodb-backup-2-run", Container: "backup", Path: "/backups"}}; !reflect.DeepEqual(mounts, want) {
+		t.Fatalf("backupVolumeMounts() = %+v, want %+v", mounts, want)
+	}
+
+	ok := Result{Status: StatusOK, StatusMessage: "this issue was not detected"}
+	if got := checkBackupFiles(ok, claims, mounts, map[string]bool{"mongodb-backup": true}, 24*time.Hour); !reflect.DeepEqual(got, ok) {
+		t.Errorf("checkBackupFiles() with a recent backup = %+v, want %+v", got, ok)
+	}
+	got := checkBackupFiles(ok, claims, mounts, map[string]bool{"mongodb-backup": false}, 24*time.Hour)
+	if got.Status != StatusWarning || len(got.Info) != 1 || got.Info[0].Name != "mongodb-backup" || got.Info[0].Namespace != "core" {
+		t.Errorf("checkBackupFiles() without a recent backup = %+v, want a warning about mongodb-backup", got)
+	}
+	got = checkBackupFiles(ok, claims, nil, map[string]bool{}, 24*time.Hour)
+	if got.Status != StatusOK || !strings.Contains(got.StatusMessage, "not listed") {
+		t.Errorf("checkBackupFiles() without listing = %+v, want OK noting the volume was not listed", got)
+	}
+}
+
+func TestCheckMongoBackupsWithoutCronJobs(t *testing.T) {
+	defer func(r cmdrunner.Runner) { runner = r }(runner)
+	runner = exitingRunner{cmdrunner.NewFakeRunner([]cmdrunner.Invocation{
+		{Args: ocCommand("-n", "rhmap-core", "get", "cronjobs", "-o=json").Args, Stderr: `error: the server doesn't have a resource type "cronjobs"`, Error: "exit status 1"},
+		{Args: ocCommand("-n", "rhmap-core", "get", "jobs", "-o=json").Args, Stdout: `{"items": []}`},
+		{Args: ocCommand("-n", "rhmap-core", "get", "pvc", "-o=json").Args, Stdout: `{"items": []}`},
+	})}
+	var stdErr bytes.Buffer
+	result, err := CheckMongoBackups(context.Background(), "rhmap-core", &stdErr)
+	if err != nil {
+		t.Fatalf("CheckMongoBackups() = %v, want no error when cron jobs are not supported", err)
+	}
+	if result.Status != StatusOK || stdErr.Len() != 0 {
+		t.Errorf("CheckMongoBackups() = %+v, stderr %q, want no issue", result, stdErr.String())
+	}
+}
+
+func TestCheckMongoBackupsListsBackupVolume(t *testing.T) {
+	defer func(r cmdrunner.Runner) { runner = r }(runner)
+	defer func(d time.Duration) { *backupMaxAge = d }(*backupMaxAge)
+	*backupMaxAge = 24 * time.Hour
+	now := time.Now().UTC().Format(time.RFC3339)
+	runner = exitingRunner{cmdrunner.NewFakeRunner([]cmdrunner.Invocation{
+		{Args: ocCommand("-n", "rhmap-core", "get", "cronjobs", "-o=json").Args, Stdout: `{"items": [{"kind": "CronJob", "metadata": {"name": "mongodb-backup"}, "spec": {"jobTemplate": {"spec": {"template": {"spec": {"volumes": [{"name": "backups", "persistentVolumeClaim": {"claimName": "mongodb-backup"}}]}}}}}, "status": {"lastScheduleTime": "` + now + `", "lastSuccessfulTime": "` + now + `"}}]}`},
+		{Args: ocCommand("-n", "rhmap-core", "get", "jobs", "-o=json").Args, Stdout: `{"items": []}`},
+		{Args: ocCommand("-n", "rhmap-core", "get", "pvc", "-o=json").Args, Stdout: `{"items": [{"metadata": {"name": "mongodb-backup"}, "status": {"phase": "Bound"}}]}`},
+		{Args: ocCommand("-n", "rhmap-core", "get", "pods", "-o=json").Args, Stdout: `{"items": [{"metadata": {"name": "mongodb-backup-1"}, "spec": {"volumes": [{"name": "backups", "persistentVolumeClaim": {"claimName": "mongodb-backup"}}], "containers": [{"name": "backup", "volumeMounts": [{"name": "backups", "mountPath": "/backups"}]}]}, "status": {"phase": "Running"}}]}`},
+		{Args: ocCommand("-n", "rhmap-core", "exec", "mongodb-backup-1", "-c", "backup", "--", "find", "/backups", "-type", "f", "-mmin", "-1440").Args, Stdout: ""},
+	})}
+	var stdErr bytes.Buffer
+	result, err := CheckMongoBackups(context.Background(), "rhmap-core", &stdErr)
+	if err != nil {
+		t.Fatalf("CheckMongoBackups() = %v, stderr %q", err, stdErr.String())
+	}
+	if result.Status != StatusWarning || len(result.Info) != 1 || result.Info[0].Kind != "PersistentVolumeClaim" {
+		t.Errorf("CheckMongoBackups() = %+v, want a warning about the backup volume", result)
+	}
+}
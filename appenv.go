@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/feedhenry/fh-system-dump-tool/dumpformat"
+)
+
+// defaultAppEnvBuilds is the default number of builds whose logs are fetched
+// by the app-env subcommand.
+const defaultAppEnvBuilds = 3
+
+var (
+	// appEnvResources are the types of resources whose definitions are
+	// collected by the app-env subcommand. Events are among them.
+	appEnvResources = []string{"deploymentconfigs", "buildconfigs", "builds", "pods", "services", "routes", "events"}
+	// appEnvChecks are the analysis checks run by the app-env subcommand,
+	// those about the routes of the environment.
+	appEnvChecks = []CheckTask{CheckAdminRoutesExposed, CheckStickySessions}
+)
+
+type Build struct {
+	Metadata struct {
+		Name              string    `json:"name"`
+		CreationTimestamp time.Time `json:"creationTimestamp"`
+	} `json:"metadata"`
+}
+
+type Builds struct {
+	Items []Build `json:"items"`
+}
+
+// recentBuilds returns the names of the n most recently created builds, most
+// recent first.
+func recentBuilds(builds Builds, n int) []string {
+	items := append([]Build(nil), builds.Items...)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Metadata.CreationTimestamp.After(items[j].Metadata.CreationTimestamp)
+	})
+	var names []string
+	for i := 0; i < len(items) && i < n; i++ {
+		names = append(names, items[i].Metadata.Name)
+	}
+	return names
+}
+
+// GetAppEnvTasks returns a list of tasks collecting a compact picture of the
+// app environment project: the definitions of its resources and its events,
+// the logs of its last builds and the results of the checks of its routes.
+func GetAppEnvTasks(ctx context.Context, project string, builds int, tarFile *Archive) ([]Task, error) {
+	if err := getResourceStruct(ctx, project, "project/"+project, &struct{}{}); err != nil {
+		return nil, err
+	}
+	var list Builds
+	if err := getResourceStruct(ctx, project, "builds", &list); err != nil {
+		return nil, err
+	}
+
+	// Add tasks to fetch resource definitions, events included.
+	tasks, err := GetResourceDefinitionsTasks([]string{project}, appEnvResources, "", false, tarFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add tasks to fetch the logs of the last builds.
+	for _, name := range recentBuilds(list, builds) {
+		r := LoggableResource{Project: project, Type: "build", Name: name}
+		task := func(ctx context.Context) error {
+			out, outCloser, err := outToTGZ("logs", "logs", tarFile)(project, "build-"+r.Name)
+			if err != nil {
+				return err
+			}
+			defer outCloser.Close()
+			errOut, errOutCloser, err := outToTGZ("logs", "stderr", tarFile)(project, "build-"+r.Name)
+			if err != nil {
+				return err
+			}
+			defer errOutCloser.Close()
+			return fetchLogsMaybeDeduped(ctx, FetchLogs, r, out, errOut)
+		}
+		tasks = append(tasks, namedTask("fetch logs of "+r.describe(), project, task))
+	}
+
+	// Add tasks to record the load balancing configuration of routes and
+	// to check them.
+	tasks = append(tasks, GetRouteBalancingTasks([]string{project}, tarFile)...)
+	outFor := outToTGZ("definitions", "json", tarFile)
+	errOutFor := outToTGZ("definitions", "stderr", tarFile)
+	checks := checkTasks(func() []CheckTask { return appEnvChecks }, project, outFor, errOutFor)
+	tasks = append(tasks, namedTask("run route checks", project, checks))
+	return tasks, nil
+}
+
+// appEnvCommand collects a compact dump of a single app environment project,
+// small enough to be sent by email.
+func appEnvCommand(args []string) error {
+	fs := flag.NewFlagSet("app-env", flag.ExitOnError)
+	dir := fs.String("dir", filepath.Join(dumpDir, "app-envs"), "directory to write the mini-dump to")
+	builds := fs.Int("builds", defaultAppEnvBuilds, "number of most recent builds whose logs are fetched")
+	fs.Parse(args)
+	if fs.NArg() != 1 || fs.Arg(0) == "" {
+		return fmt.Errorf("usage: app-env [-dir dir] [-builds n] project")
+	}
+	project := fs.Arg(0)
+	metadata := dumpformat.Metadata{Projects: []string{project}, Resources: appEnvResources}
+	return writeMiniDump(*dir, project, metadata, func(ctx context.Context, tarFile *Archive) ([]Task, error) {
+		return GetAppEnvTasks(ctx, project, *builds, tarFile)
+	})
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/feedhenry/fh-system-dump-tool/cmdrunner"
+)
+
+func TestRecentBuilds(t *testing.T) {
+	var builds Builds
+	content := `{"items": [
+		{"metadata": {"name": "app-1", "creationTimestamp": "2016-09-01T10:00:00Z"}},
+		{"metadata": {"name": "app-3", "creationTimestamp": "2016-09-01T12:00:00Z"}},
+		{"metadata": {"name": "app-2", "creationTimestamp": "2016-09-01T11:00:00Z"}}
+	]}`
+	if err := json.Unmarshal([]byte(content), &builds); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := recentBuilds(builds, 2), []string{"app-3", "app-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("recentBuilds(2) = %v, want %v", got, want)
+	}
+	if got := recentBuilds(builds, 5); len(got) != 3 {
+		t.Errorf("recentBuilds(5) = %v, want all 3 builds", got)
+	}
+}
+
+func TestGetAppEnvTasks(t *testing.T) {
+	defer func(old cmdrunner.Runner) { runner = old }(runner)
+	builds := `{"items": [{"metadata": {"name": "app-1"}}, {"metadata": {"name": "app-2"}}]}`
+	runner = cmdrunner.NewFakeRunner([]cmdrunner.Invocation{
+		{Args: ocCommand("-n", "dev", "get", "project/dev", "-o=json").Args, Stdout: "{}"},
+		{Args: ocCommand("-n", "dev", "get", "builds", "-o=json").Args, Stdout: builds},
+	})
+
+	var b bytes.Buffer
+	tarFile, err := NewTgz(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := GetAppEnvTasks(context.Background(), "dev", 1, tarFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Definitions, the logs of the last build, the route balancing
+	// configuration and the route checks.
+	if got, want := len(tasks), 1+1+1+1; got != want {
+		t.Errorf("GetAppEnvTasks() returned %d tasks, want %d", got, want)
+	}
+
+	// Missing projects have nothing to collect.
+	if _, err := GetAppEnvTasks(context.Background(), "missing", 1, tarFile); err == nil {
+		t.Error("GetAppEnvTasks() of a missing project didn't return an error")
+	}
+}
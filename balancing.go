@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Route annotations affecting how the router balances requests across the
+// pods of a route, and whether sessions stick to a pod.
+const (
+	balanceAnnotation        = "haproxy.router.openshift.io/balance"
+	disableCookiesAnnotation = "haproxy.router.openshift.io/disable_cookies"
+	cookieNameAnnotation     = "router.openshift.io/cookie_name"
+	timeoutAnnotation        = "haproxy.router.openshift.io/timeout"
+)
+
+var balancingAnnotations = []string{balanceAnnotation, disableCookiesAnnotation, cookieNameAnnotation, timeoutAnnotation}
+
+// stickyServices are the services of RHMAP components that keep session state
+// in memory, and require requests of a session to reach the same pod.
+var stickyServices = []string{"fh-ngui", "millicore"}
+
+// A RouteBalancing is the load balancing and session affinity configuration
+// of a route.
+type RouteBalancing struct {
+	Name        string            `json:"name"`
+	Host        string            `json:"host"`
+	Termination string            `json:"termination,omitempty"`
+	Backends    []RouteBackend    `json:"backends"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// routeBalancing returns the load balancing configuration of route.
+func routeBalancing(route Route) RouteBalancing {
+	b := RouteBalancing{
+		Name:        route.Metadata.Name,
+		Host:        route.Spec.Host,
+		Backends:    append([]RouteBackend{route.Spec.To}, route.Spec.AlternateBackends...),
+		Annotations: make(map[string]string),
+	}
+	if route.Spec.TLS != nil {
+		b.Termination = route.Spec.TLS.Termination
+	}
+	for _, a := range balancingAnnotations {
+		if v, ok := route.Metadata.Annotations[a]; ok {
+			b.Annotations[a] = v
+		}
+	}
+	return b
+}
+
+// GetRouteBalancingTasks returns a list of tasks to record the load balancing
+// and session affinity configuration of the routes in each project.
+func GetRouteBalancingTasks(projects []string, tarFile *Archive) []Task {
+	var tasks []Task
+	for _, p := range projects {
+		p := p
+		task := func(ctx context.Context) error {
+			var routes Routes
+			if err := getResourceStruct(ctx, p, "routes", &routes); err != nil {
+				return err
+			}
+			balancing := []RouteBalancing{}
+			for _, r := range routes.Items {
+				balancing = append(balancing, routeBalancing(r))
+			}
+			output, err := json.MarshalIndent(balancing, "", "    ")
+			if err != nil {
+				return err
+			}
+			out := tarFile.GetWriterToFile(filepath.Join("routes", p+".json"))
+			if _, err := out.Write(output); err != nil {
+				out.Close()
+				return err
+			}
+			return out.Close()
+		}
+		tasks = append(tasks, namedTask("record route balancing config", p, task))
+	}
+	return tasks
+}
+
+// isStickyRoute reports whether the route sends traffic to a component that
+// requires sticky sessions.
+func isStickyRoute(route Route) bool {
+	for _, b := range append([]RouteBackend{route.Spec.To}, route.Spec.AlternateBackends...) {
+		if containsString(stickyServices, b.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// stickinessProblem returns why the router doesn't keep the sessions of route
+// on one pod, if it doesn't.
+func stickinessProblem(route Route) (string, bool) {
+	balance := route.Metadata.Annotations[balanceAnnotation]
+	// Without cookies, only source balancing sends a client to the same
+	// pod every time.
+	if balance == "source" {
+		return "", false
+	}
+	if strings.EqualFold(route.Metadata.Annotations[disableCookiesAnnotation], "true") {
+		return fmt.Sprintf("session cookies are disabled and requests are balanced with %s", balanceName(balance)), true
+	}
+	// The router cannot set cookies on connections it doesn't terminate.
+	if route.Spec.TLS != nil && route.Spec.TLS.Termination == "passthrough" && balance != "" {
+		return fmt.Sprintf("TLS is passed through, so no session cookies are set, and requests are balanced with %s", balance), true
+	}
+	return "", false
+}
+
+func balanceName(balance string) string {
+	if balance == "" {
+		return "the router default"
+	}
+	return balance
+}
+
+// CheckStickySessions will check all routes in the supplied project that send traffic to components requiring sticky
+// sessions, and if the router doesn't keep their sessions on one pod this will be reflected in the returned Result
+// data. Any errors are written to the supplied stdErr writer
+func CheckStickySessions(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+	result := Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: "sticky-sessions-disabled", CheckName: "check routes of stateful components use sticky sessions"}
+	var routes Routes
+	if err := getResourceStruct(ctx, project, "routes", &routes); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	return checkStickySessions(result, routes), nil
+}
+
+func checkStickySessions(result Result, routes Routes) Result {
+	for _, route := range routes.Items {
+		if !isStickyRoute(route) {
+			continue
+		}
+		if problem, ok := stickinessProblem(route); ok {
+			result.Status = StatusWarning
+			result.StatusMessage = "one or more routes of components requiring sticky sessions don't use them"
+			result.Info = append(result.Info, Info{Name: route.Metadata.Name, Namespace: route.Metadata.Namespace, Kind: route.Kind, Count: 1,
+				Message: problem + ", but " + route.Spec.To.Name + " requires sticky sessions"})
+		}
+	}
+	return result
+}
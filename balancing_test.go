@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCheckStickySessions(t *testing.T) {
+	var routes Routes
+	if err := json.Unmarshal([]byte(`{"items": [
+		{"kind": "Route", "metadata": {"name": "rhmap", "annotations": {"haproxy.router.openshift.io/disable_cookies": "true", "haproxy.router.openshift.io/balance": "roundrobin"}},
+		 "spec": {"to": {"kind": "Service", "name": "fh-ngui"}}},
+		{"kind": "Route", "metadata": {"name": "rhmap-source", "annotations": {"haproxy.router.openshift.io/disable_cookies": "true", "haproxy.router.openshift.io/balance": "source"}},
+		 "spec": {"to": {"kind": "Service", "name": "fh-ngui"}}},
+		{"kind": "Route", "metadata": {"name": "millicore", "annotations": {"haproxy.router.openshift.io/balance": "leastconn"}},
+		 "spec": {"to": {"kind": "Service", "name": "millicore"}, "tls": {"termination": "passthrough"}}},
+		{"kind": "Route", "metadata": {"name": "millicore-edge", "annotations": {"haproxy.router.openshift.io/balance": "leastconn"}},
+		 "spec": {"to": {"kind": "Service", "name": "millicore"}, "tls": {"termination": "edge"}}},
+		{"kind": "Route", "metadata": {"name": "fh-aaa", "annotations": {"haproxy.router.openshift.io/disable_cookies": "true"}},
+		 "spec": {"to": {"kind": "Service", "name": "fh-aaa"}}}
+	]}`), &routes); err != nil {
+		t.Fatal(err)
+	}
+	result := checkStickySessions(Result{Status: StatusOK}, routes)
+	if result.Status != StatusWarning {
+		t.Fatalf("Status = %d, want %d", result.Status, StatusWarning)
+	}
+	var names []string
+	for _, info := range result.Info {
+		names = append(names, info.Name)
+	}
+	if len(names) != 2 || names[0] != "rhmap" || names[1] != "millicore" {
+		t.Errorf("flagged routes = %v, want [rhmap millicore]", names)
+	}
+}
+
+func TestRouteBalancing(t *testing.T) {
+	var route Route
+	if err := json.Unmarshal([]byte(`{"metadata": {"name": "rhmap", "annotations": {"haproxy.router.openshift.io/balance": "roundrobin", "openshift.io/host.generated": "true"}},
+		"spec": {"host": "rhmap.example.com", "to": {"name": "fh-ngui", "weight": 90}, "alternateBackends": [{"name": "fh-ngui-canary", "weight": 10}], "tls": {"termination": "edge"}}}`), &route); err != nil {
+		t.Fatal(err)
+	}
+	b := routeBalancing(route)
+	if len(b.Backends) != 2 || *b.Backends[1].Weight != 10 {
+		t.Errorf("Backends = %+v, want fh-ngui and fh-ngui-canary with weight 10", b.Backends)
+	}
+	if len(b.Annotations) != 1 || b.Termination != "edge" {
+		t.Errorf("routeBalancing() = %+v, want only the balance annotation and edge termination", b)
+	}
+}
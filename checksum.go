@@ -0,0 +1,62 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checksumExtension is appended to the path of a dump archive to name the file
+// holding its SHA-256 checksum, in the format of sha256sum, so that it can be
+// verified with sha256sum -c once transferred.
+const checksumExtension = ".sha256"
+
+// fileChecksum returns the hex-encoded SHA-256 checksum of the file at path.
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// WriteChecksumFile writes the checksum of the dump archive at path next to it
+// and returns the path of the checksum file.
+func WriteChecksumFile(path string) (string, error) {
+	sum, err := fileChecksum(path)
+	if err != nil {
+		return "", err
+	}
+	checksumPath := path + checksumExtension
+	content := fmt.Sprintf("%s  %s\n", sum, filepath.Base(path))
+	return checksumPath, ioutil.WriteFile(checksumPath, []byte(content), 0660)
+}
+
+// verifyChecksumFile verifies the dump archive at path against its checksum
+// file, if there is one.
+func (r *ValidationReport) verifyChecksumFile(path string) error {
+	content, err := ioutil.ReadFile(path + checksumExtension)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(string(content))
+	sum, err := fileChecksum(path)
+	if err != nil {
+		return err
+	}
+	r.check(len(fields) > 0 && fields[0] == sum, "the archive does not match its checksum in %s%s, it was modified or truncated", filepath.Base(path), checksumExtension)
+	return nil
+}
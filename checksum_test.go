@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChecksumFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checksum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dump.tar.gz")
+	if err := ioutil.WriteFile(path, []byte("dump"), 0660); err != nil {
+		t.Fatal(err)
+	}
+	checksumPath, err := WriteChecksumFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(checksumPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "b6ca0868bca6a2926b70aa1a71592038d9030fe26d4214edcfbd6cf41f2f4654  dump.tar.gz\n"; string(content) != want {
+		t.Errorf("checksum file = %q, want %q", content, want)
+	}
+
+	var report ValidationReport
+	if err := report.verifyChecksumFile(path); err != nil || report.Checked != 1 || len(report.Problems) != 0 {
+		t.Errorf("verifyChecksumFile() of an unmodified archive: %+v, %v", report, err)
+	}
+	if err := ioutil.WriteFile(path, []byte("dum"), 0660); err != nil {
+		t.Fatal(err)
+	}
+	report = ValidationReport{}
+	if err := report.verifyChecksumFile(path); err != nil || len(report.Problems) != 1 {
+		t.Errorf("verifyChecksumFile() of a truncated archive: %+v, %v", report, err)
+	}
+
+	report = ValidationReport{}
+	if err := report.verifyChecksumFile(filepath.Join(dir, "other.tar.gz")); err != nil || report.Checked != 0 {
+		t.Errorf("verifyChecksumFile() without checksum file: %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// Error classes, as returned by ClassifyError.
+const (
+	classUnauthorized      = "unauthorized"
+	classForbidden         = "forbidden"
+	classNotFound          = "not found"
+	classTimeout           = "timeout"
+	classConnectionRefused = "connection refused"
+	classTLS               = "tls"
+	classThrottled         = "throttled"
+	classInterrupted       = "interrupted"
+	// classCommandFailed is for commands that ran and exited with an
+	// error not matching any other class.
+	classCommandFailed = "command failed"
+	// classCommandNotRun is for commands that could not be started.
+	classCommandNotRun = "command not run"
+	classOther         = "other"
+)
+
+// errorClasses maps error classes to fragments of the error messages printed
+// by oc, in the order they are matched.
+var errorClasses = []struct {
+	class     string
+	fragments []string
+}{
+	{classUnauthorized, []string{"You must be logged in to the server", "Unauthorized", "the server has asked for the client to provide credentials", "token has expired"}},
+	{classForbidden, []string{"forbidden", "Forbidden", "cannot list", "cannot get"}},
+	{classTLS, []string{"x509:", "tls:", "certificate signed by unknown authority"}},
+	{classThrottled, []string{"Too Many Requests", "429", "rate limit"}},
+	{classConnectionRefused, []string{"connection refused", "no route to host", "connection reset by peer"}},
+	{classInterrupted, []string{"context canceled"}},
+	{classTimeout, []string{"timeout", "timed out", "deadline exceeded", "i/o timeout"}},
+	{classNotFound, []string{"not found", "NotFound", "doesn't have a resource type"}},
+}
+
+// errorHints are suggestions printed for each error class.
+var errorHints = map[string]string{
+	classUnauthorized:      "log in again with oc login <public-master-url>",
+	classForbidden:         "the current user lacks permissions, run the tool as a cluster administrator",
+	classNotFound:          "the resource or project was deleted, or the resource type is not supported by this cluster",
+	classTimeout:           "the master is slow to respond, try again with fewer parallel tasks (-p)",
+	classConnectionRefused: "the master could not be reached, check the network and the server URL in oc whoami --show-server",
+	classTLS:               "the master's certificate is not trusted, check the CA configured for oc",
+	classThrottled:         "the master is throttling requests, try again with fewer parallel tasks (-p) or with -adaptive",
+	classCommandNotRun:     "make sure the oc binary is installed and in the PATH",
+	classInterrupted:       "the dump was interrupted or timed out (-timeout) before the task completed",
+}
+
+// ClassifyError returns the class of err, based on the stderr output of failed
+// commands or the error message otherwise.
+func ClassifyError(err error) string {
+	if f, ok := err.(*TaskFailure); ok {
+		return ClassifyError(f.Err)
+	}
+	msg := err.Error()
+	if cmdErr, ok := err.(*CmdError); ok {
+		// Commands killed when their context is done never ran to
+		// completion, but oc is there.
+		switch cmdErr.Err {
+		case context.Canceled:
+			return classInterrupted
+		case context.DeadlineExceeded:
+			return classTimeout
+		}
+		if _, exited := cmdErr.Err.(*exec.ExitError); !exited {
+			return classCommandNotRun
+		}
+		msg = cmdErr.Stderr
+	}
+	for _, c := range errorClasses {
+		for _, f := range c.fragments {
+			if strings.Contains(msg, f) {
+				return c.class
+			}
+		}
+	}
+	if _, ok := err.(*CmdError); ok {
+		return classCommandFailed
+	}
+	return classOther
+}
+
+// isTransientClass reports whether errors of class may succeed if retried.
+func isTransientClass(class string) bool {
+	switch class {
+	case classTimeout, classConnectionRefused, classThrottled:
+		return true
+	}
+	return false
+}
+
+// isNotLoggedIn reports whether err was caused by running oc without being
+// logged in.
+func isNotLoggedIn(err error) bool {
+	return ClassifyError(err) == classUnauthorized
+}
+
+// maxListedFailures is the maximum number of failed tasks listed by
+// WriteErrorSummary.
+const maxListedFailures = 10
+
+// WriteErrorSummary writes the number of errors per class in taskErrs, with
+// hints on how to address them, and which named tasks failed to w.
+func WriteErrorSummary(w io.Writer, taskErrs []error) error {
+	var (
+		counts = make(map[string]int)
+		failed []string
+		seen   = make(map[string]bool)
+	)
+	for _, err := range taskErrs {
+		for _, e := range flattenErrors(err) {
+			class := ClassifyError(e)
+			counts[class]++
+			if f, ok := e.(*TaskFailure); ok {
+				name := f.Task
+				if f.Project != "" {
+					name += " in project " + f.Project
+				}
+				if !seen[name] {
+					seen[name] = true
+					failed = append(failed, name+" ("+class+")")
+				}
+			}
+		}
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+	var classes []string
+	for class := range counts {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	if _, err := fmt.Fprintln(w, "Some tasks failed, see errors.json in the dump for details:"); err != nil {
+		return err
+	}
+	for _, class := range classes {
+		line := fmt.Sprintf("  %d %s", counts[class], class)
+		if hint, ok := errorHints[class]; ok {
+			line += ": " + hint
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintln(w, "Failed tasks:"); err != nil {
+		return err
+	}
+	for i, name := range failed {
+		if i == maxListedFailures {
+			_, err := fmt.Fprintf(w, "  and %d more\n", len(failed)-i)
+			return err
+		}
+		if _, err := fmt.Fprintln(w, "  "+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestIsNotLoggedIn(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New(`command "oc get projects": exit status 1: error: You must be logged in to the server (the server has asked for the client to provide credentials)`), true},
+		{errors.New(`command "oc get projects": exit status 1: error: You must be logged in to the server (Unauthorized)`), true},
+		{errors.New(`command "oc get projects": exec: "oc": executable file not found in $PATH`), false},
+		{errors.New(`command "oc get projects": exit status 1: Error from server: projects is forbidden`), false},
+	}
+	for _, tt := range tests {
+		if got := isNotLoggedIn(tt.err); got != tt.want {
+			t.Errorf("isNotLoggedIn(%q) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	exitErr := &exec.ExitError{}
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&CmdError{Err: exitErr, Stderr: "Error from server: User \"dev\" cannot list pods in project \"core\""}, classForbidden},
+		{&CmdError{Err: exitErr, Stderr: "Error from server: pods \"pod-1\" not found"}, classNotFound},
+		{&CmdError{Err: exitErr, Stderr: "Unable to connect to the server: dial tcp 10.0.0.1:8443: i/o timeout"}, classTimeout},
+		{&CmdError{Err: exitErr, Stderr: "dial tcp 10.0.0.1:8443: getsockopt: connection refused"}, classConnectionRefused},
+		{&CmdError{Err: exitErr, Stderr: "Unable to connect to the server: x509: certificate signed by unknown authority"}, classTLS},
+		{&CmdError{Err: exitErr, Stderr: "error: You must be logged in to the server (Unauthorized)"}, classUnauthorized},
+		{&CmdError{Err: exitErr, Stderr: "Error from server: Too Many Requests"}, classThrottled},
+		{&CmdError{Err: exitErr, Stderr: "something else"}, classCommandFailed},
+		{&CmdError{Err: errors.New(`exec: "oc": executable file not found in $PATH`)}, classCommandNotRun},
+		{&CmdError{Err: context.DeadlineExceeded}, classTimeout},
+		{&CmdError{Err: context.Canceled}, classInterrupted},
+		{&TaskFailure{Task: "describe pod pod-1", Err: context.Canceled}, classInterrupted},
+		{errors.New("unexpected output"), classOther},
+	}
+	for _, tt := range tests {
+		if got := ClassifyError(tt.err); got != tt.want {
+			t.Errorf("ClassifyError(%q) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+	if !isTransientClass(classTimeout) || isTransientClass(classForbidden) {
+		t.Error("isTransientClass: timeouts must be transient and forbidden errors must not")
+	}
+}
+
+func TestWriteErrorSummary(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteErrorSummary(&buf, []error{nil, nil}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("WriteErrorSummary() without errors = %q, want empty", buf.String())
+	}
+	exitErr := &exec.ExitError{}
+	errs := []error{
+		&CmdError{Err: exitErr, Stderr: "pods is forbidden"},
+		&TaskFailure{Task: "fetch definitions of secrets", Project: "core", Err: errorList{&CmdError{Err: exitErr, Stderr: "secrets is forbidden"}, errors.New("oops")}},
+	}
+	if err := WriteErrorSummary(&buf, errs); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"2 forbidden: the current user lacks permissions", "1 other\n", "Failed tasks:\n  fetch definitions of secrets in project core (forbidden)\n"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("WriteErrorSummary() output doesn't include %q:\n%s", s, buf.String())
+		}
+	}
+}
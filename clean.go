@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A storedDump is a dump archive in a dump directory, along with the files
+// derived from it: split chunks and manifest, change reports, plugin results
+// and an extracted copy.
+type storedDump struct {
+	Name    string
+	Created time.Time
+	Paths   []string
+	Size    int64
+}
+
+// listStoredDumps returns the dumps in dir, newest first.
+func listStoredDumps(dir string) ([]storedDump, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var dumps []storedDump
+	for _, e := range entries {
+		if e.IsDir() || archiveExtension(e.Name()) == "" {
+			continue
+		}
+		dumps = append(dumps, storedDump{Name: trimArchiveExtension(e.Name()), Created: e.ModTime()})
+	}
+	for i := range dumps {
+		d := &dumps[i]
+		for _, e := range entries {
+			if e.Name() != d.Name && !strings.HasPrefix(e.Name(), d.Name+".") {
+				continue
+			}
+			if e.IsDir() && e.Name() != d.Name {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			size := e.Size()
+			if e.IsDir() {
+				if size, err = dirSize(path); err != nil {
+					return nil, err
+				}
+			}
+			d.Paths = append(d.Paths, path)
+			d.Size += size
+		}
+	}
+	sort.Slice(dumps, func(i, j int) bool { return dumps[i].Created.After(dumps[j].Created) })
+	return dumps, nil
+}
+
+// dirSize returns the total size of the files under dir.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// expiredDumps returns the dumps, sorted newest first, that are beyond the
+// keep newest ones or older than maxAge at now. A zero keep or maxAge doesn't
+// limit dumps by count or age.
+func expiredDumps(dumps []storedDump, keep int, maxAge time.Duration, now time.Time) []storedDump {
+	var expired []storedDump
+	for i, d := range dumps {
+		if (keep > 0 && i >= keep) || (maxAge > 0 && now.Sub(d.Created) > maxAge) {
+			expired = append(expired, d)
+		}
+	}
+	return expired
+}
+
+func cleanCommand(args []string) error {
+	fs := flag.NewFlagSet("clean", flag.ExitOnError)
+	dir := fs.String("dir", dumpDir, "dump directory to clean")
+	keep := fs.Int("keep", 0, "number of most recent dumps to keep")
+	maxAge := fs.Duration("max-age", 0, "remove dumps older than this, e.g. 720h")
+	dryRun := fs.Bool("dry-run", false, "only list the dumps that would be removed")
+	fs.Parse(args)
+	if fs.NArg() != 0 || *keep < 0 || *maxAge < 0 || (*keep == 0 && *maxAge == 0) {
+		return errors.New("usage: clean [-dir dir] [-dry-run] -keep n | -max-age duration")
+	}
+	if !*dryRun {
+		lock, err := LockDir(*dir, false)
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock()
+	}
+	dumps, err := listStoredDumps(*dir)
+	if err != nil {
+		return err
+	}
+	return cleanDumps(os.Stdout, expiredDumps(dumps, *keep, *maxAge, time.Now()), *dryRun)
+}
+
+// cleanDumps removes the files of dumps, or only lists them if dryRun is true.
+func cleanDumps(w io.Writer, dumps []storedDump, dryRun bool) error {
+	verb := "Removed"
+	if dryRun {
+		verb = "Would remove"
+	}
+	var (
+		errors  errorList
+		removed int
+		total   int64
+	)
+	for _, d := range dumps {
+		if !dryRun {
+			var failed bool
+			for _, path := range d.Paths {
+				if err := os.RemoveAll(path); err != nil {
+					errors = append(errors, err)
+					failed = true
+				}
+			}
+			if failed {
+				continue
+			}
+		}
+		removed++
+		total += d.Size
+		fmt.Fprintf(w, "%s %s (created %s, %d files, %s)\n", verb, d.Name, d.Created.Format(time.RFC3339), len(d.Paths), formatSize(d.Size))
+	}
+	fmt.Fprintf(w, "%s %d dumps, %s\n", verb, removed, formatSize(total))
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
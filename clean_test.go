@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListStoredDumps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clean")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	now := time.Now()
+	files := map[string]time.Time{
+		"2016-09-01T12-00-00Z.tar.gz":            now.Add(-48 * time.Hour),
+		"2016-09-01T12-00-00Z.tar.gz.001":        now,
+		"2016-09-01T12-00-00Z.tar.gz.split.json": now,
+		"2016-09-01T12-00-00Z.changes.txt":       now,
+		"2016-09-01T12-00-00Z-1.tar.gz":          now.Add(-24 * time.Hour),
+		"2016-09-01T12-00-00Z/core/logs.txt":     now,
+		lockFileName:                             now,
+	}
+	for name, mtime := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0770); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte("data"), 0660); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dumps, err := listStoredDumps(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dumps) != 2 || dumps[0].Name != "2016-09-01T12-00-00Z-1" || dumps[1].Name != "2016-09-01T12-00-00Z" {
+		t.Fatalf("listStoredDumps() = %+v, want the -1 dump then the other", dumps)
+	}
+	if len(dumps[0].Paths) != 1 {
+		t.Errorf("dumps[0].Paths = %v, want only its archive", dumps[0].Paths)
+	}
+	if len(dumps[1].Paths) != 5 || dumps[1].Size != 20 {
+		t.Errorf("dumps[1] = %+v, want the archive, 3 derived files and the extracted directory, 20 bytes", dumps[1])
+	}
+
+	var out bytes.Buffer
+	if err := cleanDumps(&out, expiredDumps(dumps, 1, 0, now), false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Removed 1 dumps, 20B") {
+		t.Errorf("cleanDumps() output:\n%s", out.String())
+	}
+	if dumps, _ := listStoredDumps(dir); len(dumps) != 1 {
+		t.Errorf("%d dumps left after cleaning, want 1", len(dumps))
+	}
+	if _, err := os.Stat(filepath.Join(dir, lockFileName)); err != nil {
+		t.Errorf("cleaning removed an unrelated file: %v", err)
+	}
+}
+
+func TestExpiredDumps(t *testing.T) {
+	now := time.Now()
+	dumps := []storedDump{{Name: "c", Created: now.Add(-time.Hour)}, {Name: "b", Created: now.Add(-48 * time.Hour)}, {Name: "a", Created: now.Add(-72 * time.Hour)}}
+	tests := []struct {
+		keep   int
+		maxAge time.Duration
+		want   int
+	}{
+		{keep: 2, want: 1},
+		{keep: 5, want: 0},
+		{maxAge: 24 * time.Hour, want: 2},
+		{keep: 1, maxAge: 60 * time.Hour, want: 2},
+	}
+	for _, tt := range tests {
+		if got := expiredDumps(dumps, tt.keep, tt.maxAge, now); len(got) != tt.want {
+			t.Errorf("expiredDumps(keep=%d, maxAge=%v) = %d dumps, want %d", tt.keep, tt.maxAge, len(got), tt.want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+)
+
+// clusterResources are the types of cluster-scoped resources whose definitions
+// are collected. Node pressure or failing persistent volumes break RHMAP
+// components while leaving no trace in the definitions of projects.
+var clusterResources = []string{
+	"nodes", "persistentvolumes", "clusterresourcequotas", "storageclasses",
+	"clusterroles", "clusterrolebindings", "securitycontextconstraints",
+}
+
+// GetClusterDefinitionsTasks returns a list of tasks to fetch the definitions
+// of all resources of each of the cluster-scoped types, to
+// definitions/cluster/<type>.json, keeping only their metadata and status if
+// statusOnly is true.
+func GetClusterDefinitionsTasks(types []string, statusOnly bool, tarFile *Archive) []Task {
+	var tasks []Task
+	for _, t := range types {
+		file := tarFile.GetWriterToFile(filepath.Join("definitions", "cluster", t+".json"))
+		errOut := tarFile.GetWriterToFile(filepath.Join("definitions", "cluster", t+".stderr"))
+		var out io.WriteCloser = file
+		if statusOnly {
+			out = &statusOnlyWriter{w: file, c: file}
+		}
+		cmd := ocCommand("get", t, "-o=json")
+		task := func(ctx context.Context) error {
+			defer out.Close()
+			defer errOut.Close()
+			return runCmdCaptureOutput(ctx, cmd, out, errOut)
+		}
+		tasks = append(tasks, namedTask("fetch definitions of "+t, "", task))
+	}
+	return tasks
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/feedhenry/fh-system-dump-tool/cmdrunner"
+)
+
+func TestGetClusterDefinitionsTasks(t *testing.T) {
+	defer func(old cmdrunner.Runner) { runner = old }(runner)
+	runner = cmdrunner.NewFakeRunner([]cmdrunner.Invocation{
+		{Args: ocCommand("get", "nodes", "-o=json").Args, Stdout: `{"kind": "List", "items": [{"kind": "Node", "metadata": {"name": "node-1", "annotations": {"a": "b"}}, "spec": {}, "status": {"phase": "Ready"}}]}`},
+	})
+
+	var b bytes.Buffer
+	tarFile, err := NewTgz(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tarFile.Keep = func(string) bool { return true }
+	tasks := GetClusterDefinitionsTasks([]string{"nodes", "storageclasses"}, true, tarFile)
+	if len(tasks) != 2 {
+		t.Fatalf("GetClusterDefinitionsTasks() returned %d tasks, want 2", len(tasks))
+	}
+	if err := tasks[0](context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	got := string(tarFile.KeptFiles()["definitions/cluster/nodes.json"])
+	if want := string(statusOnly([]byte(`{"kind": "List", "items": [{"kind": "Node", "metadata": {"name": "node-1"}, "status": {"phase": "Ready"}}]}`))); got != want {
+		t.Errorf("definitions/cluster/nodes.json = %s, want %s", got, want)
+	}
+}
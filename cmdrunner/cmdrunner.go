@@ -0,0 +1,156 @@
+// Package cmdrunner runs the external commands of the dump tool through a
+// Runner, so that they can be observed, recorded and replayed. Programs
+// building on the dump tool can record the commands they run with a
+// RecordingRunner and replay them in tests with a FakeRunner.
+package cmdrunner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Runner runs external commands.
+type Runner interface {
+	// Run starts cmd and waits for it to complete, like cmd.Run. The
+	// command is killed if ctx is done before it completes.
+	Run(ctx context.Context, cmd *exec.Cmd) error
+}
+
+// An Invocation records a command run.
+type Invocation struct {
+	Args     []string  `json:"args"`
+	Start    time.Time `json:"start"`
+	Duration float64   `json:"durationSeconds"`
+	Error    string    `json:"error,omitempty"`
+	// Stdout and Stderr are only recorded when capturing output.
+	Stdout string `json:"stdout,omitempty"`
+	Stderr string `json:"stderr,omitempty"`
+}
+
+// A RecordingRunner is a Runner that records every command run by Runner.
+type RecordingRunner struct {
+	Runner Runner
+	// CaptureOutput enables recording the output of commands, so that
+	// they can be replayed with a FakeRunner.
+	CaptureOutput bool
+	// Redact, if not nil, redacts the standard output of commands when
+	// they are written.
+	Redact func(stdout []byte) []byte
+
+	mu          sync.Mutex
+	invocations []Invocation
+}
+
+func (r *RecordingRunner) Run(ctx context.Context, cmd *exec.Cmd) error {
+	var stdout, stderr bytes.Buffer
+	if r.CaptureOutput {
+		cmd.Stdout = teeWriter(cmd.Stdout, &stdout)
+		cmd.Stderr = teeWriter(cmd.Stderr, &stderr)
+	}
+	inv := Invocation{Args: cmd.Args, Start: time.Now().UTC()}
+	err := r.Runner.Run(ctx, cmd)
+	inv.Duration = time.Since(inv.Start).Seconds()
+	if err != nil {
+		inv.Error = err.Error()
+	}
+	inv.Stdout, inv.Stderr = stdout.String(), stderr.String()
+	r.mu.Lock()
+	r.invocations = append(r.invocations, inv)
+	r.mu.Unlock()
+	return err
+}
+
+// Invocations returns the commands run so far, in the order they completed.
+func (r *RecordingRunner) Invocations() []Invocation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Invocation{}, r.invocations...)
+}
+
+// WriteInvocations writes the commands run so far to w, as JSON, with their
+// output redacted by Redact.
+func (r *RecordingRunner) WriteInvocations(w io.Writer) error {
+	invocations := r.Invocations()
+	if r.Redact != nil {
+		for i, inv := range invocations {
+			if inv.Stdout != "" {
+				invocations[i].Stdout = string(r.Redact([]byte(inv.Stdout)))
+			}
+		}
+	}
+	output, err := json.MarshalIndent(invocations, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(output)
+	return err
+}
+
+func teeWriter(w io.Writer, buf *bytes.Buffer) io.Writer {
+	if w == nil {
+		return buf
+	}
+	return io.MultiWriter(w, buf)
+}
+
+// A FakeRunner is a Runner that replays recorded invocations instead of
+// running commands. Commands are matched by their arguments.
+type FakeRunner struct {
+	mu        sync.Mutex
+	responses map[string]Invocation
+}
+
+func invocationKey(args []string) string {
+	return strings.Join(args, "\x00")
+}
+
+// NewFakeRunner returns a FakeRunner replaying invocations. If the same
+// command was run several times, the last invocation is replayed.
+func NewFakeRunner(invocations []Invocation) *FakeRunner {
+	f := &FakeRunner{responses: make(map[string]Invocation)}
+	for _, inv := range invocations {
+		f.Add(inv)
+	}
+	return f
+}
+
+// Add adds or replaces the response to a command.
+func (f *FakeRunner) Add(inv Invocation) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses[invocationKey(inv.Args)] = inv
+}
+
+func (f *FakeRunner) Run(ctx context.Context, cmd *exec.Cmd) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	inv, ok := f.responses[invocationKey(cmd.Args)]
+	f.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no recorded response for %q", cmd.Args)
+	}
+	for _, o := range []struct {
+		w       io.Writer
+		content string
+	}{{cmd.Stdout, inv.Stdout}, {cmd.Stderr, inv.Stderr}} {
+		if o.w == nil {
+			continue
+		}
+		if _, err := io.WriteString(o.w, o.content); err != nil {
+			return err
+		}
+	}
+	if inv.Error != "" {
+		return fmt.Errorf("%s", inv.Error)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package cmdrunner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestRecordFakeRunner(t *testing.T) {
+	fake := NewFakeRunner([]Invocation{
+		{Args: []string{"oc", "get", "projects"}, Stdout: "core mbaas\n"},
+		{Args: []string{"oc", "get", "nodes"}, Stderr: "forbidden\n", Error: "exit status 1"},
+	})
+	recorder := &RecordingRunner{Runner: fake, CaptureOutput: true, Redact: func(stdout []byte) []byte {
+		return bytes.Replace(stdout, []byte("mbaas"), []byte("REDACTED"), -1)
+	}}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command("oc", "get", "projects")
+	cmd.Stdout = &stdout
+	if err := recorder.Run(context.Background(), cmd); err != nil {
+		t.Fatal(err)
+	}
+	if got := stdout.String(); got != "core mbaas\n" {
+		t.Errorf("stdout = %q, want the recorded output", got)
+	}
+	var stderr bytes.Buffer
+	cmd = exec.Command("oc", "get", "nodes")
+	cmd.Stderr = &stderr
+	if err := recorder.Run(context.Background(), cmd); err == nil || stderr.String() != "forbidden\n" {
+		t.Errorf("Run() = %v, stderr %q, want the recorded error", err, stderr.String())
+	}
+	if err := recorder.Run(context.Background(), exec.Command("oc", "whoami")); err == nil {
+		t.Error("Run() of an unrecorded command didn't return an error")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := fake.Run(ctx, exec.Command("oc", "get", "projects")); err != context.Canceled {
+		t.Errorf("Run() with a canceled context = %v, want %v", err, context.Canceled)
+	}
+
+	var out bytes.Buffer
+	if err := recorder.WriteInvocations(&out); err != nil {
+		t.Fatal(err)
+	}
+	var invocations []Invocation
+	if err := json.Unmarshal(out.Bytes(), &invocations); err != nil {
+		t.Fatal(err)
+	}
+	if len(invocations) != 3 {
+		t.Fatalf("WriteInvocations() wrote %d invocations, want 3", len(invocations))
+	}
+	if got := invocations[0]; strings.Join(got.Args, " ") != "oc get projects" || got.Stdout != "core REDACTED\n" {
+		t.Errorf("first invocation = %+v, want the redacted output of oc get projects", got)
+	}
+	if got := invocations[1]; got.Error != "exit status 1" || got.Stderr != "forbidden\n" {
+		t.Errorf("second invocation = %+v, want the recorded error", got)
+	}
+}
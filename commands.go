@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A command is a subcommand of the dump tool, run with the arguments that
+// follow its name on the command line.
+type command func(args []string) error
+
+// commands maps subcommand names to their implementation.
+var commands = map[string]command{
+	"aggregate":      aggregateCommand,
+	"app-env":        appEnvCommand,
+	"clean":          cleanCommand,
+	"collectors":     collectorsCommand,
+	"join":           joinCommand,
+	"list-resources": listResourcesCommand,
+	"pod":            podCommand,
+	"query":          queryCommand,
+	"selftest":       selfTestCommand,
+	"validate":       validateCommand,
+}
+
+// RunCommand runs the named subcommand with args.
+func RunCommand(name string, args []string) error {
+	cmd, ok := commands[name]
+	if !ok {
+		var names []string
+		for n := range commands {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown command %q, must be one of: %s", name, strings.Join(names, ", "))
+	}
+	return cmd(args)
+}
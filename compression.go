@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+)
+
+// Compression formats of dump archives, and of the files compressed inside
+// them.
+const (
+	compressionGzip = "gzip"
+	compressionZstd = "zstd"
+	compressionNone = "none"
+)
+
+// archiveExtensions maps compression formats to the extension of dump archives
+// in that format.
+var archiveExtensions = map[string]string{
+	compressionGzip: ".tar.gz",
+	compressionZstd: ".tar.zst",
+	compressionNone: ".tar",
+}
+
+// compressedExtensions maps compression formats to the extension appended to
+// the names of files compressed in that format.
+var compressedExtensions = map[string]string{
+	compressionGzip: ".gz",
+	compressionZstd: ".zst",
+	compressionNone: "",
+}
+
+// zstdMagic and gzipMagic prefix zstd and gzip streams.
+var (
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	gzipMagic = []byte{0x1f, 0x8b}
+)
+
+// checkCompression returns an error if format is not a known compression
+// format, or cannot be used on this host.
+func checkCompression(format string) error {
+	if _, ok := archiveExtensions[format]; !ok {
+		return fmt.Errorf("unknown compression %q, must be gzip, zstd or none", format)
+	}
+	if format == compressionZstd {
+		if _, err := exec.LookPath("zstd"); err != nil {
+			return fmt.Errorf("zstd compression requires the zstd command: %v", err)
+		}
+	}
+	return nil
+}
+
+// archiveExtension returns the extension of the dump archive name, or an empty
+// string if name is not a dump archive.
+func archiveExtension(name string) string {
+	if strings.HasSuffix(name, zipExtension) {
+		return zipExtension
+	}
+	// .tar is a suffix of none of the other extensions, but would match
+	// the part before them if checked first.
+	for _, format := range []string{compressionGzip, compressionZstd, compressionNone} {
+		if ext := archiveExtensions[format]; strings.HasSuffix(name, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// trimArchiveExtension returns name without its dump archive extension.
+func trimArchiveExtension(name string) string {
+	return strings.TrimSuffix(name, archiveExtension(name))
+}
+
+// archiveCompression returns the compression format of the dump archive name,
+// based on its extension, defaulting to gzip.
+func archiveCompression(name string) string {
+	ext := archiveExtension(name)
+	for format, e := range archiveExtensions {
+		if e == ext {
+			return format
+		}
+	}
+	return compressionGzip
+}
+
+// nopWriteCloser adds a no-op Close method to an io.Writer.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// newCompressor returns a writer compressing what is written to it into w, in
+// the given format. Close flushes it, without closing w.
+func newCompressor(format string, w io.Writer) (io.WriteCloser, error) {
+	switch format {
+	case compressionGzip:
+		return gzip.NewWriter(w), nil
+	case compressionNone:
+		return nopWriteCloser{w}, nil
+	case compressionZstd:
+		return startZstd(w, nil, "-q", "-c", "-T0")
+	}
+	return nil, fmt.Errorf("unknown compression %q", format)
+}
+
+// newDecompressor returns a reader decompressing r, detecting whether it is
+// compressed with gzip, zstd or not at all.
+func newDecompressor(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, zstdMagic):
+		pr, pw := io.Pipe()
+		z, err := startZstd(pw, br, "-q", "-d", "-c")
+		if err != nil {
+			return nil, err
+		}
+		go func() { pw.CloseWithError(z.Close()) }()
+		return pr, nil
+	}
+	return ioutil.NopCloser(br), nil
+}
+
+// zstdProcess is a running zstd command. It is run directly rather than by
+// the runner, since it is part of writing the dump, not of collecting it.
+type zstdProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr bytes.Buffer
+}
+
+// startZstd starts zstd with args, writing its output to w. Its input is read
+// from r if not nil, or else from what is written to the returned process.
+func startZstd(w io.Writer, r io.Reader, args ...string) (*zstdProcess, error) {
+	z := &zstdProcess{cmd: exec.Command("zstd", args...)}
+	z.cmd.Stdout = w
+	z.cmd.Stderr = &z.stderr
+	if r != nil {
+		z.cmd.Stdin = r
+	} else {
+		stdin, err := z.cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		z.stdin = stdin
+	}
+	if err := z.cmd.Start(); err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+func (z *zstdProcess) Write(p []byte) (int, error) {
+	return z.stdin.Write(p)
+}
+
+// Close waits for zstd to complete, after closing its input if it was
+// written to z.
+func (z *zstdProcess) Close() error {
+	if z.stdin != nil {
+		z.stdin.Close()
+	}
+	if err := z.cmd.Wait(); err != nil {
+		return &CmdError{Args: z.cmd.Args, Err: err, Stderr: z.stderr.String()}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"testing"
+)
+
+func TestArchiveCompression(t *testing.T) {
+	formats := []string{compressionGzip, compressionNone}
+	if _, err := exec.LookPath("zstd"); err == nil {
+		formats = append(formats, compressionZstd)
+	}
+	for _, format := range formats {
+		var b bytes.Buffer
+		archive, err := NewArchive(&b, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := archive.AddFileByContent([]byte("test"), "test1.txt"); err != nil {
+			t.Fatal(err)
+		}
+		if err := archive.Close(); err != nil {
+			t.Fatalf("%s: Close(): %v", format, err)
+		}
+		files, err := ReadTgz(&b, func(string) bool { return true })
+		if err != nil {
+			t.Fatalf("%s: ReadTgz(): %v", format, err)
+		}
+		if string(files["test1.txt"]) != "test" {
+			t.Errorf("%s: read back %q, want %q", format, files["test1.txt"], "test")
+		}
+	}
+}
+
+func TestArchiveExtension(t *testing.T) {
+	for name, want := range map[string]string{
+		"rhmap-dumps/2016-09-01T12-00-00Z.tar.gz":  ".tar.gz",
+		"rhmap-dumps/2016-09-01T12-00-00Z.tar.zst": ".tar.zst",
+		"rhmap-dumps/2016-09-01T12-00-00Z.tar":     ".tar",
+		"rhmap-dumps/2016-09-01T12-00-00Z.txt":     "",
+	} {
+		if got := archiveExtension(name); got != want {
+			t.Errorf("archiveExtension(%q) = %q, want %q", name, got, want)
+		}
+	}
+	if got := archiveCompression("dump.tar.zst"); got != compressionZstd {
+		t.Errorf("archiveCompression(dump.tar.zst) = %q, want zstd", got)
+	}
+	if err := checkCompression("bzip2"); err == nil {
+		t.Errorf("checkCompression(bzip2) didn't return an error")
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// concurrencyWindow is the number of API commands observed between
+	// adjustments of adaptive concurrency.
+	concurrencyWindow = 10
+	// slowLatencyFactor is how many times slower than the fastest window
+	// API commands may get before adaptive concurrency is lowered.
+	slowLatencyFactor = 2
+)
+
+// An adaptiveConcurrency scales the number of tasks running in parallel between
+// 1 and max, following the latency and errors of API commands. After each
+// window of concurrencyWindow commands, it halves if any was throttled, timed
+// out or refused, decreases by one if they got slowLatencyFactor times slower
+// than in the fastest window seen, and increases by one otherwise. It is safe
+// for concurrent use.
+type adaptiveConcurrency struct {
+	mu      sync.Mutex
+	max     int
+	current int
+	// observed, latency and failed describe the current window.
+	observed int
+	latency  time.Duration
+	failed   bool
+	// fastest is the mean latency of the fastest window seen.
+	fastest time.Duration
+}
+
+// commandConcurrency, if not nil, adapts the number of tasks running in parallel
+// to the commands they run.
+var commandConcurrency *adaptiveConcurrency
+
+// newAdaptiveConcurrency returns an adaptiveConcurrency of at most max tasks,
+// starting at a quarter of them.
+func newAdaptiveConcurrency(max int) *adaptiveConcurrency {
+	current := max / 4
+	if current < 1 {
+		current = 1
+	}
+	return &adaptiveConcurrency{max: max, current: current}
+}
+
+// limit returns the number of tasks that may run in parallel, or fixed if a is
+// nil.
+func (a *adaptiveConcurrency) limit(fixed int) int {
+	if a == nil {
+		return fixed
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.current
+}
+
+// commandErrorClass returns the class of err, returned by running the command
+// with args that wrote stderr, or an empty string if err is nil.
+func commandErrorClass(args []string, err error, stderr string) string {
+	if err == nil {
+		return ""
+	}
+	return ClassifyError(&CmdError{Args: args, Err: err, Stderr: stderr})
+}
+
+// observe records that the command with args took latency and failed with an
+// error of the given class, empty if it succeeded. Only get commands account
+// for latency, the duration of others depends on how much they output.
+func (a *adaptiveConcurrency) observe(args []string, latency time.Duration, class string) {
+	if a == nil {
+		return
+	}
+	transient := isTransientClass(class)
+	get := strings.HasPrefix(commandKind(args), "get")
+	if !transient && (!get || class != "") {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.observed++
+	a.failed = a.failed || transient
+	if !transient {
+		a.latency += latency
+	}
+	if a.observed < concurrencyWindow {
+		return
+	}
+
+	previous := a.current
+	var mean time.Duration
+	if !a.failed {
+		mean = a.latency / time.Duration(a.observed)
+	}
+	switch {
+	case a.failed:
+		a.current /= 2
+	case a.fastest == 0 || mean < a.fastest:
+		a.fastest = mean
+		a.current++
+	case mean > slowLatencyFactor*a.fastest:
+		a.current--
+	default:
+		a.current++
+	}
+	if a.current < 1 {
+		a.current = 1
+	}
+	if a.current > a.max {
+		a.current = a.max
+	}
+	if a.current < previous {
+		log.Printf("Running at most %d tasks in parallel, the API is slowing down or throttling\n", a.current)
+	}
+	a.observed, a.latency, a.failed = 0, 0, false
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveConcurrency(t *testing.T) {
+	get := []string{"oc", "-n", "core", "get", "pods"}
+	window := func(a *adaptiveConcurrency, args []string, latency time.Duration, class string) int {
+		for i := 0; i < concurrencyWindow; i++ {
+			a.observe(args, latency, class)
+		}
+		return a.limit(0)
+	}
+
+	a := newAdaptiveConcurrency(16)
+	if got := a.limit(0); got != 4 {
+		t.Fatalf("limit() = %d, want a quarter of the maximum", got)
+	}
+	// Concurrency grows while the API keeps up.
+	if got := window(a, get, 100*time.Millisecond, ""); got != 5 {
+		t.Errorf("limit() after a fast window = %d, want 5", got)
+	}
+	if got := window(a, get, 150*time.Millisecond, ""); got != 6 {
+		t.Errorf("limit() after a window slightly slower than the fastest = %d, want 6", got)
+	}
+	if got := window(a, get, time.Second, ""); got != 5 {
+		t.Errorf("limit() after a slow window = %d, want 5", got)
+	}
+	// Commands other than get don't account for latency.
+	if got := window(a, []string{"oc", "logs", "pod/a"}, time.Minute, ""); got != 5 {
+		t.Errorf("limit() after slow logs = %d, want 5", got)
+	}
+	// Throttling halves it, as do timeouts of any command.
+	a.observe(get, 100*time.Millisecond, classThrottled)
+	if got := window(a, get, 100*time.Millisecond, ""); got != 2 {
+		t.Errorf("limit() after throttling = %d, want 2", got)
+	}
+	if got := window(a, []string{"oc", "logs", "pod/a"}, time.Minute, classTimeout); got != 1 {
+		t.Errorf("limit() after timeouts = %d, want 1", got)
+	}
+	if got := window(a, get, time.Minute, classTimeout); got != 1 {
+		t.Errorf("limit() = %d, want at least 1", got)
+	}
+
+	a = newAdaptiveConcurrency(2)
+	for i := 0; i < 5; i++ {
+		window(a, get, time.Millisecond, "")
+	}
+	if got := a.limit(0); got != 2 {
+		t.Errorf("limit() = %d, want at most the maximum", got)
+	}
+
+	var fixed *adaptiveConcurrency
+	fixed.observe(get, time.Second, classThrottled)
+	if got := fixed.limit(8); got != 8 {
+		t.Errorf("limit() of a nil adaptiveConcurrency = %d, want the fixed limit", got)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// A Config holds the settings read from the configuration file given with the
+// -config flag.
+type Config struct {
+	Redaction RedactionConfig `json:"redaction"`
+	// Suppressions lists accepted findings that are not reported.
+	Suppressions []Suppression `json:"suppressions"`
+	// CheckPlugins are external programs run against the dump once it is
+	// written.
+	CheckPlugins []CheckPlugin `json:"checkPlugins"`
+	// CustomChecks are run along the built-in analysis checks.
+	CustomChecks []CustomCheck `json:"customChecks"`
+	// SmokeTest, if its URL is set, configures a cloud app call made as
+	// part of the dump.
+	SmokeTest SmokeTestConfig `json:"smokeTest"`
+	// SupportMatrix adds to or replaces the entries of the support
+	// matrix shipped with the tool.
+	SupportMatrix SupportMatrix `json:"supportMatrix"`
+	// Resources selects the types of resources found by discovery whose
+	// definitions are collected.
+	Resources ResourceFilter `json:"resources"`
+}
+
+// RedactionConfig configures how sensitive data is redacted from the dump.
+type RedactionConfig struct {
+	// DisableDefaults disables the rules shipped with the tool, so that
+	// only Rules apply.
+	DisableDefaults bool            `json:"disableDefaults"`
+	Rules           []RedactionRule `json:"rules"`
+}
+
+// config is the configuration in effect. It holds the zero Config unless a
+// configuration file is given.
+var config Config
+
+// LoadConfig reads a JSON configuration file.
+func LoadConfig(path string) (Config, error) {
+	var c Config
+	f, err := os.Open(path)
+	if err != nil {
+		return c, err
+	}
+	defer f.Close()
+	decoder := json.NewDecoder(f)
+	if err := decoder.Decode(&c); err != nil {
+		return c, fmt.Errorf("config %s: %v", path, err)
+	}
+	for _, check := range c.CustomChecks {
+		if err := check.validate(); err != nil {
+			return c, fmt.Errorf("config %s: %v", path, err)
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+
+	starlarkjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+)
+
+// customScriptMaxSteps limits the computation of the scripts of custom checks,
+// so that a runaway script cannot hold the dump, however long the check
+// timeout.
+const customScriptMaxSteps = 10000000
+
+// customScriptGlobals are the values predeclared for the scripts of custom
+// checks. Scripts have no access to files, the network or commands.
+var customScriptGlobals = starlark.StringDict{"json": starlarkjson.Module}
+
+// A CustomCheck is a simple analysis check defined in the configuration file.
+// It reports every resource of a type for which a value selected by a JSON
+// path matches a regular expression, or that its script reports.
+type CustomCheck struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Resource is the resource type queried, e.g. pods.
+	Resource string `json:"resource"`
+	// Path selects values within each resource, in the same dotted syntax
+	// as redaction rules, e.g. spec.containers[*].image.
+	Path string `json:"path"`
+	// Match is the regular expression that selected values are matched
+	// against.
+	Match string `json:"match"`
+	// Script, instead of Path and Match, is a Starlark program defining a
+	// function check(project, items), called with the name of the project
+	// and the resources of the type, decoded from JSON. It returns a list
+	// of findings, dicts with a name, namespace, kind and message.
+	Script string `json:"script"`
+	// Status is the severity of findings: warning, the default, or
+	// critical.
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// status returns the status of findings of c.
+func (c CustomCheck) status() (int, error) {
+	if c.Status == "" {
+		return StatusWarning, nil
+	}
+	status, err := parseStatus(c.Status)
+	if err != nil || status == StatusOK {
+		return 0, fmt.Errorf("custom check %s: status must be warning or critical", c.ID)
+	}
+	return status, nil
+}
+
+func (c CustomCheck) validate() error {
+	if c.ID == "" || c.Resource == "" || (c.Path == "") == (c.Script == "") {
+		return fmt.Errorf("custom check %q: id, resource and either path or script are required", c.ID)
+	}
+	if c.Script != "" {
+		if _, _, err := starlark.SourceProgram(c.ID+".star", c.Script, customScriptGlobals.Has); err != nil {
+			return fmt.Errorf("custom check %s: %v", c.ID, err)
+		}
+	}
+	if _, err := regexp.Compile(c.Match); err != nil {
+		return fmt.Errorf("custom check %s: %v", c.ID, err)
+	}
+	_, err := c.status()
+	return err
+}
+
+// CheckTask returns a CheckTask running c. c must be valid.
+func (c CustomCheck) CheckTask() CheckTask {
+	return func(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+		result := Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: c.ID, CheckName: c.Name}
+		var list struct {
+			Items []map[string]interface{} `json:"items"`
+		}
+		if err := getResourceStruct(ctx, project, c.Resource, &list); err != nil {
+			stdErr.Write([]byte(err.Error()))
+			return result, err
+		}
+		if c.Script != "" {
+			info, err := runCustomScript(ctx, c, project, list.Items, stdErr)
+			if err != nil {
+				stdErr.Write([]byte(err.Error()))
+				return result, err
+			}
+			return checkCustomScript(result, c, info), nil
+		}
+		return checkCustom(result, c, list.Items), nil
+	}
+}
+
+// runCustomScript runs the script of c on the given resource definitions of
+// project, and returns the findings it reports. What the script prints is
+// written to stdErr.
+func runCustomScript(ctx context.Context, c CustomCheck, project string, items []map[string]interface{}, stdErr io.Writer) ([]Info, error) {
+	if items == nil {
+		items = []map[string]interface{}{}
+	}
+	input, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	thread := &starlark.Thread{
+		Name: c.ID,
+		Print: func(_ *starlark.Thread, msg string) {
+			fmt.Fprintln(stdErr, msg)
+		},
+	}
+	thread.SetMaxExecutionSteps(customScriptMaxSteps)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			thread.Cancel(ctx.Err().Error())
+		case <-done:
+		}
+	}()
+
+	globals, err := starlark.ExecFile(thread, c.ID+".star", c.Script, customScriptGlobals)
+	if err != nil {
+		return nil, fmt.Errorf("custom check %s: %v", c.ID, err)
+	}
+	check, ok := globals["check"].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("custom check %s: the script defines no check function", c.ID)
+	}
+	value, err := starlark.Call(thread, starlarkjson.Module.Members["decode"], starlark.Tuple{starlark.String(input)}, nil)
+	if err != nil {
+		return nil, err
+	}
+	findings, err := starlark.Call(thread, check, starlark.Tuple{starlark.String(project), value}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("custom check %s: %v", c.ID, err)
+	}
+	output, err := starlark.Call(thread, starlarkjson.Module.Members["encode"], starlark.Tuple{findings}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("custom check %s: invalid findings: %v", c.ID, err)
+	}
+	var info []Info
+	if err := json.Unmarshal([]byte(output.(starlark.String)), &info); err != nil {
+		return nil, fmt.Errorf("custom check %s: invalid findings: %v", c.ID, err)
+	}
+	return info, nil
+}
+
+// checkCustomScript adds the findings reported by the script of c to result.
+func checkCustomScript(result Result, c CustomCheck, info []Info) Result {
+	if len(info) == 0 {
+		return result
+	}
+	status, _ := c.status()
+	for _, i := range info {
+		if i.Count == 0 {
+			i.Count = 1
+		}
+		if i.Message == "" {
+			i.Message = c.Message
+		}
+		result.Info = append(result.Info, i)
+	}
+	result.Status = status
+	result.StatusMessage = fmt.Sprintf("the script reported one or more %s", c.Resource)
+	return result
+}
+
+// checkCustom applies c to the given resource definitions.
+func checkCustom(result Result, c CustomCheck, items []map[string]interface{}) Result {
+	re := regexp.MustCompile(c.Match)
+	status, _ := c.status()
+	path := splitJSONPath(c.Path)
+	for _, item := range items {
+		for _, v := range selectJSONPath(item, path) {
+			value := fmt.Sprint(v)
+			if !re.MatchString(value) {
+				continue
+			}
+			metadata, _ := item["metadata"].(map[string]interface{})
+			name, _ := metadata["name"].(string)
+			namespace, _ := metadata["namespace"].(string)
+			kind, _ := item["kind"].(string)
+			message := c.Message
+			if message == "" {
+				message = fmt.Sprintf("%s is %q", c.Path, value)
+			}
+			result.Status = status
+			result.StatusMessage = fmt.Sprintf("one or more %s matched %s", c.Resource, c.Path)
+			result.Info = append(result.Info, Info{Name: name, Namespace: namespace, Kind: kind, Count: 1, Message: message})
+			break
+		}
+	}
+	return result
+}
+
+// selectJSONPath returns the values selected by path in v.
+func selectJSONPath(v interface{}, path []string) []interface{} {
+	if len(path) == 0 {
+		if v == nil {
+			return nil
+		}
+		return []interface{}{v}
+	}
+	var values []interface{}
+	switch segment := path[0]; segment {
+	case "[*]":
+		a, _ := v.([]interface{})
+		for _, e := range a {
+			values = append(values, selectJSONPath(e, path[1:])...)
+		}
+	case "*":
+		o, _ := v.(map[string]interface{})
+		for _, e := range o {
+			values = append(values, selectJSONPath(e, path[1:])...)
+		}
+	default:
+		o, _ := v.(map[string]interface{})
+		if e, ok := o[segment]; ok {
+			values = selectJSONPath(e, path[1:])
+		}
+	}
+	return values
+}
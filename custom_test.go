@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+)
+
+func TestCheckCustom(t *testing.T) {
+	var items []map[string]interface{}
+	if err := json.Unmarshal([]byte(`[
+		{"kind": "Pod", "metadata": {"name": "fh-aaa-1", "namespace": "core"}, "spec": {"containers": [{"image": "rhmap/fh-aaa:latest"}, {"image": "rhmap/proxy:1.0"}]}},
+		{"kind": "Pod", "metadata": {"name": "millicore-1", "namespace": "core"}, "spec": {"containers": [{"image": "rhmap/millicore:4.2"}]}}
+	]`), &items); err != nil {
+		t.Fatal(err)
+	}
+	c := CustomCheck{ID: "latest-images", Resource: "pods", Path: "spec.containers[*].image", Match: ":latest$", Status: "critical"}
+	if err := c.validate(); err != nil {
+		t.Fatal(err)
+	}
+	result := checkCustom(Result{Status: StatusOK, CheckID: c.ID}, c, items)
+	if result.Status != StatusCritical {
+		t.Errorf("Status = %d, want %d", result.Status, StatusCritical)
+	}
+	if len(result.Info) != 1 || result.Info[0].Name != "fh-aaa-1" || result.Info[0].Kind != "Pod" {
+		t.Errorf("Info = %+v, want only fh-aaa-1", result.Info)
+	}
+
+	for _, invalid := range []CustomCheck{
+		{ID: "no-path", Resource: "pods"},
+		{ID: "path-and-script", Resource: "pods", Path: "status.phase", Script: "def check(project, items):\n    return []"},
+		{ID: "bad-script", Resource: "pods", Script: "def check(project, items)"},
+		{ID: "undefined-name", Resource: "pods", Script: "def check(project, items):\n    return os.listdir()"},
+		{ID: "bad-regexp", Resource: "pods", Path: "status.phase", Match: "("},
+		{ID: "bad-status", Resource: "pods", Path: "status.phase", Status: "ok"},
+	} {
+		if err := invalid.validate(); err == nil {
+			t.Errorf("%s: validate() didn't return an error", invalid.ID)
+		}
+	}
+}
+
+func TestCustomScript(t *testing.T) {
+	items := []map[string]interface{}{
+		{"kind": "DeploymentConfig", "metadata": map[string]interface{}{"name": "fh-aaa", "namespace": "core"}, "spec": map[string]interface{}{"replicas": 1}},
+		{"kind": "DeploymentConfig", "metadata": map[string]interface{}{"name": "millicore", "namespace": "core"}, "spec": map[string]interface{}{"replicas": 2}},
+	}
+	c := CustomCheck{ID: "single-replica", Resource: "dc", Message: "the deployment config has a single replica", Script: `
+def check(project, items):
+    print("checking", len(items), "deployment configs of", project)
+    return [
+        {"name": dc["metadata"]["name"], "namespace": project, "kind": dc["kind"]}
+        for dc in items
+        if dc["spec"]["replicas"] == 1
+    ]
+`}
+	if err := c.validate(); err != nil {
+		t.Fatal(err)
+	}
+	var stdErr bytes.Buffer
+	info, err := runCustomScript(context.Background(), c, "core", items, &stdErr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdErr.String(), "checking 2 deployment configs of core\n"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+	result := checkCustomScript(Result{Status: StatusOK, CheckID: c.ID}, c, info)
+	if result.Status != StatusWarning {
+		t.Errorf("Status = %d, want %d", result.Status, StatusWarning)
+	}
+	want := Info{Name: "fh-aaa", Namespace: "core", Kind: "DeploymentConfig", Count: 1, Message: "the deployment config has a single replica"}
+	if len(result.Info) != 1 || result.Info[0] != want {
+		t.Errorf("Info = %+v, want [%+v]", result.Info, want)
+	}
+
+	info, err = runCustomScript(context.Background(), c, "mbaas", nil, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := checkCustomScript(Result{Status: StatusOK, CheckID: c.ID}, c, info); result.Status != StatusOK || len(result.Info) != 0 {
+		t.Errorf("checkCustomScript() = %+v, want no findings", result)
+	}
+
+	for _, script := range []string{
+		"x = 1",
+		"def check(project, items):\n    return 1",
+		"def check(project, items):\n    return [{'name': len}]",
+		"def check(project, items):\n    for i in range(100000000):\n        pass",
+	} {
+		c.Script = script
+		if _, err := runCustomScript(context.Background(), c, "core", items, ioutil.Discard); err == nil {
+			t.Errorf("runCustomScript(%q) didn't return an error", script)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.Script = "def check(project, items):\n    return []"
+	if _, err := runCustomScript(ctx, c, "core", items, ioutil.Discard); err == nil {
+		t.Error("runCustomScript() didn't return an error once cancelled")
+	}
+}
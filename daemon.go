@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// daemonArgs returns the arguments of the dumps run with -every: the flags set
+// on fs, except -every itself, and -compare, so that each dump reports what
+// changed since the previous one.
+func daemonArgs(fs *flag.FlagSet) []string {
+	args := []string{"-compare"}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "every" || f.Name == "compare" {
+			return
+		}
+		args = append(args, "-"+f.Name+"="+f.Value.String())
+	})
+	return args
+}
+
+// RunDaemon runs a dump every interval until SIGINT or SIGTERM. Each dump is
+// run by a new process of the tool, with args, so that no state is carried
+// from one dump to the next but the dumps written.
+func RunDaemon(interval time.Duration, args []string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	return runDaemon(executable, args, interval, signals)
+}
+
+// runDaemon runs executable with args every interval, until stop receives a
+// value. The signals received while a run is in progress are forwarded to it,
+// so that it completes with what it collected, or quits on the second one, and
+// the run is waited for. Runs are in their own process group, so that the
+// signals sent to the group of the daemon, as with Ctrl-C, reach them once.
+func runDaemon(executable string, args []string, interval time.Duration, stop <-chan os.Signal) error {
+	for {
+		next := time.Now().Add(interval)
+		cmd := exec.Command(executable, args...)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		done := make(chan error, 1)
+		go func() { done <- cmd.Wait() }()
+
+		stopping := false
+	wait:
+		for {
+			select {
+			case err := <-done:
+				if err != nil {
+					log.Printf("The dump failed: %v\n", err)
+				}
+				break wait
+			case sig := <-stop:
+				if !stopping {
+					log.Printf("Received %v, stopping once the running dump completes\n", sig)
+					stopping = true
+				}
+				if err := cmd.Process.Signal(sig); err != nil {
+					log.Printf("Could not forward %v to the running dump: %v\n", sig, err)
+				}
+			}
+		}
+		if stopping {
+			return nil
+		}
+
+		log.Printf("Next dump at %s\n", next.Format(time.RFC3339))
+		select {
+		case <-time.After(time.Until(next)):
+		case <-stop:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDaemonArgs(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Duration("every", 0, "")
+	fs.Bool("compare", false, "")
+	fs.String("out", "", "")
+	fs.Int("p", 1, "")
+	if err := fs.Parse([]string{"-every", "24h", "-out", "dumps/{{.Timestamp}}", "-compare"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := daemonArgs(fs), []string{"-compare", "-out=dumps/{{.Timestamp}}"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("daemonArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestRunDaemon(t *testing.T) {
+	dir, err := ioutil.TempDir("", "daemon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	runs := filepath.Join(dir, "runs")
+
+	stop := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- runDaemon("sh", []string{"-c", "echo run >> " + runs}, 10*time.Millisecond, stop)
+	}()
+	for i := 0; ; i++ {
+		content, _ := ioutil.ReadFile(runs)
+		if strings.Count(string(content), "run") >= 3 {
+			break
+		}
+		if i == 500 {
+			t.Fatalf("the daemon ran %d dumps, want at least 3", strings.Count(string(content), "run"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	stop <- os.Interrupt
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("runDaemon() = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the daemon didn't stop")
+	}
+}
+
+func TestRunDaemonForwardsSignals(t *testing.T) {
+	dir, err := ioutil.TempDir("", "daemon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	stop := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	script := "trap 'echo terminated >> " + out + "; exit 0' TERM; echo started >> " + out + "; while :; do sleep 0.01; done"
+	go func() {
+		done <- runDaemon("sh", []string{"-c", script}, time.Hour, stop)
+	}()
+	for i := 0; ; i++ {
+		content, _ := ioutil.ReadFile(out)
+		if strings.Contains(string(content), "started") {
+			break
+		}
+		if i == 500 {
+			t.Fatal("the dump didn't start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	stop <- syscall.SIGTERM
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("runDaemon() = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the daemon didn't stop")
+	}
+	if content, _ := ioutil.ReadFile(out); !strings.Contains(string(content), "terminated") {
+		t.Errorf("the dump didn't receive SIGTERM, it wrote %q", content)
+	}
+}
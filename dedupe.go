@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+var (
+	// logErrorLine matches log lines that are never collapsed.
+	logErrorLine = regexp.MustCompile(`(?i)error|exception|fatal|panic|fail`)
+	// logDigits matches the variable parts of otherwise identical log
+	// lines, such as timestamps, counters and ids.
+	logDigits = regexp.MustCompile(`[0-9]+`)
+)
+
+// A lineDeduper is an io.Writer that collapses runs of log lines that only
+// differ by numbers, such as timestamps, into the first line of the run and a
+// count of repetitions. Lines that look like errors are always written in
+// full. Flush must be called after the last write.
+type lineDeduper struct {
+	w       io.Writer
+	partial []byte
+	// last is the pattern of the current run, and repeated the number of
+	// lines collapsed into it.
+	last     []byte
+	repeated int
+	err      error
+}
+
+func newLineDeduper(w io.Writer) *lineDeduper {
+	return &lineDeduper{w: w}
+}
+
+func (d *lineDeduper) Write(p []byte) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	d.partial = append(d.partial, p...)
+	for {
+		i := bytes.IndexByte(d.partial, '\n')
+		if i < 0 {
+			break
+		}
+		d.writeLine(d.partial[:i+1])
+		d.partial = d.partial[i+1:]
+	}
+	return len(p), d.err
+}
+
+func (d *lineDeduper) writeLine(line []byte) {
+	if logErrorLine.Match(line) {
+		d.endRun()
+		d.write(line)
+		return
+	}
+	pattern := logDigits.ReplaceAll(line, []byte("0"))
+	if d.last != nil && bytes.Equal(pattern, d.last) {
+		d.repeated++
+		return
+	}
+	d.endRun()
+	d.write(line)
+	d.last = pattern
+}
+
+// endRun writes the number of lines collapsed in the current run, if any.
+func (d *lineDeduper) endRun() {
+	if d.repeated > 0 {
+		d.write([]byte(fmt.Sprintf("... [previous line repeated %d more times]\n", d.repeated)))
+	}
+	d.last, d.repeated = nil, 0
+}
+
+func (d *lineDeduper) write(p []byte) {
+	if d.err == nil {
+		_, d.err = d.w.Write(p)
+	}
+}
+
+// Flush writes any pending output.
+func (d *lineDeduper) Flush() error {
+	if len(d.partial) > 0 {
+		d.writeLine(append(d.partial, '\n'))
+		d.partial = nil
+	}
+	d.endRun()
+	return d.err
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLineDeduper(t *testing.T) {
+	input := "12:00:01 GET /sys/info/ping 200\n" +
+		"12:00:02 GET /sys/info/ping 200\n" +
+		"12:00:03 GET /sys/info/ping 200\n" +
+		"12:00:04 ERROR connection refused\n" +
+		"12:00:05 ERROR connection refused\n" +
+		"12:00:06 GET /sys/info/ping 200\n" +
+		"12:00:07 started\n" +
+		"12:00:08 GET /sys/info/ping 200"
+	want := "12:00:01 GET /sys/info/ping 200\n" +
+		"... [previous line repeated 2 more times]\n" +
+		"12:00:04 ERROR connection refused\n" +
+		"12:00:05 ERROR connection refused\n" +
+		"12:00:06 GET /sys/info/ping 200\n" +
+		"12:00:07 started\n" +
+		"12:00:08 GET /sys/info/ping 200\n"
+
+	var buf bytes.Buffer
+	d := newLineDeduper(&buf)
+	// Write in small chunks to exercise lines split across writes.
+	for i := 0; i < len(input); i += 7 {
+		end := i + 7
+		if end > len(input) {
+			end = len(input)
+		}
+		if _, err := d.Write([]byte(input[i:end])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
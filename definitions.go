@@ -1,14 +1,33 @@
 package main
 
-import "os/exec"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+)
 
 // ResourceDefinitions is a task factory for tasks that fetch the JSON resource
 // definition for all given types in project. For each resource type, the task
 // uses outFor and errOutFor to get io.Writers to write, respectively, the JSON
 // output and any eventual error message.
 func ResourceDefinitions(project string, types []string, outFor, errOutFor projectResourceWriterCloserFactory) Task {
+	return SelectedResourceDefinitions(project, types, "", outFor, errOutFor)
+}
+
+// SelectedResourceDefinitions is like ResourceDefinitions, but only fetches the
+// resources matching the label selector, unless it is empty. Events, which have
+// no labels, are all fetched.
+func SelectedResourceDefinitions(project string, types []string, selector string, outFor, errOutFor projectResourceWriterCloserFactory) Task {
 	return resourceDefinitions(func(project, resource string) *exec.Cmd {
-		return exec.Command("oc", "-n", project, "get", resource, "-o=json")
+		args := []string{"-n", project, "get", resource, "-o=json"}
+		if selector != "" && resource != "events" {
+			args = append(args, "-l", selector)
+		}
+		return ocCommand(args...)
 	}, project, types, outFor, errOutFor)
 }
 
@@ -17,7 +36,7 @@ func ResourceDefinitions(project string, types []string, outFor, errOutFor proje
 type getProjectResourceCmdFactory func(project, resource string) *exec.Cmd
 
 func resourceDefinitions(cmdFactory getProjectResourceCmdFactory, project string, types []string, outFor, errOutFor projectResourceWriterCloserFactory) Task {
-	return func() error {
+	return func(ctx context.Context) error {
 		var errors errorList
 		// NOTE: we could fetch all resources of all types in a single
 		// call to oc, by passing a comma-separated list of resource
@@ -26,7 +45,7 @@ func resourceDefinitions(cmdFactory getProjectResourceCmdFactory, project string
 		// output from oc.
 		for _, resource := range types {
 			cmd := cmdFactory(project, resource)
-			if err := runCmdCaptureOutputDeprecated(cmd, project, resource, outFor, errOutFor); err != nil {
+			if err := runCmdCaptureOutputDeprecated(ctx, cmd, project, resource, outFor, errOutFor); err != nil {
 				// In case of errors, report it, skip the
 				// current resource type and proceed with the
 				// next.
@@ -40,3 +59,214 @@ func resourceDefinitions(cmdFactory getProjectResourceCmdFactory, project string
 		return nil
 	}
 }
+
+// Verbosities of the collected resource definitions.
+const (
+	// definitionsFull collects resource definitions as returned by oc.
+	definitionsFull = "full"
+	// definitionsStatus collects only the fields of resources kept by
+	// statusOnly.
+	definitionsStatus = "status"
+)
+
+// checkDefinitionsVerbosity returns an error if verbosity is not a verbosity of
+// resource definitions.
+func checkDefinitionsVerbosity(verbosity string) error {
+	if verbosity != definitionsFull && verbosity != definitionsStatus {
+		return fmt.Errorf("unknown definitions verbosity %q, must be full or status", verbosity)
+	}
+	return nil
+}
+
+// statusFields are the top-level fields of resources kept in status-only
+// definitions. Specs, data and annotations, which may hold configuration and
+// credentials, are left out, while events are kept whole, being status
+// records.
+var statusFields = []string{
+	"apiVersion", "kind", "metadata", "status",
+	"type", "reason", "message", "count", "source", "involvedObject", "firstTimestamp", "lastTimestamp",
+}
+
+// statusOnly returns the JSON definitions of resources in content, a resource
+// or a list of resources, with only their statusFields and without their
+// annotations. Content that is not a JSON object is returned as is.
+func statusOnly(content []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var resource map[string]interface{}
+	if err := decoder.Decode(&resource); err != nil {
+		return content
+	}
+	if items, ok := resource["items"].([]interface{}); ok {
+		for i, item := range items {
+			if item, ok := item.(map[string]interface{}); ok {
+				items[i] = statusOnlyResource(item)
+			}
+		}
+	} else {
+		resource = statusOnlyResource(resource)
+	}
+	output, err := json.MarshalIndent(resource, "", "    ")
+	if err != nil {
+		return content
+	}
+	return append(output, '\n')
+}
+
+func statusOnlyResource(resource map[string]interface{}) map[string]interface{} {
+	kept := make(map[string]interface{})
+	for _, field := range statusFields {
+		if v, ok := resource[field]; ok {
+			kept[field] = v
+		}
+	}
+	if metadata, ok := kept["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "annotations")
+		delete(metadata, "managedFields")
+	}
+	return kept
+}
+
+// statusOnlyOutFor returns a factory of writers keeping what is written to the
+// writers of outFor until closed, then writing only its statusOnly subset.
+func statusOnlyOutFor(outFor projectResourceWriterCloserFactory) projectResourceWriterCloserFactory {
+	return func(project, resource string) (io.Writer, io.Closer, error) {
+		w, c, err := outFor(project, resource)
+		if err != nil {
+			return nil, nil, err
+		}
+		writer := &statusOnlyWriter{w: w, c: c}
+		return writer, writer, nil
+	}
+}
+
+// A statusOnlyWriter writes the statusOnly subset of what was written to it to
+// w when closed, and closes c.
+type statusOnlyWriter struct {
+	buf bytes.Buffer
+	w   io.Writer
+	c   io.Closer
+}
+
+func (s *statusOnlyWriter) Write(p []byte) (int, error) {
+	return s.buf.Write(p)
+}
+
+func (s *statusOnlyWriter) Close() error {
+	_, err := s.w.Write(statusOnly(s.buf.Bytes()))
+	if cerr := s.c.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// A definitionStore keeps in memory the definitions collected in the projects
+// whose analysis checks are to run, as returned by oc before redaction, so
+// that the checks read them instead of fetching them again.
+type definitionStore struct {
+	mu          sync.Mutex
+	definitions map[string]map[string][]byte // by project, then resource type
+}
+
+// collectedDefinitions holds the definitions collected during the dump.
+var collectedDefinitions = &definitionStore{}
+
+// resourceAliases maps the short names of resource types used by the checks to
+// the names their definitions are collected under.
+var resourceAliases = map[string]string{
+	"dc":  "deploymentconfigs",
+	"pvc": "persistentvolumeclaims",
+}
+
+// expect has the definitions later collected in project kept, until released.
+func (s *definitionStore) expect(project string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.definitions == nil {
+		s.definitions = make(map[string]map[string][]byte)
+	}
+	if s.definitions[project] == nil {
+		s.definitions[project] = make(map[string][]byte)
+	}
+}
+
+// release drops the definitions kept for project, and stops keeping them.
+func (s *definitionStore) release(project string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.definitions, project)
+}
+
+func (s *definitionStore) add(project, resource string, content []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if defs, ok := s.definitions[project]; ok {
+		defs[resource] = content
+	}
+}
+
+// get returns the definitions of resources of the given type kept for project,
+// if any.
+func (s *definitionStore) get(project, resource string) ([]byte, bool) {
+	if name, ok := resourceAliases[resource]; ok {
+		resource = name
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.definitions[project][resource]
+	return content, ok
+}
+
+// outFor returns a factory of writers writing to the writers of outFor, and
+// keeping what was written in s when closed, if it is valid JSON.
+func (s *definitionStore) outFor(outFor projectResourceWriterCloserFactory) projectResourceWriterCloserFactory {
+	return func(project, resource string) (io.Writer, io.Closer, error) {
+		w, c, err := outFor(project, resource)
+		if err != nil {
+			return nil, nil, err
+		}
+		writer := &keptDefinitionsWriter{store: s, project: project, resource: resource, w: w, c: c}
+		return writer, writer, nil
+	}
+}
+
+// A keptDefinitionsWriter writes to w, and keeps what was written in store when
+// closed. It passes on the command and task of its output, and resets, to w.
+type keptDefinitionsWriter struct {
+	store             *definitionStore
+	project, resource string
+	buf               bytes.Buffer
+	w                 io.Writer
+	c                 io.Closer
+}
+
+func (k *keptDefinitionsWriter) Write(p []byte) (int, error) {
+	k.buf.Write(p)
+	return k.w.Write(p)
+}
+
+func (k *keptDefinitionsWriter) Reset() {
+	k.buf.Reset()
+	if r, ok := k.w.(resetter); ok {
+		r.Reset()
+	}
+}
+
+func (k *keptDefinitionsWriter) setCommand(args []string) {
+	if w, ok := k.w.(interface{ setCommand([]string) }); ok {
+		w.setCommand(args)
+	}
+}
+
+func (k *keptDefinitionsWriter) setTask(name, project string) {
+	if w, ok := k.w.(interface{ setTask(name, project string) }); ok {
+		w.setTask(name, project)
+	}
+}
+
+func (k *keptDefinitionsWriter) Close() error {
+	if content := k.buf.Bytes(); len(content) > 0 && json.Valid(content) {
+		k.store.add(k.project, k.resource, content)
+	}
+	return k.c.Close()
+}
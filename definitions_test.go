@@ -2,11 +2,14 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os/exec"
 	"testing"
+
+	"github.com/feedhenry/fh-system-dump-tool/cmdrunner"
 )
 
 func TestResourceDefinitions(t *testing.T) {
@@ -39,7 +42,7 @@ func TestResourceDefinitions(t *testing.T) {
 		}
 		task := resourceDefinitions(cmdFactory, tt.project, tt.types, outFor, errOutFor)
 
-		if err := task(); err != nil {
+		if err := task(context.Background()); err != nil {
 			t.Errorf("task failed: %v", err)
 		}
 		if got := stdout.String(); got != tt.wantStdout {
@@ -50,3 +53,130 @@ func TestResourceDefinitions(t *testing.T) {
 		}
 	}
 }
+
+func TestStatusOnly(t *testing.T) {
+	content := `{
+    "kind": "List",
+    "items": [
+        {
+            "kind": "Pod",
+            "metadata": {"name": "web-1", "annotations": {"config": "secret"}, "labels": {"app": "web"}},
+            "spec": {"containers": [{"env": [{"name": "PASSWORD", "value": "secret"}]}]},
+            "status": {"phase": "Running", "restartCount": 3}
+        },
+        {
+            "kind": "Event",
+            "metadata": {"name": "web-1.1"},
+            "involvedObject": {"kind": "Pod", "name": "web-1"},
+            "reason": "BackOff",
+            "count": 2
+        }
+    ]
+}`
+	want := `{
+    "items": [
+        {
+            "kind": "Pod",
+            "metadata": {
+                "labels": {
+                    "app": "web"
+                },
+                "name": "web-1"
+            },
+            "status": {
+                "phase": "Running",
+                "restartCount": 3
+            }
+        },
+        {
+            "count": 2,
+            "involvedObject": {
+                "kind": "Pod",
+                "name": "web-1"
+            },
+            "kind": "Event",
+            "metadata": {
+                "name": "web-1.1"
+            },
+            "reason": "BackOff"
+        }
+    ],
+    "kind": "List"
+}
+`
+	if got := string(statusOnly([]byte(content))); got != want {
+		t.Errorf("statusOnly() = %s, want %s", got, want)
+	}
+	if got := string(statusOnly([]byte("error: no resources"))); got != "error: no resources" {
+		t.Errorf("statusOnly() of invalid JSON = %q, want it unchanged", got)
+	}
+}
+
+func TestSelectedResourceDefinitions(t *testing.T) {
+	defer func(old cmdrunner.Runner) { runner = old }(runner)
+	runner = cmdrunner.NewFakeRunner([]cmdrunner.Invocation{
+		{Args: ocCommand("-n", "core", "get", "pods", "-o=json", "-l", "app=fh-mbaas").Args, Stdout: "pods\n"},
+		// Events have no labels.
+		{Args: ocCommand("-n", "core", "get", "events", "-o=json").Args, Stdout: "events\n"},
+	})
+	var stdout, stderr bytes.Buffer
+	outFor := func(project, resource string) (io.Writer, io.Closer, error) {
+		return &stdout, ioutil.NopCloser(nil), nil
+	}
+	errOutFor := func(project, resource string) (io.Writer, io.Closer, error) {
+		return &stderr, ioutil.NopCloser(nil), nil
+	}
+	task := SelectedResourceDefinitions("core", []string{"pods", "events"}, "app=fh-mbaas", outFor, errOutFor)
+	if err := task(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "pods\nevents\n"; got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+}
+
+func TestCollectedDefinitions(t *testing.T) {
+	defer func(old cmdrunner.Runner) { runner = old }(runner)
+	defer func(s *definitionStore) { collectedDefinitions = s }(collectedDefinitions)
+	collectedDefinitions = &definitionStore{}
+	// No command may run: the definitions are read from the store.
+	runner = cmdrunner.NewFakeRunner(nil)
+
+	var out bytes.Buffer
+	outFor := collectedDefinitions.outFor(func(project, resource string) (io.Writer, io.Closer, error) {
+		return &out, ioutil.NopCloser(nil), nil
+	})
+	write := func(project, resource, content string) {
+		w, c, err := outFor(project, resource)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, content)
+		c.Close()
+	}
+	collectedDefinitions.expect("core")
+	write("core", "deploymentconfigs", `{"items": [{"metadata": {"name": "fh-aaa"}}]}`)
+	write("core", "pods", `error: not JSON`)
+	write("mbaas", "deploymentconfigs", `{"items": []}`)
+
+	var dcs DeploymentConfigs
+	if err := getResourceStruct(context.Background(), "core", "dc", &dcs); err != nil {
+		t.Fatal(err)
+	}
+	if len(dcs.Items) != 1 || dcs.Items[0].Metadata.Name != "fh-aaa" {
+		t.Errorf("getResourceStruct() = %+v, want the collected fh-aaa", dcs)
+	}
+	if out.Len() == 0 {
+		t.Error("the definitions were not written to the writers of outFor")
+	}
+	if _, ok := collectedDefinitions.get("core", "pods"); ok {
+		t.Error("invalid definitions of pods were kept")
+	}
+	if _, ok := collectedDefinitions.get("mbaas", "deploymentconfigs"); ok {
+		t.Error("definitions of mbaas were kept without being expected")
+	}
+	collectedDefinitions.release("core")
+	if _, ok := collectedDefinitions.get("core", "deploymentconfigs"); ok {
+		t.Error("definitions of core were kept after being released")
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// projectLookupInterval is how long the tasks of a project failing because
+// resources are not found rely on the last lookup of the project, rather than
+// looking it up again.
+const projectLookupInterval = 30 * time.Second
+
+// A ProjectDeletedError is returned by the tasks of a project deleted during
+// the dump, instead of the errors of their commands. Tasks of the project that
+// did not start yet are skipped.
+type ProjectDeletedError struct {
+	Task    string
+	Project string
+}
+
+func (e *ProjectDeletedError) Error() string {
+	return fmt.Sprintf("%s in project %s: skipped, the project was deleted during the dump", e.Task, e.Project)
+}
+
+// A deletedProjectSet keeps track of the projects deleted during the dump.
+type deletedProjectSet struct {
+	mu      sync.Mutex
+	deleted map[string]bool
+	// lookedUp is when each project was last found to exist.
+	lookedUp map[string]time.Time
+}
+
+// deletedProjects are the projects found to be deleted during the dump.
+var deletedProjects = &deletedProjectSet{}
+
+// has reports whether project was found to be deleted.
+func (s *deletedProjectSet) has(project string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deleted[project]
+}
+
+// check reports whether project was deleted, given that one of its tasks
+// failed with err. Only tasks failing because resources are not found, or
+// access to them is forbidden, as happens once the project is gone, look up
+// the project, at most once every projectLookupInterval.
+func (s *deletedProjectSet) check(ctx context.Context, project string, err error) bool {
+	if class := ClassifyError(err); class != classNotFound && class != classForbidden {
+		return s.has(project)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.deleted[project] {
+		return true
+	}
+	if time.Since(s.lookedUp[project]) < projectLookupInterval {
+		return false
+	}
+	err = runCmdCaptureOutputOnce(ctx, ocCommand("get", "project", project, "-o=name"), nil, nil)
+	if err == nil || ClassifyError(err) != classNotFound {
+		if s.lookedUp == nil {
+			s.lookedUp = make(map[string]time.Time)
+		}
+		s.lookedUp[project] = time.Now()
+		return false
+	}
+	if s.deleted == nil {
+		s.deleted = make(map[string]bool)
+	}
+	s.deleted[project] = true
+	return true
+}
+
+// splitSkippedTasks separates the errors of the tasks skipped because their
+// project was deleted from the errors of tasks, which are replaced by nil. It
+// returns the number of tasks skipped in each project.
+func splitSkippedTasks(taskErrs []error) (errs, skipped []error, counts map[string]int) {
+	errs = make([]error, len(taskErrs))
+	counts = make(map[string]int)
+	for i, err := range taskErrs {
+		if d, ok := err.(*ProjectDeletedError); ok {
+			skipped = append(skipped, err)
+			counts[d.Project]++
+			continue
+		}
+		errs[i] = err
+	}
+	return errs, skipped, counts
+}
+
+// deletedProjectFindings returns a finding for each project deleted during
+// the dump, given the number of tasks skipped in each.
+func deletedProjectFindings(counts map[string]int) []Finding {
+	var projects []string
+	for p := range counts {
+		projects = append(projects, p)
+	}
+	sort.Strings(projects)
+	var findings []Finding
+	for _, p := range projects {
+		result := Result{Status: StatusWarning, StatusMessage: "the project was deleted during the dump, what was not collected before is missing", CheckID: "project-deleted", CheckName: "check projects deleted during the dump"}
+		result.Info = append(result.Info, Info{Name: p, Namespace: p, Kind: "Project", Count: counts[p], Message: fmt.Sprintf("%d tasks were skipped", counts[p])})
+		findings = append(findings, Finding{Project: p, Result: result})
+	}
+	return findings
+}
+
+// remainingProjects returns the projects not deleted during the dump, given
+// the number of tasks skipped in each.
+func remainingProjects(projects []string, skipped map[string]int) []string {
+	var remaining []string
+	for _, p := range projects {
+		if skipped[p] == 0 {
+			remaining = append(remaining, p)
+		}
+	}
+	return remaining
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"reflect"
+	"testing"
+
+	"github.com/feedhenry/fh-system-dump-tool/cmdrunner"
+)
+
+// exitingRunner is a FakeRunner whose failing commands exit with an error,
+// like oc does.
+type exitingRunner struct{ *cmdrunner.FakeRunner }
+
+func (r exitingRunner) Run(ctx context.Context, cmd *exec.Cmd) error {
+	if err := r.FakeRunner.Run(ctx, cmd); err != nil {
+		return &exec.ExitError{}
+	}
+	return nil
+}
+
+func TestNamedTaskOfDeletedProject(t *testing.T) {
+	defer func(r cmdrunner.Runner) { runner = r }(runner)
+	defer func(s *deletedProjectSet) { deletedProjects = s }(deletedProjects)
+	deletedProjects = &deletedProjectSet{}
+	notFound := `Error from server (NotFound): namespaces "rhmap-dev" not found`
+	runner = exitingRunner{cmdrunner.NewFakeRunner([]cmdrunner.Invocation{
+		{Args: ocCommand("-n", "rhmap-dev", "get", "pods").Args, Stderr: notFound, Error: "exit status 1"},
+		{Args: ocCommand("-n", "rhmap-core", "get", "statefulsets").Args, Stderr: `error: the server doesn't have a resource type "statefulsets"`, Error: "exit status 1"},
+		{Args: ocCommand("get", "project", "rhmap-dev", "-o=name").Args, Stderr: `Error from server (NotFound): namespaces "rhmap-dev" not found`, Error: "exit status 1"},
+		{Args: ocCommand("get", "project", "rhmap-core", "-o=name").Args, Stdout: "project/rhmap-core"},
+	})}
+	run := func(project string, args ...string) Task {
+		return namedTask("fetch "+args[len(args)-1], project, func(ctx context.Context) error {
+			return runCmdCaptureOutputOnce(ctx, ocCommand(append([]string{"-n", project}, args...)...), nil, nil)
+		})
+	}
+	ran := false
+	tasks := []Task{
+		run("rhmap-dev", "get", "pods"),
+		namedTask("fetch routes", "rhmap-dev", func(ctx context.Context) error { ran = true; return nil }),
+		run("rhmap-core", "get", "statefulsets"),
+	}
+	errs := RunAllTasks(context.Background(), tasks, 1, 0)
+	if ran {
+		t.Error("a task of the deleted project ran")
+	}
+	if _, ok := errs[2].(*TaskFailure); !ok {
+		t.Errorf("error of the task of the remaining project = %v, want a task failure", errs[2])
+	}
+
+	errs, skipped, counts := splitSkippedTasks(errs)
+	if errs[0] != nil || errs[1] != nil || errs[2] == nil || len(skipped) != 2 {
+		t.Errorf("splitSkippedTasks() = %v, %v, want the errors of the deleted project skipped", errs, skipped)
+	}
+	if want := map[string]int{"rhmap-dev": 2}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("skipped counts = %v, want %v", counts, want)
+	}
+	findings := deletedProjectFindings(counts)
+	if len(findings) != 1 || findings[0].Project != "rhmap-dev" || findings[0].Result.Status != StatusWarning {
+		t.Errorf("deletedProjectFindings() = %+v, want a warning for rhmap-dev", findings)
+	}
+	if got := remainingProjects([]string{"rhmap-core", "rhmap-dev"}, counts); !reflect.DeepEqual(got, []string{"rhmap-core"}) {
+		t.Errorf("remainingProjects() = %v, want rhmap-core", got)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+)
+
+// defaultDeniedResources are the types of resources found by discovery whose
+// definitions are not collected unless allowed explicitly. The values of
+// secrets are never collected, see the secrets collector.
+var defaultDeniedResources = []string{"secrets", "imagestreamtags", "imagetags", "imagestreamimages"}
+
+// A ResourceFilter selects the types of resources found by discovery whose
+// definitions are collected. Types are named by their plural name, like
+// deploymentconfigs, optionally followed by their API group, like
+// deploymentconfigs.apps.openshift.io.
+type ResourceFilter struct {
+	// Allow, if not empty, lists the only types collected. Types it
+	// lists are collected even if they are denied by default.
+	Allow []string `json:"allow"`
+	// Deny lists types not collected, along with defaultDeniedResources.
+	Deny []string `json:"deny"`
+}
+
+// DiscoverResourceTypes returns the types of namespaced resources that can be
+// listed on the cluster, as reported by API discovery, selected by filter and
+// sorted by name.
+func DiscoverResourceTypes(ctx context.Context, filter ResourceFilter) ([]string, error) {
+	var stdout bytes.Buffer
+	cmd := ocCommand("api-resources", "--namespaced=true", "--verbs=list", "-o=name")
+	if err := runCmdCaptureOutput(ctx, cmd, &stdout, nil); err != nil {
+		return nil, err
+	}
+	return filterResourceTypes(strings.Fields(stdout.String()), filter), nil
+}
+
+// filterResourceTypes returns the plural names of the types discovered, as
+// name.group, selected by filter. Types served by several API groups, like
+// events and events.events.k8s.io, are returned once.
+func filterResourceTypes(discovered []string, filter ResourceFilter) []string {
+	matches := func(list []string, t string) bool {
+		name := strings.SplitN(t, ".", 2)[0]
+		return containsString(list, t) || containsString(list, name)
+	}
+	seen := make(map[string]bool)
+	var types []string
+	for _, t := range discovered {
+		name := strings.SplitN(t, ".", 2)[0]
+		allowed := matches(filter.Allow, t)
+		switch {
+		case seen[name]:
+		case len(filter.Allow) > 0 && !allowed:
+		case matches(filter.Deny, t):
+		case matches(defaultDeniedResources, t) && !allowed:
+		default:
+			seen[name] = true
+			types = append(types, name)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
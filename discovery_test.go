@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/feedhenry/fh-system-dump-tool/cmdrunner"
+)
+
+func TestFilterResourceTypes(t *testing.T) {
+	discovered := []string{"pods", "events", "secrets", "deploymentconfigs.apps.openshift.io", "events.events.k8s.io", "imagestreamtags.image.openshift.io", "routes.route.openshift.io"}
+	tests := []struct {
+		filter ResourceFilter
+		want   []string
+	}{
+		{ResourceFilter{}, []string{"deploymentconfigs", "events", "pods", "routes"}},
+		{ResourceFilter{Deny: []string{"events", "routes.route.openshift.io"}}, []string{"deploymentconfigs", "pods"}},
+		{ResourceFilter{Allow: []string{"pods", "deploymentconfigs.apps.openshift.io", "imagestreamtags"}}, []string{"deploymentconfigs", "imagestreamtags", "pods"}},
+		{ResourceFilter{Allow: []string{"pods", "routes"}, Deny: []string{"routes"}}, []string{"pods"}},
+	}
+	for _, tt := range tests {
+		if got := filterResourceTypes(discovered, tt.filter); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("filterResourceTypes(%+v) = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestDiscoverResourceTypes(t *testing.T) {
+	defer func(r cmdrunner.Runner) { runner = r }(runner)
+	runner = cmdrunner.NewFakeRunner([]cmdrunner.Invocation{{
+		Args:   ocCommand("api-resources", "--namespaced=true", "--verbs=list", "-o=name").Args,
+		Stdout: "configmaps\nsecrets\nstatefulsets.apps\n",
+	}})
+	got, err := DiscoverResourceTypes(context.Background(), ResourceFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"configmaps", "statefulsets"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DiscoverResourceTypes() = %v, want %v", got, want)
+	}
+}
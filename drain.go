@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// An Affinity holds the scheduling constraints of pods relative to other pods.
+// Only whether pod anti-affinity is set matters to the tool.
+type Affinity struct {
+	PodAntiAffinity *struct {
+		Required  []json.RawMessage `json:"requiredDuringSchedulingIgnoredDuringExecution"`
+		Preferred []json.RawMessage `json:"preferredDuringSchedulingIgnoredDuringExecution"`
+	} `json:"podAntiAffinity"`
+}
+
+// hasPodAntiAffinity reports whether a spreads the pods it applies to across
+// nodes, as a requirement or a preference.
+func (a *Affinity) hasPodAntiAffinity() bool {
+	return a != nil && a.PodAntiAffinity != nil && len(a.PodAntiAffinity.Required)+len(a.PodAntiAffinity.Preferred) > 0
+}
+
+// An intOrPercent is a number of pods, or a percentage of pods like 50%.
+type intOrPercent string
+
+func (v *intOrPercent) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*v = intOrPercent(s)
+		return nil
+	}
+	var n int
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	*v = intOrPercent(strconv.Itoa(n))
+	return nil
+}
+
+// scaled returns the number of pods v amounts to out of total, rounding
+// percentages up as the disruption controller does.
+func (v intOrPercent) scaled(total int) (int, error) {
+	s := string(v)
+	if strings.HasSuffix(s, "%") {
+		percent, err := strconv.Atoi(strings.TrimSuffix(s, "%"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid percentage %q", s)
+		}
+		return (percent*total + 99) / 100, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number of pods %q", s)
+	}
+	return n, nil
+}
+
+type PodDisruptionBudget struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Spec struct {
+		MinAvailable   *intOrPercent `json:"minAvailable"`
+		MaxUnavailable *intOrPercent `json:"maxUnavailable"`
+		Selector       struct {
+			MatchLabels map[string]string `json:"matchLabels"`
+		} `json:"selector"`
+	} `json:"spec"`
+}
+
+type PodDisruptionBudgets struct {
+	Items []PodDisruptionBudget `json:"items"`
+}
+
+// selects reports whether pdb applies to the pods of dc.
+func (pdb PodDisruptionBudget) selects(dc DeploymentConfig) bool {
+	if len(pdb.Spec.Selector.MatchLabels) == 0 {
+		return false
+	}
+	labels := dc.Spec.Template.Metadata.Labels
+	for k, v := range pdb.Spec.Selector.MatchLabels {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// allowedDisruptions returns how many of the given number of replicas pdb lets
+// drains evict at once.
+func (pdb PodDisruptionBudget) allowedDisruptions(replicas int) (int, error) {
+	switch {
+	case pdb.Spec.MaxUnavailable != nil:
+		return pdb.Spec.MaxUnavailable.scaled(replicas)
+	case pdb.Spec.MinAvailable != nil:
+		minAvailable, err := pdb.Spec.MinAvailable.scaled(replicas)
+		return replicas - minAvailable, err
+	}
+	return replicas, nil
+}
+
+// CheckDrainReadiness will check whether the RHMAP components deployed in the supplied project stay available while
+// a node hosting them is drained, given their replicas, pod disruption budgets and pod anti-affinity, and if not this
+// will be reflected in the returned Result data. Any errors are written to the supplied stdErr writer
+func CheckDrainReadiness(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+	result := Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: "drain-readiness", CheckName: "check rhmap components survive node drains"}
+	var dcs DeploymentConfigs
+	if err := getResourceStruct(ctx, project, "dc", &dcs); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	if rhmapProjectKind(dcs) == "" {
+		return result, nil
+	}
+	var pdbs PodDisruptionBudgets
+	if err := getResourceStruct(ctx, project, "poddisruptionbudgets", &pdbs); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	return checkDrainReadiness(result, dcs, pdbs), nil
+}
+
+func checkDrainReadiness(result Result, dcs DeploymentConfigs, pdbs PodDisruptionBudgets) Result {
+	kind := rhmapProjectKind(dcs)
+	if kind == "" {
+		return result
+	}
+	components := make(map[string]bool)
+	for _, c := range referenceComponents[kind] {
+		components[c] = true
+	}
+	found := func(status int, dc DeploymentConfig, message string) {
+		if status > result.Status {
+			result.Status = status
+		}
+		result.StatusMessage = "rhmap components would be disrupted by node drains"
+		result.Info = append(result.Info, Info{Name: dc.Metadata.Name, Namespace: dc.Metadata.Namespace, Kind: "DeploymentConfig", Count: 1, Message: message})
+	}
+	for _, dc := range dcs.Items {
+		replicas := dc.Spec.Replicas
+		if !components[dc.Metadata.Name] || replicas == 0 {
+			continue
+		}
+		var budget *PodDisruptionBudget
+		for i, pdb := range pdbs.Items {
+			if pdb.selects(dc) {
+				budget = &pdbs.Items[i]
+				break
+			}
+		}
+		if budget != nil {
+			allowed, err := budget.allowedDisruptions(replicas)
+			switch {
+			case err != nil:
+				found(StatusWarning, dc, fmt.Sprintf("the pod disruption budget %s could not be read: %v", budget.Metadata.Name, err))
+			case allowed <= 0:
+				found(StatusCritical, dc, fmt.Sprintf("the pod disruption budget %s allows no disruption of its %d replicas, so draining the nodes hosting them never completes", budget.Metadata.Name, replicas))
+			}
+		}
+		switch {
+		case replicas == 1:
+			found(StatusWarning, dc, "it runs a single replica, unavailable while the node hosting it drains")
+		case !dc.Spec.Template.Spec.Affinity.hasPodAntiAffinity():
+			found(StatusWarning, dc, fmt.Sprintf("it has no pod anti-affinity, so its %d replicas may run on the same node and be drained together", replicas))
+		case budget == nil:
+			found(StatusWarning, dc, fmt.Sprintf("no pod disruption budget keeps some of its %d replicas running while nodes drain", replicas))
+		}
+	}
+	return result
+}
+
+// A maintenanceReadiness tells whether the RHMAP components of a project stay
+// available during node maintenance.
+type maintenanceReadiness struct {
+	Project string
+	Ready   bool
+	// Risks are the ways components would be disrupted, as
+	// <component>: <reason>.
+	Risks []string
+}
+
+// maintenanceReadiness returns the readiness of each project for node drains,
+// as assessed by CheckDrainReadiness, sorted by project. Projects the check did
+// not run against are left out.
+func (s DumpSummary) maintenanceReadiness() []maintenanceReadiness {
+	var readiness []maintenanceReadiness
+	for project, results := range s.Results {
+		for _, r := range results {
+			if r.CheckID != "drain-readiness" {
+				continue
+			}
+			m := maintenanceReadiness{Project: project, Ready: r.Status == StatusOK}
+			for _, info := range r.Info {
+				m.Risks = append(m.Risks, info.Name+": "+info.Message)
+			}
+			readiness = append(readiness, m)
+		}
+	}
+	sort.Slice(readiness, func(i, j int) bool { return readiness[i].Project < readiness[j].Project })
+	return readiness
+}
+
+// writeMaintenanceReadiness writes the readiness of each project for node
+// drains to w.
+func writeMaintenanceReadiness(w io.Writer, s DumpSummary) error {
+	readiness := s.maintenanceReadiness()
+	if len(readiness) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintln(w, "\nMaintenance readiness:"); err != nil {
+		return err
+	}
+	for _, m := range readiness {
+		status := "ready, RHMAP components stay available while nodes drain"
+		if !m.Ready {
+			status = "not ready"
+		}
+		if _, err := fmt.Fprintf(w, "  %s: %s\n", m.Project, status); err != nil {
+			return err
+		}
+		for _, risk := range m.Risks {
+			if _, err := fmt.Fprintf(w, "    - %s\n", risk); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestAllowedDisruptions(t *testing.T) {
+	tests := []struct {
+		spec     string
+		replicas int
+		want     int
+	}{
+		{`{}`, 3, 3},
+		{`{"minAvailable": 1}`, 3, 2},
+		{`{"minAvailable": 1}`, 1, 0},
+		{`{"minAvailable": "50%"}`, 3, 1},
+		{`{"maxUnavailable": 0}`, 3, 0},
+		{`{"maxUnavailable": "34%"}`, 3, 2},
+	}
+	for _, tt := range tests {
+		var pdb PodDisruptionBudget
+		if err := json.Unmarshal([]byte(`{"spec": `+tt.spec+`}`), &pdb); err != nil {
+			t.Fatal(err)
+		}
+		got, err := pdb.allowedDisruptions(tt.replicas)
+		if err != nil {
+			t.Errorf("allowedDisruptions(%s, %d): %v", tt.spec, tt.replicas, err)
+		} else if got != tt.want {
+			t.Errorf("allowedDisruptions(%s, %d) = %d, want %d", tt.spec, tt.replicas, got, tt.want)
+		}
+	}
+}
+
+func TestCheckDrainReadiness(t *testing.T) {
+	var dcs DeploymentConfigs
+	if err := json.Unmarshal([]byte(`{"items": [
+		{"metadata": {"name": "millicore"}, "spec": {"replicas": 1, "template": {"metadata": {"labels": {"name": "millicore"}}}}},
+		{"metadata": {"name": "fh-ngui"}, "spec": {"replicas": 2, "template": {"metadata": {"labels": {"name": "fh-ngui"}}}}},
+		{"metadata": {"name": "fh-aaa"}, "spec": {"replicas": 2, "template": {"metadata": {"labels": {"name": "fh-aaa"}},
+			"spec": {"affinity": {"podAntiAffinity": {"preferredDuringSchedulingIgnoredDuringExecution": [{}]}}}}}},
+		{"metadata": {"name": "fh-supercore"}, "spec": {"replicas": 2, "template": {"metadata": {"labels": {"name": "fh-supercore"}},
+			"spec": {"affinity": {"podAntiAffinity": {"requiredDuringSchedulingIgnoredDuringExecution": [{}]}}}}}},
+		{"metadata": {"name": "fh-messaging"}, "spec": {"replicas": 2, "template": {"metadata": {"labels": {"name": "fh-messaging"}},
+			"spec": {"affinity": {"podAntiAffinity": {"requiredDuringSchedulingIgnoredDuringExecution": [{}]}}}}}},
+		{"metadata": {"name": "my-app"}, "spec": {"replicas": 1}}]}`), &dcs); err != nil {
+		t.Fatal(err)
+	}
+	var pdbs PodDisruptionBudgets
+	if err := json.Unmarshal([]byte(`{"items": [
+		{"metadata": {"name": "fh-supercore"}, "spec": {"minAvailable": 1, "selector": {"matchLabels": {"name": "fh-supercore"}}}},
+		{"metadata": {"name": "fh-messaging"}, "spec": {"minAvailable": "100%", "selector": {"matchLabels": {"name": "fh-messaging"}}}}]}`), &pdbs); err != nil {
+		t.Fatal(err)
+	}
+	result := checkDrainReadiness(Result{Status: StatusOK}, dcs, pdbs)
+	if result.Status != StatusCritical {
+		t.Errorf("Status = %d, want %d", result.Status, StatusCritical)
+	}
+	var got []string
+	for _, info := range result.Info {
+		got = append(got, info.Name)
+	}
+	if want := "millicore fh-ngui fh-aaa fh-messaging"; strings.Join(got, " ") != want {
+		t.Errorf("findings for %v, want %s", got, want)
+	}
+
+	// Projects other than RHMAP ones are not checked.
+	result = checkDrainReadiness(Result{Status: StatusOK}, DeploymentConfigs{Items: dcs.Items[5:]}, pdbs)
+	if result.Status != StatusOK {
+		t.Errorf("Status = %d, want %d", result.Status, StatusOK)
+	}
+}
+
+func TestWriteMaintenanceReadiness(t *testing.T) {
+	s := DumpSummary{Results: map[string][]Result{
+		"rhmap-mbaas": {{Status: StatusOK, CheckID: "drain-readiness"}},
+		"rhmap-core":  {{Status: StatusWarning, CheckID: "drain-readiness", Info: []Info{{Name: "millicore", Message: "it runs a single replica"}}}},
+		"my-app":      {{Status: StatusOK, CheckID: "weak-credentials"}},
+	}}
+	var b bytes.Buffer
+	if err := writeMaintenanceReadiness(&b, s); err != nil {
+		t.Fatal(err)
+	}
+	want := `
+Maintenance readiness:
+  rhmap-core: not ready
+    - millicore: it runs a single replica
+  rhmap-mbaas: ready, RHMAP components stay available while nodes drain
+`
+	if b.String() != want {
+		t.Errorf("writeMaintenanceReadiness() = %q, want %q", b.String(), want)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// planKey is the context key of the PlannedTask of a task listed by a dry run.
+type planKey struct{}
+
+// A PlannedTask is a task a dump would run.
+type PlannedTask struct {
+	Task string
+	// Project is empty for tasks covering the whole cluster.
+	Project string
+}
+
+// A TaskPlan lists the tasks a collector would run for a project, or for the
+// whole cluster, and the files they would write.
+type TaskPlan struct {
+	Collector string
+	Project   string
+	Tasks     []PlannedTask
+	// Files are the files known before the tasks run. Some tasks name
+	// the files they write only as they run, like the events of pods.
+	Files []string
+}
+
+// PlanTasks returns the tasks collectors would run for projects, without
+// running them. Collectors still run the commands they need to know their
+// tasks, like listing the pods of each project, but these only read from the
+// cluster. It may return plans even in the presence of an error.
+func PlanTasks(ctx context.Context, collectors []*Collector, projects []string) ([]TaskPlan, error) {
+	var (
+		plans  []TaskPlan
+		errors errorList
+	)
+	for _, c := range collectors {
+		scopes := []string{""}
+		if c.PerProject {
+			scopes = projects
+		}
+		for _, project := range scopes {
+			scope := projects
+			if project != "" {
+				scope = []string{project}
+			}
+			tarFile, err := NewArchive(ioutil.Discard, compressionNone)
+			if err != nil {
+				return plans, err
+			}
+			tasks, err := c.Tasks(ctx, scope, tarFile)
+			if err != nil {
+				errors = append(errors, err)
+			}
+			plan := TaskPlan{Collector: c.Name, Project: project, Files: tarFile.RequestedFiles()}
+			for _, task := range tasks {
+				plan.Tasks = append(plan.Tasks, planTask(task))
+			}
+			plans = append(plans, plan)
+		}
+	}
+	if len(errors) > 0 {
+		return plans, errors
+	}
+	return plans, nil
+}
+
+// planTask returns the name and project of task without running it. Tasks
+// are named with namedTask, those which are not get a cancelled context, so
+// that the commands they start fail at once.
+func planTask(task Task) PlannedTask {
+	p := PlannedTask{Task: "unnamed task"}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), planKey{}, &p))
+	cancel()
+	task(ctx)
+	return p
+}
+
+// WritePlan writes the tasks and files of plans to w, grouped by collector and
+// project.
+func WritePlan(w io.Writer, plans []TaskPlan) error {
+	var tasks, files int
+	for _, plan := range plans {
+		if len(plan.Tasks) == 0 && len(plan.Files) == 0 {
+			continue
+		}
+		scope := "cluster"
+		if plan.Project != "" {
+			scope = "project " + plan.Project
+		}
+		fmt.Fprintf(w, "%s (%s)\n", plan.Collector, scope)
+		for _, t := range plan.Tasks {
+			if t.Project != "" && t.Project != plan.Project {
+				fmt.Fprintf(w, "    task: %s in project %s\n", t.Task, t.Project)
+				continue
+			}
+			fmt.Fprintf(w, "    task: %s\n", t.Task)
+		}
+		for _, f := range plan.Files {
+			fmt.Fprintf(w, "    file: %s\n", f)
+		}
+		tasks += len(plan.Tasks)
+		files += len(plan.Files)
+	}
+	_, err := fmt.Fprintf(w, "%d tasks would run, writing at least %d files\n", tasks, files)
+	return err
+}
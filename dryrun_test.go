@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPlanTasks(t *testing.T) {
+	mustNotRun := func(ctx context.Context) error {
+		t.Error("task ran during a dry run")
+		return nil
+	}
+	collectors := []*Collector{
+		{
+			Name:       "definitions",
+			PerProject: true,
+			Tasks: func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+				tarFile.GetWriterToFile("definitions/" + projects[0] + ".json")
+				return []Task{namedTask("fetch definitions", projects[0], mustNotRun)}, nil
+			},
+		},
+		{
+			Name: "inventory",
+			Tasks: func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+				tarFile.GetWriterToFile("inventory.json")
+				unnamed := func(ctx context.Context) error { return ctx.Err() }
+				return []Task{namedTask("build inventory", "", mustNotRun), unnamed}, errors.New("partial inventory")
+			},
+		},
+	}
+
+	plans, err := PlanTasks(context.Background(), collectors, []string{"p1", "p2"})
+	if err == nil {
+		t.Error("PlanTasks() error = nil, want the error of the inventory collector")
+	}
+	want := []TaskPlan{
+		{Collector: "definitions", Project: "p1", Tasks: []PlannedTask{{"fetch definitions", "p1"}}, Files: []string{"definitions/p1.json"}},
+		{Collector: "definitions", Project: "p2", Tasks: []PlannedTask{{"fetch definitions", "p2"}}, Files: []string{"definitions/p2.json"}},
+		{Collector: "inventory", Tasks: []PlannedTask{{"build inventory", ""}, {"unnamed task", ""}}, Files: []string{"inventory.json"}},
+	}
+	if !reflect.DeepEqual(plans, want) {
+		t.Errorf("PlanTasks() = %+v, want %+v", plans, want)
+	}
+
+	var buf bytes.Buffer
+	if err := WritePlan(&buf, plans); err != nil {
+		t.Fatal(err)
+	}
+	wantOutput := `definitions (project p1)
+    task: fetch definitions
+    file: definitions/p1.json
+definitions (project p2)
+    task: fetch definitions
+    file: definitions/p2.json
+inventory (cluster)
+    task: build inventory
+    task: unnamed task
+    file: inventory.json
+4 tasks would run, writing at least 3 files
+`
+	if got := buf.String(); got != wantOutput {
+		t.Errorf("WritePlan() wrote:\n%s\nwant:\n%s", got, wantOutput)
+	}
+}
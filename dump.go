@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/feedhenry/fh-system-dump-tool/dumpformat"
+)
+
+// A DumpSummary holds the parts of a dump used to analyse it after it has been
+// written: the component inventory and analysis results.
+type DumpSummary struct {
+	Path      string
+	Inventory Inventory
+	// Results maps project names to the analysis results of the project.
+	Results map[string][]Result
+	// Suppressions are applied to the results when reporting findings.
+	Suppressions []Suppression
+	// MinSeverity is the least severe status reported as a finding. Results
+	// with a lower status are kept in Results but not reported.
+	MinSeverity int
+}
+
+// isSummaryFile reports whether the named archive file is part of a
+// DumpSummary.
+func isSummaryFile(name string) bool {
+	return name == "inventory.json" || isAnalysisFile(name)
+}
+
+// isAnalysisFile reports whether the named archive file holds the analysis
+// results of a project.
+func isAnalysisFile(name string) bool {
+	return strings.HasPrefix(name, "definitions/projects/") && path.Base(name) == "analysis.json"
+}
+
+// LoadDumpSummary reads the inventory and analysis results from the dump
+// archive at path.
+func LoadDumpSummary(path string) (DumpSummary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return DumpSummary{}, err
+	}
+	defer f.Close()
+	files, err := ReadTgz(f, func(name string) bool { return name == "metadata.json" || isSummaryFile(name) })
+	if err != nil {
+		return DumpSummary{}, err
+	}
+	if err := checkDumpLayout(files); err != nil {
+		return DumpSummary{}, fmt.Errorf("%s: %v", path, err)
+	}
+	summary, err := parseDumpSummary(path, files)
+	if err != nil {
+		return summary, err
+	}
+	return summary, summary.loadPluginResults()
+}
+
+// checkDumpLayout returns an error if the dump whose files, including
+// metadata.json if any, are given has a layout version this tool cannot read.
+// Dumps without metadata.json have layout version 0.
+func checkDumpLayout(files map[string][]byte) error {
+	var metadata dumpformat.Metadata
+	if content, ok := files["metadata.json"]; ok {
+		if err := json.Unmarshal(content, &metadata); err != nil {
+			return fmt.Errorf("metadata.json: %v", err)
+		}
+	}
+	return dumpformat.CheckLayoutVersion(metadata.LayoutVersion)
+}
+
+func parseDumpSummary(dumpPath string, files map[string][]byte) (DumpSummary, error) {
+	summary := DumpSummary{Path: dumpPath, Results: make(map[string][]Result)}
+	for name, content := range files {
+		switch {
+		case name == "inventory.json":
+			if err := json.Unmarshal(content, &summary.Inventory); err != nil {
+				return summary, err
+			}
+		case isAnalysisFile(name):
+			var results CheckResults
+			if err := json.Unmarshal(content, &results); err != nil {
+				return summary, err
+			}
+			project := path.Base(path.Dir(name))
+			summary.Results[project] = results.Results
+		}
+	}
+	return summary, nil
+}
+
+// findDumps returns the paths to the dump archives in dir, in any archive and
+// compression format, sorted by name.
+func findDumps(dir string) ([]string, error) {
+	var matches []string
+	extensions := []string{zipExtension}
+	for _, ext := range archiveExtensions {
+		extensions = append(extensions, ext)
+	}
+	for _, ext := range extensions {
+		m, err := filepath.Glob(filepath.Join(dir, "*"+ext))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range m {
+			if archiveExtension(path) == ext {
+				matches = append(matches, path)
+			}
+		}
+	}
+	// Dump archives are named after a sortable timestamp.
+	sort.Slice(matches, func(i, j int) bool {
+		return trimArchiveExtension(matches[i]) < trimArchiveExtension(matches[j])
+	})
+	return matches, nil
+}
+
+// FindLatestDump returns the path to the most recent dump archive in dir. It
+// returns an empty string if there is no dump.
+func FindLatestDump(dir string) (string, error) {
+	matches, err := findDumps(dir)
+	if err != nil || len(matches) == 0 {
+		return "", err
+	}
+	return matches[len(matches)-1], nil
+}
+
+// FindPreviousDump returns the path to the most recent dump archive in the same
+// directory as the dump archive at current, that is older than current. It
+// returns an empty string if there is no such dump.
+func FindPreviousDump(current string) (string, error) {
+	matches, err := findDumps(filepath.Dir(current))
+	if err != nil {
+		return "", err
+	}
+	previous := ""
+	for _, m := range matches {
+		if filepath.Base(trimArchiveExtension(m)) >= filepath.Base(trimArchiveExtension(current)) {
+			break
+		}
+		previous = m
+	}
+	return previous, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDumpSummary(t *testing.T) {
+	files := map[string][]byte{
+		"inventory.json": []byte(`{"components": [{"project": "core", "name": "fh-ngui"}]}`),
+		"definitions/projects/core/analysis.json": []byte(`{"Results": [{"checkName": "check a", "status": 1}]}`),
+	}
+	summary, err := parseDumpSummary("dump.tar.gz", files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(summary.Inventory.Components); got != 1 {
+		t.Errorf("len(Inventory.Components) = %d, want 1", got)
+	}
+	if got := summary.Results["core"]; len(got) != 1 || got[0].CheckName != "check a" || got[0].Status != 1 {
+		t.Errorf("Results[core] = %+v, want a single result for check a", got)
+	}
+}
+
+func TestFindPreviousDump(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dumps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"2016-09-01T10-00-00Z.tar.gz", "2016-09-02T10-00-00Z.tar.gz", "2016-09-03T10-00-00Z.tar.gz"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0660); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		current, want string
+	}{
+		{"2016-09-01T10-00-00Z.tar.gz", ""},
+		{"2016-09-03T10-00-00Z.tar.gz", "2016-09-02T10-00-00Z.tar.gz"},
+	}
+	for _, tt := range tests {
+		got, err := FindPreviousDump(filepath.Join(dir, tt.current))
+		if err != nil {
+			t.Errorf("FindPreviousDump(%q) returned non-nil error: %v", tt.current, err)
+			continue
+		}
+		if tt.want != "" {
+			tt.want = filepath.Join(dir, tt.want)
+		}
+		if got != tt.want {
+			t.Errorf("FindPreviousDump(%q) = %q, want %q", tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestCheckDumpLayout(t *testing.T) {
+	for _, tt := range []struct {
+		files map[string][]byte
+		ok    bool
+	}{
+		{map[string][]byte{}, true},
+		{map[string][]byte{"metadata.json": []byte(`{"layoutVersion": 1}`)}, true},
+		{map[string][]byte{"metadata.json": []byte(`{"layoutVersion": 99}`)}, false},
+		{map[string][]byte{"metadata.json": []byte(`{`)}, false},
+	} {
+		if err := checkDumpLayout(tt.files); (err == nil) != tt.ok {
+			t.Errorf("checkDumpLayout(%s) = %v", tt.files["metadata.json"], err)
+		}
+	}
+}
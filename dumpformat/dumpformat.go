@@ -0,0 +1,127 @@
+// Package dumpformat describes the format of the dump archives written by the
+// dump tool, for programs reading them.
+//
+// The layout of dumps is versioned by LayoutVersion, recorded in
+// metadata.json, so readers must ignore the files and fields they don't know.
+// The API of this package follows the semantic versioning of the module:
+// within a major version, identifiers and fields are only ever added.
+package dumpformat
+
+import (
+	"fmt"
+	"time"
+)
+
+// LayoutVersion is the version of the layout of dump archives written by this
+// version of the tool. It is incremented whenever files are moved or their
+// format changes incompatibly. Adding files or fields does not change it.
+const LayoutVersion = 1
+
+// ReadableLayoutVersions is the number of layout versions before
+// LayoutVersion that the tool must still read. Dumps predating metadata.json
+// have layout version 0.
+const ReadableLayoutVersions = 2
+
+// CheckLayoutVersion returns an error if dumps of layout version v cannot be
+// read by this version of the tool.
+func CheckLayoutVersion(v int) error {
+	if v > LayoutVersion || v < LayoutVersion-ReadableLayoutVersions {
+		oldest := LayoutVersion - ReadableLayoutVersions
+		if oldest < 0 {
+			oldest = 0
+		}
+		return fmt.Errorf("unsupported layout version %d, this tool reads versions %d to %d", v, oldest, LayoutVersion)
+	}
+	return nil
+}
+
+// Metadata describes a dump. It is written to metadata.json at the root of the
+// archive.
+type Metadata struct {
+	LayoutVersion int    `json:"layoutVersion"`
+	ToolVersion   string `json:"toolVersion"`
+	// ToolCommit is the git commit the tool was built from, if known.
+	ToolCommit string    `json:"toolCommit,omitempty"`
+	Created    time.Time `json:"created"`
+	// Flags are the command-line flags set for the run, with the values
+	// of sensitive flags redacted by the tool.
+	Flags map[string]string `json:"flags,omitempty"`
+	// Versions are those of oc and of the cluster.
+	Versions *OcVersions `json:"versions,omitempty"`
+	// Projects and Resources are the projects dumped and the resource
+	// types whose definitions were collected in each.
+	Projects  []string `json:"projects"`
+	Resources []string `json:"resources"`
+	// Collectors are the names of the collectors run, as selected with
+	// -only, -collect and -skip. Dumps not recording them ran at least
+	// the definitions and checks collectors.
+	Collectors []string `json:"collectors,omitempty"`
+	// Refreshed lists the projects collected again after the dump was
+	// created.
+	Refreshed []Refresh `json:"refreshed,omitempty"`
+	// Pod, if not empty, is the project/pod a mini-dump written by the
+	// pod subcommand is about. Projects is then empty.
+	Pod string `json:"pod,omitempty"`
+	// Sizes accounts for the files written before metadata.json.
+	Sizes *SizeAccounting `json:"sizes,omitempty"`
+	// HostTimezone is the timezone of the host the dump was collected
+	// from. Timestamps written by the tool are in UTC, and those from the
+	// OpenShift API always are, but logs may use the local time of the
+	// host or of the pods.
+	HostTimezone *Timezone `json:"hostTimezone,omitempty"`
+}
+
+// OcVersions are the versions reported by oc version.
+type OcVersions struct {
+	Client string `json:"client,omitempty"`
+	// OpenShift and Kubernetes are the versions of the master, empty if
+	// it could not be reached.
+	OpenShift  string `json:"openshift,omitempty"`
+	Kubernetes string `json:"kubernetes,omitempty"`
+}
+
+// A Timezone records a timezone at some time.
+type Timezone struct {
+	// Name is the abbreviated name of the zone, e.g. CEST.
+	Name string `json:"name"`
+	// Offset is the offset from UTC, e.g. +02:00.
+	Offset string `json:"offset"`
+}
+
+// A Refresh records that a project was collected again into a dump.
+type Refresh struct {
+	Project string    `json:"project"`
+	Time    time.Time `json:"time"`
+}
+
+// SizeAccounting is the uncompressed size of the files of a dump, in bytes,
+// in total, by category and by project.
+type SizeAccounting struct {
+	Total      int64            `json:"total"`
+	Categories map[string]int64 `json:"categories"`
+	Projects   map[string]int64 `json:"projects"`
+}
+
+// A ManifestEntry records a file written to a dump archive.
+type ManifestEntry struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	// Task and Project identify the task whose commands produced the
+	// file, if any, and Outcome is whether the task succeeded, as in
+	// task timings.
+	Task    string `json:"task,omitempty"`
+	Project string `json:"project,omitempty"`
+	Outcome string `json:"outcome,omitempty"`
+	// Truncated is true for files cut at the limit of -max-output-size.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// A Manifest lists all files of a dump archive, except the manifest itself. It
+// is written to manifest.json, as the last file of the archive.
+type Manifest struct {
+	Files []ManifestEntry `json:"files"`
+	// Interrupted, if not empty, is why the dump was interrupted before
+	// all tasks completed.
+	Interrupted string `json:"interrupted,omitempty"`
+}
@@ -0,0 +1,19 @@
+package dumpformat
+
+import "testing"
+
+func TestCheckLayoutVersion(t *testing.T) {
+	for v := LayoutVersion - ReadableLayoutVersions; v <= LayoutVersion; v++ {
+		if v < 0 {
+			continue
+		}
+		if err := CheckLayoutVersion(v); err != nil {
+			t.Errorf("CheckLayoutVersion(%d): %v", v, err)
+		}
+	}
+	for _, v := range []int{LayoutVersion + 1, LayoutVersion - ReadableLayoutVersions - 1} {
+		if err := CheckLayoutVersion(v); err == nil {
+			t.Errorf("CheckLayoutVersion(%d) didn't return an error", v)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// elasticsearchContainer is the name of the container running
+	// Elasticsearch in the pods of the aggregated logging stack.
+	elasticsearchContainer = "elasticsearch"
+	// elasticsearchURL is where Elasticsearch listens, from inside its
+	// pod, authenticating with the admin certificates of the pod.
+	elasticsearchURL   = "https://localhost:9200"
+	elasticsearchCerts = "/etc/elasticsearch/secret"
+	// maxElasticsearchHits is the maximum number of log entries fetched
+	// for each project, the most Elasticsearch returns by default.
+	maxElasticsearchHits = 10000
+	// defaultElasticsearchHours is how far back logs are fetched when the
+	// collector is selected with -collect rather than -es-logs-hours.
+	defaultElasticsearchHours = 24
+)
+
+// isElasticsearchPod reports whether the pod named name runs the Elasticsearch
+// of the aggregated logging stack holding the logs of projects. The ops
+// cluster only holds the logs of the infrastructure.
+func isElasticsearchPod(name string) bool {
+	return strings.HasPrefix(name, "logging-es-") && !strings.HasPrefix(name, "logging-es-ops")
+}
+
+// getElasticsearchPod returns a running Elasticsearch pod of the aggregated
+// logging stack, as namespace/name.
+func getElasticsearchPod(ctx context.Context) (string, error) {
+	pods, err := getRunningPodsPerNamespace(ctx, isElasticsearchPod)
+	if err != nil {
+		return "", err
+	}
+	if len(pods) == 0 {
+		return "", fmt.Errorf("no Elasticsearch pod found, the aggregated logs of projects were not fetched")
+	}
+	return pods[0], nil
+}
+
+// elasticsearchQuery returns the query of the most recent log entries of the
+// last hours, up to maxElasticsearchHits.
+func elasticsearchQuery(hours int) string {
+	return fmt.Sprintf(`{"size": %d, "sort": [{"@timestamp": {"order": "desc"}}], "query": {"range": {"@timestamp": {"gte": "now-%dh"}}}}`, maxElasticsearchHits, hours)
+}
+
+// An elasticsearchHit is a log entry stored by the aggregated logging stack.
+type elasticsearchHit struct {
+	Source struct {
+		Timestamp  time.Time `json:"@timestamp"`
+		Message    string    `json:"message"`
+		Kubernetes struct {
+			PodName       string `json:"pod_name"`
+			ContainerName string `json:"container_name"`
+		} `json:"kubernetes"`
+	} `json:"_source"`
+}
+
+type elasticsearchResponse struct {
+	Hits struct {
+		Hits []elasticsearchHit `json:"hits"`
+	} `json:"hits"`
+}
+
+// groupLogEntries groups hits by pod and container, as <pod>-<container>,
+// each in chronological order, formatted as <timestamp> <message> lines.
+func groupLogEntries(hits []elasticsearchHit) map[string][]string {
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Source.Timestamp.Before(hits[j].Source.Timestamp) })
+	logs := make(map[string][]string)
+	for _, h := range hits {
+		k := h.Source.Kubernetes
+		if k.PodName == "" {
+			continue
+		}
+		name := k.PodName
+		if k.ContainerName != "" {
+			name += "-" + k.ContainerName
+		}
+		logs[name] = append(logs[name], h.Source.Timestamp.UTC().Format(time.RFC3339Nano)+" "+strings.TrimRight(h.Source.Message, "\n"))
+	}
+	return logs
+}
+
+// GetElasticsearchLogsTasks returns a list of tasks to fetch, for each
+// project, the log entries of the last hours from the Elasticsearch of the
+// aggregated logging stack, which keeps the logs of containers long gone, into
+// elasticsearch/projects/<project>/<pod>-<container>.logs. The Elasticsearch
+// pod is looked up once, before returning the tasks.
+// FIXME: GetElasticsearchLogsTasks should not know about tarFile.
+func GetElasticsearchLogsTasks(ctx context.Context, projects []string, hours int, tarFile *Archive) ([]Task, error) {
+	pod, err := getElasticsearchPod(ctx)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.SplitN(pod, "/", 2)
+	var tasks []Task
+	for _, p := range projects {
+		p := p
+		errOut := tarFile.GetWriterToFile(filepath.Join("elasticsearch", "projects", p, "logs.stderr"))
+		task := func(ctx context.Context) error {
+			defer errOut.Close()
+			var out bytes.Buffer
+			cmd := ocCommand("-n", parts[0], "exec", parts[1], "-c", elasticsearchContainer, "--",
+				"curl", "-sS", "--fail", "--cacert", elasticsearchCerts+"/admin-ca", "--cert", elasticsearchCerts+"/admin-cert", "--key", elasticsearchCerts+"/admin-key",
+				"-H", "Content-Type: application/json", "-d", elasticsearchQuery(hours),
+				elasticsearchURL+"/project."+p+".*/_search?ignore_unavailable=true")
+			if err := runCmdCaptureOutput(ctx, cmd, &out, errOut); err != nil {
+				return err
+			}
+			var response elasticsearchResponse
+			if err := json.Unmarshal(out.Bytes(), &response); err != nil {
+				return fmt.Errorf("reading the logs of project %s from %s: %v", p, pod, err)
+			}
+			if len(response.Hits.Hits) == maxElasticsearchHits {
+				fmt.Fprintf(errOut, "only the %d most recent log entries of the last %dh were fetched\n", maxElasticsearchHits, hours)
+			}
+			for name, lines := range groupLogEntries(response.Hits.Hits) {
+				w := tarFile.GetWriterToFile(filepath.Join("elasticsearch", "projects", p, name+".logs"))
+				io.WriteString(w, strings.Join(lines, "\n")+"\n")
+				if err := w.Close(); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		tasks = append(tasks, namedTask("fetch aggregated logs", p, task))
+	}
+	return tasks, nil
+}
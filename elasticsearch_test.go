@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/feedhenry/fh-system-dump-tool/cmdrunner"
+)
+
+func TestIsElasticsearchPod(t *testing.T) {
+	for name, want := range map[string]bool{
+		"logging-es-data-master-ilmz5zfl-1-abcde":     true,
+		"logging-es-ops-data-master-x2kq4zxw-1-abcde": false,
+		"logging-kibana-1-abcde":                      false,
+		"logging-fluentd-abcde":                       false,
+	} {
+		if got := isElasticsearchPod(name); got != want {
+			t.Errorf("isElasticsearchPod(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestGroupLogEntries(t *testing.T) {
+	var response elasticsearchResponse
+	if err := json.Unmarshal([]byte(`{"hits": {"hits": [
+		{"_source": {"@timestamp": "2017-06-01T12:00:02.000Z", "message": "listening\n", "kubernetes": {"pod_name": "fh-mbaas-1-abcde", "container_name": "fh-mbaas"}}},
+		{"_source": {"@timestamp": "2017-06-01T12:00:01.500Z", "message": "starting", "kubernetes": {"pod_name": "fh-mbaas-1-abcde", "container_name": "fh-mbaas"}}},
+		{"_source": {"@timestamp": "2017-06-01T11:59:00.000Z", "message": "out of memory", "kubernetes": {"pod_name": "mongodb-1-1-fghij", "container_name": "mongodb"}}},
+		{"_source": {"@timestamp": "2017-06-01T11:59:00.000Z", "message": "not from a pod"}}]}}`), &response); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"fh-mbaas-1-abcde-fh-mbaas": {"2017-06-01T12:00:01.5Z starting", "2017-06-01T12:00:02Z listening"},
+		"mongodb-1-1-fghij-mongodb": {"2017-06-01T11:59:00Z out of memory"},
+	}
+	if got := groupLogEntries(response.Hits.Hits); !reflect.DeepEqual(got, want) {
+		t.Errorf("groupLogEntries() = %v, want %v", got, want)
+	}
+}
+
+func TestGetElasticsearchLogsTasksWithoutPod(t *testing.T) {
+	defer func(r cmdrunner.Runner) { runner = r }(runner)
+	runner = cmdrunner.NewFakeRunner([]cmdrunner.Invocation{
+		{Args: ocCommand("get", "pods", "--all-namespaces", `-o=jsonpath={range .items[?(@.status.phase=="Running")]}{.metadata.namespace}/{.metadata.name} {end}`).Args, Stdout: "logging/logging-es-ops-data-master-x2kq4zxw-1-abcde logging/logging-kibana-1-abcde"},
+	})
+	tasks, err := GetElasticsearchLogsTasks(context.Background(), []string{"core", "mbaas"}, 24, nil)
+	if err == nil || !strings.Contains(err.Error(), "no Elasticsearch pod found") {
+		t.Errorf("GetElasticsearchLogsTasks() error = %v, want no Elasticsearch pod found", err)
+	}
+	if len(tasks) != 0 {
+		t.Errorf("GetElasticsearchLogsTasks() returned %d tasks, want none", len(tasks))
+	}
+}
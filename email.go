@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+)
+
+// SendSummaryEmail emails the text and HTML analysis summaries of the dump,
+// never the dump itself, to the comma-separated list of addresses in to. SMTP
+// credentials, if required by the server, are read from the SMTP_USERNAME and
+// SMTP_PASSWORD environment variables.
+func SendSummaryEmail(server, from, to string, s DumpSummary) error {
+	host, _, err := net.SplitHostPort(server)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+	recipients := strings.Split(to, ",")
+	for i := range recipients {
+		recipients[i] = strings.TrimSpace(recipients[i])
+	}
+	msg, err := buildSummaryEmail(from, recipients, time.Now(), s)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(server, auth, from, recipients, msg)
+}
+
+// buildSummaryEmail returns a multipart/alternative email message holding the
+// text and HTML summaries of s.
+func buildSummaryEmail(from string, to []string, date time.Time, s DumpSummary) ([]byte, error) {
+	var text, html bytes.Buffer
+	if err := WriteTextSummary(&text, s); err != nil {
+		return nil, err
+	}
+	if err := WriteHTMLSummary(&html, s); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", text.Bytes()},
+		{"text/html; charset=utf-8", html.Bytes()},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write(part.content); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: RHMAP system dump analysis: %d critical, %d warning findings\r\n",
+		s.CountFindings(StatusCritical), s.CountFindings(StatusWarning))
+	fmt.Fprintf(&msg, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildSummaryEmail(t *testing.T) {
+	summary := DumpSummary{
+		Path: "dump.tar.gz",
+		Results: map[string][]Result{
+			"core": {{CheckName: "check a", Status: StatusCritical, StatusMessage: "<a> detected"}},
+		},
+	}
+	raw, err := buildSummaryEmail("tool@example.com", []string{"ops@example.com"}, time.Now(), summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.Header.Get("Subject"); !strings.Contains(got, "1 critical") {
+		t.Errorf("Subject = %q, want it to include the number of critical findings", got)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, want multipart/alternative", msg.Header.Get("Content-Type"))
+	}
+	var types []string
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		types = append(types, part.Header.Get("Content-Type"))
+	}
+	if len(types) != 2 || !strings.HasPrefix(types[0], "text/plain") || !strings.HasPrefix(types[1], "text/html") {
+		t.Errorf("parts = %v, want text/plain and text/html", types)
+	}
+}
@@ -1,6 +1,11 @@
 package main
 
-import "strings"
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
 
 // An errorList accumulates multiple errors and implements error.
 type errorList []error
@@ -18,3 +23,129 @@ func (e errorList) Error() string {
 	}
 	return "multiple errors:\n" + strings.Join(msgs, "\n")
 }
+
+// A CmdError is returned when running an external command fails.
+type CmdError struct {
+	Args []string
+	Err  error
+	// Stderr is the standard error output of the command, if it ran.
+	Stderr string
+}
+
+func (e *CmdError) Error() string {
+	msg := "command " + strconv.Quote(strings.Join(e.Args, " ")) + ": " + e.Err.Error()
+	if e.Stderr != "" {
+		msg += ": " + e.Stderr
+	}
+	return msg
+}
+
+// Project returns the project the command operated on, taken from its -n
+// argument, or an empty string if there is none.
+func (e *CmdError) Project() string {
+	for i, arg := range e.Args {
+		if arg == "-n" && i+1 < len(e.Args) {
+			return e.Args[i+1]
+		}
+	}
+	return ""
+}
+
+// maxStderrExcerpt is the maximum number of bytes of stderr output included in
+// the error report.
+const maxStderrExcerpt = 512
+
+// A TaskError describes an error in the error report.
+type TaskError struct {
+	// Phase is either "preparation", for errors while preparing the
+	// tasks, or "collection", for errors returned by tasks.
+	Phase string `json:"phase"`
+	// Task is the index of the task that failed, in the order tasks were
+	// prepared, or -1 for errors while preparing the tasks.
+	Task int `json:"task"`
+	// Name describes the operation of the task, if it is named.
+	Name           string `json:"name,omitempty"`
+	Project        string `json:"project,omitempty"`
+	Command        string `json:"command,omitempty"`
+	Stderr         string `json:"stderr,omitempty"`
+	Classification string `json:"classification"`
+	Error          string `json:"error"`
+}
+
+// flattenErrors returns the individual errors in err, expanding nested error
+// lists. The errors of failed named tasks are each wrapped in the TaskFailure
+// of the task.
+func flattenErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if f, ok := err.(*TaskFailure); ok {
+		var errs []error
+		for _, e := range flattenErrors(f.Err) {
+			errs = append(errs, &TaskFailure{Task: f.Task, Project: f.Project, Err: e})
+		}
+		return errs
+	}
+	list, ok := err.(errorList)
+	if !ok {
+		return []error{err}
+	}
+	var errs []error
+	for _, e := range list {
+		errs = append(errs, flattenErrors(e)...)
+	}
+	return errs
+}
+
+// newTaskErrors describes err, returned by the task with the given index in
+// phase, as one or more TaskErrors.
+func newTaskErrors(phase string, task int, err error) []TaskError {
+	var taskErrors []TaskError
+	for _, e := range flattenErrors(err) {
+		te := TaskError{Phase: phase, Task: task, Classification: ClassifyError(e), Error: e.Error()}
+		if f, ok := e.(*TaskFailure); ok {
+			te.Name, te.Project = f.Task, f.Project
+			e = f.Err
+		}
+		if cmdErr, ok := e.(*CmdError); ok {
+			if p := cmdErr.Project(); p != "" {
+				te.Project = p
+			}
+			te.Command = strings.Join(cmdErr.Args, " ")
+			te.Stderr = cmdErr.Stderr
+			if len(te.Stderr) > maxStderrExcerpt {
+				te.Stderr = te.Stderr[:maxStderrExcerpt] + "..."
+			}
+		}
+		taskErrors = append(taskErrors, te)
+	}
+	return taskErrors
+}
+
+// WriteErrorReport writes a JSON report of the error returned while preparing
+// tasks, and of the errors returned by each task, to w.
+func WriteErrorReport(w io.Writer, prepareErr error, taskErrs []error) error {
+	report := struct {
+		// ByClassification counts errors per classification.
+		ByClassification map[string]int `json:"byClassification"`
+		Errors           []TaskError    `json:"errors"`
+	}{
+		ByClassification: make(map[string]int),
+		Errors:           newTaskErrors("preparation", -1, prepareErr),
+	}
+	for i, err := range taskErrs {
+		report.Errors = append(report.Errors, newTaskErrors("collection", i, err)...)
+	}
+	if report.Errors == nil {
+		report.Errors = []TaskError{}
+	}
+	for _, e := range report.Errors {
+		report.ByClassification[e.Classification]++
+	}
+	output, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(output)
+	return err
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestWriteErrorReport(t *testing.T) {
+	failed := helperCommand("stderrfail")
+	failed.Args = append(failed.Args, "-n", "core")
+	_, cmdErr := getSpaceSeparated(context.Background(), failed)
+	if cmdErr == nil {
+		t.Fatal("expected command to fail")
+	}
+
+	var buf bytes.Buffer
+	err := WriteErrorReport(&buf, errors.New("preparing"), []error{nil, &TaskFailure{Task: "fetch definitions of pods", Project: "mbaas", Err: errorList{cmdErr, errors.New("other")}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report struct {
+		Errors []TaskError `json:"errors"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Errors) != 3 {
+		t.Fatalf("len(Errors) = %d, want 3: %+v", len(report.Errors), report.Errors)
+	}
+	if e := report.Errors[0]; e.Phase != "preparation" || e.Task != -1 || e.Error != "preparing" {
+		t.Errorf("Errors[0] = %+v", e)
+	}
+	e := report.Errors[1]
+	if e.Phase != "collection" || e.Task != 1 || e.Name != "fetch definitions of pods" || e.Project != "core" || e.Stderr != "some stderr text\n" || e.Classification != classCommandFailed {
+		t.Errorf("Errors[1] = %+v", e)
+	}
+	if e := report.Errors[2]; e.Task != 1 || e.Project != "mbaas" || e.Classification != classOther {
+		t.Errorf("Errors[2] = %+v", e)
+	}
+}
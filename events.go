@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// A MissingPod is a pod referenced by events that no longer exists, typically
+// because it was replaced by a newer deployment.
+type MissingPod struct {
+	Project string `json:"project"`
+	Name    string `json:"name"`
+	// DeploymentConfig and ReplicationController are the inferred owners
+	// of the pod, if it was created by a deployment.
+	DeploymentConfig      string `json:"deploymentConfig,omitempty"`
+	ReplicationController string `json:"replicationController,omitempty"`
+	// Successors are the existing pods of the same deployment config,
+	// whose logs are collected instead.
+	Successors []string `json:"successors"`
+	// Reasons are the reasons of the events referencing the pod.
+	Reasons []string `json:"reasons"`
+}
+
+// GetEventPods inspects the events in the given projects and returns the
+// loggable resources of all existing pods involved in Warning events, and the
+// pods referenced by any event that no longer exist. The loggable resources
+// of the successors of missing pods are included with the Warning event pods.
+// It may return results even in the presence of an error.
+func GetEventPods(ctx context.Context, projects []string) ([]LoggableResource, []MissingPod, error) {
+	var (
+		loggableResources []LoggableResource
+		missingPods       []MissingPod
+		errors            errorList
+	)
+	for _, p := range projects {
+		var events Events
+		if err := getResourceStruct(ctx, p, "events", &events); err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		existing, err := GetResourceNames(ctx, p, "pods")
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		exists := make(map[string]bool)
+		for _, name := range existing {
+			exists[name] = true
+		}
+		var names []string
+		for _, name := range warningEventPods(events) {
+			if exists[name] {
+				names = append(names, name)
+			}
+		}
+		missing := findMissingPods(p, events, existing)
+		for _, m := range missing {
+			names = append(names, m.Successors...)
+		}
+		missingPods = append(missingPods, missing...)
+		for _, name := range names {
+			resources, err := GetLoggableResources(ctx, p, "pods", name)
+			if err != nil {
+				errors = append(errors, err)
+				continue
+			}
+			loggableResources = append(loggableResources, resources...)
+		}
+	}
+	if len(errors) > 0 {
+		return loggableResources, missingPods, errors
+	}
+	return loggableResources, missingPods, nil
+}
+
+// deploymentPodName matches the names of pods created by deployments, of the
+// form <deploymentconfig>-<version>-<suffix>.
+var deploymentPodName = regexp.MustCompile(`^(.+)-(\d+)-([a-z0-9]{5}|deploy|hook-pre|hook-mid|hook-post)$`)
+
+// findMissingPods returns the pods referenced by events that are not in
+// existing, along with their inferred owners and successors.
+func findMissingPods(project string, events Events, existing []string) []MissingPod {
+	exists := make(map[string]bool)
+	for _, name := range existing {
+		exists[name] = true
+	}
+	var (
+		missing []MissingPod
+		index   = make(map[string]int)
+	)
+	for _, event := range events.Items {
+		name := event.InvolvedObject.Name
+		if event.InvolvedObject.Kind != "Pod" || exists[name] {
+			continue
+		}
+		i, ok := index[name]
+		if !ok {
+			m := MissingPod{Project: project, Name: name, Successors: []string{}}
+			if match := deploymentPodName.FindStringSubmatch(name); match != nil {
+				m.DeploymentConfig = match[1]
+				m.ReplicationController = match[1] + "-" + match[2]
+				for _, pod := range existing {
+					if match := deploymentPodName.FindStringSubmatch(pod); match != nil && match[1] == m.DeploymentConfig && !strings.HasSuffix(pod, "-deploy") {
+						m.Successors = append(m.Successors, pod)
+					}
+				}
+				sort.Strings(m.Successors)
+			}
+			i = len(missing)
+			index[name] = i
+			missing = append(missing, m)
+		}
+		missing[i].Reasons = append(missing[i].Reasons, event.Reason)
+	}
+	return missing
+}
+
+// GetMissingPodsTasks returns a list of tasks to record the pods referenced by
+// events that no longer exist, and the revision history of their deployment
+// configs.
+// FIXME: GetMissingPodsTasks should not know about tarFile.
+func GetMissingPodsTasks(missing []MissingPod, tarFile *Archive) []Task {
+	var tasks []Task
+	byProject := make(map[string][]MissingPod)
+	for _, m := range missing {
+		byProject[m.Project] = append(byProject[m.Project], m)
+	}
+
+	outFor := outToTGZ("definitions", "json", tarFile)
+	errOutFor := outToTGZ("definitions", "stderr", tarFile)
+	for p, missing := range byProject {
+		p, missing := p, missing
+		task := func(ctx context.Context) error {
+			out, outCloser, err := outFor(p, "missing-pods")
+			if err != nil {
+				return err
+			}
+			defer outCloser.Close()
+			output, err := json.MarshalIndent(missing, "", "    ")
+			if err != nil {
+				return err
+			}
+			_, err = out.Write(output)
+			return err
+		}
+		tasks = append(tasks, namedTask("record missing pods", p, task))
+
+		seen := make(map[string]bool)
+		for _, m := range missing {
+			if m.DeploymentConfig == "" || seen[m.DeploymentConfig] {
+				continue
+			}
+			seen[m.DeploymentConfig] = true
+			dc := m.DeploymentConfig
+			task := resourceDefinitions(func(project, resource string) *exec.Cmd {
+				return ocCommand("-n", project, "get", "rc", "-l", "openshift.io/deployment-config.name="+dc, "-o=json")
+			}, p, []string{"replicationcontrollers-" + dc}, outFor, errOutFor)
+			tasks = append(tasks, namedTask("fetch revision history of deploymentconfig "+dc, p, task))
+		}
+	}
+	return tasks
+}
+
+// warningEventPods returns the names of pods involved in Warning events, in
+// order of first appearance and without duplicates.
+func warningEventPods(events Events) []string {
+	var (
+		names []string
+		seen  = make(map[string]bool)
+	)
+	for _, event := range events.Items {
+		if event.Type != "Warning" || event.InvolvedObject.Kind != "Pod" || seen[event.InvolvedObject.Name] {
+			continue
+		}
+		seen[event.InvolvedObject.Name] = true
+		names = append(names, event.InvolvedObject.Name)
+	}
+	return names
+}
+
+// prioritizeResources returns the priority resources followed by all other
+// resources not in priority.
+func prioritizeResources(priority, others []LoggableResource) []LoggableResource {
+	var (
+		all  []LoggableResource
+		seen = make(map[LoggableResource]bool)
+	)
+	for _, resources := range [][]LoggableResource{priority, others} {
+		for _, r := range resources {
+			if seen[r] {
+				continue
+			}
+			seen[r] = true
+			all = append(all, r)
+		}
+	}
+	return all
+}
+
+// DescribePod is a task factory for tasks that describe the named pod in
+// project, writing the description to out and eventual errors to errOut.
+func DescribePod(project, name string, out, errOut io.Writer) Task {
+	return func(ctx context.Context) error {
+		cmd := ocCommand("-n", project, "describe", "pod", name)
+		return runCmdCaptureOutput(ctx, cmd, out, errOut)
+	}
+}
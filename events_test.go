@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestWarningEventPods(t *testing.T) {
+	var events Events
+	err := json.Unmarshal([]byte(`{"items": [
+		{"type": "Warning", "involvedObject": {"kind": "Pod", "name": "pod-1"}},
+		{"type": "Normal", "involvedObject": {"kind": "Pod", "name": "pod-2"}},
+		{"type": "Warning", "involvedObject": {"kind": "DeploymentConfig", "name": "dc-1"}},
+		{"type": "Warning", "involvedObject": {"kind": "Pod", "name": "pod-3"}},
+		{"type": "Warning", "involvedObject": {"kind": "Pod", "name": "pod-1"}}
+	]}`), &events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := warningEventPods(events), []string{"pod-1", "pod-3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("warningEventPods() = %v, want %v", got, want)
+	}
+}
+
+func TestPrioritizeResources(t *testing.T) {
+	a := LoggableResource{Project: "p", Type: "pods", Name: "a", Container: "c"}
+	b := LoggableResource{Project: "p", Type: "pods", Name: "b", Container: "c"}
+	c := LoggableResource{Project: "p", Type: "deploymentconfigs", Name: "c"}
+	got := prioritizeResources([]LoggableResource{b}, []LoggableResource{a, b, c})
+	if want := []LoggableResource{b, a, c}; !reflect.DeepEqual(got, want) {
+		t.Errorf("prioritizeResources() = %v, want %v", got, want)
+	}
+}
+
+func TestFindMissingPods(t *testing.T) {
+	var events Events
+	err := json.Unmarshal([]byte(`{"items": [
+		{"reason": "Killing", "involvedObject": {"kind": "Pod", "name": "fh-mbaas-3-abcde"}},
+		{"reason": "BackOff", "involvedObject": {"kind": "Pod", "name": "fh-mbaas-3-abcde"}},
+		{"reason": "Started", "involvedObject": {"kind": "Pod", "name": "fh-mbaas-4-fghij"}},
+		{"reason": "Failed", "involvedObject": {"kind": "Pod", "name": "standalone"}},
+		{"reason": "Scaled", "involvedObject": {"kind": "DeploymentConfig", "name": "gone"}}
+	]}`), &events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := []string{"fh-mbaas-4-fghij", "fh-mbaas-4-deploy", "mongodb-1-klmno"}
+	got := findMissingPods("core", events, existing)
+	want := []MissingPod{
+		{
+			Project:               "core",
+			Name:                  "fh-mbaas-3-abcde",
+			DeploymentConfig:      "fh-mbaas",
+			ReplicationController: "fh-mbaas-3",
+			Successors:            []string{"fh-mbaas-4-fghij"},
+			Reasons:               []string{"Killing", "BackOff"},
+		},
+		{
+			Project:    "core",
+			Name:       "standalone",
+			Successors: []string{},
+			Reasons:    []string{"Failed"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findMissingPods() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,5 @@
+module github.com/feedhenry/fh-system-dump-tool
+
+go 1.13
+
+require go.starlark.net v0.0.0-20220328144851-d1966c6b9fcd
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// grafanaContainer is the name of the container running Grafana in
+	// Grafana pods.
+	grafanaContainer = "grafana"
+	// grafanaURL is where Grafana listens, from inside its pod.
+	grafanaURL = "http://localhost:3000"
+	// grafanaDataRange is how far back the data of the panels of
+	// dashboards is exported, in grafanaDataStep steps.
+	grafanaDataRange = 24 * time.Hour
+	grafanaDataStep  = 5 * time.Minute
+	// maxGrafanaPanels is the maximum number of panels of each dashboard
+	// whose data is exported, in the order of the dashboard.
+	maxGrafanaPanels = 20
+)
+
+// grafanaScript runs curl with its arguments, authenticating as the Grafana
+// admin if the pod sets the admin password in its environment. The
+// credentials are expanded inside the pod, so they never reach the tool.
+const grafanaScript = `exec curl -sS --fail -G ${GF_SECURITY_ADMIN_PASSWORD:+-u "${GF_SECURITY_ADMIN_USER:-admin}:$GF_SECURITY_ADMIN_PASSWORD"} "$@"`
+
+// isGrafanaPod reports whether the pod named name runs Grafana.
+func isGrafanaPod(name string) bool {
+	return strings.Contains(name, "grafana") && !strings.Contains(name, "operator")
+}
+
+// GetGrafanaPods returns the running Grafana pods of the cluster, as
+// namespace/name, one per namespace.
+func GetGrafanaPods(ctx context.Context) ([]string, error) {
+	return getRunningPodsPerNamespace(ctx, isGrafanaPod)
+}
+
+// A grafanaParam is a query parameter of a request to the Grafana API.
+type grafanaParam struct {
+	Name  string
+	Value string
+}
+
+// grafanaCommand returns a command calling the Grafana API at apiPath, with
+// the given query parameters, from inside pod.
+func grafanaCommand(namespace, pod, apiPath string, params ...grafanaParam) *exec.Cmd {
+	args := []string{"-n", namespace, "exec", pod, "-c", grafanaContainer, "--", "sh", "-c", grafanaScript, "grafana-api"}
+	for _, p := range params {
+		args = append(args, "--data-urlencode", p.Name+"="+p.Value)
+	}
+	return ocCommand(append(args, grafanaURL+apiPath)...)
+}
+
+// A grafanaSearchHit is a dashboard found by the Grafana search API. Grafana
+// releases older than 5.0 identify dashboards by URI rather than UID.
+type grafanaSearchHit struct {
+	UID   string   `json:"uid"`
+	URI   string   `json:"uri"`
+	Title string   `json:"title"`
+	Tags  []string `json:"tags"`
+}
+
+// apiPath returns the path of the dashboard in the Grafana API.
+func (h grafanaSearchHit) apiPath() string {
+	if h.UID != "" {
+		return "/api/dashboards/uid/" + h.UID
+	}
+	return "/api/dashboards/" + h.URI
+}
+
+// fileName returns the base name of the files holding the dashboard in dumps.
+func (h grafanaSearchHit) fileName() string {
+	if h.UID != "" {
+		return h.UID
+	}
+	return path.Base(h.URI)
+}
+
+// isRHMAPDashboard reports whether the title or tags of h mention RHMAP or one
+// of projects.
+func isRHMAPDashboard(h grafanaSearchHit, projects []string) bool {
+	for _, w := range append([]string{h.Title}, h.Tags...) {
+		w = strings.ToLower(w)
+		if strings.Contains(w, "rhmap") {
+			return true
+		}
+		for _, p := range projects {
+			if strings.Contains(w, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type grafanaDatasource struct {
+	ID        int    `json:"id"`
+	UID       string `json:"uid"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	IsDefault bool   `json:"isDefault"`
+}
+
+type grafanaPanel struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	// Datasource is the name of the data source of the panel, or since
+	// Grafana 8 an object holding its UID, or null for the default one.
+	Datasource json.RawMessage `json:"datasource"`
+	Targets    []struct {
+		Expr string `json:"expr"`
+	} `json:"targets"`
+	// Panels are those of collapsed rows.
+	Panels []grafanaPanel `json:"panels"`
+}
+
+type grafanaDashboard struct {
+	Dashboard struct {
+		Panels []grafanaPanel `json:"panels"`
+		// Rows hold the panels of dashboards of Grafana releases
+		// older than 5.0.
+		Rows []struct {
+			Panels []grafanaPanel `json:"panels"`
+		} `json:"rows"`
+	} `json:"dashboard"`
+}
+
+// panels returns the panels of d, in order, including those of rows.
+func (d grafanaDashboard) panels() []grafanaPanel {
+	var panels []grafanaPanel
+	var add func([]grafanaPanel)
+	add = func(ps []grafanaPanel) {
+		for _, p := range ps {
+			panels = append(panels, p)
+			add(p.Panels)
+		}
+	}
+	for _, row := range d.Dashboard.Rows {
+		add(row.Panels)
+	}
+	add(d.Dashboard.Panels)
+	return panels
+}
+
+// panelDatasource returns the Prometheus data source of p, among datasources.
+func panelDatasource(p grafanaPanel, datasources []grafanaDatasource) (grafanaDatasource, bool) {
+	var name, uid string
+	if err := json.Unmarshal(p.Datasource, &name); err != nil {
+		var ref struct {
+			UID string `json:"uid"`
+		}
+		json.Unmarshal(p.Datasource, &ref)
+		uid = ref.UID
+	}
+	for _, ds := range datasources {
+		if ds.Type != "prometheus" {
+			continue
+		}
+		if (name == "" && uid == "" && ds.IsDefault) || (name != "" && ds.Name == name) || (uid != "" && ds.UID == uid) {
+			return ds, true
+		}
+	}
+	return grafanaDatasource{}, false
+}
+
+// A grafanaPanelQuery is a query of a panel whose data is exported.
+type grafanaPanelQuery struct {
+	PanelID    int    `json:"panelId"`
+	Title      string `json:"title"`
+	Expr       string `json:"expr"`
+	Datasource int    `json:"-"`
+	// Result is the response of Prometheus to the query, over
+	// grafanaDataRange.
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// grafanaPanelQueries returns the Prometheus queries of the first
+// maxGrafanaPanels panels of d having some. Queries using template variables
+// are left out, as their values are only known to the browser.
+func grafanaPanelQueries(d grafanaDashboard, datasources []grafanaDatasource) []grafanaPanelQuery {
+	var queries []grafanaPanelQuery
+	panels := 0
+	for _, p := range d.panels() {
+		ds, ok := panelDatasource(p, datasources)
+		if !ok || panels == maxGrafanaPanels {
+			continue
+		}
+		found := false
+		for _, t := range p.Targets {
+			if t.Expr == "" || strings.Contains(t.Expr, "$") {
+				continue
+			}
+			found = true
+			queries = append(queries, grafanaPanelQuery{PanelID: p.ID, Title: p.Title, Expr: t.Expr, Datasource: ds.ID})
+		}
+		if found {
+			panels++
+		}
+	}
+	return queries
+}
+
+// GetGrafanaTasks returns a list of tasks to export, from each Grafana pod,
+// the dashboards related to RHMAP or to the given projects into
+// grafana/<namespace>/<pod>/dashboards/<dashboard>.json, and the data of their
+// panels over the last grafanaDataRange into
+// grafana/<namespace>/<pod>/data/<dashboard>.json.
+// FIXME: GetGrafanaTasks should not know about tarFile.
+func GetGrafanaTasks(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+	pods, err := GetGrafanaPods(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []Task
+	for _, pod := range pods {
+		parts := strings.SplitN(pod, "/", 2)
+		namespace, name := parts[0], parts[1]
+		task := func(ctx context.Context) error {
+			return exportGrafanaDashboards(ctx, namespace, name, projects, tarFile)
+		}
+		tasks = append(tasks, namedTask("export grafana dashboards of "+name, namespace, task))
+	}
+	return tasks, nil
+}
+
+// exportGrafanaDashboards exports the dashboards of the Grafana pod related to
+// projects, and the data of their panels, to tarFile.
+func exportGrafanaDashboards(ctx context.Context, namespace, pod string, projects []string, tarFile *Archive) error {
+	get := func(cmd *exec.Cmd) ([]byte, error) {
+		var out bytes.Buffer
+		err := runCmdCaptureOutput(ctx, cmd, &out, nil)
+		return out.Bytes(), err
+	}
+	var (
+		hits        []grafanaSearchHit
+		datasources []grafanaDatasource
+	)
+	for _, r := range []struct {
+		apiPath string
+		dest    interface{}
+	}{
+		{"/api/search", &hits},
+		{"/api/datasources", &datasources},
+	} {
+		output, err := get(grafanaCommand(namespace, pod, r.apiPath))
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(output, r.dest); err != nil {
+			return fmt.Errorf("reading %s of grafana pod %s: %v", r.apiPath, pod, err)
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Title < hits[j].Title })
+
+	dir := filepath.Join("grafana", namespace, pod)
+	end := time.Now()
+	start := end.Add(-grafanaDataRange)
+	var errors errorList
+	for _, h := range hits {
+		if !isRHMAPDashboard(h, projects) {
+			continue
+		}
+		output, err := get(grafanaCommand(namespace, pod, h.apiPath()))
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		if err := tarFile.AddFileByContent(output, filepath.Join(dir, "dashboards", h.fileName()+".json")); err != nil {
+			errors = append(errors, err)
+		}
+		var d grafanaDashboard
+		if err := json.Unmarshal(output, &d); err != nil {
+			errors = append(errors, fmt.Errorf("reading dashboard %s of grafana pod %s: %v", h.Title, pod, err))
+			continue
+		}
+		queries := grafanaPanelQueries(d, datasources)
+		for i, q := range queries {
+			result, err := get(grafanaCommand(namespace, pod, fmt.Sprintf("/api/datasources/proxy/%d/api/v1/query_range", q.Datasource),
+				grafanaParam{"query", q.Expr},
+				grafanaParam{"start", strconv.FormatInt(start.Unix(), 10)},
+				grafanaParam{"end", strconv.FormatInt(end.Unix(), 10)},
+				grafanaParam{"step", strconv.Itoa(int(grafanaDataStep.Seconds()))}))
+			if err != nil {
+				queries[i].Error = err.Error()
+				continue
+			}
+			queries[i].Result = result
+		}
+		data, err := json.MarshalIndent(struct {
+			Title   string              `json:"title"`
+			Start   time.Time           `json:"start"`
+			End     time.Time           `json:"end"`
+			Queries []grafanaPanelQuery `json:"queries"`
+		}{h.Title, start.UTC(), end.UTC(), append([]grafanaPanelQuery{}, queries...)}, "", "    ")
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		if err := tarFile.AddFileByContent(data, filepath.Join(dir, "data", h.fileName()+".json")); err != nil {
+			errors = append(errors, err)
+		}
+	}
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
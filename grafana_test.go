@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/feedhenry/fh-system-dump-tool/cmdrunner"
+)
+
+func TestIsRHMAPDashboard(t *testing.T) {
+	projects := []string{"core", "mbaas-dev"}
+	tests := []struct {
+		hit  grafanaSearchHit
+		want bool
+	}{
+		{grafanaSearchHit{Title: "RHMAP Core"}, true},
+		{grafanaSearchHit{Title: "Pods", Tags: []string{"mbaas-dev"}}, true},
+		{grafanaSearchHit{Title: "Kubernetes / Nodes", Tags: []string{"kubernetes-mixin"}}, false},
+	}
+	for _, tt := range tests {
+		if got := isRHMAPDashboard(tt.hit, projects); got != tt.want {
+			t.Errorf("isRHMAPDashboard(%+v) = %v, want %v", tt.hit, got, tt.want)
+		}
+	}
+}
+
+func TestGrafanaPanelQueries(t *testing.T) {
+	var d grafanaDashboard
+	if err := json.Unmarshal([]byte(`{"dashboard": {"panels": [
+		{"id": 1, "title": "CPU", "targets": [{"expr": "sum(rate(container_cpu_usage_seconds_total[5m]))"}]},
+		{"id": 2, "title": "Memory", "datasource": "thanos", "targets": [{"expr": "sum(container_memory_working_set_bytes{namespace=\"$namespace\"})"}]},
+		{"id": 3, "title": "Logs", "datasource": "elasticsearch", "targets": [{"query": "*"}]},
+		{"id": 4, "type": "row", "panels": [
+			{"id": 5, "title": "Restarts", "datasource": {"type": "prometheus", "uid": "thanos-uid"}, "targets": [{"expr": "kube_pod_container_status_restarts_total"}]}]}]}}`), &d); err != nil {
+		t.Fatal(err)
+	}
+	datasources := []grafanaDatasource{
+		{ID: 1, Name: "prometheus", Type: "prometheus", IsDefault: true},
+		{ID: 2, UID: "thanos-uid", Name: "thanos", Type: "prometheus"},
+		{ID: 3, Name: "elasticsearch", Type: "elasticsearch"},
+	}
+	want := []grafanaPanelQuery{
+		{PanelID: 1, Title: "CPU", Expr: "sum(rate(container_cpu_usage_seconds_total[5m]))", Datasource: 1},
+		{PanelID: 5, Title: "Restarts", Expr: "kube_pod_container_status_restarts_total", Datasource: 2},
+	}
+	if got := grafanaPanelQueries(d, datasources); !reflect.DeepEqual(got, want) {
+		t.Errorf("grafanaPanelQueries() = %+v, want %+v", got, want)
+	}
+}
+
+func TestExportGrafanaDashboards(t *testing.T) {
+	defer func(r cmdrunner.Runner) { runner = r }(runner)
+	runner = cmdrunner.NewFakeRunner([]cmdrunner.Invocation{
+		{Args: grafanaCommand("monitoring", "grafana-1-abcde", "/api/search").Args, Stdout: `[{"uid": "nodes", "title": "Nodes"}, {"uid": "rhmap", "title": "RHMAP"}]`},
+		{Args: grafanaCommand("monitoring", "grafana-1-abcde", "/api/datasources").Args, Stdout: `[]`},
+		{Args: grafanaCommand("monitoring", "grafana-1-abcde", "/api/dashboards/uid/rhmap").Args, Stdout: `{"dashboard": {"title": "RHMAP", "panels": []}}`},
+	})
+	var b bytes.Buffer
+	archive, err := NewArchive(&b, compressionGzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exportGrafanaDashboards(context.Background(), "monitoring", "grafana-1-abcde", []string{"core"}, archive); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ReadTgz(&b, func(string) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"grafana/monitoring/grafana-1-abcde/dashboards/rhmap.json", "grafana/monitoring/grafana-1-abcde/data/rhmap.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("%s is missing from the archive", name)
+		}
+	}
+	if _, ok := files["grafana/monitoring/grafana-1-abcde/dashboards/nodes.json"]; ok {
+		t.Errorf("the unrelated dashboard was exported")
+	}
+}
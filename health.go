@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Each finding lowers the health score of a project, out of 100, by the
+// weight of its severity.
+const (
+	criticalWeight = 20
+	warningWeight  = 5
+)
+
+// ProjectHealth returns the health score of project, from 0 to 100. Suppressed
+// findings don't count, but findings below the minimum severity reported do,
+// so that the score doesn't depend on what is shown.
+func (s DumpSummary) ProjectHealth(project string) int {
+	s.MinSeverity = StatusOK
+	score := 100
+	for _, f := range s.forProject(project).Findings() {
+		switch f.Result.Status {
+		case StatusCritical:
+			score -= criticalWeight
+		case StatusWarning:
+			score -= warningWeight
+		}
+	}
+	if score < 0 {
+		score = 0
+	}
+	return score
+}
+
+// HealthScore returns the overall health score of the dump, the average of the
+// health scores of all projects.
+func (s DumpSummary) HealthScore() int {
+	if len(s.Results) == 0 {
+		return 100
+	}
+	total := 0
+	for project := range s.Results {
+		total += s.ProjectHealth(project)
+	}
+	return total / len(s.Results)
+}
+
+// A projectHealth is the health score of a project.
+type projectHealth struct {
+	Project string `json:"project"`
+	Score   int    `json:"score"`
+}
+
+// projectHealthScores returns the health score of every project, least healthy
+// first.
+func (s DumpSummary) projectHealthScores() []projectHealth {
+	var scores []projectHealth
+	for project := range s.Results {
+		scores = append(scores, projectHealth{project, s.ProjectHealth(project)})
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score < scores[j].Score
+		}
+		return scores[i].Project < scores[j].Project
+	})
+	return scores
+}
+
+// writeHealthScores writes the health score of each project to w.
+func writeHealthScores(w io.Writer, s DumpSummary) error {
+	if len(s.Results) < 2 {
+		return nil
+	}
+	if _, err := fmt.Fprintln(w, "\nHealth scores:"); err != nil {
+		return err
+	}
+	for _, h := range s.projectHealthScores() {
+		if _, err := fmt.Fprintf(w, "  %3d %s\n", h.Score, h.Project); err != nil {
+			return err
+		}
+	}
+	return nil
+}
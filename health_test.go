@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestHealthScore(t *testing.T) {
+	summary := DumpSummary{
+		Results: map[string][]Result{
+			"core":  {{CheckID: "a", Status: StatusCritical}, {CheckID: "b", Status: StatusWarning}, {CheckID: "c", Status: StatusOK}},
+			"mbaas": {{CheckID: "a", Status: StatusWarning}},
+			"apps":  {{CheckID: "a", Status: StatusCritical, Info: []Info{{Name: "x"}}}},
+		},
+		Suppressions: []Suppression{{Check: "a", Project: "apps"}},
+		MinSeverity:  StatusCritical,
+	}
+	for project, want := range map[string]int{"core": 75, "mbaas": 95, "apps": 100} {
+		if got := summary.ProjectHealth(project); got != want {
+			t.Errorf("ProjectHealth(%q) = %d, want %d", project, got, want)
+		}
+	}
+	if got, want := summary.HealthScore(), 90; got != want {
+		t.Errorf("HealthScore() = %d, want %d", got, want)
+	}
+
+	var many []Result
+	for i := 0; i < 10; i++ {
+		many = append(many, Result{Status: StatusCritical})
+	}
+	summary = DumpSummary{Results: map[string][]Result{"core": many}}
+	if got := summary.ProjectHealth("core"); got != 0 {
+		t.Errorf("ProjectHealth with many criticals = %d, want 0", got)
+	}
+	if got := (DumpSummary{}).HealthScore(); got != 100 {
+		t.Errorf("HealthScore() of an empty dump = %d, want 100", got)
+	}
+}
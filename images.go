@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A RunningImage is the image of a running container, identified by digest.
+type RunningImage struct {
+	Project   string `json:"project"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	// Image is the image reference in the pod spec, ImageID the reference
+	// resolved by the container runtime.
+	Image   string `json:"image"`
+	ImageID string `json:"imageID"`
+	Digest  string `json:"digest,omitempty"`
+	// Created and Labels come from the image metadata known to the
+	// cluster, if any. MetadataError is why it could not be read.
+	Created       *time.Time        `json:"created,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	MetadataError string            `json:"metadataError,omitempty"`
+}
+
+// imageInfo is the subset of an OpenShift Image used to identify builds.
+type imageInfo struct {
+	DockerImageMetadata struct {
+		Created time.Time `json:"Created"`
+		Config  struct {
+			Labels map[string]string `json:"Labels"`
+		} `json:"Config"`
+	} `json:"dockerImageMetadata"`
+}
+
+// imageDigest returns the digest of a container image ID, such as
+// docker-pullable://registry.access.redhat.com/rhmap45/fh-ngui@sha256:0123...
+func imageDigest(imageID string) string {
+	i := strings.LastIndex(imageID, "@")
+	if i < 0 {
+		return ""
+	}
+	return imageID[i+1:]
+}
+
+// runningImages returns the images of the running containers of pods, in
+// project order.
+func runningImages(project string, pods Pods) []RunningImage {
+	var images []RunningImage
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != "Running" {
+			continue
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.State.Running == nil {
+				continue
+			}
+			images = append(images, RunningImage{
+				Project:   project,
+				Pod:       pod.Metadata.Name,
+				Container: cs.Name,
+				Image:     cs.Image,
+				ImageID:   cs.ImageID,
+				Digest:    imageDigest(cs.ImageID),
+			})
+		}
+	}
+	sort.Slice(images, func(i, j int) bool {
+		if images[i].Pod != images[j].Pod {
+			return images[i].Pod < images[j].Pod
+		}
+		return images[i].Container < images[j].Container
+	})
+	return images
+}
+
+// GetRunningImagesTask returns a task to record the digests of the images of
+// all running containers in the given projects to images.json, along with the
+// build date and labels of each image the cluster has metadata for. Reading
+// image metadata requires cluster-admin; the digests are recorded regardless.
+func GetRunningImagesTask(projects []string, tarFile *Archive) Task {
+	out := tarFile.GetWriterToFile("images.json")
+	return func(ctx context.Context) error {
+		defer out.Close()
+		var errors errorList
+		images := []RunningImage{}
+		for _, p := range projects {
+			var pods Pods
+			if err := getResourceStruct(ctx, p, "pods", &pods); err != nil {
+				errors = append(errors, err)
+				continue
+			}
+			images = append(images, runningImages(p, pods)...)
+		}
+		addImageMetadata(images, func(project, digest string) (imageInfo, error) {
+			var m imageInfo
+			err := getResourceStruct(ctx, project, "image/"+digest, &m)
+			return m, err
+		})
+		output, err := json.MarshalIndent(images, "", "    ")
+		if err != nil {
+			errors = append(errors, err)
+		} else if _, err := out.Write(output); err != nil {
+			errors = append(errors, err)
+		}
+		if len(errors) > 0 {
+			return errors
+		}
+		return nil
+	}
+}
+
+// addImageMetadata fills in the metadata of images using get, looking up
+// every digest only once.
+func addImageMetadata(images []RunningImage, get func(project, digest string) (imageInfo, error)) {
+	type lookup struct {
+		metadata imageInfo
+		err      error
+	}
+	seen := make(map[string]lookup)
+	for i := range images {
+		img := &images[i]
+		if img.Digest == "" {
+			continue
+		}
+		l, ok := seen[img.Digest]
+		if !ok {
+			l.metadata, l.err = get(img.Project, img.Digest)
+			seen[img.Digest] = l
+		}
+		if l.err != nil {
+			img.MetadataError = strings.TrimSpace(l.err.Error())
+			continue
+		}
+		created := l.metadata.DockerImageMetadata.Created
+		if !created.IsZero() {
+			img.Created = &created
+		}
+		img.Labels = l.metadata.DockerImageMetadata.Config.Labels
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunningImages(t *testing.T) {
+	var pods Pods
+	if err := json.Unmarshal([]byte(`{"items": [
+		{"metadata": {"name": "fh-ngui-1-abcde"}, "status": {"phase": "Running", "containerStatuses": [
+			{"name": "fh-ngui", "image": "rhmap45/fh-ngui:latest", "imageID": "docker-pullable://registry.access.redhat.com/rhmap45/fh-ngui@sha256:0123", "state": {"running": {"startedAt": "2017-06-01T10:00:00Z"}}},
+			{"name": "sidecar", "imageID": "docker://sha256:4567", "state": {"waiting": {}}}]}},
+		{"metadata": {"name": "mongodb-backup-1"}, "status": {"phase": "Succeeded", "containerStatuses": [
+			{"name": "backup", "imageID": "docker-pullable://registry.access.redhat.com/rhmap45/backup@sha256:89ab"}]}}
+	]}`), &pods); err != nil {
+		t.Fatal(err)
+	}
+	images := runningImages("core", pods)
+	if len(images) != 1 {
+		t.Fatalf("runningImages() = %+v, want only the running fh-ngui container", images)
+	}
+	if images[0].Digest != "sha256:0123" || images[0].Image != "rhmap45/fh-ngui:latest" {
+		t.Errorf("runningImages()[0] = %+v, want digest sha256:0123", images[0])
+	}
+}
+
+func TestAddImageMetadata(t *testing.T) {
+	images := []RunningImage{
+		{Project: "core", Pod: "a", Digest: "sha256:0123"},
+		{Project: "core", Pod: "b", Digest: "sha256:0123"},
+		{Project: "core", Pod: "c", Digest: "sha256:4567"},
+		{Project: "core", Pod: "d"},
+	}
+	created := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+	calls := 0
+	addImageMetadata(images, func(project, digest string) (imageInfo, error) {
+		calls++
+		if digest != "sha256:0123" {
+			return imageInfo{}, errors.New("images.image.openshift.io \"sha256:4567\" not found\n")
+		}
+		var m imageInfo
+		m.DockerImageMetadata.Created = created
+		m.DockerImageMetadata.Config.Labels = map[string]string{"release": "7"}
+		return m, nil
+	})
+	if calls != 2 {
+		t.Errorf("metadata looked up %d times, want once per digest", calls)
+	}
+	if images[1].Created == nil || !images[1].Created.Equal(created) || images[1].Labels["release"] != "7" {
+		t.Errorf("images[1] = %+v, want build date and labels", images[1])
+	}
+	if images[2].MetadataError != `images.image.openshift.io "sha256:4567" not found` {
+		t.Errorf("images[2].MetadataError = %q", images[2].MetadataError)
+	}
+	if images[3].MetadataError != "" || images[3].Labels != nil {
+		t.Errorf("images[3] = %+v, want no metadata without a digest", images[3])
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// interruptContext returns a context cancelled on the first SIGINT or SIGTERM,
+// or after timeout unless it is zero, and a function returning why it was
+// cancelled, or an empty string if it wasn't. A second signal kills the tool
+// as usual.
+func interruptContext(timeout time.Duration) (context.Context, func() string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var (
+		mu     sync.Mutex
+		reason string
+	)
+	interrupt := func(why string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if reason == "" {
+			reason = why
+			cancel()
+		}
+	}
+	if timeout > 0 {
+		time.AfterFunc(timeout, func() {
+			log.Printf("Timed out after %v, completing the dump with what was collected so far\n", timeout)
+			interrupt(fmt.Sprintf("timed out after %v", timeout))
+		})
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		signal.Stop(signals)
+		log.Printf("Received %v, completing the dump with what was collected so far, repeat to quit immediately\n", sig)
+		interrupt("received " + sig.String())
+	}()
+	interrupted := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return reason
+	}
+	return ctx, interrupted
+}
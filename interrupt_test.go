@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestInterruptContext(t *testing.T) {
+	ctx, interrupted := interruptContext(10 * time.Millisecond)
+	<-ctx.Done()
+	if got, want := interrupted(), "timed out after 10ms"; got != want {
+		t.Errorf("interrupted() after the timeout = %q, want %q", got, want)
+	}
+
+	ctx, interrupted = interruptContext(0)
+	if got := interrupted(); got != "" {
+		t.Errorf("interrupted() before any signal = %q, want none", got)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+		t.Skip(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the context wasn't cancelled by SIGINT")
+	}
+	if got, want := interrupted(), "received interrupt"; got != want {
+		t.Errorf("interrupted() after SIGINT = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// A Component is an entry in the inventory, describing a deployed RHMAP
+// component.
+type Component struct {
+	Project           string   `json:"project"`
+	Name              string   `json:"name"`
+	Images            []string `json:"images"`
+	DesiredReplicas   int      `json:"desiredReplicas"`
+	AvailableReplicas int      `json:"availableReplicas"`
+	Nodes             []string `json:"nodes"`
+	Restarts          int      `json:"restarts"`
+}
+
+// An Inventory lists all components detected in a dump, and the health scores
+// of the projects analysed.
+type Inventory struct {
+	Components []Component `json:"components"`
+	// HealthScore is the overall health score of the dump, see
+	// DumpSummary.HealthScore.
+	HealthScore int `json:"healthScore"`
+	// ProjectHealth are the health scores of the projects analysed, least
+	// healthy first.
+	ProjectHealth []projectHealth `json:"projectHealth"`
+}
+
+// setHealth sets the health scores of inventory to those of s.
+func (inventory *Inventory) setHealth(s DumpSummary) {
+	inventory.HealthScore = s.HealthScore()
+	inventory.ProjectHealth = append([]projectHealth{}, s.projectHealthScores()...)
+}
+
+// WriteInventory is a task factory for tasks that build the inventory of
+// components in all given projects, writing it as JSON to jsonOut and as a
+// Markdown table to mdOut. The health scores are computed from the analysis
+// results among the files returned by analysis, except the findings in
+// suppressions, so the task must run after the checks.
+func WriteInventory(projects []string, analysis func() map[string][]byte, suppressions []Suppression, jsonOut, mdOut io.Writer) Task {
+	return func(ctx context.Context) error {
+		var errors errorList
+		inventory, err := GetInventory(ctx, projects)
+		if err != nil {
+			errors = append(errors, err)
+		}
+		summary, err := parseDumpSummary("", analysis())
+		if err != nil {
+			errors = append(errors, err)
+		}
+		summary.Suppressions = suppressions
+		inventory.setHealth(summary)
+		output, err := json.MarshalIndent(inventory, "", "    ")
+		if err != nil {
+			errors = append(errors, err)
+		}
+		jsonOut.Write(output)
+		if err := writeInventoryMarkdown(mdOut, inventory); err != nil {
+			errors = append(errors, err)
+		}
+		if len(errors) > 0 {
+			return errors
+		}
+		return nil
+	}
+}
+
+// GetInventory returns the inventory of components deployed in the given
+// projects. It may return results even in the presence of an error.
+func GetInventory(ctx context.Context, projects []string) (Inventory, error) {
+	var (
+		inventory = Inventory{Components: []Component{}}
+		errors    errorList
+	)
+	for _, p := range projects {
+		var (
+			dcs  DeploymentConfigs
+			pods Pods
+		)
+		if err := getResourceStruct(ctx, p, "dc", &dcs); err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		if err := getResourceStruct(ctx, p, "pods", &pods); err != nil {
+			errors = append(errors, err)
+		}
+		inventory.Components = append(inventory.Components, buildComponents(p, dcs, pods)...)
+	}
+	if len(errors) > 0 {
+		return inventory, errors
+	}
+	return inventory, nil
+}
+
+// buildComponents combines the deployment configs and pods of a project into
+// components. Pods are matched to their deployment config by the
+// deploymentconfig label set by OpenShift.
+func buildComponents(project string, dcs DeploymentConfigs, pods Pods) []Component {
+	var components []Component
+	for _, dc := range dcs.Items {
+		c := Component{
+			Project:           project,
+			Name:              dc.Metadata.Name,
+			Images:            []string{},
+			DesiredReplicas:   dc.Spec.Replicas,
+			AvailableReplicas: dc.Status.AvailableReplicas,
+			Nodes:             []string{},
+		}
+		for _, container := range dc.Spec.Template.Spec.Containers {
+			c.Images = append(c.Images, container.Image)
+		}
+		nodes := make(map[string]bool)
+		for _, pod := range pods.Items {
+			if pod.Metadata.Labels["deploymentconfig"] != dc.Metadata.Name {
+				continue
+			}
+			if pod.Spec.NodeName != "" {
+				nodes[pod.Spec.NodeName] = true
+			}
+			for _, status := range pod.Status.ContainerStatuses {
+				c.Restarts += status.RestartCount
+			}
+		}
+		for node := range nodes {
+			c.Nodes = append(c.Nodes, node)
+		}
+		sort.Strings(c.Nodes)
+		components = append(components, c)
+	}
+	return components
+}
+
+// writeInventoryMarkdown writes inventory to w as Markdown tables.
+func writeInventoryMarkdown(w io.Writer, inventory Inventory) error {
+	if _, err := fmt.Fprint(w, "# RHMAP Component Inventory\n\n"+
+		"| Project | Component | Images | Replicas (available/desired) | Nodes | Restarts |\n"+
+		"|---|---|---|---|---|---|\n"); err != nil {
+		return err
+	}
+	for _, c := range inventory.Components {
+		if _, err := fmt.Fprintf(w, "| %s | %s | %s | %d/%d | %s | %d |\n",
+			c.Project, c.Name, strings.Join(c.Images, "<br>"),
+			c.AvailableReplicas, c.DesiredReplicas,
+			strings.Join(c.Nodes, ", "), c.Restarts); err != nil {
+			return err
+		}
+	}
+	if len(inventory.ProjectHealth) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "\n## Health Scores\n\n"+
+		"Overall health score: %d/100\n\n"+
+		"| Project | Health score |\n"+
+		"|---|---|\n", inventory.HealthScore); err != nil {
+		return err
+	}
+	for _, h := range inventory.ProjectHealth {
+		if _, err := fmt.Fprintf(w, "| %s | %d |\n", h.Project, h.Score); err != nil {
+			return err
+		}
+	}
+	return nil
+}
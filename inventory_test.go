@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildComponents(t *testing.T) {
+	var dc DeploymentConfig
+	dc.Metadata.Name = "fh-mbaas"
+	dc.Spec.Replicas = 2
+	dc.Status.AvailableReplicas = 1
+	dc.Spec.Template.Spec.Containers = []Container{{Name: "fh-mbaas", Image: "rhmap/fh-mbaas:4.2.0"}}
+
+	pod := func(name, dc, node string, restarts int) Pod {
+		var p Pod
+		p.Metadata.Name = name
+		p.Metadata.Labels = map[string]string{"deploymentconfig": dc}
+		p.Spec.NodeName = node
+		p.Status.ContainerStatuses = []ContainerStatus{{RestartCount: restarts}}
+		return p
+	}
+	pods := Pods{Items: []Pod{
+		pod("fh-mbaas-1-a", "fh-mbaas", "node-2", 3),
+		pod("fh-mbaas-1-b", "fh-mbaas", "node-1", 1),
+		pod("mongodb-1-a", "mongodb", "node-3", 7),
+	}}
+
+	got := buildComponents("core", DeploymentConfigs{Items: []DeploymentConfig{dc}}, pods)
+	want := []Component{{
+		Project:           "core",
+		Name:              "fh-mbaas",
+		Images:            []string{"rhmap/fh-mbaas:4.2.0"},
+		DesiredReplicas:   2,
+		AvailableReplicas: 1,
+		Nodes:             []string{"node-1", "node-2"},
+		Restarts:          4,
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildComponents() = %+v, want %+v", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := writeInventoryMarkdown(&buf, Inventory{Components: got}); err != nil {
+		t.Fatal(err)
+	}
+	if row := "| core | fh-mbaas | rhmap/fh-mbaas:4.2.0 | 1/2 | node-1, node-2 | 4 |"; !strings.Contains(buf.String(), row) {
+		t.Errorf("writeInventoryMarkdown() output doesn't include %q:\n%s", row, buf.String())
+	}
+}
+
+func TestWriteInventoryHealth(t *testing.T) {
+	analysis := func() map[string][]byte {
+		return map[string][]byte{
+			"definitions/projects/core/analysis.json":  []byte(`{"Results": [{"checkId": "a", "status": 2}, {"checkId": "b", "status": 1}]}`),
+			"definitions/projects/mbaas/analysis.json": []byte(`{"Results": [{"checkId": "b", "status": 1}]}`),
+			"definitions/projects/core/pods.json":      []byte(`{"items": []}`),
+		}
+	}
+	suppressions := []Suppression{{Check: "b", Project: "mbaas"}}
+	var jsonOut, mdOut bytes.Buffer
+	if err := WriteInventory(nil, analysis, suppressions, &jsonOut, &mdOut)(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var got Inventory
+	if err := json.Unmarshal(jsonOut.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := Inventory{
+		Components:    []Component{},
+		HealthScore:   87,
+		ProjectHealth: []projectHealth{{"core", 75}, {"mbaas", 100}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WriteInventory() wrote %+v, want %+v", got, want)
+	}
+	for _, line := range []string{"Overall health score: 87/100", "| core | 75 |", "| mbaas | 100 |"} {
+		if !strings.Contains(mdOut.String(), line) {
+			t.Errorf("WriteInventory() Markdown doesn't include %q:\n%s", line, mdOut.String())
+		}
+	}
+}
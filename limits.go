@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// componentMemoryRequirements maps the names of RHMAP components to the memory
+// limit, in bytes, they need to run without being killed for running out of
+// memory. Deployment configs named after a component, or after a component and
+// a suffix like mongodb-1, are held to it.
+var componentMemoryRequirements = map[string]int64{
+	"mongodb":      1 << 30,
+	"millicore":    1 << 30,
+	"mysql":        512 << 20,
+	"fh-mbaas":     512 << 20,
+	"fh-supercore": 512 << 20,
+	"fh-messaging": 256 << 20,
+	"fh-metrics":   256 << 20,
+	"fh-ngui":      256 << 20,
+	"fh-aaa":       256 << 20,
+	"redis":        256 << 20,
+	"memcached":    256 << 20,
+	"nagios":       256 << 20,
+}
+
+// memorySuffixes maps the suffixes of Kubernetes memory quantities to their
+// multipliers. Two letter suffixes must come first.
+var memorySuffixes = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40},
+	{"k", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12},
+}
+
+// parseMemoryQuantity parses a Kubernetes memory quantity, such as 512Mi, to
+// a number of bytes.
+func parseMemoryQuantity(s string) (int64, error) {
+	multiplier := 1.0
+	number := s
+	for _, m := range memorySuffixes {
+		if strings.HasSuffix(s, m.suffix) {
+			multiplier, number = m.multiplier, strings.TrimSuffix(s, m.suffix)
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid memory quantity %q", s)
+	}
+	return int64(n * multiplier), nil
+}
+
+type LimitRangeItem struct {
+	// Type is either "Container" or "Pod".
+	Type    string            `json:"type"`
+	Default map[string]string `json:"default"`
+	Max     map[string]string `json:"max"`
+}
+
+type LimitRange struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Spec struct {
+		Limits []LimitRangeItem `json:"limits"`
+	} `json:"spec"`
+}
+
+type LimitRanges struct {
+	Items []LimitRange `json:"items"`
+}
+
+// componentMemoryRequirement returns the memory needed by the RHMAP component
+// deployed by the named deployment config, if it is one.
+func componentMemoryRequirement(dc string) (int64, bool) {
+	for component, required := range componentMemoryRequirements {
+		if dc == component || strings.HasPrefix(dc, component+"-") {
+			return required, true
+		}
+	}
+	return 0, false
+}
+
+// CheckLimitRangeDefaults will check the limit ranges of the supplied project against the memory the RHMAP components
+// deployed by its deployconfigs need, and if the default memory limit injected into containers without limits, or the
+// maximum allowed, is too small for them this will be reflected in the returned Result data. Any errors are written to
+// the supplied stdErr writer
+func CheckLimitRangeDefaults(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+	result := Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: "limitrange-conflict", CheckName: "check limit ranges against the memory needs of RHMAP components"}
+	var limitRanges LimitRanges
+	if err := getResourceStruct(ctx, project, "limitranges", &limitRanges); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	if len(limitRanges.Items) == 0 {
+		return result, nil
+	}
+	var dcs DeploymentConfigs
+	if err := getResourceStruct(ctx, project, "dc", &dcs); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	return checkLimitRangeDefaults(result, limitRanges, dcs), nil
+}
+
+func checkLimitRangeDefaults(result Result, limitRanges LimitRanges, dcs DeploymentConfigs) Result {
+	found := func(dc DeploymentConfig, message string) {
+		result.Status = StatusWarning
+		result.StatusMessage = "one or more limit ranges give RHMAP components less memory than they need"
+		result.Info = append(result.Info, Info{Name: dc.Metadata.Name, Namespace: dc.Metadata.Namespace, Kind: dc.Kind, Count: 1, Message: message})
+	}
+	for _, dc := range dcs.Items {
+		required, ok := componentMemoryRequirement(dc.Metadata.Name)
+		if !ok {
+			continue
+		}
+		for _, lr := range limitRanges.Items {
+			for _, item := range lr.Spec.Limits {
+				if max, err := parseMemoryQuantity(item.Max["memory"]); item.Max["memory"] != "" && err == nil && max < required {
+					found(dc, fmt.Sprintf("the maximum %s memory limit %s of limit range %s is smaller than the %s the component needs",
+						strings.ToLower(item.Type), item.Max["memory"], lr.Metadata.Name, formatSize(required)))
+				}
+				if item.Type != "Container" || item.Default["memory"] == "" {
+					continue
+				}
+				def, err := parseMemoryQuantity(item.Default["memory"])
+				if err != nil || def >= required {
+					continue
+				}
+				for _, c := range dc.Spec.Template.Spec.Containers {
+					if c.Resources.Limits["memory"] == "" {
+						found(dc, fmt.Sprintf("container %s has no memory limit and gets the default %s of limit range %s, smaller than the %s the component needs",
+							c.Name, item.Default["memory"], lr.Metadata.Name, formatSize(required)))
+					}
+				}
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseMemoryQuantity(t *testing.T) {
+	for s, want := range map[string]int64{"512Mi": 512 << 20, "1Gi": 1 << 30, "0.5Gi": 512 << 20, "1G": 1e9, "128974848": 128974848} {
+		if got, err := parseMemoryQuantity(s); err != nil || got != want {
+			t.Errorf("parseMemoryQuantity(%q) = %d, %v, want %d", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "Mi", "lots"} {
+		if _, err := parseMemoryQuantity(s); err == nil {
+			t.Errorf("parseMemoryQuantity(%q) didn't return an error", s)
+		}
+	}
+}
+
+func TestCheckLimitRangeDefaults(t *testing.T) {
+	var (
+		limitRanges LimitRanges
+		dcs         DeploymentConfigs
+	)
+	for js, v := range map[string]interface{}{
+		`{"items": [{"kind": "LimitRange", "metadata": {"name": "defaults"}, "spec": {"limits": [
+			{"type": "Container", "default": {"memory": "512Mi"}},
+			{"type": "Pod", "max": {"memory": "768Mi"}}
+		]}}]}`: &limitRanges,
+		`{"items": [
+			{"kind": "DeploymentConfig", "metadata": {"name": "mongodb-1"}, "spec": {"template": {"spec": {"containers": [{"name": "mongodb"}]}}}},
+			{"kind": "DeploymentConfig", "metadata": {"name": "fh-ngui"}, "spec": {"template": {"spec": {"containers": [{"name": "fh-ngui"}]}}}},
+			{"kind": "DeploymentConfig", "metadata": {"name": "fh-mbaas"}, "spec": {"template": {"spec": {"containers": [
+				{"name": "fh-mbaas", "resources": {"limits": {"memory": "1Gi"}}}]}}}},
+			{"kind": "DeploymentConfig", "metadata": {"name": "cloudapp"}, "spec": {"template": {"spec": {"containers": [{"name": "cloudapp"}]}}}}
+		]}`: &dcs,
+	} {
+		if err := json.Unmarshal([]byte(js), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result := checkLimitRangeDefaults(Result{Status: StatusOK}, limitRanges, dcs)
+	if result.Status != StatusWarning {
+		t.Fatalf("Status = %d, want %d", result.Status, StatusWarning)
+	}
+	// MongoDB gets too small a default and is capped below what it needs,
+	// fh-ngui needs less and fh-mbaas sets its own limit.
+	if len(result.Info) != 2 {
+		t.Fatalf("Info = %+v, want 2 findings for mongodb-1", result.Info)
+	}
+	for i, want := range []string{"container mongodb has no memory limit and gets the default 512Mi", "maximum pod memory limit 768Mi"} {
+		if info := result.Info[i]; info.Name != "mongodb-1" || !strings.Contains(info.Message, want) {
+			t.Errorf("Info[%d] = %+v, want mongodb-1 with a message containing %q", i, info, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// A ProjectResources lists the resources of a project seen by the dump tool.
+type ProjectResources struct {
+	Name string `json:"name"`
+	// Resources maps resource types to resource names.
+	Resources map[string][]string `json:"resources"`
+	Loggable  []LoggableResource  `json:"loggable"`
+}
+
+// listResourcesCommand prints all projects, resources and loggable containers
+// visible to the dump tool.
+func listResourcesCommand(args []string) error {
+	fs := flag.NewFlagSet("list-resources", flag.ExitOnError)
+	format := fs.String("format", "text", "output format: text or json")
+	fs.Parse(args)
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q, must be one of: text, json", *format)
+	}
+
+	ctx := context.Background()
+	projects, err := GetProjects(ctx)
+	if err != nil {
+		return err
+	}
+	list, err := ListResources(ctx, projects, resources, resourcesWithLogs)
+	if *format == "json" {
+		if err := writeResourcesJSON(os.Stdout, list); err != nil {
+			return err
+		}
+	} else {
+		if err := writeResourcesText(os.Stdout, list); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// ListResources returns the names of all resources of the given types, and
+// all loggable resources of types withLogs, in each of the projects. It may
+// return results even in the presence of an error.
+func ListResources(ctx context.Context, projects, types, withLogs []string) ([]ProjectResources, error) {
+	var (
+		list   []ProjectResources
+		errors errorList
+	)
+	for _, p := range projects {
+		pr := ProjectResources{Name: p, Resources: make(map[string][]string), Loggable: []LoggableResource{}}
+		for _, rtype := range types {
+			names, err := GetResourceNames(ctx, p, rtype)
+			if err != nil {
+				errors = append(errors, err)
+				continue
+			}
+			pr.Resources[rtype] = names
+		}
+		loggable, err := GetLogabbleResources(ctx, []string{p}, withLogs, "")
+		if err != nil {
+			errors = append(errors, err)
+		}
+		pr.Loggable = append(pr.Loggable, loggable...)
+		list = append(list, pr)
+	}
+	if len(errors) > 0 {
+		return list, errors
+	}
+	return list, nil
+}
+
+func writeResourcesJSON(w io.Writer, list []ProjectResources) error {
+	output, err := json.MarshalIndent(struct {
+		Projects []ProjectResources `json:"projects"`
+	}{list}, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", output)
+	return err
+}
+
+func writeResourcesText(w io.Writer, list []ProjectResources) error {
+	for _, pr := range list {
+		if _, err := fmt.Fprintf(w, "project %s\n", pr.Name); err != nil {
+			return err
+		}
+		var types []string
+		for rtype := range pr.Resources {
+			types = append(types, rtype)
+		}
+		sort.Strings(types)
+		for _, rtype := range types {
+			names := pr.Resources[rtype]
+			if _, err := fmt.Fprintf(w, "  %s (%d): %s\n", rtype, len(names), strings.Join(names, " ")); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "  logs (%d):\n", len(pr.Loggable)); err != nil {
+			return err
+		}
+		for _, r := range pr.Loggable {
+			line := "    " + r.Type + "/" + r.Name
+			if r.Container != "" {
+				line += " -c " + r.Container
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
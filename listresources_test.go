@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteResources(t *testing.T) {
+	list := []ProjectResources{{
+		Name:      "core",
+		Resources: map[string][]string{"pods": {"pod-1"}, "deploymentconfigs": {"dc-1", "dc-2"}},
+		Loggable: []LoggableResource{
+			{Project: "core", Type: "pods", Name: "pod-1", Container: "mbaas"},
+			{Project: "core", Type: "deploymentconfigs", Name: "dc-1"},
+		},
+	}}
+
+	var text bytes.Buffer
+	if err := writeResourcesText(&text, list); err != nil {
+		t.Fatal(err)
+	}
+	want := `project core
+  deploymentconfigs (2): dc-1 dc-2
+  pods (1): pod-1
+  logs (2):
+    pods/pod-1 -c mbaas
+    deploymentconfigs/dc-1
+`
+	if got := text.String(); got != want {
+		t.Errorf("writeResourcesText() = %q, want %q", got, want)
+	}
+
+	var js bytes.Buffer
+	if err := writeResourcesJSON(&js, list); err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Projects []ProjectResources `json:"projects"`
+	}
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Projects) != 1 || len(decoded.Projects[0].Loggable) != 2 {
+		t.Errorf("writeResourcesJSON() = %s, want one project with two loggable resources", js.String())
+	}
+}
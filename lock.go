@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// lockFileName is the name of the lock file held in the output directory while
+// a dump is being written.
+const lockFileName = ".fh-system-dump-tool.lock"
+
+// lockMaxAge is the age after which a lock is considered stale, even if its
+// owner cannot be checked, as when it was taken from another host.
+const lockMaxAge = 24 * time.Hour
+
+// A lockOwner describes the process holding a lock.
+type lockOwner struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Started time.Time `json:"started"`
+}
+
+// A DirLock prevents concurrent dumps from writing to the same directory.
+type DirLock struct {
+	path string
+}
+
+// LockedError is returned when a directory is locked by another dump.
+type LockedError struct {
+	Path  string
+	Owner lockOwner
+	Stale bool
+}
+
+func (e *LockedError) Error() string {
+	msg := fmt.Sprintf("another dump (pid %d on %s, started %s) is writing to %s",
+		e.Owner.PID, e.Owner.Host, e.Owner.Started.Format(time.RFC3339), filepath.Dir(e.Path))
+	if e.Stale {
+		return msg + ", but it seems to have died: rerun with -force to take over its lock file " + e.Path
+	}
+	return msg + ": wait for it to complete, or remove " + e.Path + " if it is no longer running"
+}
+
+// LockDir takes the lock of dir. If the lock is held by another dump, it returns
+// a *LockedError, unless the lock is stale and force is true. Stale locks are
+// those whose owner is no longer running, or older than lockMaxAge.
+func LockDir(dir string, force bool) (*DirLock, error) {
+	if err := os.MkdirAll(dir, 0770); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, lockFileName)
+	host, _ := os.Hostname()
+	owner, err := json.Marshal(lockOwner{PID: os.Getpid(), Host: host, Started: time.Now().UTC()})
+	if err != nil {
+		return nil, err
+	}
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0660)
+		if err == nil {
+			_, err = f.Write(owner)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, err
+			}
+			return &DirLock{path: path}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		lockErr, err := readLock(path, host)
+		if err != nil {
+			return nil, err
+		}
+		if !lockErr.Stale || !force {
+			return nil, lockErr
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+}
+
+// readLock returns the error describing the lock file at path.
+func readLock(path, host string) (*LockedError, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lockErr := &LockedError{Path: path}
+	if err := json.Unmarshal(data, &lockErr.Owner); err != nil {
+		// A lock file left incomplete by a crash.
+		lockErr.Stale = true
+		return lockErr, nil
+	}
+	lockErr.Stale = time.Since(lockErr.Owner.Started) > lockMaxAge ||
+		(lockErr.Owner.Host == host && !processAlive(lockErr.Owner.PID))
+	return lockErr, nil
+}
+
+// processAlive reports whether a process with the given pid is running on this
+// host. Processes of other users, e.g. a dump run by cron as root, cannot be
+// signalled but are running.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// Unlock releases the lock.
+func (l *DirLock) Unlock() error {
+	return os.Remove(l.path)
+}
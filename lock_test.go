@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lock, err := LockDir(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The lock is held by this live process, so it is never taken over.
+	for _, force := range []bool{false, true} {
+		_, err := LockDir(dir, force)
+		if lockErr, ok := err.(*LockedError); !ok || lockErr.Stale {
+			t.Errorf("LockDir(force=%v) on a held lock: got %v, want a *LockedError that isn't stale", force, err)
+		}
+	}
+	if err := lock.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if lock, err = LockDir(dir, false); err != nil {
+		t.Fatalf("LockDir() after Unlock(): %v", err)
+	}
+	lock.Unlock()
+}
+
+func TestLockDirStale(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	host, _ := os.Hostname()
+	for _, owner := range []lockOwner{
+		{PID: os.Getpid(), Host: host, Started: time.Now().Add(-2 * lockMaxAge)},
+		{PID: os.Getpid(), Host: "elsewhere", Started: time.Now().Add(-2 * lockMaxAge)},
+	} {
+		data, _ := json.Marshal(owner)
+		if err := ioutil.WriteFile(filepath.Join(dir, lockFileName), data, 0660); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LockDir(dir, false)
+		if lockErr, ok := err.(*LockedError); !ok || !lockErr.Stale {
+			t.Errorf("LockDir() on a lock from %+v: got %v, want a stale *LockedError", owner, err)
+		}
+		lock, err := LockDir(dir, true)
+		if err != nil {
+			t.Fatalf("LockDir(force) on a stale lock: %v", err)
+		}
+		lock.Unlock()
+	}
+}
+
+func TestProcessAlive(t *testing.T) {
+	if !processAlive(os.Getpid()) {
+		t.Error("processAlive() = false for the running test")
+	}
+	// Unless the test runs as root, init cannot be signalled.
+	if !processAlive(1) {
+		t.Error("processAlive() = false for init")
+	}
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if processAlive(cmd.Process.Pid) {
+		t.Errorf("processAlive() = true for exited process %d", cmd.Process.Pid)
+	}
+}
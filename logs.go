@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"io"
 	"os/exec"
+	"path"
 	"strconv"
+	"strings"
 )
 
 // LoggableResource describes an OpenShift resource that produces logs.
@@ -18,6 +21,16 @@ type LoggableResource struct {
 	Container string
 }
 
+// describe returns a short description of r, such as "pod/fh-ngui-1-abcde
+// container fh-ngui".
+func (r LoggableResource) describe() string {
+	s := r.Type + "/" + r.Name
+	if r.Container != "" {
+		s += " container " + r.Container
+	}
+	return s
+}
+
 // FetchLogs is a task factory for tasks that fetch the logs of a
 // LoggableResource. Set maxLines to limit how many lines are fetched. Logs are
 // written to out and eventual error messages go to errOut.
@@ -34,7 +47,7 @@ func FetchPreviousLogs(resource LoggableResource, maxLines int, out, errOut io.W
 // ocLogs fetches logs from OpenShift resources using oc.
 func ocLogs(resource LoggableResource, maxLines int, extraArgs []string, out, errOut io.Writer) Task {
 	return fetchLogs(func(resource LoggableResource) *exec.Cmd {
-		return exec.Command("oc", append([]string{
+		return ocCommand(append([]string{
 			"-n", resource.Project,
 			"logs", resource.Type + "/" + resource.Name,
 			"-c", resource.Container,
@@ -48,20 +61,20 @@ func ocLogs(resource LoggableResource, maxLines int, extraArgs []string, out, er
 type logsCmdFactory func(resource LoggableResource) *exec.Cmd
 
 func fetchLogs(cmdFactory logsCmdFactory, resource LoggableResource, out, errOut io.Writer) Task {
-	return func() error {
+	return func(ctx context.Context) error {
 		cmd := cmdFactory(resource)
-		return runCmdCaptureOutput(cmd, out, errOut)
+		return runCmdCaptureOutput(ctx, cmd, out, errOut)
 	}
 }
 
 // GetLoggableResources returns a list of loggable resources for the named
 // resource of type rtype in the given project. Only pods may return multiple
 // loggable resources, as many as the number of containers in the pod.
-func GetLoggableResources(project, rtype, name string) ([]LoggableResource, error) {
-	return getLoggableResources(GetPodContainers, project, rtype, name)
+func GetLoggableResources(ctx context.Context, project, rtype, name string) ([]LoggableResource, error) {
+	return getLoggableResources(ctx, GetPodContainers, project, rtype, name)
 }
 
-func getLoggableResources(getPodContainers func(string, string) ([]string, error), project, rtype, name string) ([]LoggableResource, error) {
+func getLoggableResources(ctx context.Context, getPodContainers func(context.Context, string, string) ([]string, error), project, rtype, name string) ([]LoggableResource, error) {
 	var (
 		loggableResources []LoggableResource
 		containers        []string
@@ -69,7 +82,7 @@ func getLoggableResources(getPodContainers func(string, string) ([]string, error
 	switch rtype {
 	case "po", "pod", "pods":
 		var err error
-		containers, err = getPodContainers(project, name)
+		containers, err = getPodContainers(ctx, project, name)
 		if err != nil {
 			return nil, err
 		}
@@ -93,6 +106,35 @@ func getLoggableResources(getPodContainers func(string, string) ([]string, error
 
 // GetPodContainers returns a list of container names for the named pod in the
 // project.
-func GetPodContainers(project, name string) ([]string, error) {
-	return getSpaceSeparated(exec.Command("oc", "-n", project, "get", "pod", name, "-o=jsonpath={.spec.containers[*].name}"))
+func GetPodContainers(ctx context.Context, project, name string) ([]string, error) {
+	return getSpaceSeparated(ctx, ocCommand("-n", project, "get", "pod", name, "-o=jsonpath={.spec.containers[*].name}"))
+}
+
+// FilterContainers returns the loggable resources whose container matches any
+// of the comma-separated shell patterns in filter, as understood by
+// path.Match. Resources without a named container, i.e. those that are not
+// pods, are always kept. An empty filter matches all containers.
+func FilterContainers(resources []LoggableResource, filter string) ([]LoggableResource, error) {
+	if filter == "" {
+		return resources, nil
+	}
+	patterns := strings.Split(filter, ",")
+	var filtered []LoggableResource
+	for _, r := range resources {
+		if r.Container == "" {
+			filtered = append(filtered, r)
+			continue
+		}
+		for _, pattern := range patterns {
+			matched, err := path.Match(strings.TrimSpace(pattern), r.Container)
+			if err != nil {
+				return nil, err
+			}
+			if matched {
+				filtered = append(filtered, r)
+				break
+			}
+		}
+	}
+	return filtered, nil
 }
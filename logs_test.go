@@ -2,8 +2,10 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os/exec"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -58,7 +60,7 @@ func TestFetchLogs(t *testing.T) {
 		var stdout, stderr bytes.Buffer
 		task := fetchLogs(tt.cmdFactory, tt.resource, &stdout, &stderr)
 
-		err := task()
+		err := task(context.Background())
 		if (err != nil) != tt.shouldFail {
 			want := "nil"
 			if tt.shouldFail {
@@ -77,3 +79,38 @@ func TestFetchLogs(t *testing.T) {
 		}
 	}
 }
+
+func TestFilterContainers(t *testing.T) {
+	resources := []LoggableResource{
+		{Project: "core", Type: "pod", Name: "pod-1", Container: "mbaas"},
+		{Project: "core", Type: "pod", Name: "pod-1", Container: "mbaas-proxy"},
+		{Project: "core", Type: "pod", Name: "pod-1", Container: "sidecar"},
+		{Project: "core", Type: "dc", Name: "dc-1"},
+	}
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{"", []string{"mbaas", "mbaas-proxy", "sidecar", ""}},
+		{"mbaas", []string{"mbaas", ""}},
+		{"mbaas*", []string{"mbaas", "mbaas-proxy", ""}},
+		{"mbaas, sidecar", []string{"mbaas", "sidecar", ""}},
+	}
+	for _, tt := range tests {
+		filtered, err := FilterContainers(resources, tt.filter)
+		if err != nil {
+			t.Errorf("FilterContainers(%q) returned non-nil error: %v", tt.filter, err)
+			continue
+		}
+		var got []string
+		for _, r := range filtered {
+			got = append(got, r.Container)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FilterContainers(%q) = %q, want %q", tt.filter, got, tt.want)
+		}
+	}
+	if _, err := FilterContainers(resources, "["); err == nil {
+		t.Error("FilterContainers with malformed pattern returned nil error")
+	}
+}
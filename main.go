@@ -4,17 +4,22 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
-	"sync"
 	"time"
+
+	"github.com/feedhenry/fh-system-dump-tool/cmdrunner"
+	"github.com/feedhenry/fh-system-dump-tool/dumpformat"
 )
 
 const (
@@ -27,18 +32,169 @@ const (
 	// defaultMaxLogLines is the default limit of number of log lines to
 	// fetch.
 	defaultMaxLogLines = 1000
+	// defaultBackupMaxAge is the default age after which the last
+	// successful MongoDB backup is considered too old.
+	defaultBackupMaxAge = 24 * time.Hour
+	// defaultCheckTimeout is the default time an analysis check is allowed
+	// to run for.
+	defaultCheckTimeout = 2 * time.Minute
+	// defaultRetryBackoff is the default time to wait before retrying a
+	// command failing with a transient error.
+	defaultRetryBackoff = time.Second
+
+	// exitNotLoggedIn is the exit code used when the user is not logged
+	// in to OpenShift.
+	exitNotLoggedIn = 3
 
 	// The version of the fh-system-dump-tool
 	version = "0.1.0"
 )
 
+// gitCommit is the git commit the tool was built from, set when building with
+// -ldflags "-X main.gitCommit=<commit>", as make build does.
+var gitCommit string
+
 var (
-	maxParallelTasks = flag.Int("p", runtime.NumCPU(), "max number of tasks to run in parallel")
-	maxLogLines      = flag.Int("max-log-lines", defaultMaxLogLines, "max number of log lines fetched with oc logs")
-	versionCheck     = flag.Bool("version", false, "Output the current version of the system-dump-tool")
+	maxParallelTasks  = flag.Int("p", runtime.NumCPU(), "max number of tasks to run in parallel")
+	maxLogLines       = flag.Int("max-log-lines", defaultMaxLogLines, "max number of log lines fetched with oc logs")
+	dedupeLogs        = flag.Bool("dedupe-logs", false, "collapse runs of repeated log lines, keeping errors in full")
+	projectFilter     = flag.String("projects", "", "comma-separated project names or patterns to limit the dump to")
+	excludeProjects   = flag.String("exclude-projects", "", "comma-separated project names or patterns to leave out of the dump")
+	resourceTypes     = flag.String("resources", "", "comma-separated types of resources whose definitions are collected, instead of the default ones")
+	labelSelector     = flag.String("selector", "", "label selector, e.g. app=fh-mbaas, limiting the resource definitions and logs collected")
+	containerFilter   = flag.String("container", "", "comma-separated container names or patterns to limit log collection to")
+	kubeconfig        = flag.String("kubeconfig", "", "path to the kubeconfig file used by oc, or a list of paths to merge separated by "+string(filepath.ListSeparator)+" (defaults to $KUBECONFIG or ~/.kube/config)")
+	via               = flag.String("via", "", "run oc commands on a jump host over ssh, given as ssh://[user@]host[:port]")
+	impersonateUser   = flag.String("as", "", "user or service account to impersonate in all oc commands")
+	impersonateGroups = flag.String("as-group", "", "comma-separated groups to impersonate in all oc commands")
+	outputPath        = flag.String("out", defaultOutputPath, "path of the dump archive, without extension, as a template using {{.Cluster}} and {{.Timestamp}}")
+	configFile        = flag.String("config", "", "path to a JSON configuration file")
+	refresh           = flag.String("refresh", "", "collect one project again into an existing dump, given as project=<name>")
+	force             = flag.Bool("force", false, "take over the lock of the output directory left by a dump that is no longer running")
+	refreshDump       = flag.String("refresh-dump", "", "path to the dump archive refreshed with -refresh (defaults to the latest dump)")
+	simulateDrainNode = flag.String("simulate-drain", "", "report which RHMAP components would lose capacity or go down if a node were drained, given as node=<name>, from the pods of an existing dump")
+	drainDump         = flag.String("simulate-drain-dump", "", "path to the dump archive used by -simulate-drain (defaults to the latest dump)")
+	watchdogFactor    = flag.Float64("watchdog-factor", 10, "report commands running this many times longer than similar commands did, 0 to disable")
+	watchdogKill      = flag.Bool("watchdog-kill", false, "kill and retry once the commands reported by the watchdog")
+	recordCommands    = flag.Bool("record-commands", false, "record the output of all commands in commands.json, so that the dump can be replayed")
+	replay            = flag.String("replay", "", "replay the commands recorded in a dump archive instead of running them")
+	maxOutputSize     = flag.String("max-output-size", "", "max size of each collected file, e.g. 100M, beyond which the output of commands is truncated")
+	maxMemory         = flag.String("max-memory", "", "resident memory of the tool, e.g. 2G, above which no new task starts until memory is freed")
+	splitSize         = flag.String("split-size", "", "also split the dump archive into numbered chunks of at most this size, e.g. 100M")
+	minSeverity       = flag.String("min-severity", "warning", "least severe findings shown in the console summary and reports: warning or critical")
+	versionCheck      = flag.Bool("version", false, "Output the current version of the system-dump-tool")
+	checkTimeout      = flag.Duration("check-timeout", defaultCheckTimeout, "max time each analysis check is allowed to run for")
+	timeout           = flag.Duration("timeout", 0, "max time the whole dump is allowed to run for before running tasks are cancelled and what was collected is written, 0 for no limit")
+	taskTimeout       = flag.Duration("task-timeout", 0, "max time each task is allowed to run for before it is cancelled, 0 for no limit")
+	adaptive          = flag.Bool("adaptive", false, "adapt the number of tasks run in parallel, up to -p, to the latency and errors of the API")
+	retries           = flag.Int("retries", 0, "max number of retries of commands failing with transient errors, like timeouts or throttling")
+	collect           = flag.String("collect", "", "comma-separated collectors or categories to run in addition to the default ones, see the collectors command")
+	only              = flag.String("only", "", "comma-separated collectors or categories to run instead of the default ones, along with those they require")
+	skip              = flag.String("skip", "", "comma-separated collectors or categories not to run, see the collectors command")
+	definitionsMode   = flag.String("definitions", definitionsFull, "verbosity of the collected resource definitions: full, or status for only their metadata and status")
+	secretValues      = flag.String("secret-values", secretValuesNone, "values recorded in secret metadata: none for only their sizes, hash for their SHA-256 hashes, or redacted")
+	outputFormat      = flag.String("output", outputText, "format of the result of the run: text, or json to also print it as a JSON document on stdout")
+	dryRun            = flag.Bool("dry-run", false, "list the tasks that would run and the files they would write, without running them")
+	retryBackoff      = flag.Duration("retry-backoff", defaultRetryBackoff, "time to wait before the first retry of a command, doubled at each retry")
+	backupMaxAge      = flag.Duration("backup-max-age", defaultBackupMaxAge, "max age of the last successful mongodb backup before it is reported")
+	comparePrevious   = flag.Bool("compare", false, "compare the dump against the previous one in the dump directory and report what changed")
+	every             = flag.Duration("every", 0, "run a dump at this interval, e.g. 24h, until interrupted, comparing each with the previous one as with -compare")
+	notifyWebhook     = flag.String("notify-webhook", "", "URL to post a summary of the dump to when it completes")
+	notifyOn          = flag.String("notify-on", "always", "when to post to the notification webhook: always or critical")
+	notifySlack       = flag.Bool("notify-slack", false, "format webhook notifications as Slack messages")
+	emailTo           = flag.String("email-to", "", "comma-separated addresses to email the analysis summary to")
+	emailFrom         = flag.String("email-from", "fh-system-dump-tool@localhost", "sender address of summary emails")
+	smtpServer        = flag.String("smtp-server", "localhost:25", "host:port of the SMTP server used to send summary emails")
+	upload            = flag.Bool("upload", false, "attach the dump to the Red Hat support case given with -case-id, with the credentials in REDHAT_USERNAME and REDHAT_PASSWORD")
+	caseID            = flag.String("case-id", "", "number of the Red Hat support case to attach the dump to with -upload")
+	uploadURL         = flag.String("upload-url", defaultUploadURL, "URL of the support case API used by -upload")
+	s3Bucket          = flag.String("s3-bucket", "", "bucket of S3-compatible storage to upload the dump to, under <s3-prefix><cluster>/")
+	s3Endpoint        = flag.String("s3-endpoint", defaultS3Endpoint, "URL of the S3-compatible storage used by -s3-bucket")
+	s3Region          = flag.String("s3-region", defaultS3Region, "region of the S3-compatible storage used by -s3-bucket")
+	s3Prefix          = flag.String("s3-prefix", "", "prefix of the keys of the dumps uploaded with -s3-bucket, e.g. rhmap-dumps/")
+	s3AccessKey       = flag.String("s3-access-key", "", "access key of the S3-compatible storage, instead of AWS_ACCESS_KEY_ID")
+	s3SecretKey       = flag.String("s3-secret-key", "", "secret key of the S3-compatible storage, instead of AWS_SECRET_ACCESS_KEY")
+	networkStats      = flag.Bool("network-stats", false, "collect host-wide socket and conntrack statistics from nodes hosting pods (requires cluster-admin)")
+	coreURL           = flag.String("core-url", "", "public URL of the RHMAP Core, to record the responses of its status endpoints as seen from outside the cluster")
+	nagiosHistory     = flag.Bool("nagios-history", false, "collect the Nagios logs and history from Nagios pods")
+	nagiosHistoryGzip = flag.Bool("nagios-history-gzip", false, "compress the collected Nagios history, in the -compression format")
+	compressFiles     = flag.String("compress-files", "", "comma-separated top-level directories of the dump, e.g. logs,logs-previous, whose files are compressed individually in the -compression format")
+	compression       = flag.String("compression", compressionGzip, "compression of the dump archive and of the files compressed inside it: gzip, zstd or none")
+	statsFile         = flag.String("stats-file", "", "file keeping the durations and sizes of previous dumps of each cluster, to estimate the next ones")
+	archiveFormat     = flag.String("archive-format", archiveFormatTar, "format of the dump archive: tar, compressed with -compression, or zip")
+	imageMetadata     = flag.Bool("image-metadata", false, "record the digests of running images, and their build dates and labels where the cluster knows them (requires cluster-admin)")
+	routerStats       = flag.Bool("router", false, "collect the router HAProxy configuration and access log errors (requires cluster-admin)")
+	esLogsHours       = flag.Int("es-logs-hours", 0, "fetch the logs of the last given hours of each project from the Elasticsearch of the aggregated logging stack, which keeps the logs of deleted and restarted containers (requires cluster-admin)")
+	grafanaExport     = flag.Bool("grafana", false, "export the Grafana dashboards related to RHMAP or the projects, and the data of their panels over the last day, from Grafana pods (requires cluster-admin)")
+	prometheus        = flag.Bool("prometheus", false, "record a snapshot of the CPU, memory, restart and volume metrics of the projects, and the active alerts, from Prometheus pods (requires cluster-admin)")
+	fileMetadata      = flag.Bool("file-metadata", false, "add a .meta file next to each collected file, recording when, by which command and from which cluster it was collected")
+	whoCan            = flag.Bool("who-can", false, "record who can delete pods and update or patch deploymentconfigs in each project (requires cluster-admin)")
 )
 
-func runCmdCaptureOutput(cmd *exec.Cmd, out, errOut io.Writer) error {
+// ocCommand returns a command to run oc with args. The command inherits the
+// environment of the tool, so that oc honors the KUBECONFIG variable, unless
+// overridden with the -kubeconfig flag. Impersonation flags given with -as and
+// -as-group are passed to every command. With -via, the command runs oc on the
+// jump host over ssh, and -kubeconfig is a path on the host.
+func ocCommand(args ...string) *exec.Cmd {
+	var globalArgs []string
+	if *impersonateUser != "" {
+		globalArgs = append(globalArgs, "--as="+*impersonateUser)
+	}
+	if *impersonateGroups != "" {
+		for _, group := range strings.Split(*impersonateGroups, ",") {
+			globalArgs = append(globalArgs, "--as-group="+strings.TrimSpace(group))
+		}
+	}
+	if viaHost != nil {
+		var env []string
+		if *kubeconfig != "" {
+			env = append(env, "KUBECONFIG="+shellQuote(*kubeconfig))
+		}
+		return viaHost.command(env, "oc", append(globalArgs, args...)...)
+	}
+	cmd := exec.Command("oc", append(globalArgs, args...)...)
+	if *kubeconfig != "" {
+		cmd.Env = append(os.Environ(), "KUBECONFIG="+*kubeconfig)
+	}
+	return cmd
+}
+
+// runCmdCaptureOutput runs cmd, writing its output to out and errOut. It is
+// retried following commandRetries if both outputs can be discarded.
+func runCmdCaptureOutput(ctx context.Context, cmd *exec.Cmd, out, errOut io.Writer) error {
+	if !canRetry(out, errOut) {
+		return runCmdCaptureOutputOnce(ctx, cmd, out, errOut)
+	}
+	return commandRetries.do(ctx, func(attempt int) error {
+		if attempt > 0 {
+			out.(resetter).Reset()
+			if errOut != nil {
+				errOut.(resetter).Reset()
+			}
+			cmd = retryCommand(cmd)
+		}
+		return runCmdCaptureOutputOnce(ctx, cmd, out, errOut)
+	})
+}
+
+// recordCommand records cmd, and the task of ctx running it, in the writers
+// its output goes to, for those that keep track of them.
+func recordCommand(ctx context.Context, cmd *exec.Cmd, writers ...io.Writer) {
+	for _, w := range writers {
+		if w, ok := w.(interface{ setCommand([]string) }); ok {
+			w.setCommand(cmd.Args)
+		}
+		if t, ok := ctx.Value(timingKey{}).(runningTask); ok {
+			if w, ok := w.(interface{ setTask(name, project string) }); ok {
+				w.setTask(t.name())
+			}
+		}
+	}
+}
+
+func runCmdCaptureOutputOnce(ctx context.Context, cmd *exec.Cmd, out, errOut io.Writer) error {
+	recordCommand(ctx, cmd, out, errOut)
 	cmd.Stdout = out
 
 	// Send stderr to an in-memory buffer used to enrich error messages.
@@ -49,14 +205,41 @@ func runCmdCaptureOutput(cmd *exec.Cmd, out, errOut io.Writer) error {
 		cmd.Stderr = io.MultiWriter(cmd.Stderr, errOut)
 	}
 
-	// TODO: limit the execution time with a timeout.
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("command %q: %v: %v", strings.Join(cmd.Args, " "), err, buf.String())
+	start := time.Now()
+	err := runner.Run(ctx, cmd)
+	commandConcurrency.observe(cmd.Args, time.Since(start), commandErrorClass(cmd.Args, err, buf.String()))
+	if _, stalled := err.(*stalledError); stalled && canRetry(out, errOut) {
+		// The watchdog killed a stalled command, try once more with
+		// fresh outputs.
+		log.Printf("Retrying stalled command: %q\n", cmd.Args)
+		out.(resetter).Reset()
+		if errOut != nil {
+			errOut.(resetter).Reset()
+		}
+		buf.Reset()
+		retry := retryCommand(cmd)
+		retry.Stdout, retry.Stderr = cmd.Stdout, cmd.Stderr
+		err = runner.Run(ctx, retry)
+	}
+	if err != nil {
+		return &CmdError{Args: cmd.Args, Err: err, Stderr: buf.String()}
 	}
 	return nil
 }
 
-func runCmdCaptureOutputDeprecated(cmd *exec.Cmd, project, resource string, outFor, errOutFor projectResourceWriterCloserFactory) error {
+// canRetry reports whether the outputs of a command can be discarded to run it
+// again.
+func canRetry(out, errOut io.Writer) bool {
+	if _, ok := out.(resetter); !ok {
+		return false
+	}
+	if _, ok := errOut.(resetter); errOut != nil && !ok {
+		return false
+	}
+	return true
+}
+
+func runCmdCaptureOutputDeprecated(ctx context.Context, cmd *exec.Cmd, project, resource string, outFor, errOutFor projectResourceWriterCloserFactory) error {
 	var err error
 	var stdoutCloser, stderrCloser io.Closer
 
@@ -68,147 +251,661 @@ func runCmdCaptureOutputDeprecated(cmd *exec.Cmd, project, resource string, outF
 	}
 	defer stdoutCloser.Close()
 
-	var buf bytes.Buffer
-	cmd.Stderr, stderrCloser, err = errOutFor(project, resource)
+	errOut, stderrCloser, err := errOutFor(project, resource)
 	if err != nil {
 		// We can possibly try to run the command without an io.Writer
-		// from errOutFor. In this case, we'll attach an in-memory
-		// buffer so that we can include the stderr output in errors.
-		cmd.Stderr = &buf
+		// from errOutFor. In this case, stderr is only included in
+		// errors.
+		errOut = nil
 	} else {
 		defer stderrCloser.Close()
-		// Send stderr to both the io.Writer from errOutFor, and an
-		// in-memory buffer, used to enrich error messages.
-		cmd.Stderr = io.MultiWriter(cmd.Stderr, &buf)
 	}
-
-	// TODO: limit the execution time with a timeout.
-	if err = cmd.Run(); err != nil {
-		return fmt.Errorf("command %q: %v: %v", strings.Join(cmd.Args, " "), err, buf.String())
-	}
-	return nil
+	return runCmdCaptureOutput(ctx, cmd, cmd.Stdout, errOut)
 }
 
 // GetProjects returns a list of project names visible by the current logged in
 // user.
-func GetProjects() ([]string, error) {
-	return getSpaceSeparated(exec.Command("oc", "get", "projects", "-o=jsonpath={.items[*].metadata.name}"))
+func GetProjects(ctx context.Context) ([]string, error) {
+	return getSpaceSeparated(ctx, ocCommand("get", "projects", "-o=jsonpath={.items[*].metadata.name}"))
+}
+
+// FilterProjects returns the projects matching any of the comma-separated shell
+// patterns in include, as understood by path.Match, and none of those in
+// exclude. An empty include matches all projects.
+func FilterProjects(projects []string, include, exclude string) ([]string, error) {
+	matchesAny := func(project, patterns string) (bool, error) {
+		for _, pattern := range strings.Split(patterns, ",") {
+			if pattern = strings.TrimSpace(pattern); pattern == "" {
+				continue
+			}
+			matched, err := path.Match(pattern, project)
+			if err != nil {
+				return false, fmt.Errorf("invalid project pattern %q: %v", pattern, err)
+			}
+			if matched {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	var filtered []string
+	for _, p := range projects {
+		included, err := matchesAny(p, include)
+		if err != nil {
+			return nil, err
+		}
+		excluded, err := matchesAny(p, exclude)
+		if err != nil {
+			return nil, err
+		}
+		if (include == "" || included) && !excluded {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered, nil
 }
 
 // GetResourceNames returns a list of resource names of type rtype, visible by
 // the current logged in user, scoped by project.
-func GetResourceNames(project, rtype string) ([]string, error) {
-	return getSpaceSeparated(exec.Command("oc", "-n", project, "get", rtype, "-o=jsonpath={.items[*].metadata.name}"))
+func GetResourceNames(ctx context.Context, project, rtype string) ([]string, error) {
+	return GetSelectedResourceNames(ctx, project, rtype, "")
+}
+
+// GetSelectedResourceNames is like GetResourceNames, but only returns the names
+// of the resources matching the label selector, unless it is empty.
+func GetSelectedResourceNames(ctx context.Context, project, rtype, selector string) ([]string, error) {
+	args := []string{"-n", project, "get", rtype, "-o=jsonpath={.items[*].metadata.name}"}
+	if selector != "" {
+		args = append(args, "-l", selector)
+	}
+	return getSpaceSeparated(ctx, ocCommand(args...))
 }
 
 // getSpaceSeparated calls cmd, expected to output a space-separated list of
 // words to stdout, and returns the words.
-func getSpaceSeparated(cmd *exec.Cmd) ([]string, error) {
-	var projects []string
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
+func getSpaceSeparated(ctx context.Context, cmd *exec.Cmd) ([]string, error) {
+	var stdout bytes.Buffer
+	if err := runCmdCaptureOutput(ctx, cmd, &stdout, nil); err != nil {
 		return nil, err
 	}
-	var buf bytes.Buffer
-	cmd.Stderr = &buf
-	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("command %q: %v", strings.Join(cmd.Args, " "), err)
-	}
-	scanner := bufio.NewScanner(stdout)
+	var words []string
+	scanner := bufio.NewScanner(&stdout)
 	scanner.Split(bufio.ScanWords)
 	for scanner.Scan() {
-		projects = append(projects, scanner.Text())
+		words = append(words, scanner.Text())
 	}
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
-	if err := cmd.Wait(); err != nil {
-		return nil, fmt.Errorf("command %q: %v: %v", strings.Join(cmd.Args, " "), err, buf.String())
-	}
-	return projects, nil
+	return words, nil
 }
 
 func printError(err error) {
 	fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	runResult.Errors = append(runResult.Errors, err.Error())
+}
+
+// refreshDumpProject runs -refresh and returns the exit code of the tool.
+func refreshDumpProject(ctx context.Context, collectors []*Collector, redactor *Redactor, minStatus int) int {
+	project, err := parseRefresh(*refresh)
+	if err != nil {
+		printError(err)
+		return 1
+	}
+	dump := *refreshDump
+	if dump == "" {
+		if dump, err = FindLatestDump(dumpDir); err != nil {
+			printError(err)
+			return 1
+		}
+		if dump == "" {
+			printError(fmt.Errorf("no dump found in %s", dumpDir))
+			return 1
+		}
+	}
+	lock, err := LockDir(filepath.Dir(dump), *force)
+	if err != nil {
+		printError(err)
+		return 1
+	}
+	defer lock.Unlock()
+	log.Printf("Refreshing project %s in: %s\n", project, dump)
+	summary, err := RefreshProject(ctx, dump, project, collectors, redactor, minStatus)
+	exitCode := 0
+	if err != nil {
+		WriteErrorSummary(os.Stderr, []error{err})
+		runResult.setTaskErrors(flattenErrors(err))
+		exitCode = 1
+	}
+	runResult.Dump = dump
+	runResult.Files = append(runResult.Files, dump)
+	runResult.setSummary(summary)
+	fmt.Fprintln(os.Stderr, summary.headline())
+	return exitCode
+}
+
+// simulateDrainOfDump runs -simulate-drain and returns the exit code of the
+// tool.
+func simulateDrainOfDump() int {
+	node, err := parseSimulateDrain(*simulateDrainNode)
+	if err != nil {
+		printError(err)
+		return 1
+	}
+	dump := *drainDump
+	if dump == "" {
+		if dump, err = FindLatestDump(dumpDir); err != nil {
+			printError(err)
+			return 1
+		}
+		if dump == "" {
+			printError(fmt.Errorf("no dump found in %s", dumpDir))
+			return 1
+		}
+	}
+	if err := SimulateDrain(os.Stdout, dump, node); err != nil {
+		printError(err)
+		return 1
+	}
+	return 0
+}
+
+// exitWithError prints err and exits, with additional guidance and a distinct
+// exit code if the error was caused by not being logged in.
+func exitWithError(err error) {
+	if isNotLoggedIn(err) {
+		printError(errors.New("not logged in to OpenShift, or the session token has expired"))
+		fmt.Fprintln(os.Stderr, "Log in as an administrative user and try again:\n\n    oc login <public-master-url>")
+		runResult.Outcome = runNotLoggedIn
+		exit(exitNotLoggedIn)
+	}
+	printError(err)
+	exit(1)
 }
 
 func main() {
 	flag.Parse()
 
 	if *versionCheck {
-		fmt.Println("RHMAP fh-system-dump-tool v" + version)
+		if gitCommit != "" {
+			fmt.Println("RHMAP fh-system-dump-tool v" + version + " (" + gitCommit + ")")
+		} else {
+			fmt.Println("RHMAP fh-system-dump-tool v" + version)
+		}
 		os.Exit(0)
 	}
 
+	if err := checkOutputFormat(*outputFormat); err != nil {
+		printError(err)
+		os.Exit(1)
+	}
+	writeResult = *outputFormat == outputJSON
+
+	if *configFile != "" {
+		var err error
+		config, err = LoadConfig(*configFile)
+		if err != nil {
+			printError(err)
+			exit(1)
+		}
+	}
+
+	if *via != "" {
+		var err error
+		if viaHost, err = parseVia(*via); err != nil {
+			printError(fmt.Errorf("argument to -via flag: %v", err))
+			exit(1)
+		}
+	}
+
+	if *every != 0 {
+		if *every < 0 || flag.NArg() > 0 || *dryRun || *refresh != "" {
+			printError(errors.New("-every must be positive, and cannot be used with commands, -dry-run or -refresh"))
+			exit(1)
+		}
+		if err := RunDaemon(*every, daemonArgs(flag.CommandLine)); err != nil {
+			printError(err)
+			exit(1)
+		}
+		return
+	}
+
+	base := runner
+	if *replay != "" {
+		invocations, err := LoadInvocations(*replay)
+		if err != nil {
+			printError(err)
+			exit(1)
+		}
+		base = cmdrunner.NewFakeRunner(invocations)
+	}
+	recorder := &cmdrunner.RecordingRunner{Runner: base, CaptureOutput: *recordCommands, Redact: redactCommandOutput(nil)}
+	runner = recorder
+	commandRetries.retries, commandRetries.backoff = *retries, *retryBackoff
+
+	if flag.NArg() > 0 {
+		// Commands write their own output.
+		writeResult = false
+		if err := RunCommand(flag.Arg(0), flag.Args()[1:]); err != nil {
+			exitWithError(err)
+		}
+		return
+	}
+
+	ctx, interrupted := interruptContext(*timeout)
+
 	if !(*maxParallelTasks > 0) {
 		printError(fmt.Errorf("argument to -p flag must be greater than 0"))
-		os.Exit(1)
+		exit(1)
+	}
+	if *adaptive {
+		commandConcurrency = newAdaptiveConcurrency(*maxParallelTasks)
+	}
+
+	minStatus, err := parseStatus(*minSeverity)
+	if err != nil || minStatus == StatusOK {
+		printError(fmt.Errorf("argument to -min-severity flag must be warning or critical"))
+		exit(1)
+	}
+
+	if err := checkCompression(*compression); err != nil {
+		printError(err)
+		exit(1)
+	}
+	var uploaders []uploader
+	if *upload {
+		u, err := newCaseUploader(*uploadURL, *caseID)
+		if err != nil {
+			printError(fmt.Errorf("-upload: %v", err))
+			exit(1)
+		}
+		uploaders = append(uploaders, u)
+	}
+	var s3 *s3Uploader
+	if *s3Bucket != "" {
+		if s3, err = newS3Uploader(*s3Endpoint, *s3Region, *s3Bucket, *s3Prefix, *s3AccessKey, *s3SecretKey); err != nil {
+			printError(fmt.Errorf("-s3-bucket: %v", err))
+			exit(1)
+		}
+		uploaders = append(uploaders, s3)
+	}
+	if err := checkArchiveFormat(*archiveFormat, *compression); err != nil {
+		printError(err)
+		exit(1)
+	}
+	if err := checkDefinitionsVerbosity(*definitionsMode); err != nil {
+		printError(err)
+		exit(1)
+	}
+	if err := checkSecretValues(*secretValues); err != nil {
+		printError(err)
+		exit(1)
+	}
+	if err := checkNotifyPolicy(*notifyOn); err != nil {
+		printError(fmt.Errorf("argument to -notify-on flag: %v", err))
+		exit(1)
+	}
+
+	if *resourceTypes != "" {
+		if resources, err = parseResourceTypes(*resourceTypes); err != nil {
+			printError(fmt.Errorf("argument to -resources flag: %v", err))
+			exit(1)
+		}
+	}
+
+	collectors, err := taskRegistry.Select(*only, *collect, *skip)
+	if err != nil {
+		printError(err)
+		exit(1)
+	}
+
+	var chunkSize int64
+	if *splitSize != "" {
+		if chunkSize, err = parseSize(*splitSize); err != nil {
+			printError(fmt.Errorf("argument to -split-size flag: %v", err))
+			exit(1)
+		}
+	}
+	if *maxOutputSize != "" {
+		if outputSizeLimit, err = parseSize(*maxOutputSize); err != nil {
+			printError(fmt.Errorf("argument to -max-output-size flag: %v", err))
+			exit(1)
+		}
+	}
+	compressDirs = parseCompressDirs(*compressFiles)
+	if *maxMemory != "" {
+		limit, err := parseSize(*maxMemory)
+		if err != nil {
+			printError(fmt.Errorf("argument to -max-memory flag: %v", err))
+			exit(1)
+		}
+		taskMemory = newMemoryGuard(limit)
+	}
+
+	redactor, err := NewRedactor(config.Redaction.RedactionRules())
+	if err != nil {
+		printError(err)
+		exit(1)
+	}
+	recorder.Redact = redactCommandOutput(redactor)
+
+	if *dryRun && writeResult {
+		printError(errors.New("-dry-run cannot be used with -output json"))
+		exit(1)
+	}
+	if *refresh != "" {
+		if *dryRun {
+			printError(errors.New("-dry-run cannot be used with -refresh"))
+			exit(1)
+		}
+		exit(refreshDumpProject(ctx, collectors, redactor, minStatus))
+	}
+	if *simulateDrainNode != "" {
+		exit(simulateDrainOfDump())
 	}
 
 	log.Println("Starting RHMAP System Dump Tool...")
 
+	projects, err := GetProjects(ctx)
+	if err != nil {
+		exitWithError(err)
+	}
+	if len(projects) == 0 {
+		printError(errors.New("no projects visible to the currently logged in user"))
+		exit(1)
+	}
+	if projects, err = FilterProjects(projects, *projectFilter, *excludeProjects); err != nil {
+		printError(err)
+		exit(1)
+	}
+	if len(projects) == 0 {
+		printError(errors.New("no projects match -projects and -exclude-projects"))
+		exit(1)
+	}
+	if *resourceTypes == "" {
+		if discovered, err := DiscoverResourceTypes(ctx, config.Resources); err != nil {
+			log.Printf("Could not discover the types of resources, collecting the default ones: %v\n", err)
+		} else if len(discovered) > 0 {
+			resources = discovered
+		}
+	}
+
+	if *dryRun {
+		plans, err := PlanTasks(ctx, collectors, projects)
+		if err := WritePlan(os.Stdout, plans); err != nil {
+			exitWithError(err)
+		}
+		if err != nil {
+			exitWithError(err)
+		}
+		return
+	}
+
 	start := time.Now().UTC()
 	startTimestamp := start.Format(dumpTimestampFormat)
 
-	// Create the dumpDir if necessary.
-	err := os.MkdirAll(dumpDir, 0770)
+	pathData := outputPathData{Timestamp: startTimestamp}
+	// Statistics of replayed runs would not reflect the cluster.
+	recordStats := *statsFile != "" && *replay == ""
+	if strings.Contains(*outputPath, ".Cluster") || recordStats || *fileMetadata || s3 != nil {
+		if pathData.Cluster, err = GetClusterName(ctx); err != nil {
+			exitWithError(err)
+		}
+	}
+	if s3 != nil {
+		s3.prefix += unsafePathChars.ReplaceAllString(pathData.Cluster, "_") + "/"
+	}
+	path, err := resolveOutputPath(*outputPath, pathData)
 	if err != nil {
 		printError(err)
-		os.Exit(1)
+		exit(1)
 	}
-
-	archiveFile, err := os.Create(filepath.Join(dumpDir, startTimestamp+".tar.gz"))
+	lock, err := LockDir(filepath.Dir(path), *force)
 	if err != nil {
 		printError(err)
-		os.Exit(1)
+		exit(1)
+	}
+	archiveFile, err := createDumpFile(path, dumpExtension(*archiveFormat, *compression))
+	if err != nil {
+		printError(err)
+		lock.Unlock()
+		exit(1)
 	}
-	defer archiveFile.Close()
-	defer log.Printf("Dumped system information to: %s\n", archiveFile.Name())
 
-	tarFile, err := NewTgz(archiveFile)
+	tarFile, err := newDumpArchive(archiveFile, *archiveFormat, *compression)
 	if err != nil {
 		printError(err)
-		os.Exit(1)
+		lock.Unlock()
+		exit(1)
+	}
+	tarFile.Redactor = redactor
+	tarFile.Keep = isSummaryFile
+	tarFile.FileMetadata, tarFile.Cluster = *fileMetadata, pathData.Cluster
+	tarFile.MaxFileSize = outputSizeLimit
+	tarFile.CompressDirs, tarFile.FileCompression = compressDirs, *compression
+
+	exitCode := 0
+
+	var (
+		stats    *Stats
+		previous *ClusterStats
+	)
+	if recordStats {
+		if stats, err = LoadStats(*statsFile); err != nil {
+			printError(err)
+			exitCode = 1
+		} else {
+			previous = stats.Cluster(pathData.Cluster)
+		}
 	}
-	defer tarFile.Close()
 
 	log.Println("Preparing tasks...")
 
-	tasks, err := GetAllTasks(tarFile)
+	graph, prepareErr := CollectTasks(ctx, collectors, projects, tarFile)
+	if prepareErr != nil {
+		printError(prepareErr)
+		exitCode = 1
+	}
+	var (
+		taskErrs []error
+		timings  []TaskTiming
+	)
+	if len(graph.Tasks) > 0 {
+		if previous != nil {
+			log.Printf("Running tasks, %s...\n", previous.Estimate())
+			commandWatchdog.setHistory(previous.Commands)
+		} else {
+			log.Println("Running tasks...")
+		}
+		commandWatchdog.factor, commandWatchdog.kill = *watchdogFactor, *watchdogKill
+		stopWatchdog := make(chan struct{})
+		go commandWatchdog.run(watchdogInterval, stopWatchdog)
+		taskErrs, timings = RunTaskGraph(ctx, graph, *maxParallelTasks, *taskTimeout)
+		close(stopWatchdog)
+	}
+	taskErrs, skippedErrs, skipped := splitSkippedTasks(taskErrs)
+	for _, p := range projects {
+		if skipped[p] > 0 {
+			log.Printf("Project %s was deleted during the dump, %d of its tasks were skipped\n", p, skipped[p])
+		}
+	}
+	tasksDuration := time.Since(start)
+	interruption := interrupted()
+	if interruption != "" {
+		log.Printf("The dump was interrupted (%s), only what was collected before is written\n", interruption)
+		exitCode = 1
+	}
+
+	taskSummary := NewTaskSummary(start, tasksDuration, timings)
+	taskSummary.Outputs = graph.Outputs
+	if err := taskSummary.WriteTable(os.Stderr); err != nil {
+		printError(err)
+		exitCode = 1
+	}
+	if err := WriteTaskSummary(tarFile, taskSummary); err != nil {
+		printError(err)
+		exitCode = 1
+	}
+
+	WriteErrorSummary(os.Stderr, append([]error{prepareErr}, taskErrs...))
+
+	var errorReport bytes.Buffer
+	if err := WriteErrorReport(&errorReport, prepareErr, taskErrs); err != nil {
+		printError(err)
+		exitCode = 1
+	} else if err := tarFile.AddRedactedFile(errorReport.Bytes(), "errors.json"); err != nil {
+		printError(err)
+		exitCode = 1
+	}
+
+	var report bytes.Buffer
+	if err := redactor.WriteReport(&report); err != nil {
+		printError(err)
+		exitCode = 1
+	} else if err := tarFile.AddFileByContent(report.Bytes(), "redactions.json"); err != nil {
+		printError(err)
+		exitCode = 1
+	}
+
+	summary, err := parseDumpSummary(archiveFile.Name(), tarFile.KeptFiles())
 	if err != nil {
 		printError(err)
-		defer os.Exit(1)
+		exitCode = 1
 	}
-	if len(tasks) == 0 {
-		return
+	summary.addFindings(deletedProjectFindings(skipped))
+	summary.Suppressions = config.Suppressions
+	summary.MinSeverity = minStatus
+	if err := AddReports(tarFile, summary); err != nil {
+		printError(err)
+		exitCode = 1
+	}
+
+	commandsOut := tarFile.GetWriterToFile("commands.json")
+	if err := recorder.WriteInvocations(commandsOut); err != nil {
+		printError(err)
+		exitCode = 1
+	}
+	commandsOut.Close()
+
+	ocVersions, err := GetOcVersions()
+	if ocVersions.Client == "" && err != nil {
+		printError(err)
+		exitCode = 1
+	}
+	metadata := dumpformat.Metadata{
+		LayoutVersion: dumpformat.LayoutVersion,
+		ToolVersion:   version,
+		ToolCommit:    gitCommit,
+		Created:       start,
+		Flags:         setFlags(flag.CommandLine),
+		Versions:      &ocVersions,
+		Projects:      remainingProjects(projects, skipped),
+		Resources:     resources,
+		Collectors:    collectorNames(collectors),
+		HostTimezone:  timezoneAt(start.Local()),
+	}
+	if err := WriteMetadata(tarFile, metadata); err != nil {
+		printError(err)
+		exitCode = 1
+	}
+	if err := WriteManifest(tarFile, interruption, append(taskErrs, skippedErrs...)); err != nil {
+		printError(err)
+		exitCode = 1
+	}
+	if err := tarFile.Close(); err != nil {
+		printError(err)
+		exitCode = 1
+	}
+	archiveFile.Close()
+	log.Printf("Dumped system information to: %s\n", archiveFile.Name())
+	runResult.Dump = archiveFile.Name()
+	runResult.Files = append(runResult.Files, archiveFile.Name())
+	checksumPath, err := WriteChecksumFile(archiveFile.Name())
+	if err != nil {
+		printError(err)
+		exitCode = 1
+	} else {
+		runResult.Files = append(runResult.Files, checksumPath)
+	}
+	runResult.setTaskErrors(taskErrs)
+	if interruption != "" {
+		runResult.Outcome = runInterrupted
 	}
 
-	log.Println("Running tasks...")
+	// Interrupted dumps would skew the statistics.
+	if stats != nil && interruption == "" {
+		if fi, err := os.Stat(archiveFile.Name()); err != nil {
+			printError(err)
+			exitCode = 1
+		} else {
+			stats.Record(pathData.Cluster, tasksDuration, fi.Size(), commandWatchdog.averages())
+			if err := stats.Save(*statsFile); err != nil {
+				printError(err)
+				exitCode = 1
+			}
+		}
+	}
 
-	// Avoid the creating goroutines and other controls if we're executing
-	// tasks sequentially.
-	if *maxParallelTasks == 1 {
-		for _, task := range tasks {
-			task()
-			fmt.Fprint(os.Stderr, ".")
+	var chunks []string
+	if chunkSize > 0 {
+		if manifest, err := SplitArchive(archiveFile.Name(), chunkSize); err != nil {
+			printError(err)
+			exitCode = 1
+		} else {
+			log.Printf("Split the dump into %d chunks, reassemble them with: join %s.split.json\n", len(manifest.Chunks), archiveFile.Name())
+			for _, chunk := range manifest.Chunks {
+				chunks = append(chunks, filepath.Join(filepath.Dir(archiveFile.Name()), chunk.Name))
+			}
+			runResult.Files = append(runResult.Files, chunks...)
+			runResult.Files = append(runResult.Files, archiveFile.Name()+".split.json")
 		}
-		return
 	}
-	// Run at most N tasks in parallel, and wait for all of them to
-	// complete.
-	var wg sync.WaitGroup
-	sem := make(chan struct{}, *maxParallelTasks)
-	for _, task := range tasks {
-		task := task
-		wg.Add(1)
-		sem <- struct{}{}
-		go func() {
-			defer wg.Done()
-			task()
-			fmt.Fprint(os.Stderr, ".")
-			<-sem
-		}()
-	}
-	wg.Wait()
-	fmt.Fprintln(os.Stderr)
+
+	for _, u := range uploaders {
+		if err := u.UploadFiles(uploadedFiles(archiveFile.Name(), checksumPath, chunks)); err != nil {
+			printError(err)
+			exitCode = 1
+		}
+	}
+
+	if len(config.CheckPlugins) > 0 && interruption == "" {
+		findings, err := RunCheckPlugins(config.CheckPlugins, archiveFile.Name(), *checkTimeout)
+		if err != nil {
+			printError(err)
+			exitCode = 1
+		}
+		summary.addFindings(findings)
+	}
+
+	runResult.setSummary(summary)
+	WriteSizeBreakdown(os.Stderr, accountSizes(tarFile.Manifest(), projects))
+	fmt.Fprintln(os.Stderr, summary.headline())
+	WriteNextSteps(os.Stderr, summary)
+
+	if *comparePrevious {
+		if err := CompareWithPreviousDump(summary); err != nil {
+			printError(err)
+			exitCode = 1
+		}
+	}
+	if *notifyWebhook != "" {
+		if shouldNotify(*notifyOn, summary) {
+			if err := NotifyWebhook(*notifyWebhook, *notifySlack, summary); err != nil {
+				printError(err)
+				exitCode = 1
+			}
+		}
+	}
+	if *emailTo != "" {
+		if err := SendSummaryEmail(*smtpServer, *emailFrom, *emailTo, summary); err != nil {
+			printError(err)
+			exitCode = 1
+		}
+	}
+
+	if err := lock.Unlock(); err != nil {
+		printError(err)
+		exitCode = 1
+	}
+	exit(exitCode)
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -66,7 +67,7 @@ func TestGetSpaceSeparated(t *testing.T) {
 	}
 	for _, tt := range tests {
 		cmd := helperCommand("echo", tt.projects...)
-		got, err := getSpaceSeparated(cmd)
+		got, err := getSpaceSeparated(context.Background(), cmd)
 		if err != nil {
 			t.Errorf("getSpaceSeparated(%v) returned non-nil error: %v", cmd.Args, err)
 			continue
@@ -76,3 +77,60 @@ func TestGetSpaceSeparated(t *testing.T) {
 		}
 	}
 }
+
+func TestOcCommandKubeconfig(t *testing.T) {
+	defer func(old string) { *kubeconfig = old }(*kubeconfig)
+
+	*kubeconfig = ""
+	if cmd := ocCommand("get", "projects"); cmd.Env != nil {
+		t.Errorf("ocCommand() without -kubeconfig sets Env = %v, want inherited environment", cmd.Env)
+	}
+
+	*kubeconfig = "/a/config:/b/config"
+	cmd := ocCommand("get", "projects")
+	if got := cmd.Env[len(cmd.Env)-1]; got != "KUBECONFIG=/a/config:/b/config" {
+		t.Errorf("ocCommand() with -kubeconfig sets %q, want KUBECONFIG=/a/config:/b/config", got)
+	}
+	if !reflect.DeepEqual(cmd.Args[1:], []string{"get", "projects"}) {
+		t.Errorf("ocCommand() args = %v", cmd.Args)
+	}
+}
+
+func TestOcCommandImpersonation(t *testing.T) {
+	defer func(user, groups string) { *impersonateUser, *impersonateGroups = user, groups }(*impersonateUser, *impersonateGroups)
+
+	*impersonateUser = "system:serviceaccount:core:rhmap-operator"
+	*impersonateGroups = "system:serviceaccounts, rhmap-admins"
+	cmd := ocCommand("get", "projects")
+	want := []string{"oc", "--as=system:serviceaccount:core:rhmap-operator", "--as-group=system:serviceaccounts", "--as-group=rhmap-admins", "get", "projects"}
+	if !reflect.DeepEqual(cmd.Args, want) {
+		t.Errorf("ocCommand() args = %v, want %v", cmd.Args, want)
+	}
+}
+
+func TestFilterProjects(t *testing.T) {
+	projects := []string{"rhmap-core", "rhmap-mbaas", "rhmap-3t-dev", "customer-a", "customer-b"}
+	tests := []struct {
+		include, exclude string
+		want             []string
+	}{
+		{"", "", projects},
+		{"rhmap-*", "", []string{"rhmap-core", "rhmap-mbaas", "rhmap-3t-dev"}},
+		{"rhmap-core, customer-?", "customer-b", []string{"rhmap-core", "customer-a"}},
+		{"", "customer-*,rhmap-3t-*", []string{"rhmap-core", "rhmap-mbaas"}},
+		{"missing", "", nil},
+	}
+	for _, tt := range tests {
+		got, err := FilterProjects(projects, tt.include, tt.exclude)
+		if err != nil {
+			t.Errorf("FilterProjects(%q, %q): %v", tt.include, tt.exclude, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FilterProjects(%q, %q) = %v, want %v", tt.include, tt.exclude, got, tt.want)
+		}
+	}
+	if _, err := FilterProjects(projects, "rhmap-[", ""); err == nil {
+		t.Error("FilterProjects() with an invalid pattern didn't return an error")
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A memoryGuard reports memory pressure when the resident memory of the tool
+// exceeds max bytes, even after returning freed memory to the system. It is
+// safe for concurrent use.
+type memoryGuard struct {
+	mu  sync.Mutex
+	max int64
+	// rss returns the resident memory of the tool, in bytes.
+	rss func() (int64, error)
+	// paused records whether pressure was reported last time, so that
+	// only changes are logged.
+	paused bool
+}
+
+// taskMemory, if not nil, holds back new tasks while memory is scarce.
+var taskMemory *memoryGuard
+
+// newMemoryGuard returns a memoryGuard reporting pressure above max bytes of
+// resident memory.
+func newMemoryGuard(max int64) *memoryGuard {
+	return &memoryGuard{max: max, rss: residentMemory}
+}
+
+// pressure reports whether the resident memory of the tool is above the limit
+// of g. It is always false if g is nil or memory cannot be measured.
+func (g *memoryGuard) pressure() bool {
+	if g == nil {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	rss, err := g.rss()
+	if err == nil && rss > g.max {
+		debug.FreeOSMemory()
+		rss, err = g.rss()
+	}
+	high := err == nil && rss > g.max
+	if high != g.paused {
+		if high {
+			log.Printf("Memory use of %s is over the limit of %s set with -max-memory, holding back new tasks", formatSize(rss), formatSize(g.max))
+		} else {
+			log.Printf("Memory use is back under the limit of %s, starting new tasks again", formatSize(g.max))
+		}
+		g.paused = high
+	}
+	return high
+}
+
+// residentMemory returns the resident memory of the tool, as reported by
+// /proc on Linux, or the memory obtained from the system by the Go runtime
+// elsewhere.
+func residentMemory() (int64, error) {
+	statm, err := ioutil.ReadFile("/proc/self/statm")
+	if err != nil {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		return int64(stats.Sys), nil
+	}
+	fields := strings.Fields(string(statm))
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("unexpected content of /proc/self/statm: %q", statm)
+	}
+	pages, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return pages * int64(os.Getpagesize()), nil
+}
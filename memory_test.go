@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestMemoryGuard(t *testing.T) {
+	var g *memoryGuard
+	if g.pressure() {
+		t.Error("nil memoryGuard reported memory pressure")
+	}
+	rss := int64(100)
+	g = &memoryGuard{max: 150, rss: func() (int64, error) { return rss, nil }}
+	for _, tt := range []struct {
+		rss  int64
+		want bool
+	}{{100, false}, {200, true}, {150, false}} {
+		rss = tt.rss
+		if got := g.pressure(); got != tt.want {
+			t.Errorf("pressure() with %d bytes = %v, want %v", tt.rss, got, tt.want)
+		}
+	}
+	if _, err := residentMemory(); err != nil {
+		t.Errorf("residentMemory(): %v", err)
+	}
+}
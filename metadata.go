@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/feedhenry/fh-system-dump-tool/dumpformat"
+)
+
+// sensitiveFlags are the flags whose values are not recorded in metadata.json.
+// Webhook URLs carry their secret token, and the URLs of storage and of the
+// support case API may carry credentials.
+var sensitiveFlags = []string{"s3-access-key", "s3-secret-key", "notify-webhook", "s3-endpoint", "upload-url"}
+
+// setFlags returns the flags of fs that were set, with the values of
+// sensitiveFlags redacted, and the values of other flags redacted by
+// redactFlagURL.
+func setFlags(fs *flag.FlagSet) map[string]string {
+	flags := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		flags[f.Name] = redactFlagURL(f.Value.String())
+		if containsString(sensitiveFlags, f.Name) {
+			flags[f.Name] = "REDACTED"
+		}
+	})
+	return flags
+}
+
+// redactFlagURL returns value, keeping only the scheme and host if it is a URL
+// with user info, a path or a query, any of which may hold credentials.
+func redactFlagURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return value
+	}
+	if u.User == nil && strings.Trim(u.Path, "/") == "" && u.RawQuery == "" && u.Fragment == "" {
+		return value
+	}
+	return u.Scheme + "://" + u.Host + "/REDACTED"
+}
+
+// ocVersionTimeout limits how long oc version may take. It runs once tasks are
+// done, even if the dump was interrupted.
+const ocVersionTimeout = 30 * time.Second
+
+// GetOcVersions runs oc version and returns the versions it reports. It
+// returns the versions found even with an error, as oc still reports its own
+// version when the master cannot be reached.
+func GetOcVersions() (dumpformat.OcVersions, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ocVersionTimeout)
+	defer cancel()
+	var stdout bytes.Buffer
+	err := runCmdCaptureOutputOnce(ctx, ocCommand("version"), &stdout, nil)
+	return parseOcVersion(stdout.String()), err
+}
+
+// parseOcVersion parses the output of oc version, which lists the versions of
+// the client, then those of the server after a line starting with Server.
+func parseOcVersion(output string) dumpformat.OcVersions {
+	var v dumpformat.OcVersions
+	server := false
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch {
+		case fields[0] == "Server":
+			server = true
+		case fields[0] == "oc" && !server:
+			v.Client = fields[1]
+		case fields[0] == "openshift" && server:
+			v.OpenShift = fields[1]
+		case fields[0] == "kubernetes" && server:
+			v.Kubernetes = fields[1]
+		}
+	}
+	return v
+}
+
+// timezoneAt returns the timezone of t.
+func timezoneAt(t time.Time) *dumpformat.Timezone {
+	name, _ := t.Zone()
+	return &dumpformat.Timezone{Name: name, Offset: t.Format("-07:00")}
+}
+
+// WriteMetadata adds metadata.json to tarFile, accounting for the sizes of the
+// files written so far.
+func WriteMetadata(tarFile *Archive, m dumpformat.Metadata) error {
+	sizes := accountSizes(tarFile.Manifest(), m.Projects)
+	m.Sizes = &sizes
+	output, err := json.MarshalIndent(m, "", "    ")
+	if err != nil {
+		return err
+	}
+	return tarFile.AddFileByContent(output, "metadata.json")
+}
+
+// WriteManifest adds manifest.json, listing all files written so far, to
+// tarFile. interrupted is why the dump was interrupted, if it was, and
+// taskErrs are the errors of the tasks that produced the files, including
+// those of skipped tasks.
+func WriteManifest(tarFile *Archive, interrupted string, taskErrs []error) error {
+	manifest := tarFile.Manifest()
+	manifest.Interrupted = interrupted
+	setOutcomes(manifest, taskErrs)
+	output, err := json.MarshalIndent(manifest, "", "    ")
+	if err != nil {
+		return err
+	}
+	return tarFile.AddFileByContent(output, "manifest.json")
+}
+
+// setOutcomes records whether the tasks that produced the files of m
+// succeeded, given the errors of the tasks.
+func setOutcomes(m dumpformat.Manifest, taskErrs []error) {
+	type task struct{ name, project string }
+	outcomes := make(map[task]string)
+	for _, err := range taskErrs {
+		if d, ok := err.(*ProjectDeletedError); ok {
+			outcomes[task{d.Task, d.Project}] = outcomeSkipped
+			continue
+		}
+		for _, e := range flattenErrors(err) {
+			if f, ok := e.(*TaskFailure); ok {
+				outcomes[task{f.Task, f.Project}] = outcomeFailed
+			}
+		}
+	}
+	for i, f := range m.Files {
+		if f.Task == "" {
+			continue
+		}
+		m.Files[i].Outcome = outcomeOK
+		if outcome, ok := outcomes[task{f.Task, f.Project}]; ok {
+			m.Files[i].Outcome = outcome
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/feedhenry/fh-system-dump-tool/dumpformat"
+)
+
+// writeMiniDump writes a mini-dump, holding the files written by the tasks
+// returned by getTasks, to an archive in dir whose name starts with prefix.
+// The mini-dump has the layout of a full dump, with its errors, metadata and
+// manifest.
+func writeMiniDump(dir, prefix string, metadata dumpformat.Metadata, getTasks func(context.Context, *Archive) ([]Task, error)) error {
+	if err := checkCompression(*compression); err != nil {
+		return err
+	}
+	if err := checkArchiveFormat(*archiveFormat, *compression); err != nil {
+		return err
+	}
+	redactor, err := NewRedactor(config.Redaction.RedactionRules())
+	if err != nil {
+		return err
+	}
+
+	ctx, interrupted := interruptContext(*timeout)
+	start := time.Now().UTC()
+	path := filepath.Join(dir, prefix+"-"+start.Format(dumpTimestampFormat))
+	if err := os.MkdirAll(dir, 0770); err != nil {
+		return err
+	}
+	archiveFile, err := createDumpFile(path, dumpExtension(*archiveFormat, *compression))
+	if err != nil {
+		return err
+	}
+	defer archiveFile.Close()
+	tarFile, err := newDumpArchive(archiveFile, *archiveFormat, *compression)
+	if err != nil {
+		return err
+	}
+	tarFile.Redactor = redactor
+
+	tasks, err := getTasks(ctx, tarFile)
+	if err != nil {
+		// Without the subject of the mini-dump there is nothing to
+		// collect.
+		tarFile.Close()
+		os.Remove(archiveFile.Name())
+		return err
+	}
+	taskErrs := RunAllTasks(ctx, tasks, *maxParallelTasks, *taskTimeout)
+	WriteErrorSummary(os.Stderr, taskErrs)
+
+	var errors errorList
+	var errorReport bytes.Buffer
+	if err := WriteErrorReport(&errorReport, nil, taskErrs); err != nil {
+		errors = append(errors, err)
+	} else if err := tarFile.AddRedactedFile(errorReport.Bytes(), "errors.json"); err != nil {
+		errors = append(errors, err)
+	}
+	metadata.LayoutVersion = dumpformat.LayoutVersion
+	metadata.ToolVersion = version
+	metadata.ToolCommit = gitCommit
+	metadata.Created = start
+	metadata.HostTimezone = timezoneAt(start.Local())
+	if err := WriteMetadata(tarFile, metadata); err != nil {
+		errors = append(errors, err)
+	}
+	if err := WriteManifest(tarFile, interrupted(), taskErrs); err != nil {
+		errors = append(errors, err)
+	}
+	if err := tarFile.Close(); err != nil {
+		errors = append(errors, err)
+	}
+	log.Printf("Mini-dump written to: %s\n", archiveFile.Name())
+
+	failed := 0
+	for _, err := range taskErrs {
+		if err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		errors = append(errors, fmt.Errorf("%d of %d tasks failed", failed, len(tasks)))
+	}
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
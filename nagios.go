@@ -0,0 +1,353 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// nagiosDeploymentConfig is the name of the deployment config of
+	// Nagios, installed in every RHMAP Core and MBaaS project.
+	nagiosDeploymentConfig = "nagios"
+	// templatesNamespace is the project holding the templates shared by
+	// all projects.
+	templatesNamespace = "openshift"
+)
+
+// referenceComponents maps the kinds of RHMAP projects to the deployment
+// configs of a reference install of the project. The first component
+// identifies the kind of project.
+var referenceComponents = map[string][]string{
+	"core":  {"millicore", "fh-ngui", "fh-aaa", "fh-supercore", "fh-messaging", "fh-metrics", "fh-appstore", "gitlab-shell", "mongodb", "mysql", "redis", "ups", nagiosDeploymentConfig},
+	"mbaas": {"fh-mbaas", "fh-messaging", "fh-metrics", "fh-statsd", nagiosDeploymentConfig},
+}
+
+// A Template is an OpenShift template.
+type Template struct {
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Objects []struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+	} `json:"objects"`
+}
+
+type Templates struct {
+	Items []Template `json:"items"`
+}
+
+// isNagiosTemplate reports whether t deploys Nagios.
+func isNagiosTemplate(t Template) bool {
+	if strings.Contains(t.Metadata.Name, "nagios") {
+		return true
+	}
+	for _, o := range t.Objects {
+		if o.Kind == "DeploymentConfig" && o.Metadata.Name == nagiosDeploymentConfig {
+			return true
+		}
+	}
+	return false
+}
+
+// rhmapProjectKind returns the kind of RHMAP project with the given deployment
+// configs, or an empty string if it is not an RHMAP project.
+func rhmapProjectKind(dcs DeploymentConfigs) string {
+	names := dcNames(dcs)
+	kinds := make([]string, 0, len(referenceComponents))
+	for kind := range referenceComponents {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		if names[referenceComponents[kind][0]] {
+			return kind
+		}
+	}
+	return ""
+}
+
+func dcNames(dcs DeploymentConfigs) map[string]bool {
+	names := make(map[string]bool)
+	for _, dc := range dcs.Items {
+		names[dc.Metadata.Name] = true
+	}
+	return names
+}
+
+// isMissingNagios reports whether dcs are those of an RHMAP project without
+// Nagios.
+func isMissingNagios(dcs DeploymentConfigs) bool {
+	return rhmapProjectKind(dcs) != "" && !dcNames(dcs)[nagiosDeploymentConfig]
+}
+
+// getNagiosTemplates returns the templates deploying Nagios in project and in
+// the shared templates namespace.
+func getNagiosTemplates(ctx context.Context, project string) ([]json.RawMessage, []Template, error) {
+	var (
+		raw       []json.RawMessage
+		templates []Template
+		errors    errorList
+	)
+	for _, ns := range []string{project, templatesNamespace} {
+		var list struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		if err := getResourceStruct(ctx, ns, "templates", &list); err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		for _, item := range list.Items {
+			var t Template
+			if err := json.Unmarshal(item, &t); err != nil {
+				errors = append(errors, err)
+				continue
+			}
+			if isNagiosTemplate(t) {
+				raw = append(raw, item)
+				templates = append(templates, t)
+			}
+		}
+	}
+	if len(errors) > 0 {
+		return raw, templates, errors
+	}
+	return raw, templates, nil
+}
+
+// GetNagiosTemplatesTasks returns a list of tasks that, for each RHMAP project
+// without Nagios, collect the templates that could deploy it to
+// nagios-templates.json in the definitions of the project.
+func GetNagiosTemplatesTasks(projects []string, tarFile *Archive) []Task {
+	var tasks []Task
+	outFor := outToTGZ("definitions", "json", tarFile)
+	for _, p := range projects {
+		p := p
+		task := func(ctx context.Context) error {
+			var dcs DeploymentConfigs
+			if err := getResourceStruct(ctx, p, "dc", &dcs); err != nil {
+				return err
+			}
+			if !isMissingNagios(dcs) {
+				return nil
+			}
+			var errors errorList
+			raw, _, err := getNagiosTemplates(ctx, p)
+			if err != nil {
+				errors = append(errors, err)
+			}
+			output, err := json.MarshalIndent(struct {
+				Items []json.RawMessage `json:"items"`
+			}{append([]json.RawMessage{}, raw...)}, "", "    ")
+			if err != nil {
+				return err
+			}
+			out, outCloser, err := outFor(p, "nagios-templates")
+			if err != nil {
+				return err
+			}
+			defer outCloser.Close()
+			out.Write(output)
+			if len(errors) > 0 {
+				return errors
+			}
+			return nil
+		}
+		tasks = append(tasks, namedTask("collect nagios templates", p, task))
+	}
+	return tasks
+}
+
+// CheckNagiosPresent will check that RHMAP projects in the supplied project include Nagios, as in the reference install,
+// and if not this will be reflected in the returned Result data, explaining how it can be recreated. Any errors are
+// written to the supplied stdErr writer
+func CheckNagiosPresent(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+	result := Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: "nagios-missing", CheckName: "check nagios is deployed in rhmap projects"}
+	var dcs DeploymentConfigs
+	if err := getResourceStruct(ctx, project, "dc", &dcs); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	if !isMissingNagios(dcs) {
+		return result, nil
+	}
+	_, templates, err := getNagiosTemplates(ctx, project)
+	if err != nil {
+		stdErr.Write([]byte(err.Error()))
+	}
+	return checkNagiosPresent(result, project, dcs, templates), err
+}
+
+func checkNagiosPresent(result Result, project string, dcs DeploymentConfigs, templates []Template) Result {
+	kind := rhmapProjectKind(dcs)
+	if kind == "" || dcNames(dcs)[nagiosDeploymentConfig] {
+		return result
+	}
+	names := dcNames(dcs)
+	var missing []string
+	for _, c := range referenceComponents[kind] {
+		if !names[c] {
+			missing = append(missing, c)
+		}
+	}
+	message := fmt.Sprintf("this is an RHMAP %s project (%s is deployed), and the reference install includes a %s deployment config monitoring its components, which is missing",
+		kind, referenceComponents[kind][0], nagiosDeploymentConfig)
+	if len(templates) == 0 {
+		message += fmt.Sprintf("; no template deploying it was found in this project or the %s project", templatesNamespace)
+	} else {
+		var names []string
+		for _, t := range templates {
+			names = append(names, t.Metadata.Namespace+"/"+t.Metadata.Name)
+		}
+		message += "; it can be recreated from the template " + strings.Join(names, " or ")
+	}
+	result.Status = StatusWarning
+	result.StatusMessage = fmt.Sprintf("components of the reference %s install are missing: %s", kind, strings.Join(missing, ", "))
+	result.Info = append(result.Info, Info{Name: nagiosDeploymentConfig, Namespace: project, Kind: "DeploymentConfig", Count: 1, Message: message})
+	return result
+}
+
+// nagiosHistoryDir is the directory holding the Nagios logs and archived
+// history, in Nagios pods.
+const nagiosHistoryDir = "/var/log/nagios"
+
+// GetNagiosPods returns the names of the running Nagios pods in project.
+func GetNagiosPods(ctx context.Context, project string) ([]string, error) {
+	return getSpaceSeparated(ctx, ocCommand("-n", project, "get", "pods", "-l", "deploymentconfig="+nagiosDeploymentConfig,
+		`-o=jsonpath={.items[?(@.status.phase=="Running")].metadata.name}`))
+}
+
+// GetNagiosHistoryTasks returns a list of tasks to collect the Nagios history
+// from one running Nagios pod of each project, into
+// nagios/<project>/history.tar, compressed in the given format with the
+// matching extension appended. It may return tasks even in the presence of an
+// error.
+func GetNagiosHistoryTasks(ctx context.Context, projects []string, compression string, tarFile *Archive) ([]Task, error) {
+	var (
+		tasks  []Task
+		errors errorList
+	)
+	name := "history.tar" + compressedExtensions[compression]
+	for _, p := range projects {
+		pods, err := GetNagiosPods(ctx, p)
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		if len(pods) == 0 {
+			continue
+		}
+		p, dest := p, filepath.Join("nagios", p, name)
+		out := tarFile.GetWriterToFile(dest)
+		cmd := ocCommand("-n", p, "exec", pods[0], "--", "tar", "c", "-C", nagiosHistoryDir, ".")
+		task := func(ctx context.Context) error {
+			defer out.Close()
+			// The files of the history are redacted one by one
+			// as they are copied.
+			if w, ok := out.(interface{ setRedacted() }); ok {
+				w.setRedacted()
+			}
+			redact := func(name string, content []byte) []byte {
+				if tarFile.Redactor == nil {
+					return content
+				}
+				return tarFile.Redactor.Redact(path.Join(dest, name), content)
+			}
+			return commandRetries.do(ctx, func(attempt int) error {
+				if attempt > 0 {
+					out.(resetter).Reset()
+				}
+				return streamTar(ctx, retryCommand(cmd), out, compression, redact)
+			})
+		}
+		tasks = append(tasks, namedTask("collect nagios history of pod "+pods[0], p, task))
+	}
+	if len(errors) > 0 {
+		return tasks, errors
+	}
+	return tasks, nil
+}
+
+// streamTar runs cmd, which writes a tar archive to its standard output, and
+// copies the archive to out as it is read, applying redact to the contents of
+// each file and compressing it in the given format. Nothing is staged on disk.
+func streamTar(ctx context.Context, cmd *exec.Cmd, out io.Writer, compression string, redact func(name string, content []byte) []byte) error {
+	recordCommand(ctx, cmd, out)
+	stdout, pw := io.Pipe()
+	var stderr bytes.Buffer
+	cmd.Stdout = pw
+	cmd.Stderr = &stderr
+	done := make(chan error, 1)
+	go func() {
+		err := runner.Run(ctx, cmd)
+		pw.Close()
+		done <- err
+	}()
+
+	w, err := newCompressor(compression, out)
+	if err != nil {
+		io.Copy(ioutil.Discard, stdout)
+		<-done
+		return err
+	}
+	copyErr := copyTar(w, stdout, redact)
+	if copyErr != nil {
+		// Drain the output so that the command can exit.
+		io.Copy(ioutil.Discard, stdout)
+	}
+	if err := w.Close(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	if err := <-done; err != nil {
+		return &CmdError{Args: cmd.Args, Err: err, Stderr: stderr.String()}
+	}
+	return copyErr
+}
+
+// copyTar copies the tar archive read from r to w, applying redact to the
+// contents of regular files.
+func copyTar(w io.Writer, r io.Reader, redact func(name string, content []byte) []byte) error {
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(w)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			continue
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		content = redact(header.Name, content)
+		header.Size = int64(len(content))
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/feedhenry/fh-system-dump-tool/cmdrunner"
+)
+
+func TestCheckNagiosPresent(t *testing.T) {
+	dcs := func(names ...string) DeploymentConfigs {
+		var list DeploymentConfigs
+		for _, name := range names {
+			var dc DeploymentConfig
+			dc.Metadata.Name = name
+			list.Items = append(list.Items, dc)
+		}
+		return list
+	}
+	var template Template
+	template.Metadata.Name = "fh-mbaas-nagios"
+	template.Metadata.Namespace = "openshift"
+
+	tests := []struct {
+		dcs        DeploymentConfigs
+		templates  []Template
+		wantStatus int
+		wantText   string
+	}{
+		{dcs: dcs("my-app"), wantStatus: StatusOK},
+		{dcs: dcs("fh-mbaas", "nagios"), wantStatus: StatusOK},
+		{dcs: dcs("fh-mbaas", "fh-messaging", "fh-metrics", "fh-statsd"), templates: []Template{template}, wantStatus: StatusWarning, wantText: "openshift/fh-mbaas-nagios"},
+		{dcs: dcs("millicore"), wantStatus: StatusWarning, wantText: "no template"},
+	}
+	for i, tt := range tests {
+		result := checkNagiosPresent(Result{Status: StatusOK}, "p", tt.dcs, tt.templates)
+		if result.Status != tt.wantStatus {
+			t.Errorf("%d: Status = %d, want %d", i, result.Status, tt.wantStatus)
+		}
+		if tt.wantText != "" && (len(result.Info) != 1 || !strings.Contains(result.Info[0].Message, tt.wantText)) {
+			t.Errorf("%d: Info = %+v, want a message mentioning %q", i, result.Info, tt.wantText)
+		}
+	}
+}
+
+func TestCopyTar(t *testing.T) {
+	var src bytes.Buffer
+	tw := tar.NewWriter(&src)
+	tw.WriteHeader(&tar.Header{Name: "archives/", Typeflag: tar.TypeDir, Mode: 0755})
+	content := "[1472731200] SERVICE ALERT: mongodb;password=secret\n"
+	tw.WriteHeader(&tar.Header{Name: "archives/nagios-09-01-2016-00.log", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))})
+	tw.Write([]byte(content))
+	tw.Close()
+
+	var dst bytes.Buffer
+	redact := func(name string, content []byte) []byte {
+		return bytes.Replace(content, []byte("secret"), []byte("REDACTED"), -1)
+	}
+	if err := copyTar(&dst, &src, redact); err != nil {
+		t.Fatal(err)
+	}
+	files, err := readTarFiles(&dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := files["archives/nagios-09-01-2016-00.log"], strings.Replace(content, "secret", "REDACTED", 1); got != want {
+		t.Errorf("copied log = %q, want %q", got, want)
+	}
+}
+
+// readTarFiles reads the regular files of an uncompressed tar archive.
+func readTarFiles(r io.Reader) (map[string]string, error) {
+	files := make(map[string]string)
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag == tar.TypeReg {
+			files[header.Name] = string(content)
+		}
+	}
+}
+
+func TestGetNagiosHistoryTasks(t *testing.T) {
+	defer func(r cmdrunner.Runner) { runner = r }(runner)
+	var history bytes.Buffer
+	tw := tar.NewWriter(&history)
+	content := strings.Repeat("[1472731200] SERVICE ALERT: mongodb;CRITICAL\n", 100)
+	tw.WriteHeader(&tar.Header{Name: "nagios.log", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))})
+	tw.Write([]byte(content))
+	tw.Close()
+	runner = cmdrunner.NewFakeRunner([]cmdrunner.Invocation{
+		{Args: ocCommand("-n", "core", "get", "pods", "-l", "deploymentconfig="+nagiosDeploymentConfig, `-o=jsonpath={.items[?(@.status.phase=="Running")].metadata.name}`).Args, Stdout: "nagios-1-abcde"},
+		{Args: ocCommand("-n", "core", "exec", "nagios-1-abcde", "--", "tar", "c", "-C", nagiosHistoryDir, ".").Args, Stdout: history.String()},
+	})
+
+	var b bytes.Buffer
+	tarFile, err := NewTgz(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tarFile.MaxFileSize = 1024
+	tasks, err := GetNagiosHistoryTasks(context.Background(), []string{"core"}, compressionNone, tarFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := RunAllTasks(context.Background(), tasks, 1, 0); errs[0] != nil {
+		t.Fatal(errs[0])
+	}
+	if err := tarFile.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files := tarFile.Manifest().Files
+	if len(files) != 1 {
+		t.Fatalf("Manifest() = %+v, want the Nagios history", files)
+	}
+	if got := files[0]; got.Name != "nagios/core/history.tar" || got.Task != "collect nagios history of pod nagios-1-abcde" || got.Project != "core" || !got.Truncated {
+		t.Errorf("manifest entry = %+v, want the truncated history of the task", got)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// stuckTerminationAge is the time after which a project still terminating is
+// considered stuck. Deleting a project normally completes within a minute.
+const stuckTerminationAge = 10 * time.Minute
+
+type Project struct {
+	Metadata struct {
+		Name              string            `json:"name"`
+		Labels            map[string]string `json:"labels"`
+		DeletionTimestamp *time.Time        `json:"deletionTimestamp"`
+		Finalizers        []string          `json:"finalizers"`
+	} `json:"metadata"`
+	Spec struct {
+		Finalizers []string `json:"finalizers"`
+	} `json:"spec"`
+	Status struct {
+		Phase string `json:"phase"`
+	} `json:"status"`
+}
+
+// GetProjectDefinitionTasks returns a list of tasks to fetch the definition of
+// each project, recording its phase and finalizers.
+func GetProjectDefinitionTasks(projects []string, tarFile *Archive) []Task {
+	var tasks []Task
+	for _, p := range projects {
+		out := tarFile.GetWriterToFile(filepath.Join("definitions", "projects", p, "project.json"))
+		errOut := tarFile.GetWriterToFile(filepath.Join("definitions", "projects", p, "project.stderr"))
+		cmd := ocCommand("get", "project", p, "-o=json")
+		task := func(ctx context.Context) error {
+			defer out.Close()
+			defer errOut.Close()
+			return runCmdCaptureOutput(ctx, cmd, out, errOut)
+		}
+		tasks = append(tasks, namedTask("fetch definition of project", p, task))
+	}
+	return tasks
+}
+
+// CheckProjectTerminating will check the phase of the supplied project and if it has been terminating for longer than
+// stuckTerminationAge this will be reflected in the returned Result data, along with the finalizers blocking its
+// deletion. A project with the same name cannot be created until then. Any errors are written to the supplied stdErr
+// writer
+func CheckProjectTerminating(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+	var p Project
+	if err := getResourceStruct(ctx, project, "project/"+project, &p); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: "project-terminating", CheckName: "check projects stuck terminating"}, err
+	}
+	return checkProjectTerminating(p, time.Now()), nil
+}
+
+func checkProjectTerminating(p Project, now time.Time) Result {
+	result := Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: "project-terminating", CheckName: "check projects stuck terminating"}
+	deleted := p.Metadata.DeletionTimestamp
+	if p.Status.Phase != "Terminating" || deleted == nil || now.Sub(*deleted) < stuckTerminationAge {
+		return result
+	}
+	finalizers := append(append([]string(nil), p.Spec.Finalizers...), p.Metadata.Finalizers...)
+	blocked := "no finalizer is left, the namespace controller may be failing to delete its content"
+	if len(finalizers) > 0 {
+		blocked = "blocked by the finalizers " + strings.Join(finalizers, ", ")
+	}
+	result.Status = StatusCritical
+	result.StatusMessage = "the project is stuck terminating, it cannot be created again until its deletion completes"
+	result.Info = append(result.Info, Info{Name: p.Metadata.Name, Namespace: p.Metadata.Name, Kind: "Project", Count: 1,
+		Message: fmt.Sprintf("terminating for %v, %s", now.Sub(*deleted).Round(time.Minute), blocked)})
+	return result
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckProjectTerminating(t *testing.T) {
+	now := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		project string
+		status  int
+		message string
+	}{
+		{`{"metadata": {"name": "rhmap-dev"}, "status": {"phase": "Active"}}`, StatusOK, ""},
+		// Deletions take a little while.
+		{`{"metadata": {"name": "rhmap-dev", "deletionTimestamp": "2017-06-01T11:58:00Z"}, "status": {"phase": "Terminating"}}`, StatusOK, ""},
+		{`{"metadata": {"name": "rhmap-dev", "deletionTimestamp": "2017-06-01T10:00:00Z", "finalizers": ["example.com/cleanup"]},
+		  "spec": {"finalizers": ["kubernetes"]}, "status": {"phase": "Terminating"}}`,
+			StatusCritical, "terminating for 2h0m0s, blocked by the finalizers kubernetes, example.com/cleanup"},
+		{`{"metadata": {"name": "rhmap-dev", "deletionTimestamp": "2017-06-01T10:00:00Z"}, "status": {"phase": "Terminating"}}`,
+			StatusCritical, "no finalizer is left"},
+	}
+	for _, tt := range tests {
+		var p Project
+		if err := json.Unmarshal([]byte(tt.project), &p); err != nil {
+			t.Fatal(err)
+		}
+		result := checkProjectTerminating(p, now)
+		if result.Status != tt.status {
+			t.Errorf("%s: Status = %d, want %d", tt.project, result.Status, tt.status)
+			continue
+		}
+		if tt.message != "" && (len(result.Info) != 1 || !strings.Contains(result.Info[0].Message, tt.message)) {
+			t.Errorf("%s: Info = %+v, want a message containing %q", tt.project, result.Info, tt.message)
+		}
+	}
+}
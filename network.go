@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// networkStatsTimeout is how long, in seconds, each statistics command
+	// is allowed to run inside a pod.
+	networkStatsTimeout = 10
+	// conntrackUsageThreshold is the usage ratio of the conntrack table
+	// above which a node is considered to be exhausting it.
+	conntrackUsageThreshold = 0.9
+)
+
+// networkStatsScript prints socket and connection tracking summaries of the
+// host network namespace of a node. It only reads counters, no packet payloads
+// are ever captured.
+var networkStatsScript = fmt.Sprintf(`timeout %[1]d ss -s; `+
+	`echo; cat /proc/net/sockstat; `+
+	`echo; echo "nf_conntrack_count $(cat /proc/sys/net/netfilter/nf_conntrack_count)"; `+
+	`echo "nf_conntrack_max $(cat /proc/sys/net/netfilter/nf_conntrack_max)"`, networkStatsTimeout)
+
+// nodeStatsCommand returns a command running networkStatsScript on node, in
+// the namespaces of the host rather than those of a pod, as the counters are
+// kept per network namespace.
+func nodeStatsCommand(node string) *exec.Cmd {
+	return ocCommand("debug", "node/"+node, "--", "chroot", "/host", "sh", "-c", networkStatsScript)
+}
+
+// GetRunningPodsByNode returns a map from node name to the name of one running
+// pod scheduled on that node, for the given project.
+func GetRunningPodsByNode(ctx context.Context, project string) (map[string]string, error) {
+	return getRunningPodsByNode(ctx, ocCommand("-n", project, "get", "pods",
+		`-o=jsonpath={range .items[?(@.status.phase=="Running")]}{.metadata.name}{" "}{.spec.nodeName}{" "}{end}`))
+}
+
+func getRunningPodsByNode(ctx context.Context, cmd *exec.Cmd) (map[string]string, error) {
+	words, err := getSpaceSeparated(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(words)%2 != 0 {
+		return nil, fmt.Errorf("command %q: unexpected output: %v", strings.Join(cmd.Args, " "), words)
+	}
+	pods := make(map[string]string)
+	for i := 0; i < len(words); i += 2 {
+		pod, node := words[i], words[i+1]
+		if _, ok := pods[node]; !ok {
+			pods[node] = pod
+		}
+	}
+	return pods, nil
+}
+
+// A nodeStats holds the output of networkStatsScript on a node.
+type nodeStats struct {
+	mu             sync.Mutex
+	done           bool
+	stdout, stderr []byte
+	err            error
+}
+
+// nodeStatsCache runs networkStatsScript at most once on each node, for the
+// network-stats collector and the conntrack check to share.
+type nodeStatsCache struct {
+	mu    sync.Mutex
+	nodes map[string]*nodeStats
+}
+
+var networkStatsCache = &nodeStatsCache{}
+
+// get returns the output of networkStatsScript on node, running it unless it
+// already completed. Runs interrupted by the end of ctx are tried again by the
+// next caller.
+func (c *nodeStatsCache) get(ctx context.Context, node string) (stdout, stderr []byte, err error) {
+	c.mu.Lock()
+	if c.nodes == nil {
+		c.nodes = make(map[string]*nodeStats)
+	}
+	s, ok := c.nodes[node]
+	if !ok {
+		s = &nodeStats{}
+		c.nodes[node] = s
+	}
+	c.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.done {
+		var out, errOut bytes.Buffer
+		s.err = runCmdCaptureOutput(ctx, nodeStatsCommand(node), &out, &errOut)
+		s.stdout, s.stderr = out.Bytes(), errOut.Bytes()
+		s.done = s.err == nil || ctx.Err() == nil
+	}
+	return s.stdout, s.stderr, s.err
+}
+
+// GetNetworkStatsTasks returns a list of tasks to collect socket and
+// connection tracking statistics from every node hosting pods in the given
+// projects, into network/nodes/<node>.txt. The statistics are read on the host
+// with oc debug, which requires cluster-admin. It may return tasks even in the
+// presence of an error.
+func GetNetworkStatsTasks(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+	var (
+		tasks  []Task
+		errors errorList
+		seen   = make(map[string]bool)
+	)
+	for _, p := range projects {
+		pods, err := GetRunningPodsByNode(ctx, p)
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		for node := range pods {
+			if seen[node] {
+				continue
+			}
+			seen[node] = true
+			node := node
+			out := tarFile.GetWriterToFile(filepath.Join("network", "nodes", node+".txt"))
+			errOut := tarFile.GetWriterToFile(filepath.Join("network", "nodes", node+".stderr"))
+			task := func(ctx context.Context) error {
+				defer out.Close()
+				defer errOut.Close()
+				stdout, stderr, err := networkStatsCache.get(ctx, node)
+				out.Write(stdout)
+				errOut.Write(stderr)
+				return err
+			}
+			tasks = append(tasks, namedTask("collect network statistics of node "+node, "", task))
+		}
+	}
+	if len(errors) > 0 {
+		return tasks, errors
+	}
+	return tasks, nil
+}
+
+// parseConntrackUsage parses the output of networkStatsScript into the current
+// and maximum number of entries in the conntrack table.
+func parseConntrackUsage(output string) (count, max int, err error) {
+	values := make(map[string]int)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || (fields[0] != "nf_conntrack_count" && fields[0] != "nf_conntrack_max") {
+			continue
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return 0, 0, fmt.Errorf("unexpected conntrack output: %q", line)
+		}
+		values[fields[0]] = n
+	}
+	count, okCount := values["nf_conntrack_count"]
+	max, okMax := values["nf_conntrack_max"]
+	if !okCount || !okMax {
+		return 0, 0, fmt.Errorf("no conntrack counters in the network statistics")
+	}
+	return count, max, nil
+}
+
+// CheckConntrackExhaustion will check the conntrack table of every node hosting running pods in the supplied project,
+// from the network statistics collected on the node, and if any is close to its maximum size this will be reflected
+// in the returned Result data. Any errors are written to the supplied stdErr writer
+func CheckConntrackExhaustion(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+	result := Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: "conntrack-exhaustion", CheckName: "check nodes for conntrack table exhaustion"}
+	pods, err := GetRunningPodsByNode(ctx, project)
+	if err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	var nodes []string
+	for node := range pods {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	var errors errorList
+	for _, node := range nodes {
+		stdout, _, err := networkStatsCache.get(ctx, node)
+		if err == nil {
+			var count, max int
+			if count, max, err = parseConntrackUsage(string(stdout)); err == nil {
+				result = checkConntrackUsage(result, project, node, count, max)
+				continue
+			}
+		}
+		stdErr.Write([]byte(err.Error()))
+		errors = append(errors, err)
+	}
+
+	if len(errors) > 0 {
+		return result, errors
+	}
+	return result, nil
+}
+
+func checkConntrackUsage(result Result, project, node string, count, max int) Result {
+	if max > 0 && float64(count) >= conntrackUsageThreshold*float64(max) {
+		info := Info{Name: node, Namespace: project, Kind: "Node", Count: count, Message: fmt.Sprintf("the conntrack table is using %d of %d entries, new connections may be dropped", count, max)}
+		result.Status = StatusWarning
+		result.StatusMessage = "one or more nodes are close to exhausting their conntrack table"
+		result.Info = append(result.Info, info)
+	}
+	return result
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/feedhenry/fh-system-dump-tool/cmdrunner"
+)
+
+func TestGetRunningPodsByNode(t *testing.T) {
+	tests := []struct {
+		output []string
+		want   map[string]string
+	}{
+		{[]string{}, map[string]string{}},
+		{[]string{"pod-1", "node-1"}, map[string]string{"node-1": "pod-1"}},
+		{[]string{"pod-1", "node-1", "pod-2", "node-1", "pod-3", "node-2"}, map[string]string{"node-1": "pod-1", "node-2": "pod-3"}},
+	}
+	for _, tt := range tests {
+		cmd := helperCommand("echo", tt.output...)
+		got, err := getRunningPodsByNode(context.Background(), cmd)
+		if err != nil {
+			t.Errorf("getRunningPodsByNode(%v) returned non-nil error: %v", cmd.Args, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("getRunningPodsByNode(%v) = %v, want %v", cmd.Args, got, tt.want)
+		}
+	}
+	if _, err := getRunningPodsByNode(context.Background(), helperCommand("echo", "pod-1")); err == nil {
+		t.Error("getRunningPodsByNode with odd number of words returned nil error")
+	}
+}
+
+func TestParseConntrackUsage(t *testing.T) {
+	stats := "Total: 210\n\nsockets: used 180\n\nnf_conntrack_count %s\nnf_conntrack_max 65536\n"
+	tests := []struct {
+		output     string
+		count, max int
+		shouldFail bool
+	}{
+		{output: fmt.Sprintf(stats, "1024"), count: 1024, max: 65536},
+		{output: fmt.Sprintf(stats, "65000"), count: 65000, max: 65536},
+		{output: fmt.Sprintf(stats, "foo"), shouldFail: true},
+		{output: "Total: 210\n", shouldFail: true},
+	}
+	for _, tt := range tests {
+		count, max, err := parseConntrackUsage(tt.output)
+		if (err != nil) != tt.shouldFail {
+			t.Errorf("parseConntrackUsage(%q) error = %v, shouldFail = %v", tt.output, err, tt.shouldFail)
+			continue
+		}
+		if count != tt.count || max != tt.max {
+			t.Errorf("parseConntrackUsage(%q) = %d, %d, want %d, %d", tt.output, count, max, tt.count, tt.max)
+		}
+	}
+}
+
+func TestCheckConntrackExhaustion(t *testing.T) {
+	defer func(r cmdrunner.Runner) { runner = r }(runner)
+	defer func(c *nodeStatsCache) { networkStatsCache = c }(networkStatsCache)
+	networkStatsCache = &nodeStatsCache{}
+	runner = cmdrunner.NewFakeRunner([]cmdrunner.Invocation{
+		{Args: ocCommand("-n", "core", "get", "pods", `-o=jsonpath={range .items[?(@.status.phase=="Running")]}{.metadata.name}{" "}{.spec.nodeName}{" "}{end}`).Args, Stdout: "pod-1 node-1 pod-2 node-2"},
+		{Args: nodeStatsCommand("node-1").Args, Stdout: "nf_conntrack_count 1024\nnf_conntrack_max 65536\n"},
+		{Args: nodeStatsCommand("node-2").Args, Stdout: "nf_conntrack_count 65000\nnf_conntrack_max 65536\n"},
+	})
+	var stdErr bytes.Buffer
+	result, err := CheckConntrackExhaustion(context.Background(), "core", &stdErr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != StatusWarning || len(result.Info) != 1 || result.Info[0].Name != "node-2" {
+		t.Errorf("CheckConntrackExhaustion() = %+v, want node-2 reported", result)
+	}
+	// The statistics collected on nodes are shared rather than read again.
+	if _, _, err := networkStatsCache.get(context.Background(), "node-2"); err != nil {
+		t.Errorf("get(node-2): %v", err)
+	}
+}
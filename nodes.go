@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// minimumDockerVersion is the oldest docker release supported by the OpenShift
+// releases RHMAP runs on.
+const minimumDockerVersion = "1.12"
+
+type Node struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Status struct {
+		NodeInfo struct {
+			KernelVersion string `json:"kernelVersion"`
+			OSImage       string `json:"osImage"`
+			// ContainerRuntimeVersion is like docker://1.13.1.
+			ContainerRuntimeVersion string `json:"containerRuntimeVersion"`
+		} `json:"nodeInfo"`
+	} `json:"status"`
+}
+
+// podNodes returns the sorted names of the nodes the pods run on.
+func podNodes(pods Pods) []string {
+	seen := make(map[string]bool)
+	var nodes []string
+	for _, pod := range pods.Items {
+		if node := pod.Spec.NodeName; node != "" && !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// CheckNodeVersions will check the nodes hosting the pods of the supplied project and if they run different kernel,
+// operating system or container runtime versions, or a docker release older than minimumDockerVersion, this will be
+// reflected in the returned Result data. Any errors are written to the supplied stdErr writer
+func CheckNodeVersions(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+	result := Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: "node-versions", CheckName: "check nodes for mixed or unsupported versions"}
+	var pods Pods
+	if err := getResourceStruct(ctx, project, "pods", &pods); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	var nodes []Node
+	for _, name := range podNodes(pods) {
+		var node Node
+		if err := getResourceStruct(ctx, project, "node/"+name, &node); err != nil {
+			stdErr.Write([]byte(err.Error()))
+			return result, err
+		}
+		nodes = append(nodes, node)
+	}
+	return checkNodeVersions(result, project, nodes), nil
+}
+
+func checkNodeVersions(result Result, project string, nodes []Node) Result {
+	found := func(status int, node Node, message string) {
+		if status > result.Status {
+			result.Status = status
+		}
+		result.StatusMessage = "the nodes hosting the project run mixed or unsupported versions, pods may behave differently depending on their node"
+		result.Info = append(result.Info, Info{Name: node.Metadata.Name, Namespace: project, Kind: "Node", Count: 1, Message: message})
+	}
+	for _, node := range nodes {
+		runtime := node.Status.NodeInfo.ContainerRuntimeVersion
+		if v := strings.TrimPrefix(runtime, "docker://"); v != runtime && compareReleases(v, minimumDockerVersion) < 0 {
+			found(StatusCritical, node, fmt.Sprintf("the node runs docker %s, older than %s, the oldest release supported by OpenShift", v, minimumDockerVersion))
+		}
+	}
+	for _, a := range []struct {
+		name    string
+		version func(Node) string
+	}{
+		{"kernel", func(n Node) string { return n.Status.NodeInfo.KernelVersion }},
+		{"operating system", func(n Node) string { return n.Status.NodeInfo.OSImage }},
+		{"container runtime", func(n Node) string { return n.Status.NodeInfo.ContainerRuntimeVersion }},
+	} {
+		versions := make(map[string]bool)
+		for _, node := range nodes {
+			if v := a.version(node); v != "" {
+				versions[v] = true
+			}
+		}
+		if len(versions) < 2 {
+			continue
+		}
+		for _, node := range nodes {
+			v := a.version(node)
+			if v == "" {
+				continue
+			}
+			var others []string
+			for other := range versions {
+				if other != v {
+					others = append(others, other)
+				}
+			}
+			sort.Strings(others)
+			found(StatusWarning, node, fmt.Sprintf("the node runs %s %s, while other nodes hosting the project run %s", a.name, v, strings.Join(others, ", ")))
+		}
+	}
+	return result
+}
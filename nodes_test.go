@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPodNodes(t *testing.T) {
+	var pods Pods
+	if err := json.Unmarshal([]byte(`{"items": [{"spec": {"nodeName": "node-2"}}, {"spec": {}}, {"spec": {"nodeName": "node-1"}}, {"spec": {"nodeName": "node-2"}}]}`), &pods); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := podNodes(pods), []string{"node-1", "node-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("podNodes() = %v, want %v", got, want)
+	}
+}
+
+func TestCheckNodeVersions(t *testing.T) {
+	node := func(name, kernel, runtime string) Node {
+		var n Node
+		n.Metadata.Name = name
+		n.Status.NodeInfo.KernelVersion = kernel
+		n.Status.NodeInfo.OSImage = "Red Hat Enterprise Linux Server 7.5 (Maipo)"
+		n.Status.NodeInfo.ContainerRuntimeVersion = runtime
+		return n
+	}
+	tests := []struct {
+		nodes    []Node
+		status   int
+		messages []string
+	}{
+		{nil, StatusOK, nil},
+		{[]Node{node("node-1", "3.10.0-862.el7.x86_64", "docker://1.13.1"), node("node-2", "3.10.0-862.el7.x86_64", "docker://1.13.1")}, StatusOK, nil},
+		{[]Node{node("node-1", "3.10.0-862.el7.x86_64", "docker://1.13.1"), node("node-2", "3.10.0-693.el7.x86_64", "docker://1.13.1")}, StatusWarning, []string{
+			"the node runs kernel 3.10.0-862.el7.x86_64, while other nodes hosting the project run 3.10.0-693.el7.x86_64",
+			"the node runs kernel 3.10.0-693.el7.x86_64, while other nodes hosting the project run 3.10.0-862.el7.x86_64",
+		}},
+		{[]Node{node("node-1", "3.10.0-862.el7.x86_64", "docker://1.10.3")}, StatusCritical, []string{"the node runs docker 1.10.3, older than 1.12"}},
+		// Other runtimes aren't held to the docker releases.
+		{[]Node{node("node-1", "3.10.0-862.el7.x86_64", "cri-o://1.11.6")}, StatusOK, nil},
+	}
+	for i, tt := range tests {
+		result := checkNodeVersions(Result{Status: StatusOK}, "rhmap-core", tt.nodes)
+		if result.Status != tt.status {
+			t.Errorf("%d: Status = %d, want %d", i, result.Status, tt.status)
+			continue
+		}
+		if len(result.Info) != len(tt.messages) {
+			t.Errorf("%d: Info = %+v, want %d entries", i, result.Info, len(tt.messages))
+			continue
+		}
+		for j, m := range tt.messages {
+			if !strings.Contains(result.Info[j].Message, m) {
+				t.Errorf("%d: Info[%d].Message = %q, want it to contain %q", i, j, result.Info[j].Message, m)
+			}
+		}
+	}
+}
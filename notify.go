@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// notifyTimeout limits how long posting a notification may take.
+const notifyTimeout = 30 * time.Second
+
+// A webhookPayload is the JSON document posted to notification webhooks.
+type webhookPayload struct {
+	Text      string    `json:"text"`
+	Dump      string    `json:"dump"`
+	Criticals int       `json:"criticals"`
+	Warnings  int       `json:"warnings"`
+	Health    int       `json:"healthScore"`
+	Findings  []Finding `json:"findings"`
+}
+
+// slackPayload is the subset of a Slack incoming webhook message used for
+// notifications.
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+// checkNotifyPolicy returns an error if policy is not a -notify-on policy.
+func checkNotifyPolicy(policy string) error {
+	switch policy {
+	case "always", "critical":
+		return nil
+	}
+	return fmt.Errorf("unknown notification policy %q, must be one of: always, critical", policy)
+}
+
+// shouldNotify reports whether a notification should be sent for the summary,
+// given the -notify-on policy, already checked with checkNotifyPolicy.
+func shouldNotify(policy string, s DumpSummary) bool {
+	if policy == "critical" {
+		return s.CountFindings(StatusCritical) > 0
+	}
+	return true
+}
+
+// NotifyWebhook posts a summary of the dump to url. If slack is true, the
+// payload is formatted as a Slack-compatible message.
+func NotifyWebhook(url string, slack bool, s DumpSummary) error {
+	var text bytes.Buffer
+	if err := WriteTextSummary(&text, s); err != nil {
+		return err
+	}
+	var payload interface{}
+	if slack {
+		payload = slackPayload{Text: "```\n" + text.String() + "```"}
+	} else {
+		payload = webhookPayload{
+			Text:      text.String(),
+			Dump:      s.Path,
+			Criticals: s.CountFindings(StatusCritical),
+			Warnings:  s.CountFindings(StatusWarning),
+			Health:    s.HealthScore(),
+			Findings:  s.Findings(),
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return postJSON(url, body)
+}
+
+func postJSON(url string, body []byte) error {
+	client := http.Client{Timeout: notifyTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook %s: %s: %s", url, resp.Status, msg)
+	}
+	return nil
+}
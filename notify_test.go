@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestShouldNotify(t *testing.T) {
+	ok := DumpSummary{Results: map[string][]Result{"core": {{Status: StatusWarning}}}}
+	critical := DumpSummary{Results: map[string][]Result{"core": {{Status: StatusCritical}}}}
+	tests := []struct {
+		policy  string
+		summary DumpSummary
+		want    bool
+	}{
+		{"always", ok, true},
+		{"critical", ok, false},
+		{"critical", critical, true},
+	}
+	for _, tt := range tests {
+		if err := checkNotifyPolicy(tt.policy); err != nil {
+			t.Errorf("checkNotifyPolicy(%q) returned non-nil error: %v", tt.policy, err)
+			continue
+		}
+		if got := shouldNotify(tt.policy, tt.summary); got != tt.want {
+			t.Errorf("shouldNotify(%q) = %v, want %v", tt.policy, got, tt.want)
+		}
+	}
+	if err := checkNotifyPolicy("sometimes"); err == nil {
+		t.Error("checkNotifyPolicy with unknown policy returned nil error")
+	}
+}
+
+func TestNotifyWebhook(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("invalid JSON payload: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	summary := DumpSummary{
+		Path: "dump.tar.gz",
+		Results: map[string][]Result{
+			"core": {{CheckName: "check a", Status: StatusCritical, StatusMessage: "a detected"}},
+		},
+	}
+	if err := NotifyWebhook(server.URL, false, summary); err != nil {
+		t.Fatal(err)
+	}
+	if got["criticals"] != 1.0 || !strings.Contains(got["text"].(string), "a detected") {
+		t.Errorf("unexpected payload: %v", got)
+	}
+
+	got = nil
+	if err := NotifyWebhook(server.URL, true, summary); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got["criticals"]; ok || !strings.Contains(got["text"].(string), "a detected") {
+		t.Errorf("unexpected Slack payload: %v", got)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+const (
+	// maxObjectDataSize is the largest data the API accepts in a config
+	// map or secret, as objects are stored whole in etcd.
+	maxObjectDataSize = 1 << 20
+	// objectSizeWarning and objectSizeCritical are the percentages of
+	// maxObjectDataSize from which config maps and secrets are reported.
+	objectSizeWarning  = 75
+	objectSizeCritical = 90
+)
+
+type ConfigMaps struct {
+	Items []ConfigMap `json:"items"`
+}
+
+type ConfigMap struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Data map[string]string `json:"data"`
+	// BinaryData values are base64 encoded.
+	BinaryData map[string]string `json:"binaryData"`
+}
+
+// configMapDataSize returns the size of the data of cm as counted by the API:
+// its keys and values, binary values decoded.
+func configMapDataSize(cm ConfigMap) int {
+	size := 0
+	for key, value := range cm.Data {
+		size += len(key) + len(value)
+	}
+	for key, value := range cm.BinaryData {
+		size += len(key) + decodedSize(value)
+	}
+	return size
+}
+
+// decodedSize returns the size of the base64 encoded value once decoded.
+func decodedSize(value string) int {
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return base64.StdEncoding.DecodedLen(len(value))
+	}
+	return len(decoded)
+}
+
+// secretDataSize returns the size of the data of secret as counted by the API:
+// its values, decoded.
+func secretDataSize(secret Secret) int {
+	size := 0
+	for _, value := range secret.Data {
+		size += decodedSize(value)
+	}
+	return size
+}
+
+// CheckObjectSizes will check all config maps and secrets in the supplied project and if the data of any approaches
+// the size limit of objects, which makes updates to it fail and rollouts using it break, this will be reflected in the
+// returned Result data. Any errors are written to the supplied stdErr writer
+func CheckObjectSizes(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+	result := Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: "object-size", CheckName: "check for config maps and secrets approaching the size limit"}
+	configMaps := ConfigMaps{}
+	if err := getResourceStruct(ctx, project, "configmaps", &configMaps); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	secrets := Secrets{}
+	if err := getResourceStruct(ctx, project, "secrets", &secrets); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	return checkObjectSizes(result, configMaps, secrets), nil
+}
+
+func checkObjectSizes(result Result, configMaps ConfigMaps, secrets Secrets) Result {
+	check := func(name, namespace, kind string, size int) {
+		percent := 100 * size / maxObjectDataSize
+		if percent < objectSizeWarning {
+			return
+		}
+		if percent >= objectSizeCritical {
+			result.Status = StatusCritical
+		} else if result.Status < StatusWarning {
+			result.Status = StatusWarning
+		}
+		result.StatusMessage = "config maps or secrets approaching the size limit detected"
+		result.Info = append(result.Info, Info{Name: name, Namespace: namespace, Kind: kind, Count: 1,
+			Message: fmt.Sprintf("holds %s of data, %d%% of the %s limit", formatSize(int64(size)), percent, formatSize(maxObjectDataSize))})
+	}
+	for _, cm := range configMaps.Items {
+		check(cm.Metadata.Name, cm.Metadata.Namespace, "ConfigMap", configMapDataSize(cm))
+	}
+	for _, secret := range secrets.Items {
+		check(secret.Metadata.Name, secret.Metadata.Namespace, "Secret", secretDataSize(secret))
+	}
+	return result
+}
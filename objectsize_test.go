@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestCheckObjectSizes(t *testing.T) {
+	configMap := func(name string, size int) ConfigMap {
+		var cm ConfigMap
+		cm.Metadata.Name = name
+		cm.Data = map[string]string{"ca.crt": strings.Repeat("x", size-len("ca.crt"))}
+		return cm
+	}
+	secret := func(name string, size int) Secret {
+		var s Secret
+		s.Metadata.Name = name
+		s.Data = map[string]string{"assets.zip": base64.StdEncoding.EncodeToString(make([]byte, size))}
+		return s
+	}
+	tests := []struct {
+		configMaps []ConfigMap
+		secrets    []Secret
+		status     int
+		names      []string
+	}{
+		{nil, nil, StatusOK, nil},
+		{[]ConfigMap{configMap("small", 1024)}, []Secret{secret("small", 1024)}, StatusOK, nil},
+		{[]ConfigMap{configMap("bundle", 800<<10)}, []Secret{secret("small", 1024)}, StatusWarning, []string{"bundle"}},
+		{[]ConfigMap{configMap("bundle", 800<<10)}, []Secret{secret("assets", 1000<<10)}, StatusCritical, []string{"bundle", "assets"}},
+	}
+	for i, tt := range tests {
+		result := checkObjectSizes(Result{Status: StatusOK}, ConfigMaps{tt.configMaps}, Secrets{tt.secrets})
+		if result.Status != tt.status {
+			t.Errorf("%d: Status = %d, want %d", i, result.Status, tt.status)
+		}
+		if len(result.Info) != len(tt.names) {
+			t.Errorf("%d: got %d findings, want %d", i, len(result.Info), len(tt.names))
+			continue
+		}
+		for j, info := range result.Info {
+			if info.Name != tt.names[j] {
+				t.Errorf("%d: Info[%d].Name = %q, want %q", i, j, info.Name, tt.names[j])
+			}
+		}
+	}
+}
+
+func TestSecretDataSize(t *testing.T) {
+	var s Secret
+	s.Data = map[string]string{"a": base64.StdEncoding.EncodeToString([]byte("hunter2")), "b": base64.StdEncoding.EncodeToString([]byte("x"))}
+	if got, want := secretDataSize(s), 8; got != want {
+		t.Errorf("secretDataSize() = %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"io"
+	"strings"
+)
+
+// rhmapLabelPrefix prefixes the labels RHMAP sets on the projects it manages,
+// such as the projects of app environments.
+const rhmapLabelPrefix = "rhmap/"
+
+// serviceAccountTokenType is the type of the secrets holding the tokens of
+// service accounts, managed by OpenShift.
+const serviceAccountTokenType = "kubernetes.io/service-account-token"
+
+type PersistentVolumeClaims struct {
+	Items []struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Status struct {
+			Phase string `json:"phase"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type Services struct {
+	Items []Service `json:"items"`
+}
+
+type Service struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Spec struct {
+		Selector map[string]string `json:"selector"`
+	} `json:"spec"`
+}
+
+type ServiceAccounts struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Secrets []struct {
+			Name string `json:"name"`
+		} `json:"secrets"`
+		ImagePullSecrets []struct {
+			Name string `json:"name"`
+		} `json:"imagePullSecrets"`
+	} `json:"items"`
+}
+
+// isRHMAPManaged reports whether project is managed by RHMAP.
+func isRHMAPManaged(project Project) bool {
+	for label := range project.Metadata.Labels {
+		if strings.HasPrefix(label, rhmapLabelPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// dcReferences returns the names of the persistent volume claims and secrets
+// used by the pods of dcs.
+func dcReferences(dcs DeploymentConfigs) (claims, secrets map[string]bool) {
+	claims, secrets = make(map[string]bool), make(map[string]bool)
+	for _, dc := range dcs.Items {
+		spec := dc.Spec.Template.Spec
+		for _, v := range spec.Volumes {
+			if v.PersistentVolumeClaim != nil {
+				claims[v.PersistentVolumeClaim.ClaimName] = true
+			}
+			if v.Secret != nil {
+				secrets[v.Secret.SecretName] = true
+			}
+		}
+		for _, s := range spec.ImagePullSecrets {
+			secrets[s.Name] = true
+		}
+		for _, containers := range [][]Container{spec.InitContainers, spec.Containers} {
+			for _, c := range containers {
+				for _, env := range c.Env {
+					if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+						secrets[env.ValueFrom.SecretKeyRef.Name] = true
+					}
+				}
+			}
+		}
+	}
+	return claims, secrets
+}
+
+// selectsDC reports whether the selector of service matches the pods of any
+// of dcs.
+func selectsDC(service Service, dcs DeploymentConfigs) bool {
+	for _, dc := range dcs.Items {
+		labels := dc.Spec.Template.Metadata.Labels
+		matches := true
+		for k, v := range service.Spec.Selector {
+			if labels[k] != v {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckOrphanedResources will check the persistent volume claims, secrets, services and routes of the supplied
+// project, if it is managed by RHMAP, and if any is no longer used by a deployconfig, as left behind by deleted apps,
+// this will be reflected in the returned Result data. Any errors are written to the supplied stdErr writer
+func CheckOrphanedResources(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+	result := Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: "orphaned-resources", CheckName: "check resources not used by any deployconfig"}
+	var p Project
+	if err := getResourceStruct(ctx, project, "project/"+project, &p); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	if !isRHMAPManaged(p) {
+		return result, nil
+	}
+	var (
+		dcs      DeploymentConfigs
+		sas      ServiceAccounts
+		claims   PersistentVolumeClaims
+		secrets  Secrets
+		services Services
+		routes   Routes
+	)
+	for _, r := range []struct {
+		resource string
+		dest     interface{}
+	}{
+		{"dc", &dcs},
+		{"serviceaccounts", &sas},
+		{"pvc", &claims},
+		{"secrets", &secrets},
+		{"services", &services},
+		{"routes", &routes},
+	} {
+		if err := getResourceStruct(ctx, project, r.resource, r.dest); err != nil {
+			stdErr.Write([]byte(err.Error()))
+			return result, err
+		}
+	}
+	return checkOrphanedResources(result, dcs, sas, claims, secrets, services, routes), nil
+}
+
+func checkOrphanedResources(result Result, dcs DeploymentConfigs, sas ServiceAccounts, claims PersistentVolumeClaims, secrets Secrets, services Services, routes Routes) Result {
+	found := func(info Info) {
+		result.Status = StatusWarning
+		result.StatusMessage = "one or more resources are not used by any deployconfig, possibly left behind by deleted apps"
+		result.Info = append(result.Info, info)
+	}
+	usedClaims, usedSecrets := dcReferences(dcs)
+	// Secrets linked to service accounts are used by OpenShift.
+	for _, sa := range sas.Items {
+		for _, s := range sa.Secrets {
+			usedSecrets[s.Name] = true
+		}
+		for _, s := range sa.ImagePullSecrets {
+			usedSecrets[s.Name] = true
+		}
+	}
+
+	for _, c := range claims.Items {
+		if !usedClaims[c.Metadata.Name] {
+			found(Info{Name: c.Metadata.Name, Namespace: c.Metadata.Namespace, Kind: c.Kind, Count: 1, Message: "the persistent volume claim is not mounted by any deployconfig"})
+		}
+	}
+	for _, s := range secrets.Items {
+		if s.Type != serviceAccountTokenType && !usedSecrets[s.Metadata.Name] {
+			found(Info{Name: s.Metadata.Name, Namespace: s.Metadata.Namespace, Kind: s.Kind, Count: 1, Message: "the secret is not used by any deployconfig or service account"})
+		}
+	}
+	// Services without a selector have their endpoints managed by hand.
+	usedServices := make(map[string]bool)
+	for _, s := range services.Items {
+		if len(s.Spec.Selector) == 0 || selectsDC(s, dcs) {
+			usedServices[s.Metadata.Name] = true
+			continue
+		}
+		found(Info{Name: s.Metadata.Name, Namespace: s.Metadata.Namespace, Kind: s.Kind, Count: 1, Message: "the service selects the pods of no deployconfig"})
+	}
+	for _, r := range routes.Items {
+		used := false
+		for _, backend := range append([]RouteBackend{r.Spec.To}, r.Spec.AlternateBackends...) {
+			if backend.Kind != "Service" || usedServices[backend.Name] {
+				used = true
+			}
+		}
+		if !used {
+			found(Info{Name: r.Metadata.Name, Namespace: r.Metadata.Namespace, Kind: r.Kind, Count: 1, Message: "the route leads to no service in use at " + r.Spec.Host})
+		}
+	}
+	return result
+}
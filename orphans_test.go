@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCheckOrphanedResources(t *testing.T) {
+	var (
+		dcs      DeploymentConfigs
+		sas      ServiceAccounts
+		claims   PersistentVolumeClaims
+		secrets  Secrets
+		services Services
+		routes   Routes
+	)
+	for js, v := range map[string]interface{}{
+		`{"items": [
+			{"kind": "DeploymentConfig", "metadata": {"name": "cloudapp"}, "spec": {"template": {
+				"metadata": {"labels": {"app": "cloudapp", "deploymentconfig": "cloudapp"}},
+				"spec": {
+					"volumes": [{"name": "data", "persistentVolumeClaim": {"claimName": "cloudapp-data"}}],
+					"containers": [{"name": "cloudapp", "env": [{"name": "DB_PASSWORD", "valueFrom": {"secretKeyRef": {"name": "cloudapp-db", "key": "password"}}}]}]}}}}
+		]}`: &dcs,
+		`{"items": [{"secrets": [{"name": "default-token-abcde"}], "imagePullSecrets": [{"name": "default-dockercfg-fghij"}]}]}`: &sas,
+		`{"items": [
+			{"kind": "PersistentVolumeClaim", "metadata": {"name": "cloudapp-data"}},
+			{"kind": "PersistentVolumeClaim", "metadata": {"name": "oldapp-data"}}
+		]}`: &claims,
+		`{"items": [
+			{"kind": "Secret", "metadata": {"name": "cloudapp-db"}},
+			{"kind": "Secret", "metadata": {"name": "default-token-abcde"}, "type": "kubernetes.io/service-account-token"},
+			{"kind": "Secret", "metadata": {"name": "builder-token-klmno"}, "type": "kubernetes.io/service-account-token"},
+			{"kind": "Secret", "metadata": {"name": "default-dockercfg-fghij"}, "type": "kubernetes.io/dockercfg"},
+			{"kind": "Secret", "metadata": {"name": "oldapp-db"}}
+		]}`: &secrets,
+		`{"items": [
+			{"kind": "Service", "metadata": {"name": "cloudapp"}, "spec": {"selector": {"deploymentconfig": "cloudapp"}}},
+			{"kind": "Service", "metadata": {"name": "external-db"}, "spec": {}},
+			{"kind": "Service", "metadata": {"name": "oldapp"}, "spec": {"selector": {"deploymentconfig": "oldapp"}}}
+		]}`: &services,
+		`{"items": [
+			{"kind": "Route", "metadata": {"name": "cloudapp"}, "spec": {"to": {"kind": "Service", "name": "cloudapp"}}},
+			{"kind": "Route", "metadata": {"name": "oldapp"}, "spec": {"to": {"kind": "Service", "name": "oldapp"}}},
+			{"kind": "Route", "metadata": {"name": "gone"}, "spec": {"to": {"kind": "Service", "name": "deleted"}}}
+		]}`: &routes,
+	} {
+		if err := json.Unmarshal([]byte(js), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result := checkOrphanedResources(Result{Status: StatusOK}, dcs, sas, claims, secrets, services, routes)
+	if result.Status != StatusWarning {
+		t.Fatalf("Status = %d, want %d", result.Status, StatusWarning)
+	}
+	want := []struct{ kind, name string }{
+		{"PersistentVolumeClaim", "oldapp-data"},
+		{"Secret", "oldapp-db"},
+		{"Service", "oldapp"},
+		{"Route", "oldapp"},
+		{"Route", "gone"},
+	}
+	if len(result.Info) != len(want) {
+		t.Fatalf("Info = %+v, want %d findings", result.Info, len(want))
+	}
+	for i, w := range want {
+		if info := result.Info[i]; info.Kind != w.kind || info.Name != w.name {
+			t.Errorf("Info[%d] = %s %s, want %s %s", i, info.Kind, info.Name, w.kind, w.name)
+		}
+	}
+}
+
+func TestIsRHMAPManaged(t *testing.T) {
+	var p Project
+	if isRHMAPManaged(p) {
+		t.Error("isRHMAPManaged() of a project without labels = true")
+	}
+	p.Metadata.Labels = map[string]string{"rhmap/env": "dev"}
+	if !isRHMAPManaged(p) {
+		t.Error("isRHMAPManaged() of a project labelled rhmap/env = false")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// defaultOutputPath is the output path template used without -out, placing
+// dumps in dumpDir.
+const defaultOutputPath = dumpDir + "/{{.Timestamp}}"
+
+// maxOutputPathAttempts is the number of suffixed paths tried when the
+// resolved output path is already taken.
+const maxOutputPathAttempts = 100
+
+// outputPathData holds the values available to -out templates.
+type outputPathData struct {
+	// Cluster is the host name of the OpenShift master.
+	Cluster string
+	// Timestamp is the start time of the dump, in dumpTimestampFormat.
+	Timestamp string
+}
+
+// unsafePathChars matches the characters replaced in values substituted into
+// output paths.
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// resolveOutputPath executes the output path template tmpl with data, and
+// returns the path of the dump archive without its extension.
+func resolveOutputPath(tmpl string, data outputPathData) (string, error) {
+	t, err := template.New("out").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid output path %q: %v", tmpl, err)
+	}
+	data.Cluster = unsafePathChars.ReplaceAllString(data.Cluster, "_")
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("invalid output path %q: %v", tmpl, err)
+	}
+	path := trimArchiveExtension(filepath.Clean(buf.String()))
+	if path == "." || strings.HasSuffix(buf.String(), "/") {
+		return "", fmt.Errorf("invalid output path %q: it must name a file", tmpl)
+	}
+	return path, nil
+}
+
+// createDumpFile creates the dump archive for the output path, with the
+// extension ext, and any missing
+// parent directories. If the archive already exists, a numbered suffix is
+// added to the path, so that concurrent or repeated runs never overwrite each
+// other.
+func createDumpFile(path, ext string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0770); err != nil {
+		return nil, err
+	}
+	name := path + ext
+	for i := 1; i <= maxOutputPathAttempts; i++ {
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0660)
+		if !os.IsExist(err) {
+			return f, err
+		}
+		name = fmt.Sprintf("%s-%d%s", path, i, ext)
+	}
+	return nil, fmt.Errorf("%s%s and %d alternatives already exist", path, ext, maxOutputPathAttempts)
+}
+
+// GetClusterName returns the host name of the OpenShift master the current
+// user is logged in to.
+func GetClusterName(ctx context.Context) (string, error) {
+	words, err := getSpaceSeparated(ctx, ocCommand("whoami", "--show-server"))
+	if err != nil {
+		return "", err
+	}
+	if len(words) != 1 {
+		return "", fmt.Errorf("unexpected output of oc whoami --show-server: %v", words)
+	}
+	u, err := url.Parse(words[0])
+	if err != nil {
+		return "", err
+	}
+	if u.Hostname() == "" {
+		return "", errors.New("oc whoami --show-server returned no host: " + words[0])
+	}
+	return u.Hostname(), nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveOutputPath(t *testing.T) {
+	data := outputPathData{Cluster: "master.example.com:8443", Timestamp: "2016-09-01T12-00-00Z"}
+	tests := []struct {
+		tmpl, want string
+	}{
+		{defaultOutputPath, "rhmap-dumps/2016-09-01T12-00-00Z"},
+		{"dumps/{{.Cluster}}/{{.Timestamp}}", "dumps/master.example.com_8443/2016-09-01T12-00-00Z"},
+		{"/var/dumps/{{.Timestamp}}.tar.gz", "/var/dumps/2016-09-01T12-00-00Z"},
+	}
+	for _, tt := range tests {
+		got, err := resolveOutputPath(tt.tmpl, data)
+		if err != nil || got != tt.want {
+			t.Errorf("resolveOutputPath(%q) = %q, %v, want %q", tt.tmpl, got, err, tt.want)
+		}
+	}
+	for _, tmpl := range []string{"dumps/{{.Node}}", "dumps/{{.Timestamp", "dumps/"} {
+		if _, err := resolveOutputPath(tmpl, data); err == nil {
+			t.Errorf("resolveOutputPath(%q) didn't return an error", tmpl)
+		}
+	}
+}
+
+func TestCreateDumpFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "outpath")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cluster", "2016-09-01T12-00-00Z")
+	for _, want := range []string{path + ".tar.gz", path + "-1.tar.gz", path + "-2.tar.gz"} {
+		f, err := createDumpFile(path, ".tar.gz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		if f.Name() != want {
+			t.Errorf("createDumpFile() created %s, want %s", f.Name(), want)
+		}
+	}
+}
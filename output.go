@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Formats of the result of runs, set with -output.
+const (
+	// outputText only writes human-readable messages to stderr.
+	outputText = "text"
+	// outputJSON also writes a RunResult to stdout when the tool exits.
+	outputJSON = "json"
+)
+
+// Outcomes of runs.
+const (
+	// runOK is for dumps collected without errors.
+	runOK = "ok"
+	// runPartial is for dumps written despite errors.
+	runPartial = "partial"
+	// runInterrupted is for dumps interrupted by a signal or -timeout.
+	runInterrupted = "interrupted"
+	// runNotLoggedIn is for runs that failed because the user is not
+	// logged in to OpenShift.
+	runNotLoggedIn = "not-logged-in"
+	// runFailed is for runs that failed before writing a dump.
+	runFailed = "failed"
+)
+
+// checkOutputFormat returns an error if format is not a format of run results.
+func checkOutputFormat(format string) error {
+	if format != outputText && format != outputJSON {
+		return fmt.Errorf("unknown output format %q, must be text or json", format)
+	}
+	return nil
+}
+
+// A RunResult describes the outcome of a run of the tool, for programs wrapping
+// it.
+type RunResult struct {
+	Outcome  string `json:"outcome"`
+	ExitCode int    `json:"exitCode"`
+	// Dump is the path of the dump archive written or refreshed, if any.
+	Dump string `json:"dump,omitempty"`
+	// Files lists the paths of all files written, including Dump.
+	Files       []string  `json:"files"`
+	Criticals   int       `json:"criticals"`
+	Warnings    int       `json:"warnings"`
+	HealthScore int       `json:"healthScore"`
+	Findings    []Finding `json:"findings"`
+	FailedTasks int       `json:"failedTasks"`
+	// ErrorClasses counts the errors of failed tasks by class, as
+	// returned by ClassifyError.
+	ErrorClasses map[string]int `json:"errorClasses,omitempty"`
+	// Errors are the errors of the tool itself, as written to stderr.
+	Errors []string `json:"errors"`
+}
+
+// runResult is the result of the current run, completed as the run goes on.
+var runResult = RunResult{Files: []string{}, Findings: []Finding{}, Errors: []string{}}
+
+// setSummary records the findings of s in r.
+func (r *RunResult) setSummary(s DumpSummary) {
+	r.Criticals = s.CountFindings(StatusCritical)
+	r.Warnings = s.CountFindings(StatusWarning)
+	r.HealthScore = s.HealthScore()
+	if findings := s.Findings(); findings != nil {
+		r.Findings = findings
+	}
+}
+
+// setTaskErrors records the failed tasks among errs in r.
+func (r *RunResult) setTaskErrors(errs []error) {
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		r.FailedTasks++
+		if r.ErrorClasses == nil {
+			r.ErrorClasses = make(map[string]int)
+		}
+		r.ErrorClasses[ClassifyError(err)]++
+	}
+}
+
+// finish sets the exit code of r, and its outcome if not set yet.
+func (r *RunResult) finish(exitCode int) {
+	r.ExitCode = exitCode
+	if r.Outcome != "" {
+		return
+	}
+	switch {
+	case exitCode == 0:
+		r.Outcome = runOK
+	case r.Dump != "":
+		r.Outcome = runPartial
+	default:
+		r.Outcome = runFailed
+	}
+}
+
+// WriteRunResult writes r as a single JSON document to w.
+func WriteRunResult(w io.Writer, r RunResult) error {
+	output, err := json.MarshalIndent(r, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", output)
+	return err
+}
+
+// writeResult is true if runResult is written to stdout on exit, with -output
+// json.
+var writeResult bool
+
+// exit exits with exitCode, writing runResult to stdout first if writeResult is
+// true.
+func exit(exitCode int) {
+	if writeResult {
+		runResult.finish(exitCode)
+		if err := WriteRunResult(os.Stdout, runResult); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		}
+	}
+	os.Exit(exitCode)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestRunResultFinish(t *testing.T) {
+	tests := []struct {
+		r        RunResult
+		exitCode int
+		want     string
+	}{
+		{RunResult{Dump: "dump.tar.gz"}, 0, runOK},
+		{RunResult{Dump: "dump.tar.gz"}, 1, runPartial},
+		{RunResult{}, 1, runFailed},
+		{RunResult{Dump: "dump.tar.gz", Outcome: runInterrupted}, 1, runInterrupted},
+		{RunResult{Outcome: runNotLoggedIn}, exitNotLoggedIn, runNotLoggedIn},
+	}
+	for _, tt := range tests {
+		r := tt.r
+		r.finish(tt.exitCode)
+		if r.Outcome != tt.want || r.ExitCode != tt.exitCode {
+			t.Errorf("finish(%d) of %+v: outcome %q, exit code %d, want %q, %d", tt.exitCode, tt.r, r.Outcome, r.ExitCode, tt.want, tt.exitCode)
+		}
+	}
+}
+
+func TestWriteRunResult(t *testing.T) {
+	r := RunResult{Dump: "dump.tar.gz", Files: []string{"dump.tar.gz"}, Findings: []Finding{}, Errors: []string{}}
+	r.setTaskErrors([]error{
+		nil,
+		&CmdError{Args: []string{"oc", "get", "pods"}, Err: &exec.ExitError{}, Stderr: "Error from server (Forbidden): pods is forbidden"},
+		errors.New("something else"),
+	})
+	r.finish(1)
+
+	var b bytes.Buffer
+	if err := WriteRunResult(&b, r); err != nil {
+		t.Fatal(err)
+	}
+	var got RunResult
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("WriteRunResult() wrote invalid JSON %s: %v", b.String(), err)
+	}
+	if got.Outcome != runPartial || got.FailedTasks != 2 || !reflect.DeepEqual(got.ErrorClasses, map[string]int{classForbidden: 1, classOther: 1}) {
+		t.Errorf("WriteRunResult() wrote %+v, want a partial dump with a forbidden and an other failure", got)
+	}
+}
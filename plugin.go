@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// A CheckPlugin is an external program that analyses a dump. It is run with
+// the path to the dump archive as its last argument, and must write a JSON
+// array of findings to its standard output, in the same format as the
+// findings of webhook notifications:
+//
+//	[{"project": "core", "result": {"checkId": "...", "status": 1, ...}}]
+type CheckPlugin struct {
+	Name string `json:"name"`
+	// Command is the program and the arguments preceding the dump path.
+	Command []string `json:"command"`
+}
+
+// pluginResultsPath returns the path to the file next to a dump archive that
+// holds the findings of check plugins.
+func pluginResultsPath(dumpPath string) string {
+	return trimArchiveExtension(dumpPath) + ".plugins.json"
+}
+
+// RunCheckPlugins runs each plugin against the dump archive at dumpPath,
+// giving up on a plugin after timeout. The findings of all plugins that
+// succeeded are returned, and written to the plugin results file of the dump.
+func RunCheckPlugins(plugins []CheckPlugin, dumpPath string, timeout time.Duration) ([]Finding, error) {
+	var (
+		findings = []Finding{}
+		errors   errorList
+	)
+	for _, p := range plugins {
+		f, err := runCheckPlugin(p, dumpPath, timeout)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("check plugin %s: %v", p.Name, err))
+			continue
+		}
+		findings = append(findings, f...)
+	}
+	output, err := json.MarshalIndent(findings, "", "    ")
+	if err != nil {
+		errors = append(errors, err)
+	} else if err := ioutil.WriteFile(pluginResultsPath(dumpPath), output, 0660); err != nil {
+		errors = append(errors, err)
+	}
+	if len(errors) > 0 {
+		return findings, errors
+	}
+	return findings, nil
+}
+
+func runCheckPlugin(p CheckPlugin, dumpPath string, timeout time.Duration) ([]Finding, error) {
+	if len(p.Command) == 0 {
+		return nil, fmt.Errorf("no command")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, p.Command[0], append(p.Command[1:], dumpPath)...)
+	var stdout bytes.Buffer
+	if err := runCmdCaptureOutput(ctx, cmd, &stdout, nil); err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	if err := json.Unmarshal(stdout.Bytes(), &findings); err != nil {
+		return nil, fmt.Errorf("invalid output: %v", err)
+	}
+	for i := range findings {
+		if findings[i].Result.CheckID == "" {
+			findings[i].Result.CheckID = p.Name
+		}
+	}
+	return findings, nil
+}
+
+// addFindings adds findings to the results of s.
+func (s *DumpSummary) addFindings(findings []Finding) {
+	for _, f := range findings {
+		s.Results[f.Project] = append(s.Results[f.Project], f.Result)
+	}
+}
+
+// loadPluginResults adds the findings of check plugins stored next to the
+// dump archive of s, if any.
+func (s *DumpSummary) loadPluginResults() error {
+	content, err := ioutil.ReadFile(pluginResultsPath(s.Path))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var findings []Finding
+	if err := json.Unmarshal(content, &findings); err != nil {
+		return fmt.Errorf("%s: %v", pluginResultsPath(s.Path), err)
+	}
+	s.addFindings(findings)
+	return nil
+}
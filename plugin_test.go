@@ -0,0 +1,38 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunCheckPlugins(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dumpPath := filepath.Join(dir, "2016-09-01T12-00-00Z.tar.gz")
+
+	plugins := []CheckPlugin{
+		{Name: "custom", Command: []string{"sh", "-c", `echo '[{"project": "core", "result": {"status": 1, "info": [{"Name": "fh-aaa"}]}}]'`}},
+		{Name: "broken", Command: []string{"sh", "-c", "echo not json"}},
+	}
+	findings, err := RunCheckPlugins(plugins, dumpPath, time.Second)
+	if err == nil {
+		t.Error("RunCheckPlugins() didn't return the error of the broken plugin")
+	}
+	if len(findings) != 1 || findings[0].Project != "core" || findings[0].Result.CheckID != "custom" {
+		t.Fatalf("RunCheckPlugins() = %+v, want one finding of the custom plugin", findings)
+	}
+
+	summary := DumpSummary{Path: dumpPath, Results: map[string][]Result{}}
+	if err := summary.loadPluginResults(); err != nil {
+		t.Fatal(err)
+	}
+	if got := summary.CountFindings(StatusWarning); got != 1 {
+		t.Errorf("CountFindings(StatusWarning) after loading plugin results = %d, want 1", got)
+	}
+}
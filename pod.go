@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/feedhenry/fh-system-dump-tool/dumpformat"
+)
+
+// podExecCommands are the commands run in each container of the pod by the
+// pod subcommand, by name. Environment variables are redacted like any other
+// file of the dump.
+var podExecCommands = []struct{ name, script string }{
+	{"df", "df -h"},
+	{"env", "env | sort"},
+}
+
+// parsePodArg parses the argument of the pod subcommand, of the form
+// project/pod.
+func parsePodArg(arg string) (project, name string, err error) {
+	parts := strings.Split(arg, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid pod %q, must be of the form project/pod", arg)
+	}
+	return parts[0], parts[1], nil
+}
+
+// podEvents returns the items of the events list in content that involve the
+// named pod, as a list.
+func podEvents(content []byte, name string) ([]byte, error) {
+	var list struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(content, &list); err != nil {
+		return nil, err
+	}
+	events := struct {
+		Kind  string            `json:"kind"`
+		Items []json.RawMessage `json:"items"`
+	}{Kind: "List", Items: []json.RawMessage{}}
+	for _, item := range list.Items {
+		var event struct {
+			InvolvedObject struct {
+				Kind string `json:"kind"`
+				Name string `json:"name"`
+			} `json:"involvedObject"`
+		}
+		if err := json.Unmarshal(item, &event); err != nil {
+			return nil, err
+		}
+		if event.InvolvedObject.Kind == "Pod" && event.InvolvedObject.Name == name {
+			events.Items = append(events.Items, item)
+		}
+	}
+	return json.MarshalIndent(events, "", "    ")
+}
+
+// GetPodTasks returns a list of tasks collecting everything about the named
+// pod in project: its definition and description, the logs of all its
+// containers including init containers, its events, the output of
+// podExecCommands in its containers and the node it runs on.
+func GetPodTasks(ctx context.Context, project, name string, tarFile *Archive) ([]Task, error) {
+	var pod Pod
+	if err := getResourceStruct(ctx, project, "pod/"+name, &pod); err != nil {
+		return nil, err
+	}
+	var tasks []Task
+	base := "pod-" + name
+
+	// Add task to fetch the definition of the pod.
+	{
+		out := tarFile.GetWriterToFile(filepath.Join("definitions", "projects", project, base+".json"))
+		errOut := tarFile.GetWriterToFile(filepath.Join("definitions", "projects", project, base+".stderr"))
+		cmd := ocCommand("-n", project, "get", "pod", name, "-o=json")
+		task := func(ctx context.Context) error {
+			defer out.Close()
+			defer errOut.Close()
+			return runCmdCaptureOutput(ctx, cmd, out, errOut)
+		}
+		tasks = append(tasks, namedTask("fetch definition of pod "+name, project, task))
+	}
+
+	// Add task to describe the pod.
+	{
+		out := tarFile.GetWriterToFile(filepath.Join("describe", "projects", project, base+".txt"))
+		errOut := tarFile.GetWriterToFile(filepath.Join("describe", "projects", project, base+".stderr"))
+		task := func(ctx context.Context) error {
+			defer out.Close()
+			defer errOut.Close()
+			return DescribePod(project, name, out, errOut)(ctx)
+		}
+		tasks = append(tasks, namedTask("describe pod "+name, project, task))
+	}
+
+	// Add task to fetch the events involving the pod.
+	{
+		var events bytes.Buffer
+		cmd := ocCommand("-n", project, "get", "events", "-o=json")
+		task := func(ctx context.Context) error {
+			if err := runCmdCaptureOutput(ctx, cmd, &events, nil); err != nil {
+				return err
+			}
+			content, err := podEvents(events.Bytes(), name)
+			if err != nil {
+				return err
+			}
+			return tarFile.AddRedactedFile(content, filepath.Join("definitions", "projects", project, base+"-events.json"))
+		}
+		tasks = append(tasks, namedTask("fetch events of pod "+name, project, task))
+	}
+
+	// Add tasks to fetch current and previous logs of all containers.
+	var containers []string
+	for _, c := range pod.Spec.InitContainers {
+		containers = append(containers, c.Name)
+	}
+	for _, c := range pod.Spec.Containers {
+		containers = append(containers, c.Name)
+	}
+	for _, container := range containers {
+		r := LoggableResource{Project: project, Type: "pod", Name: name, Container: container}
+		for _, logs := range []struct {
+			dir   string
+			fetch func(LoggableResource, int, io.Writer, io.Writer) Task
+			desc  string
+		}{
+			{"logs", FetchLogs, "fetch logs of "},
+			{"logs-previous", FetchPreviousLogs, "fetch previous logs of "},
+		} {
+			logs := logs
+			out := tarFile.GetWriterToFile(filepath.Join(logs.dir, "projects", project, base+"-"+container+".logs"))
+			errOut := tarFile.GetWriterToFile(filepath.Join(logs.dir, "projects", project, base+"-"+container+".stderr"))
+			task := func(ctx context.Context) error {
+				defer out.Close()
+				defer errOut.Close()
+				return fetchLogsMaybeDeduped(ctx, logs.fetch, r, out, errOut)
+			}
+			tasks = append(tasks, namedTask(logs.desc+r.describe(), project, task))
+		}
+	}
+
+	// Add tasks to run podExecCommands in the containers, which only
+	// works while the pod is running.
+	if pod.Status.Phase == "Running" {
+		for _, c := range pod.Spec.Containers {
+			for _, e := range podExecCommands {
+				file := filepath.Join("exec", "projects", project, base+"-"+c.Name+"-"+e.name)
+				out := tarFile.GetWriterToFile(file + ".txt")
+				errOut := tarFile.GetWriterToFile(file + ".stderr")
+				cmd := ocCommand("-n", project, "exec", name, "-c", c.Name, "--", "sh", "-c", e.script)
+				task := func(ctx context.Context) error {
+					defer out.Close()
+					defer errOut.Close()
+					return runCmdCaptureOutput(ctx, cmd, out, errOut)
+				}
+				tasks = append(tasks, namedTask("run "+e.name+" in container "+c.Name+" of pod "+name, project, task))
+			}
+		}
+	}
+
+	// Add tasks to fetch the definition and description of the node.
+	if node := pod.Spec.NodeName; node != "" {
+		for _, n := range []struct {
+			args []string
+			ext  string
+		}{
+			{[]string{"get", "node", node, "-o=json"}, ".json"},
+			{[]string{"describe", "node", node}, ".txt"},
+		} {
+			out := tarFile.GetWriterToFile(filepath.Join("nodes", node+n.ext))
+			errOut := tarFile.GetWriterToFile(filepath.Join("nodes", node+n.ext+".stderr"))
+			cmd := ocCommand(n.args...)
+			task := func(ctx context.Context) error {
+				defer out.Close()
+				defer errOut.Close()
+				return runCmdCaptureOutput(ctx, cmd, out, errOut)
+			}
+			tasks = append(tasks, namedTask(n.args[0]+" node "+node, "", task))
+		}
+	}
+	return tasks, nil
+}
+
+// podCommand collects everything about a single pod into a mini-dump, for
+// quick targeted investigations.
+func podCommand(args []string) error {
+	fs := flag.NewFlagSet("pod", flag.ExitOnError)
+	dir := fs.String("dir", filepath.Join(dumpDir, "pods"), "directory to write the mini-dump to")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: pod [-dir dir] project/pod")
+	}
+	project, name, err := parsePodArg(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	metadata := dumpformat.Metadata{Projects: []string{}, Pod: project + "/" + name}
+	return writeMiniDump(*dir, project+"-"+name, metadata, func(ctx context.Context, tarFile *Archive) ([]Task, error) {
+		return GetPodTasks(ctx, project, name, tarFile)
+	})
+}
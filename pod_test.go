@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/feedhenry/fh-system-dump-tool/cmdrunner"
+)
+
+func TestParsePodArg(t *testing.T) {
+	project, name, err := parsePodArg("core/millicore-1-abcde")
+	if err != nil || project != "core" || name != "millicore-1-abcde" {
+		t.Errorf("parsePodArg() = %q, %q, %v, want core, millicore-1-abcde", project, name, err)
+	}
+	for _, arg := range []string{"core", "core/", "/pod", "core/pod/extra"} {
+		if _, _, err := parsePodArg(arg); err == nil {
+			t.Errorf("parsePodArg(%q) didn't return an error", arg)
+		}
+	}
+}
+
+func TestPodEvents(t *testing.T) {
+	content := []byte(`{"items": [
+		{"involvedObject": {"kind": "Pod", "name": "pod-1"}, "reason": "BackOff"},
+		{"involvedObject": {"kind": "Pod", "name": "pod-2"}, "reason": "Pulled"},
+		{"involvedObject": {"kind": "DeploymentConfig", "name": "pod-1"}, "reason": "DeploymentCreated"}
+	]}`)
+	got, err := podEvents(content, "pod-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events Events
+	if err := json.Unmarshal(got, &events); err != nil {
+		t.Fatal(err)
+	}
+	if len(events.Items) != 1 || events.Items[0].Reason != "BackOff" {
+		t.Errorf("podEvents() = %s, want only the BackOff event of pod-1", got)
+	}
+}
+
+func TestGetPodTasks(t *testing.T) {
+	defer func(old cmdrunner.Runner) { runner = old }(runner)
+	pod := `{"spec": {"nodeName": "node-1", "initContainers": [{"name": "init"}], "containers": [{"name": "app"}]}, "status": {"phase": "Running"}}`
+	runner = cmdrunner.NewFakeRunner([]cmdrunner.Invocation{{Args: ocCommand("-n", "core", "get", "pod/pod-1", "-o=json").Args, Stdout: pod}})
+
+	var b bytes.Buffer
+	tarFile, err := NewTgz(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := GetPodTasks(context.Background(), "core", "pod-1", tarFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Definition, description and events, current and previous logs of
+	// both containers, df and env in the app container and the node.
+	if got, want := len(tasks), 3+2*2+2+2; got != want {
+		t.Errorf("GetPodTasks() returned %d tasks, want %d", got, want)
+	}
+}
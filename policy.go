@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+)
+
+// whoCanActions are the actions whose authorized users and groups are
+// recorded in each project, for post-incident analysis of who could have
+// deleted pods or changed deploymentconfigs. There is no edit verb, editing
+// resources is updating or patching them.
+var whoCanActions = []struct {
+	verb     string
+	resource string
+}{
+	{"delete", "pods"},
+	{"update", "deploymentconfigs"},
+	{"patch", "deploymentconfigs"},
+}
+
+// GetWhoCanTasks returns a list of tasks to record the users, groups and
+// service accounts allowed to perform each of whoCanActions in each project, to
+// policy/projects/<project>/who-can-<verb>-<resource>.txt. oc adm policy
+// who-can requires cluster-admin.
+func GetWhoCanTasks(projects []string, tarFile *Archive) []Task {
+	var tasks []Task
+	for _, p := range projects {
+		for _, a := range whoCanActions {
+			base := filepath.Join("policy", "projects", p, "who-can-"+a.verb+"-"+a.resource)
+			out := tarFile.GetWriterToFile(base + ".txt")
+			errOut := tarFile.GetWriterToFile(base + ".stderr")
+			cmd := ocCommand("-n", p, "adm", "policy", "who-can", a.verb, a.resource)
+			task := func(ctx context.Context) error {
+				defer out.Close()
+				defer errOut.Close()
+				return runCmdCaptureOutput(ctx, cmd, out, errOut)
+			}
+			tasks = append(tasks, namedTask("record who can "+a.verb+" "+a.resource, p, task))
+		}
+	}
+	return tasks
+}
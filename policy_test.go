@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/feedhenry/fh-system-dump-tool/cmdrunner"
+)
+
+func TestGetWhoCanTasks(t *testing.T) {
+	defer func(old cmdrunner.Runner) { runner = old }(runner)
+	runner = cmdrunner.NewFakeRunner([]cmdrunner.Invocation{
+		{Args: ocCommand("-n", "dev", "adm", "policy", "who-can", "delete", "pods").Args, Stdout: "Users: system:admin\n"},
+	})
+
+	var b bytes.Buffer
+	tarFile, err := NewTgz(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks := GetWhoCanTasks([]string{"dev"}, tarFile)
+	if got, want := len(tasks), len(whoCanActions); got != want {
+		t.Fatalf("GetWhoCanTasks() returned %d tasks, want %d", got, want)
+	}
+	if err := tasks[0](context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := tarFile.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"policy/projects/dev/who-can-delete-pods.txt", "policy/projects/dev/who-can-delete-pods.stderr",
+		"policy/projects/dev/who-can-update-deploymentconfigs.txt", "policy/projects/dev/who-can-update-deploymentconfigs.stderr",
+		"policy/projects/dev/who-can-patch-deploymentconfigs.txt", "policy/projects/dev/who-can-patch-deploymentconfigs.stderr",
+	}
+	if got := tarFile.RequestedFiles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("RequestedFiles() = %v, want %v", got, want)
+	}
+	if got := tarFile.Manifest().Files; len(got) != 2 || got[0].Name != want[1] || got[1].Name != want[0] {
+		t.Errorf("Manifest() = %+v, want the output and errors of the first task", got)
+	}
+}
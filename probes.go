@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// minProbeTimeout is the smallest probe timeout, in seconds, suggested
+	// for probes that time out.
+	minProbeTimeout = 5
+	// probeStartupMargin is the margin added to the observed startup time
+	// of a component when suggesting the initial delay of its probes.
+	probeStartupMargin = 1.2
+)
+
+// Defaults applied by OpenShift to unset probe parameters.
+const (
+	defaultProbeTimeout          = 1
+	defaultProbePeriod           = 10
+	defaultProbeFailureThreshold = 3
+)
+
+// probeTimedOut matches the messages of probe failures caused by the probe
+// not answering in time.
+var probeTimedOut = regexp.MustCompile(`(?i)timeout|timed out|deadline exceeded`)
+
+// containerFieldPath matches the field path of the container involved in an
+// event.
+var containerFieldPath = regexp.MustCompile(`^spec\.containers\{(.+)\}$`)
+
+// A probeKey identifies a probe of a container in a deployment config.
+type probeKey struct {
+	DeploymentConfig string
+	Container        string
+	// Kind is either "readiness" or "liveness".
+	Kind string
+}
+
+// probeFailures counts the Unhealthy events of a probe.
+type probeFailures struct {
+	Count    int
+	Timeouts int
+}
+
+// CheckProbeTimeouts will check the Unhealthy events in the supplied project against the probes configured in the
+// deployconfigs, and if any probe is too aggressive for the observed startup time or latency of its component this
+// will be reflected in the returned Result data. Any errors are written to the supplied stdErr writer
+func CheckProbeTimeouts(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+	result := Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: "probe-too-aggressive", CheckName: "check probe timeouts against observed startup times"}
+	var events Events
+	if err := getResourceStruct(ctx, project, "events", &events); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	var dcs DeploymentConfigs
+	if err := getResourceStruct(ctx, project, "dc", &dcs); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	var pods Pods
+	if err := getResourceStruct(ctx, project, "pods", &pods); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	return checkProbeTimeouts(result, events, dcs, pods), nil
+}
+
+func checkProbeTimeouts(result Result, events Events, dcs DeploymentConfigs, pods Pods) Result {
+	failures := unhealthyProbes(events)
+	startups := observedStartups(pods)
+	for _, dc := range dcs.Items {
+		for _, c := range dc.Spec.Template.Spec.Containers {
+			for _, kind := range []string{"readiness", "liveness"} {
+				probe := c.ReadinessProbe
+				if kind == "liveness" {
+					probe = c.LivenessProbe
+				}
+				f, ok := failures[probeKey{dc.Metadata.Name, c.Name, kind}]
+				if probe == nil || !ok {
+					continue
+				}
+				startup := startups[dc.Metadata.Name+"/"+c.Name]
+				if message, ok := probeAdvice(kind, *probe, f, startup); ok {
+					result.Status = StatusWarning
+					result.StatusMessage = "one or more probes are configured too aggressively for their components"
+					result.Info = append(result.Info, Info{Name: dc.Metadata.Name, Namespace: dc.Metadata.Namespace, Kind: dc.Kind, Count: f.Count,
+						Message: fmt.Sprintf("container %s: %s", c.Name, message)})
+				}
+			}
+		}
+	}
+	return result
+}
+
+// probeAdvice returns a description of why probe is too aggressive, with
+// suggested values, given its failures and the observed startup time of its
+// container. It returns false if the probe seems fine.
+func probeAdvice(kind string, probe Probe, f probeFailures, startup time.Duration) (string, bool) {
+	timeout, period, threshold := probe.TimeoutSeconds, probe.PeriodSeconds, probe.FailureThreshold
+	if timeout == 0 {
+		timeout = defaultProbeTimeout
+	}
+	if period == 0 {
+		period = defaultProbePeriod
+	}
+	if threshold == 0 {
+		threshold = defaultProbeFailureThreshold
+	}
+	var problems, suggestions []string
+	if f.Timeouts > 0 && timeout < minProbeTimeout {
+		problems = append(problems, fmt.Sprintf("%d of %d failures timed out after %ds", f.Timeouts, f.Count, timeout))
+		suggestions = append(suggestions, fmt.Sprintf("timeoutSeconds=%d", minProbeTimeout))
+	}
+	// The probe gives up on the container after its initial delay and
+	// failureThreshold failed attempts.
+	window := time.Duration(probe.InitialDelaySeconds+period*threshold) * time.Second
+	if startup > window {
+		problems = append(problems, fmt.Sprintf("startup takes %v but the probe fails after %v", startup, window))
+		delay := int(math.Ceil(startup.Seconds() * probeStartupMargin))
+		suggestions = append(suggestions, fmt.Sprintf("initialDelaySeconds=%d", delay))
+	}
+	if len(problems) == 0 {
+		return "", false
+	}
+	return fmt.Sprintf("the %s probe (initialDelaySeconds=%d, timeoutSeconds=%d, periodSeconds=%d, failureThreshold=%d) is too aggressive: %s; suggested %s",
+		kind, probe.InitialDelaySeconds, timeout, period, threshold, strings.Join(problems, ", "), strings.Join(suggestions, ", ")), true
+}
+
+// unhealthyProbes counts the failures of the probes of pods created by
+// deployments, from Unhealthy events.
+func unhealthyProbes(events Events) map[probeKey]probeFailures {
+	failures := make(map[probeKey]probeFailures)
+	for _, event := range events.Items {
+		if event.Reason != "Unhealthy" || event.InvolvedObject.Kind != "Pod" {
+			continue
+		}
+		pod := deploymentPodName.FindStringSubmatch(event.InvolvedObject.Name)
+		container := containerFieldPath.FindStringSubmatch(event.InvolvedObject.FieldPath)
+		if pod == nil || container == nil {
+			continue
+		}
+		var kind string
+		switch {
+		case strings.HasPrefix(event.Message, "Readiness probe failed"):
+			kind = "readiness"
+		case strings.HasPrefix(event.Message, "Liveness probe failed"):
+			kind = "liveness"
+		default:
+			continue
+		}
+		count := event.Count
+		if count == 0 {
+			count = 1
+		}
+		key := probeKey{pod[1], container[1], kind}
+		f := failures[key]
+		f.Count += count
+		if probeTimedOut.MatchString(event.Message) {
+			f.Timeouts += count
+		}
+		failures[key] = f
+	}
+	return failures
+}
+
+// observedStartups returns the longest time containers of pods created by
+// deployments took to become ready, by deployment config and container name
+// joined with a slash.
+func observedStartups(pods Pods) map[string]time.Duration {
+	startups := make(map[string]time.Duration)
+	for _, pod := range pods.Items {
+		match := deploymentPodName.FindStringSubmatch(pod.Metadata.Name)
+		if match == nil {
+			continue
+		}
+		var ready time.Time
+		for _, c := range pod.Status.Conditions {
+			if c.Type == "Ready" && c.Status == "True" {
+				ready = c.LastTransitionTime
+			}
+		}
+		if ready.IsZero() {
+			continue
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.State.Running == nil || cs.State.Running.StartedAt.IsZero() {
+				continue
+			}
+			key := match[1] + "/" + cs.Name
+			if d := ready.Sub(cs.State.Running.StartedAt); d > startups[key] {
+				startups[key] = d
+			}
+		}
+	}
+	return startups
+}
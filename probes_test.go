@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckProbeTimeouts(t *testing.T) {
+	var (
+		events Events
+		dcs    DeploymentConfigs
+		pods   Pods
+	)
+	for js, v := range map[string]interface{}{
+		`{"items": [
+			{"reason": "Unhealthy", "count": 6, "message": "Readiness probe failed: Get http://10.1.2.3:8080/sys/info/ping: net/http: request canceled (Client.Timeout exceeded while awaiting headers)",
+			 "involvedObject": {"kind": "Pod", "name": "fh-ngui-3-abcde", "fieldPath": "spec.containers{fh-ngui}"}},
+			{"reason": "Unhealthy", "count": 3, "message": "Liveness probe failed: dial tcp 10.1.2.4:8080: getsockopt: connection refused",
+			 "involvedObject": {"kind": "Pod", "name": "millicore-2-fghij", "fieldPath": "spec.containers{millicore}"}},
+			{"reason": "Unhealthy", "count": 1, "message": "Readiness probe failed: HTTP probe failed with statuscode: 500",
+			 "involvedObject": {"kind": "Pod", "name": "fh-aaa-1-klmno", "fieldPath": "spec.containers{fh-aaa}"}}
+		]}`: &events,
+		`{"items": [
+			{"kind": "DeploymentConfig", "metadata": {"name": "fh-ngui", "namespace": "core"}, "spec": {"template": {"spec": {"containers": [
+				{"name": "fh-ngui", "readinessProbe": {"initialDelaySeconds": 10, "timeoutSeconds": 1}}]}}}},
+			{"kind": "DeploymentConfig", "metadata": {"name": "millicore", "namespace": "core"}, "spec": {"template": {"spec": {"containers": [
+				{"name": "millicore", "livenessProbe": {"initialDelaySeconds": 30, "timeoutSeconds": 10, "periodSeconds": 10, "failureThreshold": 3}}]}}}},
+			{"kind": "DeploymentConfig", "metadata": {"name": "fh-aaa", "namespace": "core"}, "spec": {"template": {"spec": {"containers": [
+				{"name": "fh-aaa", "readinessProbe": {"initialDelaySeconds": 10, "timeoutSeconds": 5}}]}}}}
+		]}`: &dcs,
+		`{"items": [
+			{"metadata": {"name": "millicore-2-fghij"}, "status": {
+				"conditions": [{"type": "Ready", "status": "True", "lastTransitionTime": "2017-06-01T10:02:00Z"}],
+				"containerStatuses": [{"name": "millicore", "state": {"running": {"startedAt": "2017-06-01T10:00:00Z"}}}]}},
+			{"metadata": {"name": "fh-aaa-1-klmno"}, "status": {
+				"conditions": [{"type": "Ready", "status": "True", "lastTransitionTime": "2017-06-01T10:00:20Z"}],
+				"containerStatuses": [{"name": "fh-aaa", "state": {"running": {"startedAt": "2017-06-01T10:00:00Z"}}}]}}
+		]}`: &pods,
+	} {
+		if err := json.Unmarshal([]byte(js), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result := checkProbeTimeouts(Result{Status: StatusOK}, events, dcs, pods)
+	if result.Status != StatusWarning {
+		t.Fatalf("Status = %d, want %d", result.Status, StatusWarning)
+	}
+	if len(result.Info) != 2 {
+		t.Fatalf("Info = %+v, want findings for fh-ngui and millicore", result.Info)
+	}
+	for i, want := range []struct{ name, message string }{
+		{"fh-ngui", "6 of 6 failures timed out after 1s; suggested timeoutSeconds=5"},
+		{"millicore", "startup takes 2m0s but the probe fails after 1m0s; suggested initialDelaySeconds=144"},
+	} {
+		if info := result.Info[i]; info.Name != want.name || !strings.HasSuffix(info.Message, want.message) {
+			t.Errorf("Info[%d] = %+v, want %s with message ending in %q", i, info, want.name, want.message)
+		}
+	}
+}
+
+func TestObservedStartups(t *testing.T) {
+	var pods Pods
+	if err := json.Unmarshal([]byte(`{"items": [
+		{"metadata": {"name": "fh-mbaas-1-abcde"}, "status": {
+			"conditions": [{"type": "Ready", "status": "False", "lastTransitionTime": "2017-06-01T10:05:00Z"}],
+			"containerStatuses": [{"name": "fh-mbaas", "state": {"running": {"startedAt": "2017-06-01T10:00:00Z"}}}]}},
+		{"metadata": {"name": "fh-mbaas-1-fghij"}, "status": {
+			"conditions": [{"type": "Ready", "status": "True", "lastTransitionTime": "2017-06-01T10:00:30Z"}],
+			"containerStatuses": [{"name": "fh-mbaas", "state": {"running": {"startedAt": "2017-06-01T10:00:00Z"}}}]}}
+	]}`), &pods); err != nil {
+		t.Fatal(err)
+	}
+	startups := observedStartups(pods)
+	if got, want := startups["fh-mbaas/fh-mbaas"], 30*time.Second; got != want {
+		t.Errorf("observedStartups()[fh-mbaas/fh-mbaas] = %v, want %v", got, want)
+	}
+}
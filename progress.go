@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// progressInterval is how often the progress of tasks is redrawn on
+	// terminals.
+	progressInterval = time.Second
+	// progressLogInterval is how often the progress of tasks is written
+	// when it is not shown on a terminal.
+	progressLogInterval = 30 * time.Second
+	// progressWidth is the maximum width of progress lines.
+	progressWidth = 120
+)
+
+// A progress shows how many of a list of tasks are done, which are running and
+// when all of them should be done. On terminals, it is redrawn on a single
+// line, elsewhere it is written every progressLogInterval.
+type progress struct {
+	w        io.Writer
+	terminal bool
+	total    int
+	done     int
+	start    time.Time
+	lastLog  time.Time
+	running  map[int]bool
+	// name returns the name of the task at index i, or an empty string if
+	// it is not known yet.
+	name func(i int) string
+}
+
+// newProgress returns a progress of total tasks starting now, written to w.
+func newProgress(w io.Writer, total int, name func(i int) string) *progress {
+	now := time.Now()
+	return &progress{w: w, terminal: isTerminal(w), total: total, start: now, lastLog: now, running: make(map[int]bool), name: name}
+}
+
+// isTerminal reports whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// started records that the task at index i started.
+func (p *progress) started(i int) {
+	p.running[i] = true
+}
+
+// finished records that the task at index i is done, and shows the progress.
+func (p *progress) finished(i int) {
+	delete(p.running, i)
+	p.done++
+	p.show(time.Now())
+}
+
+// show draws the progress on terminals, and writes it elsewhere if it was
+// not written for progressLogInterval.
+func (p *progress) show(now time.Time) {
+	if p.terminal {
+		fmt.Fprintf(p.w, "\r%s\033[K", p.line(now))
+		return
+	}
+	if now.Sub(p.lastLog) >= progressLogInterval || p.done == p.total {
+		p.lastLog = now
+		fmt.Fprintln(p.w, p.line(now))
+	}
+}
+
+// end terminates the progress line on terminals.
+func (p *progress) end() {
+	if p.terminal {
+		fmt.Fprintln(p.w)
+	}
+}
+
+// line describes the progress at now.
+func (p *progress) line(now time.Time) string {
+	eta := "unknown"
+	if p.done > 0 {
+		elapsed := now.Sub(p.start)
+		eta = (elapsed * time.Duration(p.total-p.done) / time.Duration(p.done)).Round(time.Second).String()
+	}
+	percent := 100
+	if p.total > 0 {
+		percent = 100 * p.done / p.total
+	}
+	line := fmt.Sprintf("[%d/%d] %d%% done, ETA %s", p.done, p.total, percent, eta)
+	var running []int
+	for i := range p.running {
+		running = append(running, i)
+	}
+	sort.Ints(running)
+	var names []string
+	for _, i := range running {
+		if name := p.name(i); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		line += ", running: " + strings.Join(names, ", ")
+	}
+	if len(line) > progressWidth {
+		line = line[:progressWidth-3] + "..."
+	}
+	return line
+}
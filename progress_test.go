@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	var b bytes.Buffer
+	names := map[int]string{0: "fetch definitions", 1: "fetch logs of pod/fh-ngui-1-abcde"}
+	p := newProgress(&b, 4, func(i int) string { return names[i] })
+	if p.terminal {
+		t.Fatal("a buffer is not a terminal")
+	}
+	if got, want := p.line(p.start), "[0/4] 0% done, ETA unknown"; got != want {
+		t.Errorf("line() = %q, want %q", got, want)
+	}
+
+	for i := 0; i < 3; i++ {
+		p.started(i)
+	}
+	delete(p.running, 0)
+	p.done = 1
+	if got, want := p.line(p.start.Add(10*time.Second)), "[1/4] 25% done, ETA 30s, running: fetch logs of pod/fh-ngui-1-abcde"; got != want {
+		t.Errorf("line() = %q, want %q", got, want)
+	}
+
+	// Progress is only written every progressLogInterval, and once done.
+	p.show(p.start.Add(time.Second))
+	if b.Len() != 0 {
+		t.Errorf("show() wrote %q before progressLogInterval", b.String())
+	}
+	p.show(p.start.Add(progressLogInterval))
+	p.finished(1)
+	p.finished(2)
+	p.finished(3)
+	if lines := strings.Split(strings.TrimSpace(b.String()), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[1], "[4/4] 100% done") {
+		t.Errorf("show() wrote %q, want 2 lines ending with the completion", b.String())
+	}
+}
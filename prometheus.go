@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// prometheusContainer is the name of the container running Prometheus
+	// in Prometheus pods, alongside proxies and reloaders.
+	prometheusContainer = "prometheus"
+	// prometheusURL is where Prometheus listens, from inside its pod.
+	prometheusURL = "http://localhost:9090"
+)
+
+// prometheusExcluded are substrings of the names of pods that contain
+// "prometheus" without running Prometheus itself.
+var prometheusExcluded = []string{"operator", "exporter", "adapter", "alertmanager"}
+
+// isPrometheusPod reports whether the pod named name runs Prometheus.
+func isPrometheusPod(name string) bool {
+	if !strings.Contains(name, "prometheus") {
+		return false
+	}
+	for _, s := range prometheusExcluded {
+		if strings.Contains(name, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// A prometheusQuery is a query whose result is recorded in dumps.
+type prometheusQuery struct {
+	Name  string
+	Query string
+}
+
+// prometheusQueries returns the queries recording, for the pods of projects,
+// their CPU and memory usage, their restarts over the last hour and the usage
+// of their persistent volume claims, along with the alerts pending or firing.
+func prometheusQueries(projects []string) []prometheusQuery {
+	var quoted []string
+	for _, p := range projects {
+		quoted = append(quoted, regexp.QuoteMeta(p))
+	}
+	namespaces := fmt.Sprintf(`namespace=~"%s"`, strings.Join(quoted, "|"))
+	return []prometheusQuery{
+		{"cpu", fmt.Sprintf(`sum by (namespace, pod_name) (rate(container_cpu_usage_seconds_total{container_name!="", %s}[5m]))`, namespaces)},
+		{"memory", fmt.Sprintf(`sum by (namespace, pod_name) (container_memory_working_set_bytes{container_name!="", %s})`, namespaces)},
+		{"restarts", fmt.Sprintf(`sum by (namespace, pod) (increase(kube_pod_container_status_restarts_total{%s}[1h]))`, namespaces)},
+		{"pvc-usage", fmt.Sprintf(`kubelet_volume_stats_used_bytes{%s} / kubelet_volume_stats_capacity_bytes{%s}`, namespaces, namespaces)},
+		{"alerts", "ALERTS"},
+	}
+}
+
+// GetPrometheusPods returns the running Prometheus pods of the cluster, as
+// namespace/name, one per namespace, as replicas hold the same metrics.
+func GetPrometheusPods(ctx context.Context) ([]string, error) {
+	return getRunningPodsPerNamespace(ctx, isPrometheusPod)
+}
+
+// getRunningPodsPerNamespace returns the first running pod of each namespace,
+// by name, whose name is matched by match, as namespace/name.
+func getRunningPodsPerNamespace(ctx context.Context, match func(name string) bool) ([]string, error) {
+	pods, err := getSpaceSeparated(ctx, ocCommand("get", "pods", "--all-namespaces",
+		`-o=jsonpath={range .items[?(@.status.phase=="Running")]}{.metadata.namespace}/{.metadata.name} {end}`))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(pods)
+	var selected []string
+	seen := make(map[string]bool)
+	for _, pod := range pods {
+		parts := strings.SplitN(pod, "/", 2)
+		if len(parts) != 2 || seen[parts[0]] || !match(parts[1]) {
+			continue
+		}
+		seen[parts[0]] = true
+		selected = append(selected, pod)
+	}
+	return selected, nil
+}
+
+// GetPrometheusTasks returns a list of tasks to record the results of
+// prometheusQueries for the given projects, from each Prometheus pod, into
+// prometheus/<namespace>/<pod>/<query>.json. Queries run with curl in the
+// Prometheus container.
+// FIXME: GetPrometheusTasks should not know about tarFile.
+func GetPrometheusTasks(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+	pods, err := GetPrometheusPods(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []Task
+	for _, pod := range pods {
+		parts := strings.SplitN(pod, "/", 2)
+		namespace, name := parts[0], parts[1]
+		for _, q := range prometheusQueries(projects) {
+			dir := filepath.Join("prometheus", namespace, name)
+			out := tarFile.GetWriterToFile(filepath.Join(dir, q.Name+".json"))
+			errOut := tarFile.GetWriterToFile(filepath.Join(dir, q.Name+".stderr"))
+			cmd := ocCommand("-n", namespace, "exec", name, "-c", prometheusContainer, "--",
+				"curl", "-sS", "--fail", "-G", "--data-urlencode", "query="+q.Query, prometheusURL+"/api/v1/query")
+			task := func(ctx context.Context) error {
+				defer out.Close()
+				defer errOut.Close()
+				return runCmdCaptureOutput(ctx, cmd, out, errOut)
+			}
+			tasks = append(tasks, namedTask("query "+q.Name+" metrics from "+name, namespace, task))
+		}
+	}
+	return tasks, nil
+}
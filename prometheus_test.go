@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/feedhenry/fh-system-dump-tool/cmdrunner"
+)
+
+func TestIsPrometheusPod(t *testing.T) {
+	for name, want := range map[string]bool{
+		"prometheus-k8s-0":                   true,
+		"prometheus-0":                       true,
+		"prometheus-operator-7f9f8b7c-x2kq4": false,
+		"prometheus-node-exporter-abcde":     false,
+		"alertmanager-main-0":                false,
+		"nagios-1-abcde":                     false,
+	} {
+		if got := isPrometheusPod(name); got != want {
+			t.Errorf("isPrometheusPod(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestGetPrometheusPods(t *testing.T) {
+	defer func(r cmdrunner.Runner) { runner = r }(runner)
+	runner = cmdrunner.NewFakeRunner([]cmdrunner.Invocation{{
+		Args: ocCommand("get", "pods", "--all-namespaces",
+			`-o=jsonpath={range .items[?(@.status.phase=="Running")]}{.metadata.namespace}/{.metadata.name} {end}`).Args,
+		Stdout: "openshift-monitoring/prometheus-k8s-1 openshift-monitoring/prometheus-operator-1 openshift-monitoring/prometheus-k8s-0 rhmap-core/millicore-1-abcde openshift-metrics/prometheus-0 ",
+	}})
+	got, err := GetPrometheusPods(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"openshift-metrics/prometheus-0", "openshift-monitoring/prometheus-k8s-0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetPrometheusPods() = %v, want %v", got, want)
+	}
+}
+
+func TestPrometheusQueries(t *testing.T) {
+	for _, q := range prometheusQueries([]string{"rhmap-core", "rhmap-mbaas"}) {
+		if q.Name != "alerts" && !strings.Contains(q.Query, `namespace=~"rhmap-core|rhmap-mbaas"`) {
+			t.Errorf("query %s = %q, want it limited to the projects", q.Name, q.Query)
+		}
+	}
+}
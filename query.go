@@ -0,0 +1,398 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A queryStep is one step of a query, applied to each value selected by the
+// previous steps.
+type queryStep struct {
+	// field selects a field of objects. Arrays are searched element by
+	// element.
+	field string
+	// all selects all elements of arrays and values of objects.
+	all bool
+	// index selects an element of arrays, if not negative.
+	index int
+	// filter keeps the elements of arrays, or objects, for which the value
+	// selected by filterPath compares to filterValue with filterOp.
+	filter      bool
+	filterPath  []queryStep
+	filterOp    string
+	filterValue interface{}
+}
+
+// A query selects values from the resource definitions of a dump, with a
+// JSONPath-like syntax. The first step is the resource type, e.g.
+//
+//	pods[?status.phase=="Pending"].metadata.name
+type query struct {
+	resource string
+	steps    []queryStep
+}
+
+// parseQuery parses a query expression.
+func parseQuery(expr string) (query, error) {
+	var q query
+	steps, err := parseQuerySteps(expr)
+	if err != nil {
+		return q, fmt.Errorf("query %q: %v", expr, err)
+	}
+	if len(steps) == 0 || steps[0].field == "" {
+		return q, fmt.Errorf("query %q: must start with a resource type", expr)
+	}
+	q.resource, q.steps = steps[0].field, steps[1:]
+	return q, nil
+}
+
+func parseQuerySteps(expr string) ([]queryStep, error) {
+	var steps []queryStep
+	for len(expr) > 0 {
+		switch expr[0] {
+		case '.':
+			expr = expr[1:]
+		case '[':
+			end := matchingBracket(expr)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [")
+			}
+			step, err := parseBracket(expr[1:end])
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+			expr = expr[end+1:]
+		default:
+			n := strings.IndexAny(expr, ".[")
+			if n < 0 {
+				n = len(expr)
+			}
+			steps = append(steps, queryStep{field: expr[:n], index: -1})
+			expr = expr[n:]
+		}
+	}
+	return steps, nil
+}
+
+// matchingBracket returns the index of the ] closing the [ that s starts with,
+// ignoring brackets in quoted strings, or -1.
+func matchingBracket(s string) int {
+	depth, quoted := 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func parseBracket(s string) (queryStep, error) {
+	switch {
+	case s == "*":
+		return queryStep{all: true, index: -1}, nil
+	case strings.HasPrefix(s, `"`):
+		// Quoted field names may hold dots, like annotation names.
+		var field string
+		if err := json.Unmarshal([]byte(s), &field); err != nil || field == "" {
+			return queryStep{}, fmt.Errorf("invalid field name %s", s)
+		}
+		return queryStep{field: field, index: -1}, nil
+	case strings.HasPrefix(s, "?"):
+		s = strings.TrimSpace(s[1:])
+		if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+			s = s[1 : len(s)-1]
+		}
+		step := queryStep{filter: true, index: -1}
+		for _, op := range []string{"==", "!="} {
+			if i := strings.Index(s, op); i >= 0 {
+				step.filterOp = op
+				lhs := strings.TrimPrefix(strings.TrimSpace(s[:i]), "@.")
+				path, err := parseQuerySteps(lhs)
+				if err != nil {
+					return step, err
+				}
+				step.filterPath = path
+				if err := json.Unmarshal([]byte(strings.TrimSpace(s[i+len(op):])), &step.filterValue); err != nil {
+					return step, fmt.Errorf("invalid value in filter %q: %v", s, err)
+				}
+				return step, nil
+			}
+		}
+		return step, fmt.Errorf("filter %q must compare with == or !=", s)
+	default:
+		i, err := strconv.Atoi(s)
+		if err != nil || i < 0 {
+			return queryStep{}, fmt.Errorf("invalid index %q", s)
+		}
+		return queryStep{index: i}, nil
+	}
+}
+
+// evalQuery returns the values selected by steps in v.
+func evalQuery(v interface{}, steps []queryStep) []interface{} {
+	values := []interface{}{v}
+	for _, step := range steps {
+		var next []interface{}
+		for _, v := range values {
+			next = append(next, step.apply(v)...)
+		}
+		values = next
+	}
+	return values
+}
+
+func (step queryStep) apply(v interface{}) []interface{} {
+	var values []interface{}
+	a, isArray := v.([]interface{})
+	o, isObject := v.(map[string]interface{})
+	switch {
+	case step.field != "" && isArray:
+		for _, e := range a {
+			values = append(values, step.apply(e)...)
+		}
+	case step.field != "" && isObject:
+		if e, ok := o[step.field]; ok {
+			values = append(values, e)
+		}
+	case step.all && isArray:
+		values = append(values, a...)
+	case step.all && isObject:
+		keys := make([]string, 0, len(o))
+		for k := range o {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			values = append(values, o[k])
+		}
+	case step.index >= 0 && isArray:
+		if step.index < len(a) {
+			values = append(values, a[step.index])
+		}
+	case step.filter && isArray:
+		for _, e := range a {
+			if step.matches(e) {
+				values = append(values, e)
+			}
+		}
+	case step.filter && isObject:
+		if step.matches(o) {
+			values = append(values, o)
+		}
+	}
+	return values
+}
+
+// removeQuery removes the values selected by steps in v from the arrays and
+// objects holding them, and returns the new value and the number of values
+// removed.
+func removeQuery(v interface{}, steps []queryStep) (interface{}, int) {
+	if len(steps) == 0 {
+		return v, 0
+	}
+	step, rest := steps[0], steps[1:]
+	if len(rest) == 0 {
+		return step.remove(v)
+	}
+	a, isArray := v.([]interface{})
+	o, isObject := v.(map[string]interface{})
+	n := 0
+	switch {
+	case step.field != "" && isArray:
+		for i := range a {
+			var m int
+			a[i], m = removeQuery(a[i], steps)
+			n += m
+		}
+	case step.field != "" && isObject:
+		if e, ok := o[step.field]; ok {
+			o[step.field], n = removeQuery(e, rest)
+		}
+	case step.all && isArray:
+		for i := range a {
+			var m int
+			a[i], m = removeQuery(a[i], rest)
+			n += m
+		}
+	case step.all && isObject:
+		for k := range o {
+			var m int
+			o[k], m = removeQuery(o[k], rest)
+			n += m
+		}
+	case step.index >= 0 && isArray:
+		if step.index < len(a) {
+			a[step.index], n = removeQuery(a[step.index], rest)
+		}
+	case step.filter && isArray:
+		for i := range a {
+			if step.matches(a[i]) {
+				var m int
+				a[i], m = removeQuery(a[i], rest)
+				n += m
+			}
+		}
+	case step.filter && isObject:
+		if step.matches(o) {
+			return removeQuery(o, rest)
+		}
+	}
+	return v, n
+}
+
+// remove removes the values selected by step in v from v, and returns the new
+// value and the number of values removed.
+func (step queryStep) remove(v interface{}) (interface{}, int) {
+	a, isArray := v.([]interface{})
+	o, isObject := v.(map[string]interface{})
+	n := 0
+	switch {
+	case step.field != "" && isArray:
+		for i := range a {
+			var m int
+			a[i], m = step.remove(a[i])
+			n += m
+		}
+	case step.field != "" && isObject:
+		if _, ok := o[step.field]; ok {
+			delete(o, step.field)
+			n = 1
+		}
+	case step.all && isArray:
+		return []interface{}{}, len(a)
+	case step.all && isObject:
+		for k := range o {
+			delete(o, k)
+			n++
+		}
+	case step.index >= 0 && isArray:
+		if step.index < len(a) {
+			return append(a[:step.index:step.index], a[step.index+1:]...), 1
+		}
+	case step.filter && isArray:
+		kept := []interface{}{}
+		for _, e := range a {
+			if !step.matches(e) {
+				kept = append(kept, e)
+			}
+		}
+		return kept, len(a) - len(kept)
+	}
+	return v, n
+}
+
+// matches reports whether the filter of step keeps v.
+func (step queryStep) matches(v interface{}) bool {
+	equal := false
+	for _, selected := range evalQuery(v, step.filterPath) {
+		if fmt.Sprint(selected) == fmt.Sprint(step.filterValue) {
+			equal = true
+			break
+		}
+	}
+	return equal == (step.filterOp == "==")
+}
+
+// isResourceDefinition returns a function reporting whether an archive file
+// holds the definitions of resource.
+func isResourceDefinition(resource string) func(name string) bool {
+	return func(name string) bool {
+		return strings.HasPrefix(name, "definitions/projects/") && path.Base(name) == resource+".json"
+	}
+}
+
+// queryCommand prints the values selected by a query expression in the
+// resource definitions of a dump.
+func queryCommand(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	project := fs.String("project", "", "only query the resources of this project")
+	dump := fs.String("dump", "", "path to the dump archive to query (defaults to the latest dump)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: query [-project name] [-dump path] expression")
+	}
+	q, err := parseQuery(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *dump == "" {
+		if *dump, err = FindLatestDump(dumpDir); err != nil {
+			return err
+		}
+		if *dump == "" {
+			return fmt.Errorf("no dump found in %s", dumpDir)
+		}
+	}
+	f, err := os.Open(*dump)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	isDefinition := isResourceDefinition(q.resource)
+	files, err := ReadTgz(f, func(name string) bool { return name == "metadata.json" || isDefinition(name) })
+	if err != nil {
+		return err
+	}
+	if err := checkDumpLayout(files); err != nil {
+		return fmt.Errorf("%s: %v", *dump, err)
+	}
+	delete(files, "metadata.json")
+	return runQuery(os.Stdout, q, files, *project)
+}
+
+// runQuery writes the values selected by q in files, the resource definitions
+// of each project, optionally limited to one project. Each value is written
+// on its own line, preceded by the project if querying all projects.
+func runQuery(w io.Writer, q query, files map[string][]byte, project string) error {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := path.Base(path.Dir(name))
+		if project != "" && p != project {
+			continue
+		}
+		var list struct {
+			Items interface{} `json:"items"`
+		}
+		if err := json.Unmarshal(files[name], &list); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		for _, v := range evalQuery(list.Items, q.steps) {
+			out, ok := v.(string)
+			if !ok {
+				b, err := json.Marshal(v)
+				if err != nil {
+					return err
+				}
+				out = string(b)
+			}
+			if project == "" {
+				out = p + "\t" + out
+			}
+			if _, err := fmt.Fprintln(w, out); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
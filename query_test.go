@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestRunQuery(t *testing.T) {
+	files := map[string][]byte{
+		"definitions/projects/core/pods.json": []byte(`{"items": [
+			{"metadata": {"name": "fh-aaa-1"}, "status": {"phase": "Pending"}, "spec": {"containers": [{"name": "fh-aaa"}, {"name": "proxy"}]}},
+			{"metadata": {"name": "millicore-1"}, "status": {"phase": "Running"}, "spec": {"containers": [{"name": "millicore"}]}}
+		]}`),
+		"definitions/projects/mbaas/pods.json": []byte(`{"items": [
+			{"metadata": {"name": "fh-mbaas-1"}, "status": {"phase": "Pending"}}
+		]}`),
+	}
+	tests := []struct {
+		expr    string
+		project string
+		want    string
+	}{
+		{`pods[?status.phase=="Pending"].metadata.name`, "core", "fh-aaa-1\n"},
+		{`pods[?(@.status.phase == "Pending")].metadata.name`, "", "core\tfh-aaa-1\nmbaas\tfh-mbaas-1\n"},
+		{`pods[?status.phase!="Pending"].metadata.name`, "", "core\tmillicore-1\n"},
+		{`pods[0].spec.containers[*].name`, "core", "fh-aaa\nproxy\n"},
+		{`pods.spec.containers.name`, "core", "fh-aaa\nproxy\nmillicore\n"},
+		{`pods[1].metadata`, "core", "{\"name\":\"millicore-1\"}\n"},
+		{`pods[1]["metadata"]["name"]`, "core", "millicore-1\n"},
+	}
+	for _, tt := range tests {
+		q, err := parseQuery(tt.expr)
+		if err != nil {
+			t.Errorf("parseQuery(%q): %v", tt.expr, err)
+			continue
+		}
+		if q.resource != "pods" {
+			t.Errorf("parseQuery(%q).resource = %q, want pods", tt.expr, q.resource)
+		}
+		var buf bytes.Buffer
+		if err := runQuery(&buf, q, files, tt.project); err != nil {
+			t.Errorf("runQuery(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("runQuery(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, expr := range []string{"", "[*]", "pods[?status.phase]", "pods[x]", "pods[?status.phase==Pending]", "pods[0"} {
+		if _, err := parseQuery(expr); err == nil {
+			t.Errorf("parseQuery(%q) didn't return an error", expr)
+		}
+	}
+}
+
+func TestRemoveQuery(t *testing.T) {
+	tests := []struct {
+		expr string
+		n    int
+		want string
+	}{
+		{`items[0]`, 1, `{"items":[{"name":"b"},{"name":"c"}]}`},
+		{`items[?name!="b"]`, 2, `{"items":[{"name":"b"}]}`},
+		{`items[*]`, 3, `{"items":[]}`},
+		{`items.name`, 3, `{"items":[{},{},{}]}`},
+		{`missing.name`, 0, `{"items":[{"name":"a"},{"name":"b"},{"name":"c"}]}`},
+	}
+	for _, tt := range tests {
+		var v interface{}
+		if err := json.Unmarshal([]byte(`{"items":[{"name":"a"},{"name":"b"},{"name":"c"}]}`), &v); err != nil {
+			t.Fatal(err)
+		}
+		steps, err := parseQuerySteps(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, n := removeQuery(v, steps)
+		if output, _ := json.Marshal(v); n != tt.n || string(output) != tt.want {
+			t.Errorf("removeQuery(%q) = %s, %d, want %s, %d", tt.expr, output, n, tt.want, tt.n)
+		}
+	}
+}
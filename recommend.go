@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxNextSteps is the maximum number of next steps suggested at the end of a
+// run.
+const maxNextSteps = 5
+
+// recommendations maps check IDs to functions suggesting the next action to
+// take for a finding of the check.
+var recommendations = map[string]func(f Finding) string{
+	"image-pull-backoff": func(f Finding) string {
+		return fmt.Sprintf("Images of %s in project %s cannot be pulled — verify the image names and the registry credentials", infoNames(f), f.Project)
+	},
+	"dc-replicas-zero": func(f Finding) string {
+		return fmt.Sprintf("%s in project %s are scaled to 0 — scale them up with oc scale --replicas=1 if they are expected to run", infoNames(f), f.Project)
+	},
+	"mongodb-backups": func(f Finding) string {
+		return fmt.Sprintf("MongoDB backups in project %s are not healthy (%s) — inspect the logs of the backup jobs", f.Project, f.Result.StatusMessage)
+	},
+	"conntrack-exhaustion": func(f Finding) string {
+		return fmt.Sprintf("Nodes %s are running out of conntrack entries — raise net.netfilter.nf_conntrack_max on them", infoNames(f))
+	},
+	"router-503-rate": func(f Finding) string {
+		return fmt.Sprintf("Routes %s in project %s are failing with 503 — check that their pods are ready and their services have endpoints", infoNames(f), f.Project)
+	},
+	"weak-credentials": func(f Finding) string {
+		return fmt.Sprintf("Default or weak credentials are used by %s in project %s — rotate them", infoNames(f), f.Project)
+	},
+	"studio-url-mismatch": func(f Finding) string {
+		return fmt.Sprintf("The Studio in project %s is configured with hosts no route serves — update its environment or routes after a domain change", f.Project)
+	},
+	"nagios-missing": func(f Finding) string {
+		return fmt.Sprintf("Nagios is not deployed in project %s — recreate it from the templates in nagios-templates.json of the dump", f.Project)
+	},
+	"failed-scheduling": func(f Finding) string {
+		return fmt.Sprintf("Pods %s in project %s cannot be scheduled, %s — add node capacity, or fix their node selectors, tolerations or claims", infoNames(f), f.Project, strings.TrimPrefix(f.Result.StatusMessage, "one or more pods cannot be scheduled "))
+	},
+	"probe-too-aggressive": func(f Finding) string {
+		return fmt.Sprintf("Probes of %s in project %s fail before the components are ready — apply the suggested probe values with oc set probe", infoNames(f), f.Project)
+	},
+	"sticky-sessions-disabled": func(f Finding) string {
+		return fmt.Sprintf("Routes %s in project %s don't keep sessions on one pod — remove the %s annotation or set %s to source", infoNames(f), f.Project, disableCookiesAnnotation, balanceAnnotation)
+	},
+	"check-crashed": func(f Finding) string {
+		return fmt.Sprintf("An analysis check did not complete in project %s (%s) — rerun the dump, with a longer -check-timeout if it timed out", f.Project, f.Result.StatusMessage)
+	},
+	"project-deleted": func(f Finding) string {
+		return fmt.Sprintf("Project %s was deleted while it was being dumped — dump again if it was recreated, or ignore it if the deletion was expected", f.Project)
+	},
+	"project-terminating": func(f Finding) string {
+		return fmt.Sprintf("Project %s is stuck terminating — remove the finalizers blocking it, once what they wait for is cleaned up, to be able to create it again", f.Project)
+	},
+	"limitrange-conflict": func(f Finding) string {
+		return fmt.Sprintf("Limit ranges in project %s give %s less memory than they need — raise their defaults and maximums, or set explicit memory limits on the containers", f.Project, infoNames(f))
+	},
+	"unsupported-versions": func(f Finding) string {
+		return fmt.Sprintf("The RHMAP release in project %s or the OpenShift release it runs on is not or soon no longer supported (%s) — plan an upgrade to a supported combination", f.Project, infoNames(f))
+	},
+	"node-versions": func(f Finding) string {
+		return fmt.Sprintf("Nodes %s hosting project %s run mixed or unsupported kernel, operating system or docker versions — upgrade them to the same supported versions so that pods behave the same on every node", infoNames(f), f.Project)
+	},
+	"object-size": func(f Finding) string {
+		return fmt.Sprintf("Config maps or secrets %s in project %s are close to the 1MiB size limit, past which updates to them fail and rollouts using them break — move certificate bundles and app assets to volumes or images, or split them across several objects", infoNames(f), f.Project)
+	},
+	"drain-readiness": func(f Finding) string {
+		return fmt.Sprintf("RHMAP components %s in project %s would be disrupted by node drains — before maintenance, scale them to several replicas spread with pod anti-affinity and covered by pod disruption budgets allowing one disruption", infoNames(f), f.Project)
+	},
+	"orphaned-resources": func(f Finding) string {
+		return fmt.Sprintf("Resources %s in project %s are not used by any deployment config — delete them with oc delete if the apps they served were removed", infoNames(f), f.Project)
+	},
+	"admin-routes-exposed": func(f Finding) string {
+		return fmt.Sprintf("Routes %s in project %s expose admin interfaces — restrict them with the %s annotation", infoNames(f), f.Project, ipWhitelistAnnotation)
+	},
+}
+
+// infoNames returns the names of the objects involved in a finding.
+func infoNames(f Finding) string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, info := range f.Result.Info {
+		if !seen[info.Name] {
+			seen[info.Name] = true
+			names = append(names, info.Name)
+		}
+	}
+	if len(names) == 0 {
+		return "some resources"
+	}
+	return strings.Join(names, ", ")
+}
+
+// findingID returns the identifier used to refer to a finding in reports.
+func findingID(f Finding) string {
+	if f.Result.CheckID == "" {
+		return f.Project + "/" + f.Result.CheckName
+	}
+	return f.Project + "/" + f.Result.CheckID
+}
+
+// NextSteps returns up to max suggested actions for the findings in s, most
+// severe first.
+func NextSteps(s DumpSummary, max int) []string {
+	var steps []string
+	for _, f := range s.Findings() {
+		if len(steps) == max {
+			break
+		}
+		step := fmt.Sprintf("Investigate %q in project %s: %s", f.Result.CheckName, f.Project, f.Result.StatusMessage)
+		if recommend, ok := recommendations[f.Result.CheckID]; ok {
+			step = recommend(f)
+		}
+		steps = append(steps, fmt.Sprintf("%s (see finding %s)", step, findingID(f)))
+	}
+	return steps
+}
+
+// WriteNextSteps writes a numbered list of the next steps for the findings in
+// s to w.
+func WriteNextSteps(w io.Writer, s DumpSummary) error {
+	steps := NextSteps(s, maxNextSteps)
+	if len(steps) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintln(w, "\nSuggested next steps:"); err != nil {
+		return err
+	}
+	for i, step := range steps {
+		if _, err := fmt.Fprintf(w, "  %d. %s\n", i+1, step); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNextSteps(t *testing.T) {
+	summary := DumpSummary{Results: map[string][]Result{
+		"core": {
+			{CheckID: "dc-replicas-zero", CheckName: "check deployconfig replicas not 0", Status: StatusWarning, Info: []Info{{Name: "fh-ngui"}}},
+			{CheckID: "image-pull-backoff", CheckName: "check deploys for ImagePullBackOff error", Status: StatusCritical, Info: []Info{{Name: "millicore"}, {Name: "millicore"}}},
+			{CheckID: "unknown", CheckName: "check something", Status: StatusWarning, StatusMessage: "something detected"},
+			{CheckID: "mongodb-backups", Status: StatusOK},
+		},
+	}}
+	steps := NextSteps(summary, 2)
+	if len(steps) != 2 {
+		t.Fatalf("NextSteps() returned %d steps, want 2: %q", len(steps), steps)
+	}
+	if !strings.HasPrefix(steps[0], "Images of millicore in project core cannot be pulled") || !strings.HasSuffix(steps[0], "(see finding core/image-pull-backoff)") {
+		t.Errorf("steps[0] = %q", steps[0])
+	}
+	if !strings.Contains(steps[1], "fh-ngui") {
+		t.Errorf("steps[1] = %q", steps[1])
+	}
+
+	steps = NextSteps(summary, maxNextSteps)
+	if got := steps[len(steps)-1]; !strings.HasPrefix(got, `Investigate "check something" in project core: something detected`) {
+		t.Errorf("step for a check without recommendation = %q", got)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteNextSteps(&buf, DumpSummary{}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("WriteNextSteps() without findings = %q, want empty", buf.String())
+	}
+}
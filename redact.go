@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// redacted replaces sensitive values in the dump.
+const redacted = "REDACTED"
+
+// A RedactionRule describes data to redact from the dump. Exactly one of
+// Pattern, JSONPath and Remove must be set.
+type RedactionRule struct {
+	Name string `json:"name"`
+	// Pattern is a regular expression matched against the contents of
+	// every file. Matches are replaced with Replacement, which may refer
+	// to submatches as in regexp.Regexp.ReplaceAll, and defaults to
+	// REDACTED.
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+	// JSONPath selects values to replace with REDACTED in JSON files. It
+	// is a dot-separated list of object keys, where "*" matches all keys
+	// of an object, a "[*]" suffix matches all elements of an array and a
+	// filter suffix, in the syntax of the query command, matches the
+	// elements it keeps, e.g. `items[?kind=="Secret"].data.*`.
+	JSONPath string `json:"jsonPath"`
+	// Remove is a query, in the syntax of the query command, selecting
+	// values to remove from JSON files along with their keys, e.g.
+	// `items[*].metadata.managedFields`.
+	Remove string `json:"remove"`
+}
+
+// defaultRedactionRules are the rules shipped with the tool.
+var defaultRedactionRules = []RedactionRule{
+	{
+		Name:    "private-keys",
+		Pattern: `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+	},
+	{
+		Name:        "bearer-tokens",
+		Pattern:     `(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`,
+		Replacement: "${1}" + redacted,
+	},
+	{
+		Name:        "basic-auth",
+		Pattern:     `(?i)(authorization:\s*basic\s+)[A-Za-z0-9+/]+=*`,
+		Replacement: "${1}" + redacted,
+	},
+	{
+		// Service account tokens are JSON Web Tokens, and show up in
+		// logs and errors without a bearer prefix.
+		Name:    "json-web-tokens",
+		Pattern: `eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`,
+	},
+	{
+		Name:        "registry-auths",
+		Pattern:     `("auth"\s*:\s*")[^"]+`,
+		Replacement: "${1}" + redacted,
+	},
+	{
+		Name:        "connection-string-credentials",
+		Pattern:     `([a-z][a-z0-9+.-]*://)[^:/@\s"]+:[^@\s"]+@`,
+		Replacement: "${1}" + redacted + ":" + redacted + "@",
+	},
+	{
+		// Only scalar values are redacted: keys such as "secret" or
+		// "serviceAccountToken" also name objects in definitions.
+		Name:        "password-assignments",
+		Pattern:     `(?i)((?:password|passwd|secret|token|api[_-]?key)(?:"\s*:\s*"|\s*[:=]\s*"?))[^"\s,{}\[][^"\s,}]*`,
+		Replacement: "${1}" + redacted,
+	},
+	{
+		Name:        "haproxy-stats-auth",
+		Pattern:     `(stats auth\s+[^:\s]+:)\S+`,
+		Replacement: "${1}" + redacted,
+	},
+	{
+		Name:        "secret-env-values",
+		Pattern:     `(?i)("name":\s*"[^"]*(?:password|passwd|secret|token|key)[^"]*",\s*"value":\s*")[^"]*`,
+		Replacement: "${1}" + redacted,
+	},
+	{
+		Name:     "secret-data",
+		JSONPath: `items[?kind=="Secret"].data.*`,
+	},
+}
+
+// A Redactor applies redaction rules to the contents of files, counting the
+// number of redactions performed by each rule. It is safe for concurrent use.
+type Redactor struct {
+	rules  []compiledRule
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+type compiledRule struct {
+	RedactionRule
+	re     *regexp.Regexp
+	path   []string
+	remove []queryStep
+}
+
+// NewRedactor returns a Redactor applying rules.
+func NewRedactor(rules []RedactionRule) (*Redactor, error) {
+	r := &Redactor{counts: make(map[string]int)}
+	for i, rule := range rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		c := compiledRule{RedactionRule: rule}
+		set := 0
+		for _, s := range []string{rule.Pattern, rule.JSONPath, rule.Remove} {
+			if s != "" {
+				set++
+			}
+		}
+		switch {
+		case set != 1:
+			return nil, fmt.Errorf("redaction rule %s: exactly one of pattern, jsonPath and remove must be set", rule.Name)
+		case rule.Pattern != "":
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("redaction rule %s: %v", rule.Name, err)
+			}
+			c.re = re
+			if c.Replacement == "" {
+				c.Replacement = redacted
+			}
+		case rule.JSONPath != "":
+			c.path = splitJSONPath(rule.JSONPath)
+			for _, segment := range c.path {
+				if strings.HasPrefix(segment, "[") && segment != "[*]" {
+					if _, err := parseJSONPathFilter(segment); err != nil {
+						return nil, fmt.Errorf("redaction rule %s: %v", rule.Name, err)
+					}
+				}
+			}
+		default:
+			steps, err := parseQuerySteps(rule.Remove)
+			if err != nil {
+				return nil, fmt.Errorf("redaction rule %s: %v", rule.Name, err)
+			}
+			c.remove = steps
+		}
+		r.rules = append(r.rules, c)
+		r.counts[rule.Name] = 0
+	}
+	return r, nil
+}
+
+// RedactionRules returns the rules configured in c, including the default
+// rules unless disabled.
+func (c RedactionConfig) RedactionRules() []RedactionRule {
+	var rules []RedactionRule
+	if !c.DisableDefaults {
+		rules = append(rules, defaultRedactionRules...)
+	}
+	return append(rules, c.Rules...)
+}
+
+// Redact returns content with all rules applied. JSONPath and Remove rules only
+// apply to files with a .json extension.
+func (r *Redactor) Redact(name string, content []byte) []byte {
+	counts := make(map[string]int)
+	if path.Ext(name) == ".json" {
+		content = r.redactJSON(content, counts)
+	}
+	for _, rule := range r.rules {
+		if rule.re == nil {
+			continue
+		}
+		n := 0
+		content = rule.re.ReplaceAllFunc(content, func(match []byte) []byte {
+			n++
+			return rule.re.ReplaceAll(match, []byte(rule.Replacement))
+		})
+		counts[rule.Name] += n
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, n := range counts {
+		r.counts[name] += n
+	}
+	return content
+}
+
+// redactJSON applies JSONPath and Remove rules to a stream of JSON documents. The content
+// is returned unchanged if it is not valid JSON or no rule matched.
+func (r *Redactor) redactJSON(content []byte, counts map[string]int) []byte {
+	var docs []interface{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	for {
+		var doc interface{}
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return content
+		}
+		docs = append(docs, doc)
+	}
+	total := 0
+	for _, rule := range r.rules {
+		if rule.path == nil && rule.remove == nil {
+			continue
+		}
+		for i := range docs {
+			var n int
+			if rule.remove != nil {
+				docs[i], n = removeQuery(docs[i], rule.remove)
+			} else {
+				docs[i], n = redactJSONPath(docs[i], rule.path)
+			}
+			counts[rule.Name] += n
+			total += n
+		}
+	}
+	if total == 0 {
+		return content
+	}
+	var buf bytes.Buffer
+	for _, doc := range docs {
+		output, err := json.MarshalIndent(doc, "", "    ")
+		if err != nil {
+			return content
+		}
+		buf.Write(output)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// splitJSONPath splits a JSONPath rule into segments, where "[*]" and filters
+// are separate segments.
+func splitJSONPath(p string) []string {
+	var segments []string
+	for len(p) > 0 {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+		case '[':
+			end := matchingBracket(p)
+			if end < 0 {
+				end = len(p) - 1
+			}
+			segments = append(segments, p[:end+1])
+			p = p[end+1:]
+		default:
+			n := strings.IndexAny(p, ".[")
+			if n < 0 {
+				n = len(p)
+			}
+			segments = append(segments, p[:n])
+			p = p[n:]
+		}
+	}
+	return segments
+}
+
+// parseJSONPathFilter parses a filter segment of a JSONPath rule, e.g.
+// `[?kind=="Secret"]`.
+func parseJSONPathFilter(segment string) (queryStep, error) {
+	if !strings.HasPrefix(segment, "[?") || !strings.HasSuffix(segment, "]") {
+		return queryStep{}, fmt.Errorf("invalid JSONPath segment %s", segment)
+	}
+	return parseBracket(segment[1 : len(segment)-1])
+}
+
+// redactJSONPath replaces the values selected by path in v with REDACTED, and
+// returns the new value and the number of replacements.
+func redactJSONPath(v interface{}, path []string) (interface{}, int) {
+	if len(path) == 0 {
+		if v == nil {
+			return v, 0
+		}
+		return redacted, 1
+	}
+	n := 0
+	switch segment := path[0]; {
+	case segment == "[*]":
+		a, ok := v.([]interface{})
+		if !ok {
+			return v, 0
+		}
+		for i := range a {
+			var m int
+			a[i], m = redactJSONPath(a[i], path[1:])
+			n += m
+		}
+	case strings.HasPrefix(segment, "[?"):
+		a, ok := v.([]interface{})
+		if !ok {
+			return v, 0
+		}
+		filter, err := parseJSONPathFilter(segment)
+		if err != nil {
+			return v, 0
+		}
+		for i := range a {
+			if filter.matches(a[i]) {
+				var m int
+				a[i], m = redactJSONPath(a[i], path[1:])
+				n += m
+			}
+		}
+	case segment == "*":
+		o, ok := v.(map[string]interface{})
+		if !ok {
+			return v, 0
+		}
+		for k := range o {
+			var m int
+			o[k], m = redactJSONPath(o[k], path[1:])
+			n += m
+		}
+	default:
+		o, ok := v.(map[string]interface{})
+		if !ok {
+			return v, 0
+		}
+		child, ok := o[segment]
+		if !ok {
+			return v, 0
+		}
+		o[segment], n = redactJSONPath(child, path[1:])
+	}
+	return v, n
+}
+
+// Counts returns the number of redactions performed by each rule so far.
+func (r *Redactor) Counts() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := make(map[string]int, len(r.counts))
+	for name, n := range r.counts {
+		counts[name] = n
+	}
+	return counts
+}
+
+// WriteReport writes a JSON report of the number of redactions performed by
+// each rule to w.
+func (r *Redactor) WriteReport(w io.Writer) error {
+	type ruleReport struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	counts := r.Counts()
+	var report struct {
+		Rules []ruleReport `json:"rules"`
+	}
+	for _, rule := range r.rules {
+		report.Rules = append(report.Rules, ruleReport{rule.Name, counts[rule.Name]})
+	}
+	output, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(output)
+	return err
+}
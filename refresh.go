@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/feedhenry/fh-system-dump-tool/dumpformat"
+)
+
+// refreshedCategories are the top-level directories of a dump holding the
+// per-project data replaced when refreshing a project, in
+// <category>/projects/<project>.
+var refreshedCategories = []string{"definitions", "logs", "logs-previous", "describe"}
+
+// isProjectFile reports whether the named archive file holds data of project
+// collected by the project tasks.
+func isProjectFile(name, project string) bool {
+	for _, category := range refreshedCategories {
+		if strings.HasPrefix(name, path.Join(category, "projects", project)+"/") {
+			return true
+		}
+	}
+	return name == path.Join("studio", project+".json") || strings.HasPrefix(name, path.Join("nagios", project)+"/")
+}
+
+// isRegeneratedFile reports whether the named archive file is written again
+// when refreshing any project.
+func isRegeneratedFile(name string) bool {
+	switch name {
+	case "metadata.json", "manifest.json", "inventory.json", "inventory.md", "report.txt", "report.html":
+		return true
+	}
+	return strings.HasPrefix(name, "analysis/")
+}
+
+// parseRefresh parses the argument to the -refresh flag, project=<name>.
+func parseRefresh(arg string) (string, error) {
+	if !strings.HasPrefix(arg, "project=") || arg == "project=" {
+		return "", fmt.Errorf("argument to -refresh flag must be project=<name>")
+	}
+	return strings.TrimPrefix(arg, "project="), nil
+}
+
+// RefreshProject collects the data of project again into the existing dump
+// archive at dumpPath, replacing the previous data of the project and
+// updating the inventory, metadata, reports and manifest of the dump. Data of
+// other projects and cluster-wide data are kept as they are. Only the project
+// and analysis collectors among collectors run. Reports only include findings
+// at least as severe as minStatus.
+func RefreshProject(ctx context.Context, dumpPath, project string, collectors []*Collector, redactor *Redactor, minStatus int) (DumpSummary, error) {
+	var errors errorList
+	old, err := os.Open(dumpPath)
+	if err != nil {
+		return DumpSummary{}, err
+	}
+	defer old.Close()
+	tmpPath := dumpPath + ".refresh"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return DumpSummary{}, err
+	}
+	defer os.Remove(tmpPath)
+	defer tmp.Close()
+	tarFile, err := newDumpArchive(tmp, dumpArchiveFormat(dumpPath), archiveCompression(dumpPath))
+	if err != nil {
+		return DumpSummary{}, err
+	}
+	tarFile.Redactor = redactor
+	tarFile.MaxFileSize = outputSizeLimit
+	tarFile.CompressDirs, tarFile.FileCompression = compressDirs, *compression
+	tarFile.Keep = isSummaryFile
+
+	// Copy everything but the data of the project and the files that cover
+	// all projects.
+	var (
+		metadata  dumpformat.Metadata
+		inventory Inventory
+	)
+	err = WalkTgz(old, func(string) bool { return true }, func(name string, content []byte) error {
+		switch {
+		case name == "metadata.json":
+			return json.Unmarshal(content, &metadata)
+		case name == "inventory.json":
+			return json.Unmarshal(content, &inventory)
+		case isRegeneratedFile(name) || isProjectFile(name, project):
+			return nil
+		}
+		return tarFile.AddFileByContent(content, name)
+	})
+	if err != nil {
+		return DumpSummary{}, fmt.Errorf("%s: %v", dumpPath, err)
+	}
+	if err := dumpformat.CheckLayoutVersion(metadata.LayoutVersion); err != nil {
+		return DumpSummary{}, fmt.Errorf("%s: %v", dumpPath, err)
+	}
+
+	collectors = inCategories(collectors, categoryProject, categoryAnalysis)
+	graph, err := CollectTasks(ctx, collectors, []string{project}, tarFile)
+	if err != nil {
+		errors = append(errors, err)
+	}
+	taskErrs, _ := RunTaskGraph(ctx, graph, *maxParallelTasks, *taskTimeout)
+	for _, err := range taskErrs {
+		errors = append(errors, err)
+	}
+	if ctx.Err() != nil {
+		// Keep the dump as it was rather than with part of the
+		// project.
+		return DumpSummary{}, fmt.Errorf("refresh of project %s interrupted, %s is unchanged", project, dumpPath)
+	}
+
+	components := []Component{}
+	for _, c := range inventory.Components {
+		if c.Project != project {
+			components = append(components, c)
+		}
+	}
+	refreshed, err := GetInventory(ctx, []string{project})
+	if err != nil {
+		errors = append(errors, err)
+	}
+	inventory.Components = append(components, refreshed.Components...)
+	summary, err := parseDumpSummary(dumpPath, tarFile.KeptFiles())
+	if err != nil {
+		errors = append(errors, err)
+	}
+	summary.Suppressions = config.Suppressions
+	summary.MinSeverity = minStatus
+	inventory.setHealth(summary)
+	summary.Inventory = inventory
+	jsonOut := tarFile.GetWriterToFile("inventory.json")
+	mdOut := tarFile.GetWriterToFile("inventory.md")
+	if output, err := json.MarshalIndent(inventory, "", "    "); err != nil {
+		errors = append(errors, err)
+	} else {
+		jsonOut.Write(output)
+	}
+	if err := writeInventoryMarkdown(mdOut, inventory); err != nil {
+		errors = append(errors, err)
+	}
+	jsonOut.Close()
+	mdOut.Close()
+
+	if err := AddReports(tarFile, summary); err != nil {
+		errors = append(errors, err)
+	}
+	if metadata.LayoutVersion == 0 {
+		// The dump predates metadata.json.
+		metadata.LayoutVersion = dumpformat.LayoutVersion
+		metadata.ToolVersion = version
+		metadata.Resources = resources
+	}
+	if !containsString(metadata.Projects, project) {
+		metadata.Projects = append(metadata.Projects, project)
+	}
+	metadata.Refreshed = append(metadata.Refreshed, dumpformat.Refresh{Project: project, Time: time.Now().UTC()})
+	if err := WriteMetadata(tarFile, metadata); err != nil {
+		errors = append(errors, err)
+	}
+
+	if err := WriteManifest(tarFile, "", taskErrs); err != nil {
+		return summary, err
+	}
+	if err := tarFile.Close(); err != nil {
+		return summary, err
+	}
+	if err := tmp.Close(); err != nil {
+		return summary, err
+	}
+	if err := os.Rename(tmpPath, dumpPath); err != nil {
+		return summary, err
+	}
+	if _, err := WriteChecksumFile(dumpPath); err != nil {
+		errors = append(errors, err)
+	}
+	if len(errors) > 0 {
+		return summary, errors
+	}
+	return summary, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
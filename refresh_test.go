@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestIsProjectFile(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"definitions/projects/core/pods.json", true},
+		{"logs/projects/core/dc-millicore.logs", true},
+		{"describe/projects/core/pod-millicore-1.txt", true},
+		{"studio/core.json", true},
+		{"nagios/core/history.tar", true},
+		{"definitions/projects/core-mbaas/pods.json", false},
+		{"router/projects/core/route-5xx.logs", false},
+		{"inventory.json", false},
+	}
+	for _, tt := range tests {
+		if got := isProjectFile(tt.name, "core"); got != tt.want {
+			t.Errorf("isProjectFile(%q, core) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseRefresh(t *testing.T) {
+	if got, err := parseRefresh("project=core"); err != nil || got != "core" {
+		t.Errorf("parseRefresh(project=core) = %q, %v, want core", got, err)
+	}
+	for _, arg := range []string{"core", "project=", "namespace=core"} {
+		if _, err := parseRefresh(arg); err == nil {
+			t.Errorf("parseRefresh(%q) didn't return an error", arg)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Categories of collectors.
+const (
+	// categoryProject is for collectors of the data of each project,
+	// collected again by -refresh.
+	categoryProject = "project"
+	// categoryCluster is for collectors of data covering the whole
+	// cluster or all projects.
+	categoryCluster = "cluster"
+	// categoryAnalysis is for collectors running analysis checks.
+	categoryAnalysis = "analysis"
+)
+
+// A Collector adds the tasks collecting one kind of data to a dump.
+type Collector struct {
+	Name     string
+	Category string
+	// Requires lists the names of the collectors whose tasks must
+	// complete before the tasks of this one start. Between collectors
+	// running per project, only the tasks of the same project wait for
+	// each other.
+	Requires []string
+	// PerProject collectors have Tasks called once for each project.
+	PerProject bool
+	// Enabled reports whether the collector runs unless selected
+	// otherwise from the command line. Collectors with a nil Enabled run
+	// by default.
+	Enabled func() bool
+	// Tasks returns the tasks of the collector for the given projects. It
+	// may return tasks even in the presence of an error.
+	Tasks func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error)
+}
+
+// A TaskRegistry holds collectors, in the order they were registered.
+type TaskRegistry struct {
+	collectors []*Collector
+	byName     map[string]*Collector
+}
+
+// NewTaskRegistry returns an empty registry.
+func NewTaskRegistry() *TaskRegistry {
+	return &TaskRegistry{byName: make(map[string]*Collector)}
+}
+
+// taskRegistry holds all collectors of the dump tool. Collectors register with
+// it from init functions.
+var taskRegistry = NewTaskRegistry()
+
+// Register adds c to the registry. It panics if a collector of the same name
+// was already registered.
+func (r *TaskRegistry) Register(c Collector) {
+	if _, ok := r.byName[c.Name]; ok {
+		panic("collector registered twice: " + c.Name)
+	}
+	r.collectors = append(r.collectors, &c)
+	r.byName[c.Name] = &c
+}
+
+// Levels of precedence of the ways to select collectors.
+const (
+	selectByCategory = iota
+	selectByPrefix
+	selectByName
+)
+
+// lookup returns the collectors selected by name: the collector of that name,
+// or else those whose names it prefixes, like nagios for nagios-history and
+// nagios-templates, or else those of that category. It also returns the level
+// of precedence of the selection.
+func (r *TaskRegistry) lookup(name string) ([]*Collector, int) {
+	if c := r.byName[name]; c != nil {
+		return []*Collector{c}, selectByName
+	}
+	var matches []*Collector
+	for _, c := range r.collectors {
+		if strings.HasPrefix(c.Name, name+"-") {
+			matches = append(matches, c)
+		}
+	}
+	if len(matches) > 0 {
+		return matches, selectByPrefix
+	}
+	for _, c := range r.collectors {
+		if c.Category == name {
+			matches = append(matches, c)
+		}
+	}
+	return matches, selectByCategory
+}
+
+// Select returns the collectors enabled by default or in enable, or only those
+// in only and the collectors they require if only is not empty, except those
+// in disable, ordered so that each collector comes after those it requires.
+// only, enable and disable are comma-separated lists of collector names, name
+// prefixes or categories, see lookup. Names take precedence over prefixes,
+// prefixes over categories, and disable over only and enable.
+func (r *TaskRegistry) Select(only, enable, disable string) ([]*Collector, error) {
+	if only != "" && enable != "" {
+		return nil, errors.New("-only cannot be used with -collect")
+	}
+	type choice struct {
+		level int
+		on    bool
+	}
+	choices := make(map[string]choice)
+	for _, list := range []struct {
+		names   string
+		enabled bool
+	}{{only, true}, {enable, true}, {disable, false}} {
+		for _, name := range strings.Split(list.names, ",") {
+			if name == "" {
+				continue
+			}
+			matches, level := r.lookup(name)
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("unknown collector or category %q, must be one of: %s", name, strings.Join(r.names(), ", "))
+			}
+			for _, c := range matches {
+				if prev, ok := choices[c.Name]; !ok || level >= prev.level {
+					choices[c.Name] = choice{level, list.enabled}
+				}
+			}
+		}
+	}
+
+	enabled := make(map[string]bool)
+	for _, c := range r.collectors {
+		on := only == "" && (c.Enabled == nil || c.Enabled())
+		if v, ok := choices[c.Name]; ok {
+			on = v.on
+		}
+		enabled[c.Name] = on
+	}
+
+	// Order the collectors depth-first, keeping the order of registration
+	// otherwise.
+	var (
+		selected []*Collector
+		state    = make(map[string]int) // 1 while visiting, 2 once done
+		visit    func(c *Collector) error
+	)
+	visit = func(c *Collector) error {
+		switch state[c.Name] {
+		case 1:
+			return fmt.Errorf("collector %s depends on itself through its requirements", c.Name)
+		case 2:
+			return nil
+		}
+		state[c.Name] = 1
+		for _, name := range c.Requires {
+			dep := r.byName[name]
+			if dep == nil {
+				return fmt.Errorf("collector %s requires unknown collector %s", c.Name, name)
+			}
+			// The requirements of the only collectors selected
+			// run too, unless disabled.
+			if v, ok := choices[name]; only != "" && !(ok && !v.on) {
+				enabled[name] = true
+			}
+			if !enabled[name] {
+				return fmt.Errorf("collector %s requires %s, which is disabled", c.Name, name)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[c.Name] = 2
+		selected = append(selected, c)
+		return nil
+	}
+	for _, c := range r.collectors {
+		if !enabled[c.Name] {
+			continue
+		}
+		if err := visit(c); err != nil {
+			return nil, err
+		}
+	}
+	return selected, nil
+}
+
+// collectorsCommand prints the collectors of the dump tool, whether they run
+// with the current flags, and the collectors they require.
+func collectorsCommand(args []string) error {
+	fs := flag.NewFlagSet("collectors", flag.ExitOnError)
+	fs.Parse(args)
+	selected, err := taskRegistry.Select(*only, *collect, *skip)
+	if err != nil {
+		return err
+	}
+	enabled := make(map[string]bool)
+	for _, c := range selected {
+		enabled[c.Name] = true
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCATEGORY\tENABLED\tREQUIRES")
+	for _, c := range taskRegistry.collectors {
+		fmt.Fprintf(w, "%s\t%s\t%v\t%s\n", c.Name, c.Category, enabled[c.Name], strings.Join(c.Requires, ","))
+	}
+	return w.Flush()
+}
+
+// names returns the sorted names of the collectors and categories in r.
+func (r *TaskRegistry) names() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, c := range r.collectors {
+		for _, name := range []string{c.Name, c.Category} {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// collectorNames returns the names of collectors, in order.
+func collectorNames(collectors []*Collector) []string {
+	names := make([]string, 0, len(collectors))
+	for _, c := range collectors {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// inCategories returns the collectors of the given categories, in order.
+func inCategories(collectors []*Collector, categories ...string) []*Collector {
+	var in []*Collector
+	for _, c := range collectors {
+		for _, category := range categories {
+			if c.Category == category {
+				in = append(in, c)
+			}
+		}
+	}
+	return in
+}
+
+// A taskGroup is a range of the tasks of a graph returned by a collector for
+// a project, or for all projects if project is empty.
+type taskGroup struct {
+	project    string
+	start, end int
+}
+
+// CollectTasks returns the graph of the tasks of all collectors for the given
+// projects, where tasks depend on the tasks of the collectors their collector
+// requires, along with the files requested by each collector. It may return
+// tasks even in the presence of an error.
+// FIXME: CollectTasks should not know about tarFile.
+func CollectTasks(ctx context.Context, collectors []*Collector, projects []string, tarFile *Archive) (TaskGraph, error) {
+	var (
+		g      TaskGraph
+		errors errorList
+		groups = make(map[string][]taskGroup)
+	)
+	for _, c := range collectors {
+		scopes := []string{""}
+		if c.PerProject {
+			scopes = projects
+		}
+		for _, project := range scopes {
+			scope := projects
+			if project != "" {
+				scope = []string{project}
+			}
+			var requested int
+			if tarFile != nil {
+				requested = len(tarFile.RequestedFiles())
+			}
+			tasks, err := c.Tasks(ctx, scope, tarFile)
+			if err != nil {
+				errors = append(errors, err)
+			}
+			if tarFile != nil {
+				if files := tarFile.RequestedFiles()[requested:]; len(files) > 0 {
+					g.Outputs = append(g.Outputs, TaskOutputs{Collector: c.Name, Project: project, Tasks: len(tasks), Files: files})
+				}
+			}
+			var deps []int
+			for _, name := range c.Requires {
+				for _, dep := range groups[name] {
+					if project == "" || dep.project == "" || dep.project == project {
+						for i := dep.start; i < dep.end; i++ {
+							deps = append(deps, i)
+						}
+					}
+				}
+			}
+			group := taskGroup{project: project, start: len(g.Tasks)}
+			for range tasks {
+				g.Deps = append(g.Deps, deps)
+				g.Collectors = append(g.Collectors, c.Name)
+			}
+			g.Tasks = append(g.Tasks, tasks...)
+			group.end = len(g.Tasks)
+			groups[c.Name] = append(groups[c.Name], group)
+		}
+	}
+	if len(errors) > 0 {
+		return g, errors
+	}
+	return g, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestTaskRegistrySelect(t *testing.T) {
+	noTasks := func(context.Context, []string, *Archive) ([]Task, error) { return nil, nil }
+	off := func() bool { return false }
+	r := NewTaskRegistry()
+	r.Register(Collector{Name: "checks", Category: categoryAnalysis, Requires: []string{"definitions"}, Tasks: noTasks})
+	r.Register(Collector{Name: "definitions", Category: categoryProject, Tasks: noTasks})
+	r.Register(Collector{Name: "logs", Category: categoryProject, Tasks: noTasks})
+	r.Register(Collector{Name: "router", Category: categoryCluster, Enabled: off, Tasks: noTasks})
+	r.Register(Collector{Name: "nagios-history", Category: categoryProject, Tasks: noTasks})
+	r.Register(Collector{Name: "nagios-templates", Category: categoryProject, Tasks: noTasks})
+
+	tests := []struct {
+		only, enable, disable string
+		want                  []string
+	}{
+		// Required collectors come first.
+		{"", "", "", []string{"definitions", "checks", "logs", "nagios-history", "nagios-templates"}},
+		{"", "router", "nagios", []string{"definitions", "checks", "logs", "router"}},
+		{"", "cluster", "project,analysis", []string{"router"}},
+		// Names take precedence over prefixes and categories.
+		{"", "", "project,analysis,cluster", nil},
+		{"", "definitions,nagios-history", "project,analysis", []string{"definitions", "nagios-history"}},
+		{"", "", "nagios,nagios-history", []string{"definitions", "checks", "logs"}},
+		{"", "nagios-templates", "nagios", []string{"definitions", "checks", "logs", "nagios-templates"}},
+		// Disable takes precedence over enable.
+		{"", "logs", "logs", []string{"definitions", "checks", "nagios-history", "nagios-templates"}},
+		// Only the selected collectors run, with those they require.
+		{"logs,router", "", "", []string{"logs", "router"}},
+		{"analysis", "", "", []string{"definitions", "checks"}},
+		{"project", "", "nagios", []string{"definitions", "logs"}},
+	}
+	for _, tt := range tests {
+		selected, err := r.Select(tt.only, tt.enable, tt.disable)
+		if err != nil {
+			t.Errorf("Select(%q, %q, %q): %v", tt.only, tt.enable, tt.disable, err)
+			continue
+		}
+		var got []string
+		for _, c := range selected {
+			got = append(got, c.Name)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Select(%q, %q, %q) = %v, want %v", tt.only, tt.enable, tt.disable, got, tt.want)
+		}
+	}
+
+	for _, tt := range []struct{ only, enable, disable string }{
+		{"", "unknown", ""},
+		{"", "", "definitions"},
+		{"checks", "", "definitions"},
+		{"logs", "router", ""},
+	} {
+		if _, err := r.Select(tt.only, tt.enable, tt.disable); err == nil {
+			t.Errorf("Select(%q, %q, %q) didn't return an error", tt.only, tt.enable, tt.disable)
+		}
+	}
+
+	r.Register(Collector{Name: "a", Category: "cycle", Requires: []string{"b"}, Tasks: noTasks})
+	r.Register(Collector{Name: "b", Category: "cycle", Requires: []string{"a"}, Tasks: noTasks})
+	if _, err := r.Select("", "", ""); err == nil {
+		t.Error("Select() with a dependency cycle didn't return an error")
+	}
+}
+
+func TestDefaultCollectors(t *testing.T) {
+	selected, err := taskRegistry.Select("", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, c := range selected {
+		for _, name := range c.Requires {
+			if !seen[name] {
+				t.Errorf("collector %s comes before %s, which it requires", c.Name, name)
+			}
+		}
+		seen[c.Name] = true
+	}
+	if !seen["definitions"] || !seen["checks"] || seen["network-stats"] {
+		t.Errorf("Select() = %v, want the definitions and checks collectors but not network-stats", selected)
+	}
+}
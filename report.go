@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path"
+	"sort"
+)
+
+// A Finding is an issue detected by an analysis check in a project.
+type Finding struct {
+	Project string `json:"project"`
+	Result  Result `json:"result"`
+}
+
+// statusName returns a human readable name for a Result status.
+func statusName(status int) string {
+	switch status {
+	case StatusOK:
+		return "ok"
+	case StatusWarning:
+		return "warning"
+	case StatusCritical:
+		return "critical"
+	}
+	return fmt.Sprintf("status %d", status)
+}
+
+// parseStatus returns the status with the given name, as returned by
+// statusName.
+func parseStatus(name string) (int, error) {
+	for _, status := range []int{StatusOK, StatusWarning, StatusCritical} {
+		if statusName(status) == name {
+			return status, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown status: %q", name)
+}
+
+// Findings returns all results in the summary that detected an issue at least
+// as severe as s.MinSeverity and are not suppressed, most severe first.
+func (s DumpSummary) Findings() []Finding {
+	findings, _ := s.findings()
+	return findings
+}
+
+// SuppressedFindings returns the findings suppressed by s.Suppressions.
+func (s DumpSummary) SuppressedFindings() []SuppressedFinding {
+	_, suppressed := s.findings()
+	return suppressed
+}
+
+func (s DumpSummary) findings() ([]Finding, []SuppressedFinding) {
+	var (
+		findings   []Finding
+		suppressed []SuppressedFinding
+	)
+	for project, results := range s.Results {
+		for _, r := range results {
+			if r.Status == StatusOK || r.Status < s.MinSeverity {
+				continue
+			}
+			f, sup := applySuppressions(s.Suppressions, Finding{Project: project, Result: r})
+			if f != nil {
+				findings = append(findings, *f)
+			}
+			suppressed = append(suppressed, sup...)
+		}
+	}
+	sort.Sort(bySeverity(findings))
+	return findings, suppressed
+}
+
+// CountFindings returns the number of findings with the given status.
+func (s DumpSummary) CountFindings(status int) int {
+	n := 0
+	for _, f := range s.Findings() {
+		if f.Result.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// bySeverity sorts findings by decreasing severity, then by project and check
+// name.
+type bySeverity []Finding
+
+func (f bySeverity) Len() int      { return len(f) }
+func (f bySeverity) Swap(i, j int) { f[i], f[j] = f[j], f[i] }
+func (f bySeverity) Less(i, j int) bool {
+	if f[i].Result.Status != f[j].Result.Status {
+		return f[i].Result.Status > f[j].Result.Status
+	}
+	if f[i].Project != f[j].Project {
+		return f[i].Project < f[j].Project
+	}
+	return f[i].Result.CheckName < f[j].Result.CheckName
+}
+
+// headline returns a one line summary of the analysis of a dump.
+func (s DumpSummary) headline() string {
+	return fmt.Sprintf("RHMAP system dump %s: %d critical, %d warning findings in %d projects, health score %d/100",
+		s.Path, s.CountFindings(StatusCritical), s.CountFindings(StatusWarning), len(s.Results), s.HealthScore())
+}
+
+// WriteTextSummary writes a plain text summary of the analysis findings in s
+// to w.
+func WriteTextSummary(w io.Writer, s DumpSummary) error {
+	if _, err := fmt.Fprintln(w, s.headline()); err != nil {
+		return err
+	}
+	if err := writeHealthScores(w, s); err != nil {
+		return err
+	}
+	if err := WriteNextSteps(w, s); err != nil {
+		return err
+	}
+	if err := writeMaintenanceReadiness(w, s); err != nil {
+		return err
+	}
+	for _, f := range s.Findings() {
+		if _, err := fmt.Fprintf(w, "\n[%s] %s: %s: %s\n", statusName(f.Result.Status), findingID(f), f.Result.CheckName, f.Result.StatusMessage); err != nil {
+			return err
+		}
+		for _, info := range f.Result.Info {
+			if _, err := fmt.Fprintf(w, "  - %s %s/%s: %s\n", info.Kind, info.Namespace, info.Name, info.Message); err != nil {
+				return err
+			}
+		}
+	}
+	if suppressed := s.SuppressedFindings(); len(suppressed) > 0 {
+		if _, err := fmt.Fprintln(w, "\nSuppressed findings:"); err != nil {
+			return err
+		}
+		for _, sf := range suppressed {
+			if _, err := fmt.Fprintf(w, "  - %s (%s): %s\n", findingID(sf.Finding), infoNames(sf.Finding), sf.Justification); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var htmlSummaryTemplate = template.Must(template.New("summary").Funcs(template.FuncMap{
+	"statusName": statusName,
+	"findingID":  findingID,
+	"infoNames":  infoNames,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>RHMAP System Dump Analysis</title></head>
+<body>
+<h1>RHMAP System Dump Analysis</h1>
+<p>{{.Headline}}</p>
+{{if gt (len .Health) 1}}<h2>Health scores</h2>
+<table>
+{{range .Health}}<tr><td>{{.Project}}</td><td>{{.Score}}</td></tr>
+{{end}}</table>
+{{end}}{{if .NextSteps}}<h2>Suggested next steps</h2>
+<ol>
+{{range .NextSteps}}<li>{{.}}</li>
+{{end}}</ol>
+{{end}}{{if .Maintenance}}<h2>Maintenance readiness</h2>
+<ul>
+{{range .Maintenance}}<li>{{.Project}}: {{if .Ready}}ready, RHMAP components stay available while nodes drain{{else}}not ready{{end}}{{if .Risks}}
+<ul>
+{{range .Risks}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}</li>
+{{end}}</ul>
+{{end}}{{range .Findings}}<h2>[{{statusName .Result.Status}}] {{findingID .}}: {{.Result.CheckName}}</h2>
+<p>{{.Result.StatusMessage}}</p>
+{{if .Result.Info}}<ul>
+{{range .Result.Info}}<li>{{.Kind}} {{.Namespace}}/{{.Name}}: {{.Message}}</li>
+{{end}}</ul>
+{{end}}{{else}}<p>No issues were detected.</p>
+{{end}}{{if .Suppressed}}<h2>Suppressed findings</h2>
+<ul>
+{{range .Suppressed}}<li>{{findingID .Finding}} ({{infoNames .Finding}}): {{.Justification}}</li>
+{{end}}</ul>
+{{end}}</body>
+</html>
+`))
+
+// WriteHTMLSummary writes an HTML summary of the analysis findings in s to w.
+func WriteHTMLSummary(w io.Writer, s DumpSummary) error {
+	return htmlSummaryTemplate.Execute(w, struct {
+		Headline    string
+		Health      []projectHealth
+		NextSteps   []string
+		Maintenance []maintenanceReadiness
+		Findings    []Finding
+		Suppressed  []SuppressedFinding
+	}{s.headline(), s.projectHealthScores(), NextSteps(s, maxNextSteps), s.maintenanceReadiness(), s.Findings(), s.SuppressedFindings()})
+}
+
+// forProject returns the part of s about project.
+func (s DumpSummary) forProject(project string) DumpSummary {
+	s.Results = map[string][]Result{project: s.Results[project]}
+	return s
+}
+
+// A projectReport is the JSON report of the findings in one project.
+type projectReport struct {
+	Project     string              `json:"project"`
+	HealthScore int                 `json:"healthScore"`
+	Findings    []Finding           `json:"findings"`
+	Suppressed  []SuppressedFinding `json:"suppressed"`
+}
+
+// addProjectReports adds a text and a JSON report of the findings of each
+// project of summary to tarFile, in analysis/projects/<project>.
+func addProjectReports(tarFile *Archive, summary DumpSummary) error {
+	var errors errorList
+	for project := range summary.Results {
+		s := summary.forProject(project)
+		dir := path.Join("analysis", "projects", project)
+		var textReport bytes.Buffer
+		if err := WriteTextSummary(&textReport, s); err != nil {
+			errors = append(errors, err)
+		} else if err := tarFile.AddFileByContent(textReport.Bytes(), path.Join(dir, "report.txt")); err != nil {
+			errors = append(errors, err)
+		}
+		report := projectReport{Project: project, HealthScore: s.ProjectHealth(project), Findings: append([]Finding{}, s.Findings()...), Suppressed: append([]SuppressedFinding{}, s.SuppressedFindings()...)}
+		if output, err := json.MarshalIndent(report, "", "    "); err != nil {
+			errors = append(errors, err)
+		} else if err := tarFile.AddFileByContent(output, path.Join(dir, "report.json")); err != nil {
+			errors = append(errors, err)
+		}
+	}
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// AddReports adds the text and HTML reports of summary to tarFile, as
+// report.txt and report.html, along with the reports of each project.
+func AddReports(tarFile *Archive, summary DumpSummary) error {
+	var (
+		errors                 errorList
+		textReport, htmlReport bytes.Buffer
+	)
+	if err := addProjectReports(tarFile, summary); err != nil {
+		errors = append(errors, err)
+	}
+	if err := WriteTextSummary(&textReport, summary); err != nil {
+		errors = append(errors, err)
+	} else if err := tarFile.AddFileByContent(textReport.Bytes(), "report.txt"); err != nil {
+		errors = append(errors, err)
+	}
+	if err := WriteHTMLSummary(&htmlReport, summary); err != nil {
+		errors = append(errors, err)
+	} else if err := tarFile.AddFileByContent(htmlReport.Bytes(), "report.html"); err != nil {
+		errors = append(errors, err)
+	}
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFindings(t *testing.T) {
+	summary := DumpSummary{Results: map[string][]Result{
+		"b": {{CheckName: "check a", Status: StatusWarning}, {CheckName: "check b", Status: StatusOK}},
+		"a": {{CheckName: "check a", Status: StatusWarning}, {CheckName: "check c", Status: StatusCritical}},
+	}}
+	var got []string
+	for _, f := range summary.Findings() {
+		got = append(got, f.Project+"/"+f.Result.CheckName)
+	}
+	if want := "a/check c a/check a b/check a"; strings.Join(got, " ") != want {
+		t.Errorf("Findings() = %v, want %v", got, want)
+	}
+
+	summary.MinSeverity = StatusCritical
+	if got := len(summary.Findings()); got != 1 {
+		t.Errorf("len(Findings()) with MinSeverity critical = %d, want 1", got)
+	}
+}
+
+func TestWriteHTMLSummary(t *testing.T) {
+	summary := DumpSummary{Results: map[string][]Result{
+		"core": {{CheckID: "check-a", CheckName: "check a", Status: StatusCritical, StatusMessage: "<script>"}},
+	}}
+	var buf bytes.Buffer
+	if err := WriteHTMLSummary(&buf, summary); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "<script>") {
+		t.Errorf("HTML summary doesn't escape status messages:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "[critical] core/check-a: check a") {
+		t.Errorf("HTML summary doesn't include the finding:\n%s", buf.String())
+	}
+}
+
+func TestAddReports(t *testing.T) {
+	summary := DumpSummary{Results: map[string][]Result{
+		"core":  {{CheckID: "check-a", CheckName: "check a", Status: StatusCritical}},
+		"mbaas": {{CheckID: "check-b", CheckName: "check b", Status: StatusWarning}},
+	}}
+	var b bytes.Buffer
+	tgz, err := NewTgz(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := AddReports(tgz, summary); err != nil {
+		t.Fatal(err)
+	}
+	tgz.Close()
+	files, err := ReadTgz(&b, func(string) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"report.txt", "report.html", "analysis/projects/core/report.txt", "analysis/projects/mbaas/report.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("%s is missing", name)
+		}
+	}
+	if core := string(files["analysis/projects/core/report.txt"]); !strings.Contains(core, "check a") || strings.Contains(core, "check b") {
+		t.Errorf("core report should only include the findings of core:\n%s", core)
+	}
+}
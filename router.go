@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// routerNamespace is the project where the OpenShift router is
+	// deployed.
+	routerNamespace = "default"
+	// routerSelector selects the router pods.
+	routerSelector = "deploymentconfig=router"
+	// haproxyConfigPath is the path of the generated HAProxy configuration
+	// within router pods.
+	haproxyConfigPath = "/var/lib/haproxy/conf/haproxy.config"
+	// maxErrorSamples is the number of most recent 5xx access log entries
+	// kept per route.
+	maxErrorSamples = 20
+	// min503Requests is the minimum number of requests to a backend before
+	// its 503 rate is reported.
+	min503Requests = 20
+	// max503Rate is the ratio of 503 responses above which a backend is
+	// reported.
+	max503Rate = 0.05
+)
+
+// GetRouterPods returns the names of the router pods.
+func GetRouterPods(ctx context.Context) ([]string, error) {
+	return getSpaceSeparated(ctx, ocCommand("-n", routerNamespace, "get", "pods", "-l", routerSelector, "-o=jsonpath={.items[*].metadata.name}"))
+}
+
+// An accessLogEntry is an HAProxy access log line for a route.
+type accessLogEntry struct {
+	Project string
+	Route   string
+	Status  int
+	Line    string
+}
+
+// haproxyBackend matches backend names generated by the router for routes,
+// e.g. be_http_project_route, be_edge_http:project:route.
+var haproxyBackend = regexp.MustCompile(`^be_(?:http|edge_http|secure|tcp)[_:]([a-z0-9-]+)[_:]([a-z0-9-.]+)/`)
+
+// parseAccessLogLine parses an HAProxy access log line in the httplog format,
+// returning false if the line is not an access log entry for a route.
+func parseAccessLogLine(line string) (accessLogEntry, bool) {
+	fields := strings.Fields(line)
+	for i, f := range fields {
+		m := haproxyBackend.FindStringSubmatch(f)
+		// The backend/server field is followed by the timers and the
+		// status code.
+		if m == nil || i+2 >= len(fields) {
+			continue
+		}
+		status, err := strconv.Atoi(fields[i+2])
+		if err != nil {
+			return accessLogEntry{}, false
+		}
+		return accessLogEntry{Project: m[1], Route: m[2], Status: status, Line: line}, true
+	}
+	return accessLogEntry{}, false
+}
+
+// routerAccessLog holds the access log entries fetched by GetRouterTasks, for
+// CheckRouter503Rate to use. It is set before any task runs.
+var routerAccessLog struct {
+	fetched bool
+	entries []accessLogEntry
+	err     error
+}
+
+// getRouterAccessLog returns the access log entries for routes from the logs
+// of the given router pods.
+func getRouterAccessLog(ctx context.Context, pods []string) ([]accessLogEntry, error) {
+	var (
+		entries []accessLogEntry
+		errors  errorList
+	)
+	for _, pod := range pods {
+		var out bytes.Buffer
+		cmd := ocCommand("-n", routerNamespace, "logs", pod, "--tail", strconv.Itoa(*maxLogLines))
+		if err := runCmdCaptureOutput(ctx, cmd, &out, nil); err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		entries = append(entries, parseAccessLog(&out)...)
+	}
+	if len(errors) > 0 {
+		return entries, errors
+	}
+	return entries, nil
+}
+
+func parseAccessLog(r io.Reader) []accessLogEntry {
+	var entries []accessLogEntry
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if entry, ok := parseAccessLogLine(scanner.Text()); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// GetRouterTasks returns a list of tasks to collect the HAProxy configuration of
+// every router pod, and a sample of recent 5xx access log entries for each
+// route in the given projects. The access logs are fetched once, before
+// returning the tasks, and shared with CheckRouter503Rate. It may return tasks
+// even in the presence of an error.
+// FIXME: GetRouterTasks should not know about tarFile.
+func GetRouterTasks(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+	pods, err := GetRouterPods(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []Task
+	for _, pod := range pods {
+		out := tarFile.GetWriterToFile(filepath.Join("router", pod, "haproxy.config"))
+		errOut := tarFile.GetWriterToFile(filepath.Join("router", pod, "haproxy.config.stderr"))
+		cmd := ocCommand("-n", routerNamespace, "exec", pod, "--", "cat", haproxyConfigPath)
+		task := func(ctx context.Context) error {
+			defer out.Close()
+			defer errOut.Close()
+			return runCmdCaptureOutput(ctx, cmd, out, errOut)
+		}
+		tasks = append(tasks, namedTask("fetch haproxy config of router "+pod, routerNamespace, task))
+	}
+
+	entries, logErr := getRouterAccessLog(ctx, pods)
+	routerAccessLog.fetched = true
+	routerAccessLog.entries, routerAccessLog.err = entries, logErr
+
+	inProjects := make(map[string]bool)
+	for _, p := range projects {
+		inProjects[p] = true
+	}
+	task := func(ctx context.Context) error {
+		samples := make(map[[2]string][]string)
+		for _, e := range entries {
+			if e.Status < 500 || !inProjects[e.Project] {
+				continue
+			}
+			key := [2]string{e.Project, e.Route}
+			samples[key] = append(samples[key], e.Line)
+			if len(samples[key]) > maxErrorSamples {
+				samples[key] = samples[key][1:]
+			}
+		}
+		for key, lines := range samples {
+			w := tarFile.GetWriterToFile(filepath.Join("router", "projects", key[0], key[1]+"-5xx.logs"))
+			fmt.Fprintln(w, strings.Join(lines, "\n"))
+			w.Close()
+		}
+		return logErr
+	}
+	return append(tasks, namedTask("collect router access log errors", routerNamespace, task)), nil
+}
+
+// CheckRouter503Rate will check the router access logs for requests to routes in the supplied project and if any
+// route backend responds with 503 to a high rate of requests this will be reflected in the returned Result data. The
+// access logs are those fetched by the router collector. Any errors are written to the supplied stdErr writer
+func CheckRouter503Rate(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+	entries, err := routerAccessLog.entries, routerAccessLog.err
+	if !routerAccessLog.fetched {
+		err = errors.New("the router access logs were not collected, the router collector did not run")
+	}
+	if err != nil {
+		stdErr.Write([]byte(err.Error()))
+	}
+	return checkRouter503Rate(project, entries), err
+}
+
+func checkRouter503Rate(project string, entries []accessLogEntry) Result {
+	result := Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: "router-503-rate", CheckName: "check router 503 rates per route backend"}
+	requests := make(map[string]int)
+	unavailable := make(map[string]int)
+	for _, e := range entries {
+		if e.Project != project {
+			continue
+		}
+		requests[e.Route]++
+		if e.Status == 503 {
+			unavailable[e.Route]++
+		}
+	}
+	var routes []string
+	for route := range requests {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	for _, route := range routes {
+		n, n503 := requests[route], unavailable[route]
+		if n < min503Requests || float64(n503) <= max503Rate*float64(n) {
+			continue
+		}
+		info := Info{Name: route, Namespace: project, Kind: "Route", Count: n503, Message: fmt.Sprintf("%d of the last %d requests (%.1f%%) failed with 503 Service Unavailable", n503, n, 100*float64(n503)/float64(n))}
+		result.Status = StatusWarning
+		result.StatusMessage = "one or more route backends respond with a high rate of 503 errors"
+		result.Info = append(result.Info, info)
+	}
+	return result
+}
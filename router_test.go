@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/feedhenry/fh-system-dump-tool/cmdrunner"
+)
+
+func TestParseAccessLogLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want accessLogEntry
+		ok   bool
+	}{
+		{
+			line: `Sep  1 12:00:00 router haproxy[42]: 10.1.0.1:5432 [01/Sep/2016:12:00:00.000] fe_sni~ be_edge_http_core_rhmap/route-1 0/0/1/2/3 503 212 - - ---- 1/1/0/0/0 0/0 "GET / HTTP/1.1"`,
+			want: accessLogEntry{Project: "core", Route: "rhmap", Status: 503},
+			ok:   true,
+		},
+		{
+			line: `10.1.0.1:5432 [01/Sep/2016:12:00:00.000] public be_http:mbaas:fh-mbaas/pod:fh-mbaas-1 0/0/1/2/3 200 1024 - - ---- 1/1/0/0/0 0/0 "GET /sys/info/ping HTTP/1.1"`,
+			want: accessLogEntry{Project: "mbaas", Route: "fh-mbaas", Status: 200},
+			ok:   true,
+		},
+		{line: `I0901 12:00:00.000000 1 router.go:123] Router reloaded`},
+	}
+	for _, tt := range tests {
+		got, ok := parseAccessLogLine(tt.line)
+		if ok != tt.ok {
+			t.Errorf("parseAccessLogLine(%q) ok = %v, want %v", tt.line, ok, tt.ok)
+			continue
+		}
+		tt.want.Line = got.Line
+		if got != tt.want {
+			t.Errorf("parseAccessLogLine(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestCheckRouter503Rate(t *testing.T) {
+	var log []string
+	for i := 0; i < 100; i++ {
+		status := 200
+		if i%10 == 0 {
+			status = 503
+		}
+		log = append(log, fmt.Sprintf("fe be_http_core_bad/s 0/0/0/0/0 %d 0", status))
+		log = append(log, "fe be_http_core_good/s 0/0/0/0/0 200 0")
+		log = append(log, "fe be_http_other_bad/s 0/0/0/0/0 503 0")
+	}
+	entries := parseAccessLog(strings.NewReader(strings.Join(log, "\n")))
+
+	result := checkRouter503Rate("core", entries)
+	if result.Status != StatusWarning {
+		t.Errorf("Status = %d, want %d", result.Status, StatusWarning)
+	}
+	if len(result.Info) != 1 || result.Info[0].Name != "bad" || result.Info[0].Count != 10 {
+		t.Errorf("Info = %+v, want a single entry for route bad with 10 errors", result.Info)
+	}
+}
+
+func TestGetRouterTasksSharesAccessLog(t *testing.T) {
+	defer func(r cmdrunner.Runner) { runner = r }(runner)
+	defer func() { routerAccessLog.fetched, routerAccessLog.entries, routerAccessLog.err = false, nil, nil }()
+	var log []string
+	for i := 0; i < 20; i++ {
+		log = append(log, "fe be_http_core_bad/s 0/0/0/0/0 503 0")
+	}
+	runner = cmdrunner.NewFakeRunner([]cmdrunner.Invocation{
+		{Args: ocCommand("-n", routerNamespace, "get", "pods", "-l", routerSelector, "-o=jsonpath={.items[*].metadata.name}").Args, Stdout: "router-1-abcde"},
+		{Args: ocCommand("-n", routerNamespace, "logs", "router-1-abcde", "--tail", fmt.Sprint(*maxLogLines)).Args, Stdout: strings.Join(log, "\n")},
+	})
+	var b bytes.Buffer
+	archive, err := NewArchive(&b, compressionGzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetRouterTasks(context.Background(), []string{"core"}, archive); err != nil {
+		t.Fatal(err)
+	}
+	// The check uses the logs fetched with the context of the dump, even
+	// once the context of its own task is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var stdErr bytes.Buffer
+	result, err := CheckRouter503Rate(ctx, "core", &stdErr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != StatusWarning || len(result.Info) != 1 {
+		t.Errorf("CheckRouter503Rate() = %+v, want a warning for route bad", result)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/feedhenry/fh-system-dump-tool/cmdrunner"
+)
+
+// runner is the Runner used by the dump tool. All commands run by the dump
+// tool go through it, so that they can be observed, recorded and replayed.
+var runner cmdrunner.Runner = execRunner{}
+
+// A stalledError is returned by execRunner when the watchdog killed a
+// stalled command.
+type stalledError struct {
+	err error
+}
+
+func (e *stalledError) Error() string {
+	return "stalled command killed by the watchdog: " + e.err.Error()
+}
+
+// execRunner runs commands under the watch of commandWatchdog.
+type execRunner struct{}
+
+func (execRunner) Run(ctx context.Context, cmd *exec.Cmd) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	commandWatchdog.start(cmd)
+	waited := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			cmd.Process.Kill()
+		case <-waited:
+		}
+	}()
+	err := cmd.Wait()
+	close(waited)
+	if commandWatchdog.done(cmd) {
+		return &stalledError{err}
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil && err != nil {
+		// The command was killed, or failed while being killed.
+		return ctxErr
+	}
+	return err
+}
+
+// redactCommandOutput returns a function redacting the recorded output of
+// commands with redactor, if not nil. The output of each command is redacted
+// as a file of its own, since the rules for JSON files cannot reach into the
+// strings holding it. The values of secrets are redacted regardless.
+func redactCommandOutput(redactor *Redactor) func(stdout []byte) []byte {
+	return func(stdout []byte) []byte {
+		if redactor != nil {
+			stdout = redactor.Redact("stdout.json", stdout)
+		}
+		return secretDataRedactor.Redact("stdout.json", stdout)
+	}
+}
+
+// secretDataRedactor redacts the values of secrets from the output of
+// commands, even when the default redaction rules are disabled.
+var secretDataRedactor = func() *Redactor {
+	for _, rule := range defaultRedactionRules {
+		if rule.Name == "secret-data" {
+			r, err := NewRedactor([]RedactionRule{rule})
+			if err != nil {
+				panic(err)
+			}
+			return r
+		}
+	}
+	panic("no secret-data redaction rule")
+}()
+
+// LoadInvocations reads the commands recorded in the dump archive at path.
+func LoadInvocations(path string) ([]cmdrunner.Invocation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	files, err := ReadTgz(f, func(name string) bool { return name == "commands.json" })
+	if err != nil {
+		return nil, err
+	}
+	content, ok := files["commands.json"]
+	if !ok {
+		return nil, fmt.Errorf("%s: no recorded commands", path)
+	}
+	var invocations []cmdrunner.Invocation
+	if err := json.Unmarshal(content, &invocations); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return invocations, nil
+}
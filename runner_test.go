@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"os/exec"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/feedhenry/fh-system-dump-tool/cmdrunner"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	recorder := &cmdrunner.RecordingRunner{Runner: execRunner{}, CaptureOutput: true}
+	defer func(old cmdrunner.Runner) { runner = old }(runner)
+	runner = recorder
+
+	want := []string{"core", "mbaas"}
+	got, err := getSpaceSeparated(context.Background(), helperCommand("echo", want...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("getSpaceSeparated() = %v, want %v", got, want)
+	}
+	invocations := recorder.Invocations()
+	if len(invocations) != 1 || invocations[0].Stdout != "core mbaas\n" {
+		t.Fatalf("Invocations() = %+v, want the echo command and its output", invocations)
+	}
+
+	// Replaying must not run the command.
+	runner = cmdrunner.NewFakeRunner(invocations)
+	cmd := helperCommand("echo", want...)
+	cmd.Path = "/nonexistent"
+	got, err = getSpaceSeparated(context.Background(), cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed getSpaceSeparated() = %v, want %v", got, want)
+	}
+
+	var out bytes.Buffer
+	if err := runCmdCaptureOutput(context.Background(), helperCommand("echo", "unrecorded"), &out, nil); err == nil {
+		t.Error("replaying an unrecorded command didn't return an error")
+	}
+}
+
+func TestExecRunnerCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	cmd := exec.Command("sleep", "10")
+	start := time.Now()
+	err := execRunner{}.Run(ctx, cmd)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Run() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run() returned after %v, want the command to be killed", elapsed)
+	}
+}
+
+// fixedOutputRunner writes the same output for every command.
+type fixedOutputRunner string
+
+func (r fixedOutputRunner) Run(ctx context.Context, cmd *exec.Cmd) error {
+	_, err := io.WriteString(cmd.Stdout, string(r))
+	return err
+}
+
+func TestRecordedSecretsRedacted(t *testing.T) {
+	value := base64.StdEncoding.EncodeToString([]byte("hunter2"))
+	recorder := &cmdrunner.RecordingRunner{Runner: fixedOutputRunner(`{"kind": "List", "items": [{"kind": "Secret", "metadata": {"name": "db"}, "data": {"password": "` + value + `"}}]}`), CaptureOutput: true, Redact: redactCommandOutput(nil)}
+	defer func(old cmdrunner.Runner) { runner = old }(runner)
+	runner = recorder
+
+	var b bytes.Buffer
+	archive, err := NewArchive(&b, compressionGzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, task := range GetSecretsTasks([]string{"core"}, secretValuesNone, archive) {
+		if err := task(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Even without redaction rules, secret values are not recorded.
+	out := archive.GetWriterToFile("commands.json")
+	if err := recorder.WriteInvocations(out); err != nil {
+		t.Fatal(err)
+	}
+	out.Close()
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ReadTgz(&b, func(name string) bool { return name == "commands.json" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	commands := files["commands.json"]
+	if !bytes.Contains(commands, []byte(`\"password\"`)) {
+		t.Fatalf("commands.json doesn't record the output of the secrets commands: %s", commands)
+	}
+	if bytes.Contains(commands, []byte(value)) {
+		t.Errorf("commands.json holds the value of a secret: %s", commands)
+	}
+}
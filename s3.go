@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultS3Endpoint and defaultS3Region are those of Amazon S3.
+	defaultS3Endpoint = "https://s3.amazonaws.com"
+	defaultS3Region   = "us-east-1"
+	// s3UnsignedPayload is the payload hash of requests whose body is
+	// not signed, so that files are streamed from disk rather than read
+	// twice.
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	// s3MaxObjectSize is the largest object a single PUT may upload.
+	s3MaxObjectSize = 5 << 30
+)
+
+// An s3Uploader uploads files to a bucket of S3-compatible object storage,
+// with path-style requests signed with AWS Signature Version 4.
+type s3Uploader struct {
+	endpoint string
+	region   string
+	bucket   string
+	// prefix is prepended to the base names of files to form their keys.
+	prefix       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	client       *http.Client
+	backoff      time.Duration
+	progress     io.Writer
+	now          func() time.Time
+}
+
+// newS3Uploader returns an uploader to bucket at endpoint. The credentials
+// are accessKey and secretKey if given, or the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables.
+func newS3Uploader(endpoint, region, bucket, prefix, accessKey, secretKey string) (*s3Uploader, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+	}
+	if bucket == "" || strings.Contains(bucket, "/") {
+		return nil, fmt.Errorf("invalid S3 bucket %q", bucket)
+	}
+	sessionToken := ""
+	if accessKey == "" && secretKey == "" {
+		accessKey, secretKey, sessionToken = os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("uploading to S3 requires credentials, in the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables or with -s3-access-key and -s3-secret-key")
+	}
+	return &s3Uploader{endpoint: strings.TrimSuffix(endpoint, "/"), region: region, bucket: bucket, prefix: prefix,
+		accessKey: accessKey, secretKey: secretKey, sessionToken: sessionToken,
+		client: &http.Client{}, backoff: uploadBackoff, progress: os.Stderr, now: time.Now}, nil
+}
+
+// UploadFiles uploads the files at paths to the bucket, in order. It stops at
+// the first file that could not be uploaded.
+func (u *s3Uploader) UploadFiles(paths []string) error {
+	return uploadFiles(paths, u.backoff, u.upload, func(path string) {
+		log.Printf("Uploaded %s to s3://%s/%s\n", filepath.Base(path), u.bucket, u.key(path))
+	})
+}
+
+// key returns the key of the object holding the file at path.
+func (u *s3Uploader) key(path string) string {
+	return u.prefix + filepath.Base(path)
+}
+
+// upload puts the file at path to the bucket, streaming it from disk.
+func (u *s3Uploader) upload(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() > s3MaxObjectSize {
+		return fmt.Errorf("%s is larger than the %s S3 accepts in a single upload, split the dump with -split-size", filepath.Base(path), formatSize(s3MaxObjectSize))
+	}
+	var escaped []string
+	for _, segment := range strings.Split(u.key(path), "/") {
+		escaped = append(escaped, s3Escape(segment))
+	}
+	req, err := http.NewRequest(http.MethodPut, u.endpoint+"/"+s3Escape(u.bucket)+"/"+strings.Join(escaped, "/"),
+		newUploadProgress(f, u.progress, filepath.Base(path), fi.Size()))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = fi.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if u.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", u.sessionToken)
+	}
+	signS3Request(req, s3UnsignedPayload, u.accessKey, u.secretKey, u.region, u.now())
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &uploadStatusError{file: filepath.Base(path), status: resp.Status, code: resp.StatusCode, message: strings.TrimSpace(string(msg))}
+	}
+	return nil
+}
+
+// s3Escape escapes s as a segment of the paths of S3 requests, leaving only
+// unreserved characters as they are.
+func s3Escape(s string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// signS3Request signs req for S3 with AWS Signature Version 4, as of now, over
+// the host and all headers set on req. payloadHash is the hex SHA-256 hash of
+// the body, or s3UnsignedPayload.
+func signS3Request(req *http.Request, payloadHash, accessKey, secretKey, region string, now time.Time) {
+	now = now.UTC()
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	var names []string
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{req.Method, req.URL.EscapedPath(), req.URL.RawQuery, canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + now.Format("20060102T150405Z") + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+	key := []byte("AWS4" + secretKey)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
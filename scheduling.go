@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Causes of FailedScheduling events, in the order they are reported.
+var schedulingCauses = []string{"insufficient CPU", "insufficient memory", "node selector mismatch", "taints", "PVC binding", "other"}
+
+// constraintCount matches the number of nodes prefixed or suffixed to the
+// constraints in FailedScheduling messages, as in "2 Insufficient cpu" or,
+// before OpenShift 3.9, "Insufficient cpu (2)".
+var constraintCount = regexp.MustCompile(`^\d+\s+|\s*\(\d+\)$`)
+
+// schedulingConstraints returns the unsatisfied constraints listed in the
+// message of a FailedScheduling event.
+func schedulingConstraints(message string) []string {
+	message = strings.TrimSpace(message)
+	if i := strings.LastIndex(message, ":"); i >= 0 {
+		message = message[i+1:]
+	}
+	var constraints []string
+	for _, c := range strings.Split(message, ",") {
+		c = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(c), "."))
+		c = constraintCount.ReplaceAllString(c, "")
+		if c != "" {
+			constraints = append(constraints, c)
+		}
+	}
+	return constraints
+}
+
+// classifyConstraint returns the cause, one of schedulingCauses, of an
+// unsatisfied scheduling constraint.
+func classifyConstraint(constraint string) string {
+	c := strings.ToLower(constraint)
+	switch {
+	case strings.Contains(c, "insufficient cpu"):
+		return "insufficient CPU"
+	case strings.Contains(c, "insufficient memory"):
+		return "insufficient memory"
+	case strings.Contains(c, "persistentvolumeclaim"), strings.Contains(c, "volume node affinity"), strings.Contains(c, "volumebinding"):
+		return "PVC binding"
+	case strings.Contains(c, "node selector"), strings.Contains(c, "matchnodeselector"), strings.Contains(c, "node affinity"):
+		return "node selector mismatch"
+	case strings.Contains(c, "taint"):
+		return "taints"
+	}
+	return "other"
+}
+
+// CheckFailedScheduling will check all events in the supplied project for pods that could not be scheduled, and
+// classify the unsatisfied constraints by cause in the returned Result data. Any errors are written to the supplied
+// stdErr writer
+func CheckFailedScheduling(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+	result := Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: "failed-scheduling", CheckName: "check pods for scheduling failures"}
+	var events Events
+	if err := getResourceStruct(ctx, project, "events", &events); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	return checkFailedScheduling(result, events), nil
+}
+
+func checkFailedScheduling(result Result, events Events) Result {
+	counts := make(map[string]int)
+	for _, event := range events.Items {
+		if event.Reason != "FailedScheduling" {
+			continue
+		}
+		byCause := make(map[string][]string)
+		for _, c := range schedulingConstraints(event.Message) {
+			cause := classifyConstraint(c)
+			byCause[cause] = append(byCause[cause], c)
+		}
+		var causes []string
+		for _, cause := range schedulingCauses {
+			if constraints, ok := byCause[cause]; ok {
+				counts[cause]++
+				causes = append(causes, fmt.Sprintf("%s (%s)", cause, strings.Join(constraints, ", ")))
+			}
+		}
+		result.Status = StatusWarning
+		result.Info = append(result.Info, Info{Name: event.InvolvedObject.Name, Namespace: event.InvolvedObject.Namespace, Kind: event.InvolvedObject.Kind, Count: event.Count,
+			Message: "the pod cannot be scheduled: " + strings.Join(causes, "; ")})
+	}
+	if result.Status == StatusOK {
+		return result
+	}
+	sort.Slice(result.Info, func(i, j int) bool { return result.Info[i].Name < result.Info[j].Name })
+	var summary []string
+	for _, cause := range schedulingCauses {
+		if counts[cause] > 0 {
+			summary = append(summary, fmt.Sprintf("%s: %d", cause, counts[cause]))
+		}
+	}
+	result.StatusMessage = "one or more pods cannot be scheduled (" + strings.Join(summary, ", ") + ")"
+	return result
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSchedulingConstraints(t *testing.T) {
+	tests := []struct {
+		message string
+		want    []string
+	}{
+		{"0/3 nodes are available: 1 Insufficient cpu, 2 node(s) didn't match node selector.", []string{"Insufficient cpu", "node(s) didn't match node selector"}},
+		{"No nodes are available that match all of the following predicates:: Insufficient memory (2), PodToleratesNodeTaints (1).", []string{"Insufficient memory", "PodToleratesNodeTaints"}},
+		{"pod has unbound PersistentVolumeClaims (repeated 3 times)", []string{"pod has unbound PersistentVolumeClaims (repeated 3 times)"}},
+	}
+	for _, tt := range tests {
+		if got := schedulingConstraints(tt.message); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("schedulingConstraints(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyConstraint(t *testing.T) {
+	for constraint, want := range map[string]string{
+		"Insufficient cpu":                                "insufficient CPU",
+		"Insufficient memory":                             "insufficient memory",
+		"node(s) didn't match node selector":              "node selector mismatch",
+		"MatchNodeSelector":                               "node selector mismatch",
+		"node(s) had taints that the pod didn't tolerate": "taints",
+		"PodToleratesNodeTaints":                          "taints",
+		"pod has unbound PersistentVolumeClaims":          "PVC binding",
+		"node(s) had volume node affinity conflict":       "PVC binding",
+		"NoDiskConflict":                                  "other",
+	} {
+		if got := classifyConstraint(constraint); got != want {
+			t.Errorf("classifyConstraint(%q) = %q, want %q", constraint, got, want)
+		}
+	}
+}
+
+func TestCheckFailedScheduling(t *testing.T) {
+	var events Events
+	if err := json.Unmarshal([]byte(`{"items": [
+		{"reason": "FailedScheduling", "count": 4, "involvedObject": {"kind": "Pod", "namespace": "core", "name": "fh-ngui-2-abcde"},
+		 "message": "0/3 nodes are available: 1 Insufficient cpu, 2 node(s) had taints that the pod didn't tolerate."},
+		{"reason": "FailedScheduling", "count": 1, "involvedObject": {"kind": "Pod", "namespace": "core", "name": "mongodb-1-1-fghij"},
+		 "message": "pod has unbound PersistentVolumeClaims"},
+		{"reason": "Scheduled", "involvedObject": {"kind": "Pod", "namespace": "core", "name": "fh-aaa-1-klmno"}}
+	]}`), &events); err != nil {
+		t.Fatal(err)
+	}
+	result := checkFailedScheduling(Result{Status: StatusOK}, events)
+	if result.Status != StatusWarning {
+		t.Fatalf("Status = %d, want %d", result.Status, StatusWarning)
+	}
+	if want := "one or more pods cannot be scheduled (insufficient CPU: 1, taints: 1, PVC binding: 1)"; result.StatusMessage != want {
+		t.Errorf("StatusMessage = %q, want %q", result.StatusMessage, want)
+	}
+	if len(result.Info) != 2 {
+		t.Fatalf("len(Info) = %d, want 2", len(result.Info))
+	}
+	if want := "the pod cannot be scheduled: insufficient CPU (Insufficient cpu); taints (node(s) had taints that the pod didn't tolerate)"; result.Info[0].Message != want {
+		t.Errorf("Info[0].Message = %q, want %q", result.Info[0].Message, want)
+	}
+
+	if result := checkFailedScheduling(Result{Status: StatusOK}, Events{}); result.Status != StatusOK {
+		t.Errorf("Status without events = %d, want %d", result.Status, StatusOK)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Modes of collection of the values of secrets, set with -secret-values.
+const (
+	// secretValuesNone records only the keys of secrets and the sizes of
+	// their values.
+	secretValuesNone = "none"
+	// secretValuesHash also records the SHA-256 hash of each value, so
+	// that values can be compared without being seen.
+	secretValuesHash = "hash"
+	// secretValuesRedacted also records each value, as redacted.
+	secretValuesRedacted = "redacted"
+)
+
+// checkSecretValues returns an error if mode is not a mode of collection of
+// secret values.
+func checkSecretValues(mode string) error {
+	switch mode {
+	case secretValuesNone, secretValuesHash, secretValuesRedacted:
+		return nil
+	}
+	return fmt.Errorf("unknown secret values mode %q, must be none, hash or redacted", mode)
+}
+
+// A SecretSummary describes a secret without revealing its values.
+type SecretSummary struct {
+	Name    string      `json:"name"`
+	Type    string      `json:"type"`
+	Created time.Time   `json:"created"`
+	Keys    []SecretKey `json:"keys"`
+	// UsedBy lists the deploymentconfigs and service accounts using the
+	// secret, as kind/name.
+	UsedBy []string `json:"usedBy"`
+}
+
+// A SecretKey describes one of the values of a secret.
+type SecretKey struct {
+	Name string `json:"name"`
+	// Size is the size of the decoded value, in bytes.
+	Size int `json:"size"`
+	// Value is the hash of the value, or redacted, depending on the
+	// secret values mode.
+	Value string `json:"value,omitempty"`
+}
+
+// GetSecretsTasks returns a list of tasks to record the summaries of the
+// secrets of each project to secrets/projects/<project>/secrets.json, with
+// their values collected following mode.
+func GetSecretsTasks(projects []string, mode string, tarFile *Archive) []Task {
+	var tasks []Task
+	for _, p := range projects {
+		p := p
+		out := tarFile.GetWriterToFile(filepath.Join("secrets", "projects", p, "secrets.json"))
+		errOut := tarFile.GetWriterToFile(filepath.Join("secrets", "projects", p, "secrets.stderr"))
+		task := func(ctx context.Context) error {
+			defer out.Close()
+			defer errOut.Close()
+			var (
+				secrets Secrets
+				dcs     DeploymentConfigs
+				sas     ServiceAccounts
+			)
+			for _, r := range []struct {
+				resource string
+				dest     interface{}
+			}{
+				{"secrets", &secrets},
+				{"dc", &dcs},
+				{"serviceaccounts", &sas},
+			} {
+				if err := getResourceStruct(ctx, p, r.resource, r.dest); err != nil {
+					errOut.Write([]byte(err.Error()))
+					return err
+				}
+			}
+			output, err := json.MarshalIndent(summarizeSecrets(secrets, dcs, sas, mode), "", "    ")
+			if err != nil {
+				return err
+			}
+			_, err = out.Write(output)
+			return err
+		}
+		tasks = append(tasks, namedTask("record secret metadata", p, task))
+	}
+	return tasks
+}
+
+// summarizeSecrets returns the summaries of secrets, used by dcs and sas, with
+// their values collected following mode.
+func summarizeSecrets(secrets Secrets, dcs DeploymentConfigs, sas ServiceAccounts, mode string) []SecretSummary {
+	usedBy := make(map[string][]string)
+	for _, dc := range dcs.Items {
+		_, used := dcReferences(DeploymentConfigs{Items: []DeploymentConfig{dc}})
+		for name := range used {
+			usedBy[name] = append(usedBy[name], "deploymentconfig/"+dc.Metadata.Name)
+		}
+	}
+	for _, sa := range sas.Items {
+		used := make(map[string]bool)
+		for _, s := range sa.Secrets {
+			used[s.Name] = true
+		}
+		for _, s := range sa.ImagePullSecrets {
+			used[s.Name] = true
+		}
+		for name := range used {
+			usedBy[name] = append(usedBy[name], "serviceaccount/"+sa.Metadata.Name)
+		}
+	}
+
+	summaries := []SecretSummary{}
+	for _, s := range secrets.Items {
+		summary := SecretSummary{Name: s.Metadata.Name, Type: s.Type, Created: s.Metadata.CreationTimestamp.UTC(), Keys: []SecretKey{}, UsedBy: []string{}}
+		for name, value := range s.Data {
+			decoded, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				decoded = []byte(value)
+			}
+			key := SecretKey{Name: name, Size: len(decoded)}
+			switch mode {
+			case secretValuesHash:
+				sum := sha256.Sum256(decoded)
+				key.Value = "sha256:" + hex.EncodeToString(sum[:])
+			case secretValuesRedacted:
+				key.Value = redacted
+			}
+			summary.Keys = append(summary.Keys, key)
+		}
+		sort.Slice(summary.Keys, func(i, j int) bool { return summary.Keys[i].Name < summary.Keys[j].Name })
+		summary.UsedBy = append(summary.UsedBy, usedBy[s.Metadata.Name]...)
+		sort.Strings(summary.UsedBy)
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSummarizeSecrets(t *testing.T) {
+	var (
+		secrets Secrets
+		dcs     DeploymentConfigs
+		sas     ServiceAccounts
+	)
+	for js, v := range map[string]interface{}{
+		`{"items": [
+			{"kind": "Secret", "metadata": {"name": "cloudapp-db", "creationTimestamp": "2017-06-01T12:00:00Z"}, "type": "Opaque", "data": {"user": "YWRtaW4=", "password": "c2VjcmV0"}},
+			{"kind": "Secret", "metadata": {"name": "default-dockercfg-fghij"}, "type": "kubernetes.io/dockercfg"}
+		]}`: &secrets,
+		`{"items": [{"kind": "DeploymentConfig", "metadata": {"name": "cloudapp"}, "spec": {"template": {"spec": {
+			"containers": [{"name": "cloudapp", "env": [{"name": "DB_PASSWORD", "valueFrom": {"secretKeyRef": {"name": "cloudapp-db", "key": "password"}}}]}]}}}}]}`: &dcs,
+		`{"items": [{"metadata": {"name": "default"}, "imagePullSecrets": [{"name": "default-dockercfg-fghij"}]}]}`: &sas,
+	} {
+		if err := json.Unmarshal([]byte(js), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []SecretSummary{
+		{Name: "cloudapp-db", Type: "Opaque", Created: time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC),
+			Keys:   []SecretKey{{Name: "password", Size: 6, Value: redacted}, {Name: "user", Size: 5, Value: redacted}},
+			UsedBy: []string{"deploymentconfig/cloudapp"}},
+		{Name: "default-dockercfg-fghij", Type: "kubernetes.io/dockercfg", Created: time.Time{}.UTC(), Keys: []SecretKey{}, UsedBy: []string{"serviceaccount/default"}},
+	}
+	if got := summarizeSecrets(secrets, dcs, sas, secretValuesRedacted); !reflect.DeepEqual(got, want) {
+		t.Errorf("summarizeSecrets() = %+v, want %+v", got, want)
+	}
+
+	keys := summarizeSecrets(secrets, dcs, sas, secretValuesHash)[0].Keys
+	if got, want := keys[1].Value, "sha256:8c6976e5b5410415bde908bd4dee15dfb167a9c873fc4bb8a81f6f2ab448a918"; got != want {
+		t.Errorf("hash of user = %s, want %s", got, want)
+	}
+	if keys := summarizeSecrets(secrets, dcs, sas, secretValuesNone)[0].Keys; keys[0].Value != "" {
+		t.Errorf("summarizeSecrets() without values recorded the value %q", keys[0].Value)
+	}
+}
+
+func TestCheckSecretValues(t *testing.T) {
+	for _, mode := range []string{secretValuesNone, secretValuesHash, secretValuesRedacted} {
+		if err := checkSecretValues(mode); err != nil {
+			t.Errorf("checkSecretValues(%q): %v", mode, err)
+		}
+	}
+	if err := checkSecretValues("plain"); err == nil {
+		t.Error(`checkSecretValues("plain") didn't return an error`)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"strings"
+	"time"
+)
+
+type Secrets struct {
+	Items []Secret `json:"items"`
+}
+
+type Secret struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name              string    `json:"name"`
+		Namespace         string    `json:"namespace"`
+		CreationTimestamp time.Time `json:"creationTimestamp"`
+	} `json:"metadata"`
+	Type string            `json:"type"`
+	Data map[string]string `json:"data"`
+}
+
+// weakCredentialHashes are the SHA-256 hashes of well-known default and weak
+// credentials, so that values can be compared without storing or reporting
+// them.
+var weakCredentialHashes = map[string]bool{
+	"5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8": true, // password
+	"8c6976e5b5410415bde908bd4dee15dfb167a9c873fc4bb8a81f6f2ab448a918": true, // admin
+	"057ba03d6c44104863dc7361fe4578965d1887360f90a0895882e58a6248fc86": true, // changeme
+	"989a24b5591c6e81c3f490a6112661fea40b5aa449e114060f1dacd0c6d10f9d": true, // nagiosadmin
+	"2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b": true, // secret
+	"8d969eef6ecad3c29a3a629280e686cf0c3f5d5a86aff3ca12020c923adc6c92": true, // 123456
+	"7d3b5c83009fadf734c06eeecd7fbe256c69f71c8ba0429e4d7ad5f54b2e4097": true, // redhat
+	"1cc9a79bdaf09793062750a17e559d40d5c0f1372cc859f67fe1081e6bc8a307": true, // mongodb
+	"1c8bfe8f801d79745c4631d09fff36c82aa37fc4cce4fc946683d7b336b63032": true, // letmein
+	"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08": true, // test
+}
+
+// isWeakCredential reports whether value is a well-known default or weak
+// credential.
+func isWeakCredential(value string) bool {
+	sum := sha256.Sum256([]byte(value))
+	return weakCredentialHashes[hex.EncodeToString(sum[:])]
+}
+
+// isCredentialName reports whether the name of an environment variable or
+// secret key suggests it holds a credential.
+func isCredentialName(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range []string{"password", "passwd", "secret", "token", "key"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckWeakCredentials will check all secrets and the credential-like environment variables of all deployconfigs in
+// the supplied project and if any holds a well-known default or weak credential this will be reflected in the
+// returned Result data. Values are only compared by hash and never reported. Any errors are written to the supplied
+// stdErr writer
+func CheckWeakCredentials(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+	result := Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: "weak-credentials", CheckName: "check for default or weak credentials"}
+	secrets := Secrets{}
+	if err := getResourceStruct(ctx, project, "secrets", &secrets); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	deploymentConfigs := DeploymentConfigs{}
+	if err := getResourceStruct(ctx, project, "dc", &deploymentConfigs); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	return checkWeakCredentials(result, secrets, deploymentConfigs), nil
+}
+
+func checkWeakCredentials(result Result, secrets Secrets, deploymentConfigs DeploymentConfigs) Result {
+	found := func(info Info) {
+		result.Status = StatusCritical
+		result.StatusMessage = "default or weak credentials detected"
+		result.Info = append(result.Info, info)
+	}
+	for _, secret := range secrets.Items {
+		for key, value := range secret.Data {
+			decoded, err := base64.StdEncoding.DecodeString(value)
+			if err != nil || !isWeakCredential(string(decoded)) {
+				continue
+			}
+			found(Info{Name: secret.Metadata.Name, Namespace: secret.Metadata.Namespace, Kind: secret.Kind, Count: 1, Message: "the key '" + key + "' holds a default or weak credential"})
+		}
+	}
+	for _, dc := range deploymentConfigs.Items {
+		for _, container := range dc.Spec.Template.Spec.Containers {
+			for _, env := range container.Env {
+				if !isCredentialName(env.Name) || !isWeakCredential(env.Value) {
+					continue
+				}
+				found(Info{Name: dc.Metadata.Name, Namespace: dc.Metadata.Namespace, Kind: dc.Kind, Count: 1, Message: "the environment variable '" + env.Name + "' of container '" + container.Name + "' holds a default or weak credential"})
+			}
+		}
+	}
+	return result
+}
+
+type Routes struct {
+	Items []Route `json:"items"`
+}
+
+type RouteBackend struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Weight *int   `json:"weight"`
+}
+
+type Route struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name        string            `json:"name"`
+		Namespace   string            `json:"namespace"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		Host string       `json:"host"`
+		To   RouteBackend `json:"to"`
+		// AlternateBackends receive a share of the traffic of the
+		// route, according to their weights.
+		AlternateBackends []RouteBackend `json:"alternateBackends"`
+		TLS               *struct {
+			Termination string `json:"termination"`
+		} `json:"tls"`
+	} `json:"spec"`
+}
+
+// ipWhitelistAnnotation restricts the source addresses allowed to access a
+// route.
+const ipWhitelistAnnotation = "haproxy.router.openshift.io/ip_whitelist"
+
+// adminInterfaceNames are fragments of the names of routes or services that
+// expose internal admin interfaces.
+var adminInterfaceNames = []string{"nagios", "mongo", "metrics", "grafana", "prometheus", "kibana", "admin"}
+
+// isAdminRoute reports whether the route exposes an internal admin interface.
+func isAdminRoute(route Route) bool {
+	for _, name := range []string{route.Metadata.Name, route.Spec.To.Name} {
+		name = strings.ToLower(name)
+		for _, s := range adminInterfaceNames {
+			if strings.Contains(name, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CheckAdminRoutesExposed will check all routes in the supplied project and if any exposes an internal admin interface
+// without an IP whitelist this will be reflected in the returned Result data. Routes without TLS are critical. Any
+// errors are written to the supplied stdErr writer
+func CheckAdminRoutesExposed(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+	routes := Routes{}
+	if err := getResourceStruct(ctx, project, "routes", &routes); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: "admin-routes-exposed", CheckName: "check routes exposing admin interfaces"}, err
+	}
+	return checkAdminRoutesExposed(routes), nil
+}
+
+func checkAdminRoutesExposed(routes Routes) Result {
+	result := Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: "admin-routes-exposed", CheckName: "check routes exposing admin interfaces"}
+	for _, route := range routes.Items {
+		if !isAdminRoute(route) || route.Metadata.Annotations[ipWhitelistAnnotation] != "" {
+			continue
+		}
+		status, message := StatusWarning, "the route exposes an admin interface to any address"
+		if route.Spec.TLS == nil {
+			status, message = StatusCritical, "the route exposes an admin interface to any address without TLS"
+		}
+		info := Info{Name: route.Metadata.Name, Namespace: route.Metadata.Namespace, Kind: route.Kind, Count: 1, Message: message + " at " + route.Spec.Host}
+		if status > result.Status {
+			result.Status = status
+		}
+		result.StatusMessage = "one or more routes expose admin interfaces without IP whitelisting"
+		result.Info = append(result.Info, info)
+	}
+	return result
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCheckWeakCredentials(t *testing.T) {
+	var secret Secret
+	secret.Kind = "Secret"
+	secret.Metadata.Name = "nagios"
+	secret.Data = map[string]string{
+		"password": base64.StdEncoding.EncodeToString([]byte("password")),
+		"username": base64.StdEncoding.EncodeToString([]byte("nagiosadmin")),
+		"strong":   base64.StdEncoding.EncodeToString([]byte("Tr0ub4dor&3-correct-horse")),
+	}
+	var dc DeploymentConfig
+	dc.Kind = "DeploymentConfig"
+	dc.Metadata.Name = "mongodb"
+	dc.Spec.Template.Spec.Containers = []Container{{
+		Name: "mongodb",
+		Env: []EnvVar{
+			{Name: "MONGODB_ADMIN_PASSWORD", Value: "changeme"},
+			{Name: "MONGODB_USER", Value: "admin"},
+		},
+	}}
+
+	result := checkWeakCredentials(Result{}, Secrets{Items: []Secret{secret}}, DeploymentConfigs{Items: []DeploymentConfig{dc}})
+	if result.Status != StatusCritical {
+		t.Errorf("Status = %d, want %d", result.Status, StatusCritical)
+	}
+	// Secret keys are matched regardless of their name, environment
+	// variables only if their name suggests a credential.
+	if len(result.Info) != 3 {
+		t.Fatalf("len(Info) = %d, want 3: %+v", len(result.Info), result.Info)
+	}
+	for _, info := range result.Info {
+		for _, value := range []string{"changeme", "nagiosadmin", "cGFzc3dvcmQ="} {
+			if strings.Contains(info.Message, value) {
+				t.Errorf("Info exposes a credential value: %q", info.Message)
+			}
+		}
+	}
+
+	if result := checkWeakCredentials(Result{}, Secrets{}, DeploymentConfigs{}); result.Status != StatusOK {
+		t.Errorf("Status = %d, want %d", result.Status, StatusOK)
+	}
+}
+
+func TestCheckAdminRoutesExposed(t *testing.T) {
+	var routes Routes
+	err := json.Unmarshal([]byte(`{"items": [
+		{"metadata": {"name": "nagios"}, "spec": {"host": "nagios.example.com", "to": {"name": "nagios"}}},
+		{"metadata": {"name": "ui"}, "spec": {"host": "mongo.example.com", "to": {"name": "mongo-express"}, "tls": {"termination": "edge"}}},
+		{"metadata": {"name": "grafana", "annotations": {"haproxy.router.openshift.io/ip_whitelist": "10.0.0.0/8"}}, "spec": {"to": {"name": "grafana"}}},
+		{"metadata": {"name": "rhmap"}, "spec": {"host": "rhmap.example.com", "to": {"name": "fh-ngui"}}}
+	]}`), &routes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := checkAdminRoutesExposed(routes)
+	if result.Status != StatusCritical {
+		t.Errorf("Status = %d, want %d", result.Status, StatusCritical)
+	}
+	var names []string
+	for _, info := range result.Info {
+		names = append(names, info.Name)
+	}
+	if got, want := strings.Join(names, " "), "nagios ui"; got != want {
+		t.Errorf("flagged routes = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// selfTestTimeout limits how long running oc may take during a self-test.
+const selfTestTimeout = 30 * time.Second
+
+// Fixtures of the self-test, in the format printed by oc. The limit range
+// gives mongodb less memory than it needs, which the sample check reports.
+const (
+	selfTestDeploymentConfigs = `{"kind": "List", "items": [{"kind": "DeploymentConfig", "metadata": {"name": "mongodb-1", "namespace": "rhmap-core"},
+		"spec": {"replicas": 1, "template": {"spec": {"containers": [{"name": "mongodb", "image": "rhmap47/mongodb:3.2"}]}}}}]}`
+	selfTestLimitRanges = `{"kind": "List", "items": [{"kind": "LimitRange", "metadata": {"name": "limits", "namespace": "rhmap-core"},
+		"spec": {"limits": [{"type": "Container", "max": {"memory": "256Mi"}}]}}]}`
+	selfTestSecret = "password=hunter2\n"
+)
+
+// A SelfTestStep is the outcome of one verification of the self-test.
+type SelfTestStep struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+}
+
+// A SelfTestReport lists the outcome of each verification of the self-test.
+type SelfTestReport struct {
+	Version string         `json:"toolVersion"`
+	Steps   []SelfTestStep `json:"steps"`
+}
+
+// Failed returns the number of steps that failed.
+func (r SelfTestReport) Failed() int {
+	failed := 0
+	for _, s := range r.Steps {
+		if !s.OK {
+			failed++
+		}
+	}
+	return failed
+}
+
+func (r *SelfTestReport) step(name string, detail string, err error) {
+	if err != nil {
+		detail = err.Error()
+	}
+	r.Steps = append(r.Steps, SelfTestStep{Name: name, OK: err == nil, Detail: detail})
+}
+
+// RunSelfTest verifies that the tool can run oc, parse its output, write to
+// dir, build archives and run checks, using bundled fixtures rather than the
+// cluster wherever possible.
+func RunSelfTest(ctx context.Context, dir string) SelfTestReport {
+	report := SelfTestReport{Version: version}
+	report.step(selfTestOc(ctx))
+	report.step(selfTestParse())
+	report.step(selfTestWrite(dir))
+	report.step(selfTestArchive())
+	report.step(selfTestCheck())
+	return report
+}
+
+// selfTestOc runs oc version. Failing to reach the server is not a problem of
+// the tool, as long as oc ran.
+func selfTestOc(ctx context.Context) (string, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	err := runCmdCaptureOutputOnce(ctx, ocCommand("version"), &stdout, &stderr)
+	client := strings.SplitN(strings.TrimSpace(stdout.String()), "\n", 2)[0]
+	switch {
+	case err != nil && client == "":
+		return "run oc", "", fmt.Errorf("%v (%s)", err, errorHints[classCommandNotRun])
+	case err != nil:
+		return "run oc", client + ", the server could not be reached: " + strings.SplitN(strings.TrimSpace(stderr.String()), "\n", 2)[0], nil
+	}
+	return "run oc", client, nil
+}
+
+// selfTestParse parses the fixtures of the self-test.
+func selfTestParse() (string, string, error) {
+	var dcs DeploymentConfigs
+	if err := json.Unmarshal([]byte(selfTestDeploymentConfigs), &dcs); err != nil {
+		return "parse oc output", "", err
+	}
+	if len(dcs.Items) != 1 || dcs.Items[0].Metadata.Name != "mongodb-1" {
+		return "parse oc output", "", fmt.Errorf("parsed %d deployment configs, want mongodb-1", len(dcs.Items))
+	}
+	return "parse oc output", "parsed the deployment config fixture", nil
+}
+
+// selfTestWrite writes a file to dir, where dumps are written, and removes
+// it.
+func selfTestWrite(dir string) (string, string, error) {
+	if err := os.MkdirAll(dir, 0770); err != nil {
+		return "write to the dump directory", "", err
+	}
+	f, err := ioutil.TempFile(dir, ".selftest-")
+	if err != nil {
+		return "write to the dump directory", "", err
+	}
+	_, err = f.WriteString(selfTestSecret)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if removeErr := os.Remove(f.Name()); err == nil {
+		err = removeErr
+	}
+	return "write to the dump directory", "wrote to " + dir, err
+}
+
+// selfTestArchive builds an archive of a redacted fixture with the default
+// compression and reads it back.
+func selfTestArchive() (string, string, error) {
+	var b bytes.Buffer
+	archive, err := NewArchive(&b, compressionGzip)
+	if err != nil {
+		return "build an archive", "", err
+	}
+	if archive.Redactor, err = NewRedactor(defaultRedactionRules); err != nil {
+		return "build an archive", "", err
+	}
+	out := archive.GetWriterToFile("selftest.txt")
+	io.WriteString(out, selfTestSecret)
+	if err := out.Close(); err != nil {
+		return "build an archive", "", err
+	}
+	if err := archive.Close(); err != nil {
+		return "build an archive", "", err
+	}
+	files, err := ReadTgz(&b, func(string) bool { return true })
+	if err != nil {
+		return "build an archive", "", err
+	}
+	content, ok := files["selftest.txt"]
+	switch {
+	case !ok:
+		return "build an archive", "", fmt.Errorf("selftest.txt is missing from the archive")
+	case bytes.Contains(content, []byte("hunter2")):
+		return "build an archive", "", fmt.Errorf("selftest.txt was not redacted: %q", content)
+	}
+	return "build an archive", "built, redacted and read back a gzip archive", nil
+}
+
+// selfTestCheck runs the limit range check against the fixtures, which it
+// must report.
+func selfTestCheck() (string, string, error) {
+	var (
+		dcs         DeploymentConfigs
+		limitRanges LimitRanges
+	)
+	if err := json.Unmarshal([]byte(selfTestDeploymentConfigs), &dcs); err != nil {
+		return "run a sample check", "", err
+	}
+	if err := json.Unmarshal([]byte(selfTestLimitRanges), &limitRanges); err != nil {
+		return "run a sample check", "", err
+	}
+	result := checkLimitRangeDefaults(Result{Status: StatusOK, CheckID: "limitrange-conflict"}, limitRanges, dcs)
+	if result.Status != StatusWarning || len(result.Info) != 1 {
+		return "run a sample check", "", fmt.Errorf("the limit range check returned status %d with %d findings, want a warning", result.Status, len(result.Info))
+	}
+	return "run a sample check", "the limit range check reported the fixture", nil
+}
+
+// WriteText writes the report as text to w.
+func (r SelfTestReport) WriteText(w io.Writer) error {
+	for _, s := range r.Steps {
+		outcome := "PASS"
+		if !s.OK {
+			outcome = "FAIL"
+		}
+		if _, err := fmt.Fprintf(w, "%s  %s: %s\n", outcome, s.Name, s.Detail); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "fh-system-dump-tool %s: %d of %d steps passed\n", r.Version, len(r.Steps)-r.Failed(), len(r.Steps))
+	return err
+}
+
+// selfTestCommand verifies that the tool itself works, to tell problems of the
+// tool from problems of the cluster.
+func selfTestCommand(args []string) error {
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+	dir := fs.String("dir", dumpDir, "dump directory to verify the tool can write to")
+	format := fs.String("format", "text", "output format: text or json")
+	fs.Parse(args)
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q, must be one of: text, json", *format)
+	}
+	report := RunSelfTest(context.Background(), *dir)
+	if *format == "json" {
+		output, err := json.MarshalIndent(report, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "%s\n", output)
+	} else if err := report.WriteText(os.Stdout); err != nil {
+		return err
+	}
+	if failed := report.Failed(); failed > 0 {
+		return fmt.Errorf("%d of %d self-test steps failed", failed, len(report.Steps))
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/feedhenry/fh-system-dump-tool/cmdrunner"
+)
+
+func TestRunSelfTest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "selftest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(r cmdrunner.Runner) { runner = r }(runner)
+
+	runner = cmdrunner.NewFakeRunner([]cmdrunner.Invocation{{Args: ocCommand("version").Args, Stdout: "oc v3.11.0+0cbc58b\nkubernetes v1.11.0+d4cacc0\n"}})
+	report := RunSelfTest(context.Background(), filepath.Join(dir, "rhmap-dumps"))
+	if report.Failed() != 0 {
+		t.Errorf("Steps = %+v, want all of them to pass", report.Steps)
+	}
+	var b bytes.Buffer
+	if err := report.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "PASS  run oc: oc v3.11.0+0cbc58b\n") || !strings.Contains(b.String(), "5 of 5 steps passed") {
+		t.Errorf("WriteText() = %q", b.String())
+	}
+	if files, _ := ioutil.ReadDir(filepath.Join(dir, "rhmap-dumps")); len(files) != 0 {
+		t.Errorf("the self-test left %d files behind", len(files))
+	}
+
+	// Reaching the server is not required.
+	runner = cmdrunner.NewFakeRunner([]cmdrunner.Invocation{{Args: ocCommand("version").Args, Stdout: "oc v3.11.0+0cbc58b\n", Stderr: "dial tcp: connection refused", Error: "exit status 1"}})
+	if name, detail, err := selfTestOc(context.Background()); err != nil || !strings.Contains(detail, "the server could not be reached") {
+		t.Errorf("selfTestOc() = %s, %q, %v, want a pass noting the server could not be reached", name, detail, err)
+	}
+	runner = cmdrunner.NewFakeRunner(nil)
+	if _, _, err := selfTestOc(context.Background()); err == nil {
+		t.Error("selfTestOc() without oc didn't return an error")
+	}
+}
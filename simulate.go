@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// parseSimulateDrain returns the node named by the argument of the
+// -simulate-drain flag.
+func parseSimulateDrain(arg string) (string, error) {
+	if !strings.HasPrefix(arg, "node=") || arg == "node=" {
+		return "", fmt.Errorf("argument to -simulate-drain flag must be node=<name>")
+	}
+	return strings.TrimPrefix(arg, "node="), nil
+}
+
+// A drainImpact is how draining a node affects an RHMAP component.
+type drainImpact struct {
+	Project   string
+	Component string
+	// Running is the number of running replicas of the component, and
+	// OnNode how many of them run on the drained node.
+	Running int
+	OnNode  int
+}
+
+// down reports whether the component has no replica left while the node
+// drains, until its pods are scheduled elsewhere.
+func (i drainImpact) down() bool {
+	return i.OnNode == i.Running
+}
+
+// simulateDrain returns how draining node affects the RHMAP components of each
+// project, given the deployment configs and pods of each project, sorted by
+// project and component, the components going down first. Components with no
+// replica on node are left out. It also returns the nodes hosting pods.
+func simulateDrain(node string, dcs map[string]DeploymentConfigs, pods map[string]Pods) ([]drainImpact, []string) {
+	var (
+		impacts []drainImpact
+		all     Pods
+	)
+	for project, projectDCs := range dcs {
+		kind := rhmapProjectKind(projectDCs)
+		if kind == "" {
+			continue
+		}
+		components := make(map[string]bool)
+		for _, dc := range projectDCs.Items {
+			components[dc.Metadata.Name] = true
+		}
+		for _, c := range referenceComponents[kind] {
+			if !components[c] {
+				continue
+			}
+			impact := drainImpact{Project: project, Component: c}
+			for _, pod := range pods[project].Items {
+				if pod.Metadata.Labels["deploymentconfig"] != c || pod.Status.Phase != "Running" {
+					continue
+				}
+				impact.Running++
+				if pod.Spec.NodeName == node {
+					impact.OnNode++
+				}
+			}
+			if impact.OnNode > 0 {
+				impacts = append(impacts, impact)
+			}
+		}
+	}
+	for _, p := range pods {
+		all.Items = append(all.Items, p.Items...)
+	}
+	sort.Slice(impacts, func(i, j int) bool {
+		if impacts[i].down() != impacts[j].down() {
+			return impacts[i].down()
+		}
+		if impacts[i].Project != impacts[j].Project {
+			return impacts[i].Project < impacts[j].Project
+		}
+		return impacts[i].Component < impacts[j].Component
+	})
+	return impacts, podNodes(all)
+}
+
+// writeDrainSimulation writes the impacts of draining node to w.
+func writeDrainSimulation(w io.Writer, node string, impacts []drainImpact) error {
+	if len(impacts) == 0 {
+		_, err := fmt.Fprintf(w, "Draining node %s affects no RHMAP component\n", node)
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "Draining node %s affects %d RHMAP components:\n", node, len(impacts)); err != nil {
+		return err
+	}
+	for _, i := range impacts {
+		effect := fmt.Sprintf("loses %d of its %d running replicas", i.OnNode, i.Running)
+		switch {
+		case i.down() && i.Running == 1:
+			effect = "goes down, its only running replica is on the node, until it is scheduled elsewhere"
+		case i.down():
+			effect = fmt.Sprintf("goes down, all its %d running replicas are on the node, until they are scheduled elsewhere", i.Running)
+		}
+		if _, err := fmt.Fprintf(w, "  %s/%s: %s\n", i.Project, i.Component, effect); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SimulateDrain reports which RHMAP components would lose capacity or go down
+// if node were drained, from the placement of pods recorded in the dump
+// archive at dumpPath.
+func SimulateDrain(w io.Writer, dumpPath, node string) error {
+	f, err := os.Open(dumpPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	files, err := ReadTgz(f, func(name string) bool {
+		return isResourceDefinition("deploymentconfigs")(name) || isResourceDefinition("pods")(name)
+	})
+	if err != nil {
+		return err
+	}
+	dcs := make(map[string]DeploymentConfigs)
+	pods := make(map[string]Pods)
+	for name, content := range files {
+		project := path.Base(path.Dir(name))
+		if path.Base(name) == "pods.json" {
+			var p Pods
+			if err := json.Unmarshal(content, &p); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+			pods[project] = p
+			continue
+		}
+		var d DeploymentConfigs
+		if err := json.Unmarshal(content, &d); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		dcs[project] = d
+	}
+	impacts, nodes := simulateDrain(node, dcs, pods)
+	if !containsString(nodes, node) {
+		if len(nodes) == 0 {
+			return fmt.Errorf("dump %s records the placement of no pods", dumpPath)
+		}
+		return fmt.Errorf("node %s hosts none of the pods of dump %s, which run on: %s", node, dumpPath, strings.Join(nodes, ", "))
+	}
+	return writeDrainSimulation(w, node, impacts)
+}
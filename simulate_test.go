@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseSimulateDrain(t *testing.T) {
+	if node, err := parseSimulateDrain("node=node-1.example.com"); err != nil || node != "node-1.example.com" {
+		t.Errorf("parseSimulateDrain() = %q, %v, want node-1.example.com", node, err)
+	}
+	for _, arg := range []string{"node=", "node-1", "project=core"} {
+		if _, err := parseSimulateDrain(arg); err == nil {
+			t.Errorf("parseSimulateDrain(%q) didn't return an error", arg)
+		}
+	}
+}
+
+func TestSimulateDrain(t *testing.T) {
+	var dcs DeploymentConfigs
+	if err := json.Unmarshal([]byte(`{"items": [{"metadata": {"name": "fh-mbaas"}}, {"metadata": {"name": "fh-messaging"}}, {"metadata": {"name": "fh-metrics"}}]}`), &dcs); err != nil {
+		t.Fatal(err)
+	}
+	var pods Pods
+	if err := json.Unmarshal([]byte(`{"items": [
+		{"metadata": {"labels": {"deploymentconfig": "fh-mbaas"}}, "spec": {"nodeName": "node-1"}, "status": {"phase": "Running"}},
+		{"metadata": {"labels": {"deploymentconfig": "fh-mbaas"}}, "spec": {"nodeName": "node-2"}, "status": {"phase": "Running"}},
+		{"metadata": {"labels": {"deploymentconfig": "fh-messaging"}}, "spec": {"nodeName": "node-1"}, "status": {"phase": "Running"}},
+		{"metadata": {"labels": {"deploymentconfig": "fh-messaging"}}, "spec": {"nodeName": "node-2"}, "status": {"phase": "Failed"}},
+		{"metadata": {"labels": {"deploymentconfig": "fh-metrics"}}, "spec": {"nodeName": "node-3"}, "status": {"phase": "Running"}}]}`), &pods); err != nil {
+		t.Fatal(err)
+	}
+	impacts, nodes := simulateDrain("node-1", map[string]DeploymentConfigs{"mbaas": dcs}, map[string]Pods{"mbaas": pods})
+	want := []drainImpact{
+		{Project: "mbaas", Component: "fh-messaging", Running: 1, OnNode: 1},
+		{Project: "mbaas", Component: "fh-mbaas", Running: 2, OnNode: 1},
+	}
+	if !reflect.DeepEqual(impacts, want) {
+		t.Errorf("simulateDrain() = %+v, want %+v", impacts, want)
+	}
+	if want := []string{"node-1", "node-2", "node-3"}; !reflect.DeepEqual(nodes, want) {
+		t.Errorf("simulateDrain() nodes = %v, want %v", nodes, want)
+	}
+
+	var b bytes.Buffer
+	if err := writeDrainSimulation(&b, "node-1", impacts); err != nil {
+		t.Fatal(err)
+	}
+	wantText := `Draining node node-1 affects 2 RHMAP components:
+  mbaas/fh-messaging: goes down, its only running replica is on the node, until it is scheduled elsewhere
+  mbaas/fh-mbaas: loses 1 of its 2 running replicas
+`
+	if b.String() != wantText {
+		t.Errorf("writeDrainSimulation() = %q, want %q", b.String(), wantText)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/feedhenry/fh-system-dump-tool/dumpformat"
+)
+
+// maxSizeProjects is the number of projects listed in the size breakdown
+// printed at the end of a run.
+const maxSizeProjects = 5
+
+// execCategories are the top-level directories holding the output of commands
+// executed inside pods, accounted for together.
+var execCategories = map[string]bool{"network": true, "router": true}
+
+// sizeKnobs suggests the flags reducing the size of a category.
+var sizeKnobs = map[string]string{
+	"logs":   "-max-log-lines, -container or -dedupe-logs",
+	"nagios": "-nagios-history-gzip",
+}
+
+// fileCategory returns the category of the dump file name, and the project it
+// belongs to, if any. Files at the root of the dump are in the "summary"
+// category.
+func fileCategory(name string, projects []string) (category, project string) {
+	parts := strings.Split(name, "/")
+	if len(parts) == 1 {
+		return "summary", ""
+	}
+	category = parts[0]
+	if execCategories[category] {
+		category = "exec"
+	}
+	switch {
+	case len(parts) > 3 && parts[1] == "projects":
+		// <category>/projects/<project>/...
+		project = parts[2]
+	case len(parts) > 2 && containsString(projects, parts[1]):
+		// <category>/<project>/...
+		project = parts[1]
+	case len(parts) == 2 && containsString(projects, strings.TrimSuffix(parts[1], path.Ext(parts[1]))):
+		// <category>/<project>.json
+		project = strings.TrimSuffix(parts[1], path.Ext(parts[1]))
+	}
+	return category, project
+}
+
+// accountSizes returns the sizes of the files listed in m, given the projects
+// of the dump.
+func accountSizes(m dumpformat.Manifest, projects []string) dumpformat.SizeAccounting {
+	sizes := dumpformat.SizeAccounting{Categories: make(map[string]int64), Projects: make(map[string]int64)}
+	for _, f := range m.Files {
+		category, project := fileCategory(f.Name, projects)
+		sizes.Total += f.Size
+		sizes.Categories[category] += f.Size
+		if project != "" {
+			sizes.Projects[project] += f.Size
+		}
+	}
+	return sizes
+}
+
+// A namedSize is the size of a category or project.
+type namedSize struct {
+	Name string
+	Size int64
+}
+
+// largest returns the entries of sizes, largest first.
+func largest(sizes map[string]int64) []namedSize {
+	var list []namedSize
+	for name, size := range sizes {
+		list = append(list, namedSize{name, size})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Size != list[j].Size {
+			return list[i].Size > list[j].Size
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// WriteSizeBreakdown writes the size of the dump by category and its largest
+// projects to w, with the flags to reduce the largest category.
+func WriteSizeBreakdown(w io.Writer, sizes dumpformat.SizeAccounting) {
+	fmt.Fprintf(w, "Dump size (uncompressed): %s\n", formatSize(sizes.Total))
+	categories := largest(sizes.Categories)
+	for _, c := range categories {
+		fmt.Fprintf(w, "  %-12s %8s\n", c.Name, formatSize(c.Size))
+	}
+	projects := largest(sizes.Projects)
+	if len(projects) > maxSizeProjects {
+		projects = projects[:maxSizeProjects]
+	}
+	if len(projects) > 0 {
+		fmt.Fprintln(w, "Largest projects:")
+	}
+	for _, p := range projects {
+		fmt.Fprintf(w, "  %-12s %8s\n", p.Name, formatSize(p.Size))
+	}
+	if len(categories) > 0 {
+		if knobs, ok := sizeKnobs[categories[0].Name]; ok {
+			fmt.Fprintf(w, "Most of the dump is %s, reduce it with %s.\n", categories[0].Name, knobs)
+		}
+	}
+}
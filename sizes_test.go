@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/feedhenry/fh-system-dump-tool/dumpformat"
+)
+
+func TestAccountSizes(t *testing.T) {
+	projects := []string{"core", "mbaas"}
+	m := dumpformat.Manifest{Files: []dumpformat.ManifestEntry{
+		{Name: "metadata.json", Size: 1},
+		{Name: "definitions/projects/core/pods.json", Size: 10},
+		{Name: "logs/projects/core/pods_fh-ngui-1-abcde.logs", Size: 1000},
+		{Name: "logs/projects/mbaas/pods_fh-mbaas-1-abcde.logs", Size: 500},
+		{Name: "nagios/mbaas/history.tar.gz", Size: 200},
+		{Name: "studio/core.json", Size: 2},
+		{Name: "network/nodes/node1.txt", Size: 30},
+		{Name: "router/haproxy.config", Size: 40},
+	}}
+	sizes := accountSizes(m, projects)
+	if sizes.Total != 1783 {
+		t.Errorf("Total = %d, want 1783", sizes.Total)
+	}
+	for category, want := range map[string]int64{"summary": 1, "definitions": 10, "logs": 1500, "nagios": 200, "studio": 2, "exec": 70} {
+		if got := sizes.Categories[category]; got != want {
+			t.Errorf("Categories[%q] = %d, want %d", category, got, want)
+		}
+	}
+	for project, want := range map[string]int64{"core": 1012, "mbaas": 700} {
+		if got := sizes.Projects[project]; got != want {
+			t.Errorf("Projects[%q] = %d, want %d", project, got, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	WriteSizeBreakdown(&buf, sizes)
+	if !strings.Contains(buf.String(), "Most of the dump is logs, reduce it with -max-log-lines") {
+		t.Errorf("size breakdown doesn't suggest how to reduce logs:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultSmokeTestPath is the cloud app endpoint called by the smoke test when
+// none is configured.
+const defaultSmokeTestPath = "/cloud/hello"
+
+// SmokeTestConfig configures a call to a cloud app made the way client devices
+// make it, to test the path from outside the cluster to one MBaaS
+// environment.
+type SmokeTestConfig struct {
+	// URL is the host URL of the cloud app in the environment.
+	URL string `json:"url"`
+	// Path is the endpoint called, defaultSmokeTestPath by default.
+	Path   string `json:"path"`
+	AppID  string `json:"appId"`
+	AppKey string `json:"appKey"`
+}
+
+// A TraceEvent is a step of an HTTP request, with the time elapsed since the
+// request started.
+type TraceEvent struct {
+	Event   string  `json:"event"`
+	Detail  string  `json:"detail,omitempty"`
+	Elapsed float64 `json:"elapsedSeconds"`
+}
+
+// A SmokeTestTrace records a smoke test call.
+type SmokeTestTrace struct {
+	Request struct {
+		Method string              `json:"method"`
+		URL    string              `json:"url"`
+		Header map[string][]string `json:"header"`
+		Body   json.RawMessage     `json:"body"`
+	} `json:"request"`
+	Events   []TraceEvent   `json:"events"`
+	Response EndpointStatus `json:"response"`
+}
+
+// smokeTestRequest returns the request a client device makes to call the
+// configured cloud endpoint.
+func (c SmokeTestConfig) smokeTestRequest() (*http.Request, []byte, error) {
+	path := c.Path
+	if path == "" {
+		path = defaultSmokeTestPath
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"__fh": map[string]string{
+			"appid":       c.AppID,
+			"appkey":      c.AppKey,
+			"cuid":        "fh-system-dump-tool",
+			"destination": "fh-system-dump-tool",
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequest("POST", strings.TrimSuffix(c.URL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-FH-AUTH-APP", c.AppKey)
+	return req, body, nil
+}
+
+// RunSmokeTest makes the configured cloud app call with client, and returns a
+// trace of it. The app key is not recorded.
+func RunSmokeTest(client *http.Client, c SmokeTestConfig) (SmokeTestTrace, error) {
+	var trace SmokeTestTrace
+	req, body, err := c.smokeTestRequest()
+	if err != nil {
+		return trace, err
+	}
+	trace.Request.Method = req.Method
+	trace.Request.URL = req.URL.String()
+	trace.Request.Header = map[string][]string{}
+	for k, v := range req.Header {
+		trace.Request.Header[k] = v
+	}
+	trace.Request.Header["X-Fh-Auth-App"] = []string{redacted}
+	trace.Request.Body = body
+	if c.AppKey != "" {
+		trace.Request.Body = bytes.Replace(body, []byte(c.AppKey), []byte(redacted), -1)
+	}
+
+	start := time.Now()
+	// Trace hooks may be called concurrently.
+	var mu sync.Mutex
+	event := func(name, detail string) {
+		mu.Lock()
+		defer mu.Unlock()
+		trace.Events = append(trace.Events, TraceEvent{Event: name, Detail: detail, Elapsed: time.Since(start).Seconds()})
+	}
+	errDetail := func(err error) string {
+		if err != nil {
+			return err.Error()
+		}
+		return ""
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		DNSStart: func(info httptrace.DNSStartInfo) { event("dns start", info.Host) },
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			var addrs []string
+			for _, a := range info.Addrs {
+				addrs = append(addrs, a.String())
+			}
+			detail := strings.Join(addrs, " ")
+			if info.Err != nil {
+				detail = info.Err.Error()
+			}
+			event("dns done", detail)
+		},
+		ConnectStart:      func(network, addr string) { event("connect start", addr) },
+		ConnectDone:       func(network, addr string, err error) { event("connect done", errDetail(err)) },
+		TLSHandshakeStart: func() { event("tls handshake start", "") },
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			detail := errDetail(err)
+			if err == nil && len(state.PeerCertificates) > 0 {
+				detail = "certificate subject: " + state.PeerCertificates[0].Subject.CommonName
+			}
+			event("tls handshake done", detail)
+		},
+		WroteRequest:         func(info httptrace.WroteRequestInfo) { event("wrote request", errDetail(info.Err)) },
+		GotFirstResponseByte: func() { event("first response byte", "") },
+	}))
+
+	trace.Response.URL = trace.Request.URL
+	resp, err := client.Do(req)
+	mu.Lock()
+	defer mu.Unlock()
+	if err != nil {
+		trace.Response.Error = err.Error()
+	} else {
+		defer resp.Body.Close()
+		trace.Response.StatusCode = resp.StatusCode
+		trace.Response.Header = resp.Header
+		respBody, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxStatusBody))
+		if err != nil {
+			trace.Response.Error = err.Error()
+		}
+		if json.Valid(respBody) {
+			trace.Response.Body = respBody
+		} else {
+			trace.Response.Text = string(respBody)
+		}
+	}
+	trace.Response.Duration = time.Since(start).Seconds()
+	return trace, nil
+}
+
+// GetSmokeTestTask returns a task that runs the configured smoke test and
+// writes its trace to status/smoke-test.json. A failing call is recorded in
+// the trace, not returned as an error.
+func GetSmokeTestTask(c SmokeTestConfig, tarFile *Archive) Task {
+	out := tarFile.GetWriterToFile("status/smoke-test.json")
+	return func(ctx context.Context) error {
+		defer out.Close()
+		trace, err := RunSmokeTest(&http.Client{Timeout: statusTimeout}, c)
+		if err != nil {
+			return err
+		}
+		output, err := json.MarshalIndent(trace, "", "    ")
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(output)
+		return err
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRunSmokeTest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			FH map[string]string `json:"__fh"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path != defaultSmokeTestPath || r.Header.Get("X-FH-AUTH-APP") != "secret-key" || body.FH["appkey"] != "secret-key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"msg": "Hello World"}`))
+	}))
+	defer server.Close()
+
+	trace, err := RunSmokeTest(server.Client(), SmokeTestConfig{URL: server.URL + "/", AppID: "app", AppKey: "secret-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trace.Response.StatusCode != http.StatusOK || trace.Response.Error != "" {
+		t.Errorf("Response = %+v, want 200 OK", trace.Response)
+	}
+	if len(trace.Events) == 0 {
+		t.Error("no trace events recorded")
+	}
+	output, err := json.Marshal(trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(output), "secret-key") {
+		t.Errorf("trace records the app key: %s", output)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/feedhenry/fh-system-dump-tool/dumpformat"
+)
+
+// A SplitManifest describes how a dump archive was split into chunks. It is
+// written next to the chunks, as <archive>.split.json.
+type SplitManifest struct {
+	File   string                     `json:"file"`
+	Size   int64                      `json:"size"`
+	SHA256 string                     `json:"sha256"`
+	Chunks []dumpformat.ManifestEntry `json:"chunks"`
+}
+
+// parseSize parses a size in bytes, with an optional K, M or G suffix for
+// multiples of 1024.
+func parseSize(s string) (int64, error) {
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * multiplier, nil
+}
+
+// formatSize formats a size in bytes for humans, with the suffixes accepted by
+// parseSize.
+func formatSize(n int64) string {
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}} {
+		if n >= unit.size {
+			return fmt.Sprintf("%.1f%s", float64(n)/float64(unit.size), unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%dB", n)
+}
+
+// SplitArchive splits the file at path into numbered chunks of at most size
+// bytes, path.001, path.002 and so on, and writes their manifest to
+// path.split.json. The original file is left in place.
+func SplitArchive(path string, size int64) (SplitManifest, error) {
+	manifest := SplitManifest{File: filepath.Base(path)}
+	f, err := os.Open(path)
+	if err != nil {
+		return manifest, err
+	}
+	defer f.Close()
+	whole := sha256.New()
+	r := io.TeeReader(f, whole)
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("%s.%03d", path, i)
+		entry, err := writeChunk(name, io.LimitReader(r, size))
+		if err != nil {
+			return manifest, err
+		}
+		if entry.Size == 0 {
+			os.Remove(name)
+			break
+		}
+		manifest.Chunks = append(manifest.Chunks, entry)
+		manifest.Size += entry.Size
+		if entry.Size < size {
+			break
+		}
+	}
+	manifest.SHA256 = hex.EncodeToString(whole.Sum(nil))
+	output, err := json.MarshalIndent(manifest, "", "    ")
+	if err != nil {
+		return manifest, err
+	}
+	return manifest, ioutil.WriteFile(path+".split.json", output, 0660)
+}
+
+func writeChunk(name string, r io.Reader) (dumpformat.ManifestEntry, error) {
+	entry := dumpformat.ManifestEntry{Name: filepath.Base(name)}
+	f, err := os.Create(name)
+	if err != nil {
+		return entry, err
+	}
+	h := sha256.New()
+	entry.Size, err = io.Copy(io.MultiWriter(f, h), r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	entry.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return entry, err
+}
+
+// JoinArchive reassembles the chunks described by the split manifest at
+// manifestPath, verifying their checksums, into the file out.
+func JoinArchive(manifestPath, out string) error {
+	content, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return err
+	}
+	var manifest SplitManifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return fmt.Errorf("%s: %v", manifestPath, err)
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	whole := sha256.New()
+	w := io.MultiWriter(f, whole)
+	dir := filepath.Dir(manifestPath)
+	for _, chunk := range manifest.Chunks {
+		c, err := os.Open(filepath.Join(dir, chunk.Name))
+		if err != nil {
+			return err
+		}
+		h := sha256.New()
+		_, err = io.Copy(io.MultiWriter(w, h), c)
+		c.Close()
+		if err != nil {
+			return err
+		}
+		if hex.EncodeToString(h.Sum(nil)) != chunk.SHA256 {
+			return fmt.Errorf("chunk %s doesn't match its checksum", chunk.Name)
+		}
+	}
+	if hex.EncodeToString(whole.Sum(nil)) != manifest.SHA256 {
+		return fmt.Errorf("%s doesn't match its checksum", out)
+	}
+	return f.Close()
+}
+
+// joinCommand reassembles a dump archive split with -split-size.
+func joinCommand(args []string) error {
+	fs := flag.NewFlagSet("join", flag.ExitOnError)
+	out := fs.String("o", "", "path of the reassembled archive (defaults to the original name, next to the chunks)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: join [-o path] archive.split.json")
+	}
+	manifestPath := fs.Arg(0)
+	if *out == "" {
+		*out = strings.TrimSuffix(manifestPath, ".split.json")
+		if *out == manifestPath {
+			return fmt.Errorf("%s is not a split manifest, give the output path with -o", manifestPath)
+		}
+	}
+	return JoinArchive(manifestPath, *out)
+}
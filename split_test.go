@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitAndJoinArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "split")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "2016-09-01T12-00-00Z.tar.gz")
+	content := bytes.Repeat([]byte("0123456789"), 25)
+	if err := ioutil.WriteFile(path, content, 0660); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := SplitArchive(path, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Chunks) != 3 || manifest.Chunks[2].Size != 50 || manifest.Size != 250 {
+		t.Errorf("SplitArchive() = %+v, want chunks of 100, 100 and 50 bytes", manifest)
+	}
+
+	joined := filepath.Join(dir, "joined.tar.gz")
+	if err := JoinArchive(path+".split.json", joined); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := ioutil.ReadFile(joined); !bytes.Equal(got, content) {
+		t.Errorf("joined archive differs from the original")
+	}
+
+	if err := ioutil.WriteFile(path+".002", []byte("corrupt"), 0660); err != nil {
+		t.Fatal(err)
+	}
+	if err := JoinArchive(path+".split.json", joined); err == nil {
+		t.Error("JoinArchive() with a corrupt chunk didn't return an error")
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for s, want := range map[string]int64{"100": 100, "2K": 2048, "100M": 100 << 20, "1G": 1 << 30} {
+		if got, err := parseSize(s); err != nil || got != want {
+			t.Errorf("parseSize(%q) = %d, %v, want %d", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "M", "-1M", "10T"} {
+		if _, err := parseSize(s); err == nil {
+			t.Errorf("parseSize(%q) didn't return an error", s)
+		}
+	}
+}
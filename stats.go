@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// statsWindow is the number of runs statistics are averaged over, so that
+// they follow clusters as they grow or shrink.
+const statsWindow = 10
+
+// Stats records statistics of previous dumps, by cluster.
+type Stats struct {
+	Clusters map[string]*ClusterStats `json:"clusters"`
+}
+
+// ClusterStats records the averages of previous dumps of a cluster.
+type ClusterStats struct {
+	Runs int `json:"runs"`
+	// Duration is the time from the start of the dump to the end of its
+	// tasks.
+	Duration time.Duration `json:"duration"`
+	// Size is the size of the dump archive, in bytes.
+	Size int64 `json:"size"`
+	// Commands maps command kinds, as returned by commandKind, to the
+	// statistics of the commands of that kind.
+	Commands map[string]CommandStats `json:"commands"`
+}
+
+// CommandStats records the average duration of commands of a kind.
+type CommandStats struct {
+	Count   int           `json:"count"`
+	Average time.Duration `json:"average"`
+}
+
+// LoadStats reads the statistics file at path. A missing file holds no
+// statistics.
+func LoadStats(path string) (*Stats, error) {
+	stats := &Stats{Clusters: make(map[string]*ClusterStats)}
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return stats, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, stats); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if stats.Clusters == nil {
+		stats.Clusters = make(map[string]*ClusterStats)
+	}
+	return stats, nil
+}
+
+// Save writes stats to the file at path, replacing it only once it is
+// complete.
+func (s *Stats) Save(path string) error {
+	content, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0770); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, content, 0660); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Cluster returns the statistics of cluster, or nil if it was never dumped.
+func (s *Stats) Cluster(cluster string) *ClusterStats {
+	return s.Clusters[cluster]
+}
+
+// Record adds a dump of cluster whose tasks ran for duration, with commands
+// of each kind running as in commands, and written to an archive of size
+// bytes.
+func (s *Stats) Record(cluster string, duration time.Duration, size int64, commands map[string]CommandStats) {
+	c := s.Clusters[cluster]
+	if c == nil {
+		c = &ClusterStats{Commands: make(map[string]CommandStats)}
+		s.Clusters[cluster] = c
+	}
+	if c.Runs < statsWindow {
+		c.Runs++
+	}
+	c.Duration += (duration - c.Duration) / time.Duration(c.Runs)
+	c.Size += (size - c.Size) / int64(c.Runs)
+	for kind, run := range commands {
+		if run.Count == 0 {
+			continue
+		}
+		old := c.Commands[kind]
+		count := old.Count + run.Count
+		if max := statsWindow * run.Count; count > max {
+			// Weigh old commands as if they came from at most
+			// statsWindow runs like this one.
+			old.Count -= count - max
+			count = max
+		}
+		old.Average += time.Duration(int64(run.Average-old.Average) * int64(run.Count) / int64(count))
+		c.Commands[kind] = CommandStats{Count: count, Average: old.Average}
+	}
+}
+
+// Estimate describes the expected duration and size of the next dump, based
+// on the statistics of previous ones.
+func (c *ClusterStats) Estimate() string {
+	return fmt.Sprintf("previous dumps took %v and were %s", c.Duration.Round(time.Second), formatSize(c.Size))
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStatsRecord(t *testing.T) {
+	stats := &Stats{Clusters: make(map[string]*ClusterStats)}
+	if stats.Cluster("master.example.com") != nil {
+		t.Fatal("Cluster() of a cluster never dumped is not nil")
+	}
+	stats.Record("master.example.com", 2*time.Minute, 100, map[string]CommandStats{"get pods": {Count: 2, Average: time.Second}})
+	stats.Record("master.example.com", 4*time.Minute, 300, map[string]CommandStats{"get pods": {Count: 2, Average: 3 * time.Second}})
+	c := stats.Cluster("master.example.com")
+	if c.Runs != 2 || c.Duration != 3*time.Minute || c.Size != 200 {
+		t.Errorf("after two runs, got %+v, want the averages of both", c)
+	}
+	if got := c.Commands["get pods"]; got.Count != 4 || got.Average != 2*time.Second {
+		t.Errorf("get pods = %+v, want 4 commands averaging 2s", got)
+	}
+
+	// Old runs fade away past the window.
+	for i := 0; i < 10*statsWindow; i++ {
+		stats.Record("master.example.com", time.Minute, 100, map[string]CommandStats{"get pods": {Count: 2, Average: time.Second}})
+	}
+	if c.Runs != statsWindow {
+		t.Errorf("Runs = %d, want at most %d", c.Runs, statsWindow)
+	}
+	if c.Duration > time.Minute+time.Second || c.Commands["get pods"].Average > time.Second+10*time.Millisecond {
+		t.Errorf("after many short runs, got %+v, want close to the recent averages", c)
+	}
+}
+
+func TestStatsSaveAndLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "stats.json")
+
+	stats, err := LoadStats(path)
+	if err != nil || len(stats.Clusters) != 0 {
+		t.Fatalf("LoadStats() of a missing file = %+v, %v, want no statistics", stats, err)
+	}
+	stats.Record("master.example.com", time.Minute, 100, nil)
+	if err := stats.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadStats(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := loaded.Cluster("master.example.com"); c == nil || c.Duration != time.Minute || c.Size != 100 {
+		t.Errorf("loaded %+v, want the saved statistics", c)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// statusTimeout limits how long fetching each status endpoint may
+	// take.
+	statusTimeout = 30 * time.Second
+	// maxStatusBody is the maximum number of bytes of each response body
+	// recorded.
+	maxStatusBody = 64 * 1024
+)
+
+// coreStatusEndpoints are the paths of the unauthenticated status endpoints of
+// the RHMAP Core, as used by client devices and the Studio.
+var coreStatusEndpoints = []string{"/sys/info/ping", "/sys/info/version", "/sys/info/health"}
+
+// An EndpointStatus records the response of a status endpoint, as seen from
+// where the dump tool runs.
+type EndpointStatus struct {
+	URL        string              `json:"url"`
+	StatusCode int                 `json:"statusCode,omitempty"`
+	Header     map[string][]string `json:"header,omitempty"`
+	// Body is the response body, or the first maxStatusBody bytes of it.
+	Body json.RawMessage `json:"body,omitempty"`
+	// Text is the response body when it is not JSON.
+	Text     string  `json:"text,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"durationSeconds"`
+}
+
+// FetchEndpointStatus requests url and records the response, or the error.
+func FetchEndpointStatus(client *http.Client, url string) EndpointStatus {
+	status := EndpointStatus{URL: url}
+	start := time.Now()
+	resp, err := client.Get(url)
+	if err != nil {
+		status.Error = err.Error()
+		status.Duration = time.Since(start).Seconds()
+		return status
+	}
+	defer resp.Body.Close()
+	status.StatusCode = resp.StatusCode
+	status.Header = resp.Header
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxStatusBody))
+	if err != nil {
+		status.Error = err.Error()
+	}
+	if json.Valid(body) {
+		status.Body = body
+	} else {
+		status.Text = string(body)
+	}
+	status.Duration = time.Since(start).Seconds()
+	return status
+}
+
+// GetCoreStatusTask returns a task that fetches the status endpoints of the
+// RHMAP Core at baseURL, and writes their responses to status/core.json.
+// Failing requests are recorded along the responses, not returned as errors.
+func GetCoreStatusTask(baseURL string, tarFile *Archive) Task {
+	out := tarFile.GetWriterToFile("status/core.json")
+	return func(ctx context.Context) error {
+		defer out.Close()
+		client := &http.Client{Timeout: statusTimeout}
+		statuses := []EndpointStatus{}
+		for _, endpoint := range coreStatusEndpoints {
+			statuses = append(statuses, FetchEndpointStatus(client, strings.TrimSuffix(baseURL, "/")+endpoint))
+		}
+		output, err := json.MarshalIndent(statuses, "", "    ")
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(output)
+		return err
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchEndpointStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sys/info/ping":
+			w.Write([]byte(`"OK"`))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := server.Client()
+
+	status := FetchEndpointStatus(client, server.URL+"/sys/info/ping")
+	if status.StatusCode != http.StatusOK || string(status.Body) != `"OK"` || status.Error != "" {
+		t.Errorf("ping: got %+v", status)
+	}
+	status = FetchEndpointStatus(client, server.URL+"/sys/info/health")
+	if status.StatusCode != http.StatusNotFound || status.Body != nil || status.Text != "not found\n" {
+		t.Errorf("health: got %+v", status)
+	}
+	server.Close()
+	status = FetchEndpointStatus(client, server.URL+"/sys/info/ping")
+	if status.Error == "" {
+		t.Errorf("closed server: got %+v, want an error", status)
+	}
+}
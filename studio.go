@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// studioDeploymentConfig is the name of the deployment config of the Studio
+// frontend, in the RHMAP Core project.
+const studioDeploymentConfig = "fh-ngui"
+
+// studioConfigSuffixes are the suffixes of the names of Studio environment
+// variables configuring URLs, hosts and API base paths.
+var studioConfigSuffixes = []string{"_URL", "_HOST", "_HOSTNAME", "_PATH", "_BASE"}
+
+// studioHostSuffixes are the suffixes of the names of Studio environment
+// variables holding URLs or host names, checked against routes.
+var studioHostSuffixes = []string{"_URL", "_HOST", "_HOSTNAME"}
+
+func hasAnySuffix(s string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// studioConfig returns the environment variables of the Studio frontend with a
+// name ending in one of suffixes.
+func studioConfig(dc DeploymentConfig, suffixes []string) map[string]string {
+	env := make(map[string]string)
+	for _, c := range dc.Spec.Template.Spec.Containers {
+		for _, e := range c.Env {
+			if e.Value != "" && hasAnySuffix(e.Name, suffixes) {
+				env[e.Name] = e.Value
+			}
+		}
+	}
+	return env
+}
+
+// findStudio returns the Studio deployment config among dcs, if any.
+func findStudio(dcs DeploymentConfigs) (DeploymentConfig, bool) {
+	for _, dc := range dcs.Items {
+		if dc.Metadata.Name == studioDeploymentConfig {
+			return dc, true
+		}
+	}
+	return DeploymentConfig{}, false
+}
+
+// GetStudioConfigTasks returns a list of tasks to record the URLs and API base
+// paths configured for the Studio frontend, in each project where it is
+// deployed, to studio/<project>.json. The logs of the Studio are collected
+// along those of all deployment configs.
+func GetStudioConfigTasks(projects []string, tarFile *Archive) []Task {
+	var tasks []Task
+	for _, p := range projects {
+		p := p
+		task := func(ctx context.Context) error {
+			var dcs DeploymentConfigs
+			if err := getResourceStruct(ctx, p, "dc", &dcs); err != nil {
+				return err
+			}
+			dc, ok := findStudio(dcs)
+			if !ok {
+				return nil
+			}
+			output, err := json.MarshalIndent(studioConfig(dc, studioConfigSuffixes), "", "    ")
+			if err != nil {
+				return err
+			}
+			out := tarFile.GetWriterToFile(filepath.Join("studio", p+".json"))
+			if _, err := out.Write(output); err != nil {
+				out.Close()
+				return err
+			}
+			return out.Close()
+		}
+		tasks = append(tasks, namedTask("record studio config", p, task))
+	}
+	return tasks
+}
+
+// externalHost returns the host name in value, a URL or host name, if it is
+// reachable from outside the cluster.
+func externalHost(value string) (string, bool) {
+	host := value
+	if strings.Contains(value, "://") {
+		u, err := url.Parse(value)
+		if err != nil {
+			return "", false
+		}
+		host = u.Host
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "" || strings.ContainsAny(host, "/ ") || !strings.Contains(host, ".") || net.ParseIP(host) != nil ||
+		strings.HasSuffix(host, ".svc") || strings.HasSuffix(host, ".cluster.local") {
+		return "", false
+	}
+	return strings.ToLower(host), true
+}
+
+// CheckStudioURL will check that the external URLs configured for the Studio frontend in the supplied project match
+// the host of a deployed route, and if not this will be reflected in the returned Result data. Any errors are written
+// to the supplied stdErr writer
+func CheckStudioURL(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+	result := Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: "studio-url-mismatch", CheckName: "check studio urls match deployed routes"}
+	var dcs DeploymentConfigs
+	if err := getResourceStruct(ctx, project, "dc", &dcs); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	if _, ok := findStudio(dcs); !ok {
+		return result, nil
+	}
+	var routes Routes
+	if err := getResourceStruct(ctx, project, "routes", &routes); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	return checkStudioURL(result, dcs, routes), nil
+}
+
+func checkStudioURL(result Result, dcs DeploymentConfigs, routes Routes) Result {
+	dc, ok := findStudio(dcs)
+	if !ok {
+		return result
+	}
+	hosts := make(map[string]bool)
+	for _, r := range routes.Items {
+		hosts[strings.ToLower(r.Spec.Host)] = true
+	}
+	env := studioConfig(dc, studioHostSuffixes)
+	var names []string
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		host, ok := externalHost(env[name])
+		if !ok || hosts[host] {
+			continue
+		}
+		result.Status = StatusWarning
+		result.StatusMessage = "the studio is configured with hosts that no route serves"
+		result.Info = append(result.Info, Info{Name: dc.Metadata.Name, Namespace: dc.Metadata.Namespace, Kind: dc.Kind, Count: 1,
+			Message: fmt.Sprintf("%s is set to %s, but no route has the host %s", name, env[name], host)})
+	}
+	return result
+}
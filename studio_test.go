@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestCheckStudioURL(t *testing.T) {
+	var dc DeploymentConfig
+	dc.Kind = "DeploymentConfig"
+	dc.Metadata.Name = studioDeploymentConfig
+	dc.Spec.Template.Spec.Containers = []Container{{Name: "fh-ngui", Env: []EnvVar{
+		{Name: "FH_STUDIO_URL", Value: "https://studio.new-domain.example.com"},
+		{Name: "FH_MILLICORE_HOST", Value: "millicore.core.svc:8080"},
+		{Name: "FH_API_HOST", Value: "https://api.old-domain.example.com:443/box"},
+		{Name: "FH_API_BASE_PATH", Value: "/box/api"},
+	}}}
+	dcs := DeploymentConfigs{Items: []DeploymentConfig{dc}}
+	var route Route
+	route.Spec.Host = "studio.new-domain.example.com"
+	routes := Routes{Items: []Route{route}}
+
+	result := checkStudioURL(Result{Status: StatusOK}, dcs, routes)
+	if result.Status != StatusWarning {
+		t.Errorf("Status = %d, want %d", result.Status, StatusWarning)
+	}
+	if len(result.Info) != 1 {
+		t.Fatalf("Info = %+v, want only FH_API_HOST", result.Info)
+	}
+
+	if got := studioConfig(dc, studioConfigSuffixes); len(got) != 4 {
+		t.Errorf("studioConfig() = %v, want all 4 variables", got)
+	}
+
+	result = checkStudioURL(Result{Status: StatusOK}, DeploymentConfigs{}, routes)
+	if result.Status != StatusOK {
+		t.Errorf("without a studio, Status = %d, want %d", result.Status, StatusOK)
+	}
+}
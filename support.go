@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// eolWarningPeriod is how long before the end of life of a release it is
+// reported.
+const eolWarningPeriod = 90 * 24 * time.Hour
+
+// A SupportMatrix lists the OpenShift releases each RHMAP release is supported
+// on, and when releases reach their end of life.
+type SupportMatrix struct {
+	// Supported maps RHMAP releases, like 4.7, to the OpenShift releases
+	// they are supported on, like 3.11.
+	Supported map[string][]string `json:"supported"`
+	// EndOfLife maps releases, as rhmap-<release> or openshift-<release>,
+	// to the date their support ends, as YYYY-MM-DD.
+	EndOfLife map[string]string `json:"endOfLife"`
+}
+
+// defaultSupportMatrix is the support matrix shipped with the tool. End of life
+// dates are added with the supportMatrix of the configuration file.
+var defaultSupportMatrix = SupportMatrix{
+	Supported: map[string][]string{
+		"4.4": {"3.3", "3.4", "3.5"},
+		"4.5": {"3.5", "3.6", "3.7"},
+		"4.6": {"3.7", "3.9", "3.10"},
+		"4.7": {"3.11"},
+	},
+	EndOfLife: map[string]string{},
+}
+
+// supportMatrix returns the default support matrix with the entries of the
+// configuration file added, replacing those of the same releases.
+func supportMatrix() SupportMatrix {
+	m := SupportMatrix{Supported: make(map[string][]string), EndOfLife: make(map[string]string)}
+	for _, source := range []SupportMatrix{defaultSupportMatrix, config.SupportMatrix} {
+		for release, versions := range source.Supported {
+			m.Supported[release] = versions
+		}
+		for release, date := range source.EndOfLife {
+			m.EndOfLife[release] = date
+		}
+	}
+	return m
+}
+
+// rhmapImageRepository matches the repositories of RHMAP images, named after
+// their release, e.g. rhmap47/millicore for RHMAP 4.7.
+var rhmapImageRepository = regexp.MustCompile(`(?:^|/)rhmap(\d)(\d+)/`)
+
+// rhmapRelease returns the RHMAP release of the images deployed by dcs, or an
+// empty string if none is an RHMAP image. The most recent release wins during
+// upgrades.
+func rhmapRelease(dcs DeploymentConfigs) string {
+	release := ""
+	for _, dc := range dcs.Items {
+		for _, c := range dc.Spec.Template.Spec.Containers {
+			if m := rhmapImageRepository.FindStringSubmatch(c.Image); m != nil {
+				if r := m[1] + "." + m[2]; compareReleases(r, release) > 0 {
+					release = r
+				}
+			}
+		}
+	}
+	return release
+}
+
+// compareReleases compares two major.minor releases, returning -1, 0 or 1. The
+// empty release is older than all others.
+func compareReleases(a, b string) int {
+	var amajor, aminor, bmajor, bminor int
+	fmt.Sscanf(a, "%d.%d", &amajor, &aminor)
+	fmt.Sscanf(b, "%d.%d", &bmajor, &bminor)
+	switch {
+	case a == b:
+		return 0
+	case b == "", amajor > bmajor, amajor == bmajor && aminor > bminor:
+		return 1
+	}
+	return -1
+}
+
+// openShiftVersion is the version reported by the /version/openshift endpoint
+// of the master.
+type openShiftVersion struct {
+	GitVersion string `json:"gitVersion"`
+}
+
+// GetOpenShiftRelease returns the major.minor release of OpenShift the master
+// runs, e.g. 3.11.
+func GetOpenShiftRelease(ctx context.Context) (string, error) {
+	var stdout bytes.Buffer
+	if err := runCmdCaptureOutput(ctx, ocCommand("get", "--raw", "/version/openshift"), &stdout, nil); err != nil {
+		return "", err
+	}
+	var v openShiftVersion
+	if err := json.Unmarshal(stdout.Bytes(), &v); err != nil {
+		return "", err
+	}
+	return parseRelease(v.GitVersion)
+}
+
+// parseRelease returns the major.minor release of a version like v3.11.0+abc.
+func parseRelease(version string) (string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", fmt.Errorf("unknown version %q", version)
+	}
+	return parts[0] + "." + strings.TrimRight(parts[1], "+"), nil
+}
+
+// CheckSupportMatrix will check the RHMAP release deployed in the supplied project, if any, against the OpenShift
+// release of the cluster and if the combination is not in the support matrix, or either release reached or is about to
+// reach its end of life, this will be reflected in the returned Result data. Any errors are written to the supplied
+// stdErr writer
+func CheckSupportMatrix(ctx context.Context, project string, stdErr io.Writer) (Result, error) {
+	result := Result{Status: StatusOK, StatusMessage: "this issue was not detected", CheckID: "unsupported-versions", CheckName: "check RHMAP and OpenShift versions against the support matrix"}
+	var dcs DeploymentConfigs
+	if err := getResourceStruct(ctx, project, "dc", &dcs); err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	rhmap := rhmapRelease(dcs)
+	if rhmap == "" {
+		return result, nil
+	}
+	openshift, err := GetOpenShiftRelease(ctx)
+	if err != nil {
+		stdErr.Write([]byte(err.Error()))
+		return result, err
+	}
+	return checkSupportMatrix(result, supportMatrix(), project, rhmap, openshift, time.Now()), nil
+}
+
+func checkSupportMatrix(result Result, matrix SupportMatrix, project, rhmap, openshift string, now time.Time) Result {
+	found := func(status int, kind, name, message string) {
+		if status > result.Status {
+			result.Status = status
+		}
+		result.StatusMessage = "the RHMAP release or the OpenShift release it runs on is not or soon no longer supported"
+		result.Info = append(result.Info, Info{Name: name, Namespace: project, Kind: kind, Count: 1, Message: message})
+	}
+	if versions, ok := matrix.Supported[rhmap]; !ok {
+		found(StatusWarning, "RHMAP", rhmap, fmt.Sprintf("RHMAP %s is not in the support matrix of the tool", rhmap))
+	} else if !containsString(versions, openshift) {
+		found(StatusCritical, "RHMAP", rhmap, fmt.Sprintf("RHMAP %s is not supported on OpenShift %s, only on OpenShift %s", rhmap, openshift, strings.Join(versions, ", ")))
+	}
+	for _, r := range []struct{ kind, release, key string }{
+		{"RHMAP", rhmap, "rhmap-" + rhmap},
+		{"OpenShift", openshift, "openshift-" + openshift},
+	} {
+		date, ok := matrix.EndOfLife[r.key]
+		if !ok {
+			continue
+		}
+		eol, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			continue
+		}
+		switch {
+		case !now.Before(eol):
+			found(StatusCritical, r.kind, r.release, fmt.Sprintf("%s %s reached its end of life on %s", r.kind, r.release, date))
+		case eol.Sub(now) <= eolWarningPeriod:
+			found(StatusWarning, r.kind, r.release, fmt.Sprintf("%s %s reaches its end of life on %s", r.kind, r.release, date))
+		}
+	}
+	return result
+}
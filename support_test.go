@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRhmapRelease(t *testing.T) {
+	var dcs DeploymentConfigs
+	if err := json.Unmarshal([]byte(`{"items": [
+		{"spec": {"template": {"spec": {"containers": [{"image": "registry.access.redhat.com/rhmap46/millicore:4.6.0-12"}]}}}},
+		{"spec": {"template": {"spec": {"containers": [{"image": "rhmap47/fh-ngui:5.9.2"}, {"image": "docker.io/mongo:3.2"}]}}}}
+	]}`), &dcs); err != nil {
+		t.Fatal(err)
+	}
+	if got := rhmapRelease(dcs); got != "4.7" {
+		t.Errorf("rhmapRelease() = %q, want 4.7", got)
+	}
+	if got := rhmapRelease(DeploymentConfigs{}); got != "" {
+		t.Errorf("rhmapRelease() without RHMAP images = %q, want none", got)
+	}
+}
+
+func TestParseRelease(t *testing.T) {
+	for version, want := range map[string]string{"v3.11.0+d4cacc0": "3.11", "v3.9.0": "3.9", "3.6": "3.6"} {
+		if got, err := parseRelease(version); err != nil || got != want {
+			t.Errorf("parseRelease(%q) = %q, %v, want %q", version, got, err, want)
+		}
+	}
+	if _, err := parseRelease("unknown"); err == nil {
+		t.Error(`parseRelease("unknown") didn't return an error`)
+	}
+}
+
+func TestCheckSupportMatrix(t *testing.T) {
+	matrix := SupportMatrix{
+		Supported: map[string][]string{"4.6": {"3.7", "3.9"}, "4.7": {"3.11"}},
+		EndOfLife: map[string]string{"openshift-3.11": "2017-08-01", "rhmap-4.6": "2017-05-01"},
+	}
+	now := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		rhmap, openshift string
+		status, infos    int
+	}{
+		{"4.7", "3.11", StatusWarning, 1},
+		{"4.6", "3.9", StatusCritical, 1},
+		{"4.6", "3.11", StatusCritical, 3},
+		{"4.8", "3.7", StatusWarning, 1},
+	}
+	for _, tt := range tests {
+		result := checkSupportMatrix(Result{Status: StatusOK}, matrix, "core", tt.rhmap, tt.openshift, now)
+		if result.Status != tt.status || len(result.Info) != tt.infos {
+			t.Errorf("checkSupportMatrix(%s, %s) = %+v, want status %d with %d infos", tt.rhmap, tt.openshift, result, tt.status, tt.infos)
+		}
+	}
+	if result := checkSupportMatrix(Result{Status: StatusOK}, matrix, "core", "4.6", "3.7", time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)); result.Status != StatusOK {
+		t.Errorf("checkSupportMatrix() of a supported combination = %+v, want no issue", result)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+// A Suppression accepts a known condition so that it is no longer reported as
+// a finding. Suppressed findings are still listed in reports, along with the
+// justification.
+type Suppression struct {
+	// Check is the ID of the check whose findings are suppressed.
+	Check string `json:"check"`
+	// Project and Name, if not empty, limit the suppression to findings
+	// in the named project, and to the named objects of findings.
+	Project       string `json:"project"`
+	Name          string `json:"name"`
+	Justification string `json:"justification"`
+}
+
+// A SuppressedFinding is a finding, or the part of a finding concerning some
+// objects, that was suppressed.
+type SuppressedFinding struct {
+	Finding       Finding `json:"finding"`
+	Justification string  `json:"justification"`
+}
+
+func (s Suppression) matches(project string, r Result) bool {
+	return s.Check == r.CheckID && (s.Project == "" || s.Project == project)
+}
+
+// applySuppressions splits finding into the part that is not suppressed, if
+// any, and the suppressed parts.
+func applySuppressions(suppressions []Suppression, finding Finding) (*Finding, []SuppressedFinding) {
+	var suppressed []SuppressedFinding
+	for _, s := range suppressions {
+		if !s.matches(finding.Project, finding.Result) {
+			continue
+		}
+		if s.Name == "" {
+			return nil, append(suppressed, SuppressedFinding{finding, s.Justification})
+		}
+		// Only suppress the parts of the finding about the named
+		// object.
+		var kept, dropped []Info
+		for _, info := range finding.Result.Info {
+			if info.Name == s.Name {
+				dropped = append(dropped, info)
+			} else {
+				kept = append(kept, info)
+			}
+		}
+		if len(dropped) == 0 {
+			continue
+		}
+		part := finding
+		part.Result.Info = dropped
+		suppressed = append(suppressed, SuppressedFinding{part, s.Justification})
+		if len(kept) == 0 {
+			return nil, suppressed
+		}
+		finding.Result.Info = kept
+	}
+	return &finding, suppressed
+}
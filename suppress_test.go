@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSuppressions(t *testing.T) {
+	summary := DumpSummary{
+		Results: map[string][]Result{
+			"core": {
+				{CheckID: "dc-replicas-zero", Status: StatusWarning, Info: []Info{{Name: "fh-aaa"}, {Name: "fh-ngui"}}},
+				{CheckID: "image-pull-backoff", Status: StatusCritical, Info: []Info{{Name: "millicore"}}},
+			},
+			"mbaas": {
+				{CheckID: "image-pull-backoff", Status: StatusCritical, Info: []Info{{Name: "fh-mbaas"}}},
+			},
+		},
+		Suppressions: []Suppression{
+			{Check: "dc-replicas-zero", Name: "fh-aaa", Justification: "AAA is not used"},
+			{Check: "image-pull-backoff", Project: "mbaas", Justification: "registry migration"},
+		},
+	}
+
+	findings := summary.Findings()
+	if len(findings) != 2 {
+		t.Fatalf("len(Findings()) = %d, want 2: %+v", len(findings), findings)
+	}
+	if got := infoNames(findings[1]); findings[1].Result.CheckID != "dc-replicas-zero" || got != "fh-ngui" {
+		t.Errorf("partially suppressed finding reports %q, want fh-ngui only", got)
+	}
+	if got := summary.CountFindings(StatusCritical); got != 1 {
+		t.Errorf("CountFindings(StatusCritical) = %d, want 1", got)
+	}
+	if got := len(summary.SuppressedFindings()); got != 2 {
+		t.Errorf("len(SuppressedFindings()) = %d, want 2", got)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTextSummary(&buf, summary); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"core/dc-replicas-zero (fh-aaa): AAA is not used", "mbaas/image-pull-backoff (fh-mbaas): registry migration"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("text summary doesn't include %q:\n%s", s, buf.String())
+		}
+	}
+}
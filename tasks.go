@@ -1,119 +1,569 @@
 package main
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
 
-// A Task performs some part of the RHMAP System Dump Tool.
-type Task func() error
+// A Task performs some part of the RHMAP System Dump Tool. It gives up when ctx
+// is done.
+type Task func(ctx context.Context) error
 
-// GetAllTasks returns a list of all tasks performed by the dump tool. It may
-// return tasks even in the presence of an error.
-// FIXME: GetAllTasks should not know about tarFile.
-func GetAllTasks(tarFile *Archive) ([]Task, error) {
-	var (
-		tasks     []Task
-		retErrors errorList
-	)
+// A TaskFailure is the error of a named task, identifying the operation that
+// failed and the project it operated on.
+type TaskFailure struct {
+	Task    string
+	Project string
+	Err     error
+}
 
-	var (
-		resources         = []string{"deploymentconfigs", "pods", "services", "events"}
-		resourcesWithLogs = []string{"deploymentconfigs", "pods"}
-	)
+func (e *TaskFailure) Error() string {
+	if e.Project == "" {
+		return e.Task + ": " + e.Err.Error()
+	}
+	return e.Task + " in project " + e.Project + ": " + e.Err.Error()
+}
 
-	projects, err := GetProjects()
-	if err != nil {
-		return nil, err
+// namedTask returns a task running task, and identifying it by name and
+// project in the error returned if it fails and in its timing. Leave project
+// empty for tasks covering the whole cluster. Tasks listed by a dry run only
+// record their name and project. Tasks of projects deleted during the dump
+// return a ProjectDeletedError, without running if the deletion was noticed
+// before they start.
+func namedTask(name, project string, task Task) Task {
+	return func(ctx context.Context) error {
+		if p, ok := ctx.Value(planKey{}).(*PlannedTask); ok {
+			p.Task, p.Project = name, project
+			return nil
+		}
+		if t, ok := ctx.Value(timingKey{}).(runningTask); ok {
+			t.setName(name, project)
+		}
+		if project != "" && deletedProjects.has(project) {
+			return &ProjectDeletedError{Task: name, Project: project}
+		}
+		if err := task(ctx); err != nil {
+			if project != "" && deletedProjects.check(ctx, project, err) {
+				return &ProjectDeletedError{Task: name, Project: project}
+			}
+			return &TaskFailure{Task: name, Project: project, Err: err}
+		}
+		return nil
+	}
+}
+
+// A retryPolicy retries commands failing with transient errors, as reported
+// by isTransientClass, waiting longer before each retry.
+type retryPolicy struct {
+	// retries is the maximum number of retries of a command.
+	retries int
+	// backoff is the time to wait before the first retry. It doubles at
+	// each retry.
+	backoff time.Duration
+}
+
+// commandRetries is the retry policy of all commands run by tasks.
+var commandRetries = &retryPolicy{backoff: defaultRetryBackoff}
+
+// do calls fn with the number of previous attempts, until it succeeds, fails
+// with an error that is not transient or the retries are exhausted. fn must
+// discard the output of failed attempts. do gives up waiting when ctx is
+// done.
+func (p *retryPolicy) do(ctx context.Context, fn func(attempt int) error) error {
+	err := fn(0)
+	for attempt := 0; err != nil && attempt < p.retries; attempt++ {
+		if ctx.Err() != nil || !isTransientClass(ClassifyError(err)) {
+			break
+		}
+		backoff := p.backoff << uint(attempt)
+		log.Printf("Retrying in %v after a transient error: %v\n", backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		err = fn(attempt + 1)
+	}
+	return err
+}
+
+// runTask runs task, cancelling it after timeout unless timeout is zero.
+func runTask(ctx context.Context, task Task, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return task(ctx)
+}
+
+// RunAllTasks runs all tasks, at most maxParallel at a time, and waits for all
+// of them to complete. Each task is cancelled after timeout, unless timeout is
+// zero, and all remaining tasks are cancelled when ctx is done. It returns the
+// error returned by each task, in the same order as tasks.
+func RunAllTasks(ctx context.Context, tasks []Task, maxParallel int, timeout time.Duration) []error {
+	errs, _ := RunTaskGraph(ctx, TaskGraph{Tasks: tasks}, maxParallel, timeout)
+	return errs
+}
+
+// A TaskGraph holds tasks along with the tasks each of them depends on.
+type TaskGraph struct {
+	Tasks []Task
+	// Deps holds, for each task, the indexes of the tasks that must
+	// complete before it starts. It may be shorter than Tasks.
+	Deps [][]int
+	// Collectors holds the name of the collector of each task. It may be
+	// shorter than Tasks.
+	Collectors []string
+	// Outputs lists the files requested by each collector, for each
+	// project or for all projects.
+	Outputs []TaskOutputs
+}
+
+// timingKey is the context key of the runningTask of a task.
+type timingKey struct{}
+
+// A runningTask gives a running task access to its timing, guarded by mu.
+type runningTask struct {
+	mu     *sync.Mutex
+	timing *TaskTiming
+}
+
+// setName records the name and project of the task.
+func (t runningTask) setName(name, project string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.timing.Task, t.timing.Project = name, project
+}
+
+// name returns the name and project of the task.
+func (t runningTask) name() (string, string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.timing.Task, t.timing.Project
+}
+
+// RunTaskGraph runs the tasks of g like RunAllTasks, starting each task once
+// the tasks it depends on have completed, successfully or not. Tasks ready to
+// start do so in the order of g.Tasks, at most maxParallel at a time, or as
+// many as commandConcurrency allows if it is set, and no new task starts while
+// taskMemory reports memory pressure, unless no other task is running. Tasks
+// depending on each other in a cycle never start and fail. It also returns the
+// timing of each task.
+func RunTaskGraph(ctx context.Context, g TaskGraph, maxParallel int, timeout time.Duration) ([]error, []TaskTiming) {
+	errs := make([]error, len(g.Tasks))
+	timings := make([]TaskTiming, len(g.Tasks))
+	for i := range g.Collectors {
+		timings[i].Collector = g.Collectors[i]
+	}
+	waiting := make([]int, len(g.Tasks))
+	dependents := make([][]int, len(g.Tasks))
+	for i, deps := range g.Deps {
+		for _, dep := range deps {
+			waiting[i]++
+			dependents[dep] = append(dependents[dep], i)
+		}
+	}
+	var ready []int
+	for i := range g.Tasks {
+		if waiting[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	var mu sync.Mutex
+	progress := newProgress(os.Stderr, len(g.Tasks), func(i int) string {
+		mu.Lock()
+		defer mu.Unlock()
+		return timings[i].Task
+	})
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	done := make(chan int)
+	running := 0
+	for completed := 0; completed < len(g.Tasks); {
+		for running < commandConcurrency.limit(maxParallel) && len(ready) > 0 && (running == 0 || !taskMemory.pressure()) {
+			i := ready[0]
+			ready = ready[1:]
+			running++
+			progress.started(i)
+			go func() {
+				timing := &timings[i]
+				timing.Start = time.Now()
+				errs[i] = runTask(context.WithValue(ctx, timingKey{}, runningTask{&mu, timing}), g.Tasks[i], timeout)
+				timing.Duration = time.Since(timing.Start)
+				timing.setOutcome(errs[i])
+				done <- i
+			}()
+		}
+		if running == 0 {
+			// The remaining tasks wait for each other.
+			for i := range g.Tasks {
+				if waiting[i] > 0 {
+					errs[i] = errors.New("task never started, it depends on itself")
+					timings[i].setOutcome(errs[i])
+				}
+			}
+			break
+		}
+		var i int
+		select {
+		case now := <-ticker.C:
+			progress.show(now)
+			continue
+		case i = <-done:
+		}
+		running--
+		completed++
+		progress.finished(i)
+		for _, j := range dependents[i] {
+			if waiting[j]--; waiting[j] == 0 {
+				ready = append(ready, j)
+				sort.Ints(ready)
+			}
+		}
 	}
+	progress.end()
+	return errs, timings
+}
 
-	if len(projects) == 0 {
-		return []Task{}, errors.New("no projects visible to the currently logged in user")
+var (
+	// resources are the types of resources whose definitions are
+	// collected. They are replaced by the types found by discovery, or
+	// by those given with -resources. The default ones are collected
+	// from clusters too old for discovery.
+	resources = []string{
+		"deploymentconfigs", "pods", "services", "events", "limitranges",
+		"configmaps", "persistentvolumeclaims", "replicationcontrollers",
+		"routes", "imagestreams", "buildconfigs", "builds",
+		"statefulsets", "daemonsets", "jobs", "cronjobs", "serviceaccounts",
+		"poddisruptionbudgets",
 	}
+	// resourcesWithLogs are the types of resources whose logs are
+	// collected.
+	resourcesWithLogs = []string{"deploymentconfigs", "pods"}
+)
 
-	// Add tasks to fetch resource definitions.
-	definitionsTasks, err := GetResourceDefinitionsTasks(projects, resources, tarFile)
+// parseResourceTypes returns the types of resources in the comma-separated
+// list s, without duplicates.
+func parseResourceTypes(s string) ([]string, error) {
+	var types []string
+	seen := make(map[string]bool)
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		types = append(types, t)
+	}
+	if len(types) == 0 {
+		return nil, fmt.Errorf("no resource types in %q", s)
+	}
+	return types, nil
+}
+
+func init() {
+	taskRegistry.Register(Collector{
+		Name:       "logs",
+		Category:   categoryProject,
+		PerProject: true,
+		Tasks:      GetEventPodsAndLogsTasks,
+	})
+	taskRegistry.Register(Collector{
+		Name:       "definitions",
+		Category:   categoryProject,
+		PerProject: true,
+		Tasks: func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+			return GetResourceDefinitionsTasks(projects, resources, *labelSelector, *definitionsMode == definitionsStatus, tarFile)
+		},
+	})
+	taskRegistry.Register(Collector{
+		Name:       "project",
+		Category:   categoryProject,
+		PerProject: true,
+		Tasks: func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+			return GetProjectDefinitionTasks(projects, tarFile), nil
+		},
+	})
+	taskRegistry.Register(Collector{
+		Name:       "studio-config",
+		Category:   categoryProject,
+		PerProject: true,
+		Tasks: func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+			return GetStudioConfigTasks(projects, tarFile), nil
+		},
+	})
+	taskRegistry.Register(Collector{
+		Name:       "route-balancing",
+		Category:   categoryProject,
+		PerProject: true,
+		Tasks: func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+			return GetRouteBalancingTasks(projects, tarFile), nil
+		},
+	})
+	taskRegistry.Register(Collector{
+		Name:       "nagios-history",
+		Category:   categoryProject,
+		PerProject: true,
+		Enabled:    func() bool { return *nagiosHistory },
+		Tasks: func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+			historyCompression := compressionNone
+			if *nagiosHistoryGzip {
+				historyCompression = *compression
+			}
+			return GetNagiosHistoryTasks(ctx, projects, historyCompression, tarFile)
+		},
+	})
+	taskRegistry.Register(Collector{
+		Name:       "nagios-templates",
+		Category:   categoryProject,
+		PerProject: true,
+		Tasks: func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+			return GetNagiosTemplatesTasks(projects, tarFile), nil
+		},
+	})
+	taskRegistry.Register(Collector{
+		Name:       "secrets",
+		Category:   categoryProject,
+		PerProject: true,
+		Tasks: func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+			return GetSecretsTasks(projects, *secretValues, tarFile), nil
+		},
+	})
+	taskRegistry.Register(Collector{
+		Name:     "elasticsearch-logs",
+		Category: categoryProject,
+		Enabled:  func() bool { return *esLogsHours > 0 },
+		Tasks: func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+			hours := *esLogsHours
+			if hours <= 0 {
+				hours = defaultElasticsearchHours
+			}
+			return GetElasticsearchLogsTasks(ctx, projects, hours, tarFile)
+		},
+	})
+	taskRegistry.Register(Collector{
+		Name:       "who-can",
+		Category:   categoryProject,
+		PerProject: true,
+		Enabled:    func() bool { return *whoCan },
+		Tasks: func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+			return GetWhoCanTasks(projects, tarFile), nil
+		},
+	})
+	taskRegistry.Register(Collector{
+		Name:     "cluster-definitions",
+		Category: categoryCluster,
+		Tasks: func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+			return GetClusterDefinitionsTasks(clusterResources, *definitionsMode == definitionsStatus, tarFile), nil
+		},
+	})
+	taskRegistry.Register(Collector{
+		Name:     "network-stats",
+		Category: categoryCluster,
+		Enabled:  func() bool { return *networkStats },
+		Tasks:    GetNetworkStatsTasks,
+	})
+	taskRegistry.Register(Collector{
+		Name:     "router",
+		Category: categoryCluster,
+		Enabled:  func() bool { return *routerStats },
+		Tasks:    GetRouterTasks,
+	})
+	taskRegistry.Register(Collector{
+		Name:     "prometheus",
+		Category: categoryCluster,
+		Enabled:  func() bool { return *prometheus },
+		Tasks:    GetPrometheusTasks,
+	})
+	taskRegistry.Register(Collector{
+		Name:     "grafana",
+		Category: categoryCluster,
+		Enabled:  func() bool { return *grafanaExport },
+		Tasks:    GetGrafanaTasks,
+	})
+	taskRegistry.Register(Collector{
+		Name:     "image-metadata",
+		Category: categoryCluster,
+		Enabled:  func() bool { return *imageMetadata },
+		Tasks: func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+			return []Task{namedTask("record running images", "", GetRunningImagesTask(projects, tarFile))}, nil
+		},
+	})
+	taskRegistry.Register(Collector{
+		Name:     "core-status",
+		Category: categoryCluster,
+		Enabled:  func() bool { return *coreURL != "" },
+		Tasks: func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+			return []Task{namedTask("fetch core status endpoints", "", GetCoreStatusTask(*coreURL, tarFile))}, nil
+		},
+	})
+	taskRegistry.Register(Collector{
+		Name:     "smoke-test",
+		Category: categoryCluster,
+		Enabled:  func() bool { return config.SmokeTest.URL != "" },
+		Tasks: func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+			return []Task{namedTask("smoke test cloud app", "", GetSmokeTestTask(config.SmokeTest, tarFile))}, nil
+		},
+	})
+	taskRegistry.Register(Collector{
+		Name:     "inventory",
+		Category: categoryCluster,
+		// The inventory holds the health scores computed from the
+		// results of the checks.
+		Requires: []string{"checks"},
+		Tasks: func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+			jsonOut := tarFile.GetWriterToFile("inventory.json")
+			mdOut := tarFile.GetWriterToFile("inventory.md")
+			task := func(ctx context.Context) error {
+				defer jsonOut.Close()
+				defer mdOut.Close()
+				return WriteInventory(projects, tarFile.KeptFiles, config.Suppressions, jsonOut, mdOut)(ctx)
+			}
+			return []Task{namedTask("build inventory", "", task)}, nil
+		},
+	})
+	taskRegistry.Register(Collector{
+		Name:     "checks",
+		Category: categoryAnalysis,
+		// The checks of a project read the definitions collected in it.
+		Requires:   []string{"definitions"},
+		PerProject: true,
+		Tasks: func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+			return GetCheckTasks(projects, tarFile), nil
+		},
+	})
+}
+
+// GetEventPodsAndLogsTasks returns a list of tasks to describe pods involved
+// in Warning events, and fetch logs, making sure the logs of those pods are
+// fetched first so that the evidence behind visible symptoms is always part of
+// the dump. Pods that no longer exist are recorded along with the revision
+// history of their owners, and the logs of their successors are fetched
+// instead. It may return tasks even in the presence of an error.
+func GetEventPodsAndLogsTasks(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+	var retErrors errorList
+	warningPods, missingPods, err := GetEventPods(ctx, projects)
 	if err != nil {
 		retErrors = append(retErrors, err)
 	}
-	tasks = append(tasks, definitionsTasks...)
-
-	// Add tasks to fetch logs.
-	logsTasks, err := GetFetchLogsTasks(projects, resourcesWithLogs, tarFile)
+	tasks, err := GetDescribePodsTasks(warningPods, tarFile)
+	if err != nil {
+		retErrors = append(retErrors, err)
+	}
+	tasks = append(tasks, GetMissingPodsTasks(missingPods, tarFile)...)
+	logsTasks, err := GetFetchLogsTasks(ctx, projects, resourcesWithLogs, warningPods, tarFile)
 	if err != nil {
 		retErrors = append(retErrors, err)
 	}
 	tasks = append(tasks, logsTasks...)
+	if len(retErrors) > 0 {
+		return tasks, retErrors
+	}
+	return tasks, nil
+}
 
-	// Add check tasks
+// GetCheckTasks returns a list of tasks to run the analysis checks against
+// each of the given projects. They read the definitions collected in each
+// project, if complete, so they must run after the tasks collecting them.
+func GetCheckTasks(projects []string, tarFile *Archive) []Task {
+	var tasks []Task
 	for _, p := range projects {
+		p := p
 		outFor := outToTGZ("definitions", "json", tarFile)
 		errOutFor := outToTGZ("definitions", "stderr", tarFile)
-		task := CheckTasks(p, outFor, errOutFor)
-		tasks = append(tasks, task)
-	}
-
-	if len(retErrors) > 0 {
-		return tasks, retErrors
+		checks := CheckTasks(p, outFor, errOutFor)
+		collectedDefinitions.expect(p)
+		task := func(ctx context.Context) error {
+			defer collectedDefinitions.release(p)
+			return checks(ctx)
+		}
+		tasks = append(tasks, namedTask("run analysis checks", p, task))
 	}
-	return tasks, nil
+	return tasks
 }
 
 // GetResourceDefinitionsTasks returns a list of tasks to fetch the definitions
-// of all resources in all projects.
+// of all resources in all projects, or those matching the label selector if
+// not empty, keeping only their status if statusOnly is true.
 // FIXME: GetResourceDefinitionsTasks should not know about tarFile.
-func GetResourceDefinitionsTasks(projects, resources []string, tarFile *Archive) ([]Task, error) {
+func GetResourceDefinitionsTasks(projects, resources []string, selector string, statusOnly bool, tarFile *Archive) ([]Task, error) {
 	var tasks []Task
 	for _, p := range projects {
 		outFor := outToTGZ("definitions", "json", tarFile)
+		if statusOnly {
+			outFor = statusOnlyOutFor(outFor)
+		} else if selector == "" {
+			// The checks read the definitions of all resources.
+			outFor = collectedDefinitions.outFor(outFor)
+		}
 		errOutFor := outToTGZ("definitions", "stderr", tarFile)
-		task := ResourceDefinitions(p, resources, outFor, errOutFor)
-		tasks = append(tasks, task)
+		task := SelectedResourceDefinitions(p, resources, selector, outFor, errOutFor)
+		tasks = append(tasks, namedTask("fetch definitions", p, task))
 	}
 	return tasks, nil
 }
 
-// GetFetchLogsTasks returns a list of tasks to fetch resource logs. It may
+// GetFetchLogsTasks returns a list of tasks to fetch resource logs, of the
+// resources matching the -selector flag. Logs of the priority resources are
+// always fetched, regardless of filters, and before any other logs. It may
 // return tasks even in the presence of an error.
 // FIXME: GetFetchLogsTasks should not know about tarFile.
-func GetFetchLogsTasks(projects, resources []string, tarFile *Archive) ([]Task, error) {
+func GetFetchLogsTasks(ctx context.Context, projects, resources []string, priority []LoggableResource, tarFile *Archive) ([]Task, error) {
 	var (
 		tasks  []Task
 		errors errorList
 	)
-	loggableResources, err := GetLogabbleResources(projects, resources)
+	loggableResources, err := GetLogabbleResources(ctx, projects, resources, *labelSelector)
 	if err != nil {
 		errors = append(errors, err)
 	}
-	if len(loggableResources) == 0 {
-		return nil, errors
+	loggableResources, err = FilterContainers(loggableResources, *containerFilter)
+	if err != nil {
+		return nil, append(errors, err)
 	}
+	loggableResources = prioritizeResources(priority, loggableResources)
 	for _, r := range loggableResources {
 		r := r
 		name := r.Type + "-" + r.Name
 		if r.Container != "" {
 			name += "-" + r.Container
 		}
-		// Add tasks to fetch current logs.
-		{
-			// FIXME: Do not ignore errors.
-			out, outCloser, _ := outToTGZ("logs", "logs", tarFile)(r.Project, name)
-			errOut, errOutCloser, _ := outToTGZ("logs", "stderr", tarFile)(r.Project, name)
-			task := func() error {
-				defer outCloser.Close()
-				defer errOutCloser.Close()
-				return FetchLogs(r, *maxLogLines, out, errOut)()
+		// Add tasks to fetch current and previous logs.
+		for _, l := range []struct {
+			dir, description string
+			fetch            func(LoggableResource, int, io.Writer, io.Writer) Task
+		}{
+			{"logs", "fetch logs of ", FetchLogs},
+			{"logs-previous", "fetch previous logs of ", FetchPreviousLogs},
+		} {
+			l := l
+			out, outCloser, err := outToTGZ(l.dir, "logs", tarFile)(r.Project, name)
+			if err != nil {
+				errors = append(errors, err)
+				continue
+			}
+			errOut, errOutCloser, err := outToTGZ(l.dir, "stderr", tarFile)(r.Project, name)
+			if err != nil {
+				outCloser.Close()
+				errors = append(errors, err)
+				continue
 			}
-			tasks = append(tasks, task)
-		}
-		// Add tasks to fetch previous logs.
-		{
-			// FIXME: Do not ignore errors.
-			out, outCloser, _ := outToTGZ("logs-previous", "logs", tarFile)(r.Project, name)
-			errOut, errOutCloser, _ := outToTGZ("logs-previous", "stderr", tarFile)(r.Project, name)
-			task := func() error {
+			task := func(ctx context.Context) error {
 				defer outCloser.Close()
 				defer errOutCloser.Close()
-				return FetchPreviousLogs(r, *maxLogLines, out, errOut)()
+				return fetchLogsMaybeDeduped(ctx, l.fetch, r, out, errOut)
 			}
-			tasks = append(tasks, task)
+			tasks = append(tasks, namedTask(l.description+r.describe(), r.Project, task))
 		}
 	}
 	if len(errors) > 0 {
@@ -122,22 +572,23 @@ func GetFetchLogsTasks(projects, resources []string, tarFile *Archive) ([]Task,
 	return tasks, nil
 }
 
-// GetLogabbleResources returns a list of loggable resources. It may return
-// results even in the presence of an error.
-func GetLogabbleResources(projects, resources []string) ([]LoggableResource, error) {
+// GetLogabbleResources returns a list of loggable resources, or of those
+// matching the label selector if not empty. It may return results even in the
+// presence of an error.
+func GetLogabbleResources(ctx context.Context, projects, resources []string, selector string) ([]LoggableResource, error) {
 	var (
 		loggableResources []LoggableResource
 		errors            errorList
 	)
 	for _, p := range projects {
 		for _, rtype := range resources {
-			names, err := GetResourceNames(p, rtype)
+			names, err := GetSelectedResourceNames(ctx, p, rtype, selector)
 			if err != nil {
 				errors = append(errors, err)
 				continue
 			}
 			for _, name := range names {
-				resources, err := GetLoggableResources(p, rtype, name)
+				resources, err := GetLoggableResources(ctx, p, rtype, name)
 				if err != nil {
 					errors = append(errors, err)
 					continue
@@ -151,3 +602,57 @@ func GetLogabbleResources(projects, resources []string) ([]LoggableResource, err
 	}
 	return loggableResources, nil
 }
+
+// GetDescribePodsTasks returns a list of tasks to describe the pods of the
+// given loggable resources. Pods with multiple containers are described once.
+// It may return tasks even in the presence of an error.
+// FIXME: GetDescribePodsTasks should not know about tarFile.
+func GetDescribePodsTasks(pods []LoggableResource, tarFile *Archive) ([]Task, error) {
+	var (
+		tasks  []Task
+		errors errorList
+	)
+	seen := make(map[[2]string]bool)
+	for _, r := range pods {
+		r := r
+		if seen[[2]string{r.Project, r.Name}] {
+			continue
+		}
+		seen[[2]string{r.Project, r.Name}] = true
+		out, outCloser, err := outToTGZ("describe", "txt", tarFile)(r.Project, "pod-"+r.Name)
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		errOut, errOutCloser, err := outToTGZ("describe", "stderr", tarFile)(r.Project, "pod-"+r.Name)
+		if err != nil {
+			outCloser.Close()
+			errors = append(errors, err)
+			continue
+		}
+		task := func(ctx context.Context) error {
+			defer outCloser.Close()
+			defer errOutCloser.Close()
+			return DescribePod(r.Project, r.Name, out, errOut)(ctx)
+		}
+		tasks = append(tasks, namedTask("describe pod "+r.Name, r.Project, task))
+	}
+	if len(errors) > 0 {
+		return tasks, errors
+	}
+	return tasks, nil
+}
+
+// fetchLogsMaybeDeduped runs the task created by fetch for resource, collapsing
+// repeated log lines if enabled with the -dedupe-logs flag.
+func fetchLogsMaybeDeduped(ctx context.Context, fetch func(LoggableResource, int, io.Writer, io.Writer) Task, resource LoggableResource, out, errOut io.Writer) error {
+	if !*dedupeLogs {
+		return fetch(resource, *maxLogLines, out, errOut)(ctx)
+	}
+	d := newLineDeduper(out)
+	err := fetch(resource, *maxLogLines, d, errOut)(ctx)
+	if flushErr := d.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os/exec"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunAllTasksTimeout(t *testing.T) {
+	hangs := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	quick := func(ctx context.Context) error { return nil }
+	for _, maxParallel := range []int{1, 2} {
+		errs := RunAllTasks(context.Background(), []Task{hangs, quick}, maxParallel, 10*time.Millisecond)
+		if errs[0] != context.DeadlineExceeded || errs[1] != nil {
+			t.Errorf("RunAllTasks(maxParallel=%d) = %v, want [%v <nil>]", maxParallel, errs, context.DeadlineExceeded)
+		}
+	}
+
+	// Tasks are cancelled along with ctx, even without a timeout.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	errs := RunAllTasks(ctx, []Task{hangs}, 1, 0)
+	if errs[0] != context.Canceled {
+		t.Errorf("RunAllTasks() with a cancelled context = %v, want %v", errs, context.Canceled)
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	exitErr := &exec.ExitError{}
+	throttled := &CmdError{Args: []string{"oc", "get", "pods"}, Err: exitErr, Stderr: "Error from server: Too Many Requests"}
+	forbidden := &CmdError{Args: []string{"oc", "get", "pods"}, Err: exitErr, Stderr: "Error from server (Forbidden): pods is forbidden"}
+	p := &retryPolicy{retries: 3, backoff: time.Millisecond}
+	tests := []struct {
+		errs         []error
+		wantAttempts int
+		wantErr      error
+	}{
+		{[]error{nil}, 1, nil},
+		{[]error{throttled, throttled, nil}, 3, nil},
+		{[]error{throttled, throttled, throttled, throttled}, 4, throttled},
+		{[]error{forbidden}, 1, forbidden},
+		{[]error{throttled, forbidden}, 2, forbidden},
+	}
+	for _, tt := range tests {
+		attempts := 0
+		err := p.do(context.Background(), func(attempt int) error {
+			if attempt != attempts {
+				t.Errorf("attempt = %d, want %d", attempt, attempts)
+			}
+			attempts++
+			return tt.errs[attempt]
+		})
+		if err != tt.wantErr || attempts != tt.wantAttempts {
+			t.Errorf("do(%v) = %v after %d attempts, want %v after %d", tt.errs, err, attempts, tt.wantErr, tt.wantAttempts)
+		}
+	}
+
+	// Commands are not retried once ctx is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts := 0
+	p.do(ctx, func(int) error {
+		attempts++
+		return throttled
+	})
+	if attempts != 1 {
+		t.Errorf("do() with a cancelled context made %d attempts, want 1", attempts)
+	}
+}
+
+func TestRunTaskGraph(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	record := func(name string) Task {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return nil
+		}
+	}
+	// The definitions block until the independent task ran, so the
+	// independent task must overlap with them.
+	independent := make(chan struct{})
+	definitions := func(ctx context.Context) error {
+		<-independent
+		return record("definitions")(ctx)
+	}
+	g := TaskGraph{
+		Tasks: []Task{definitions, record("checks"), func(ctx context.Context) error {
+			defer close(independent)
+			return record("independent")(ctx)
+		}},
+		Deps: [][]int{nil, {0}},
+	}
+	errs, timings := RunTaskGraph(context.Background(), g, 2, 0)
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("task %d: %v", i, err)
+		}
+	}
+	if want := []string{"independent", "definitions", "checks"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("tasks ran in order %v, want %v", order, want)
+	}
+	for i, timing := range timings {
+		if timing.Outcome != outcomeOK || timing.Start.IsZero() {
+			t.Errorf("timings[%d] = %+v, want a started task with outcome ok", i, timing)
+		}
+	}
+
+	// Tasks depending on each other never start.
+	g = TaskGraph{Tasks: []Task{record("a"), record("b"), record("c")}, Deps: [][]int{{1}, {0}}}
+	errs, _ = RunTaskGraph(context.Background(), g, 1, 0)
+	if errs[0] == nil || errs[1] == nil || errs[2] != nil {
+		t.Errorf("RunTaskGraph() with a cycle = %v, want errors for the first two tasks", errs)
+	}
+}
+
+func TestCollectTasks(t *testing.T) {
+	task := func(context.Context) error { return nil }
+	perProject := func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+		if len(projects) != 1 {
+			t.Errorf("per project collector called with %v", projects)
+		}
+		return []Task{task}, nil
+	}
+	cluster := func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+		return []Task{task}, nil
+	}
+	collectors := []*Collector{
+		{Name: "definitions", PerProject: true, Tasks: perProject},
+		{Name: "inventory", Tasks: cluster},
+		{Name: "checks", PerProject: true, Requires: []string{"definitions", "inventory"}, Tasks: perProject},
+		{Name: "report", Requires: []string{"checks"}, Tasks: cluster},
+	}
+	g, err := CollectTasks(context.Background(), collectors, []string{"core", "mbaas"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Definitions of core and mbaas, inventory, checks of core and mbaas
+	// and the report.
+	want := [][]int{nil, nil, nil, {0, 2}, {1, 2}, {3, 4}}
+	if !reflect.DeepEqual(g.Deps, want) || len(g.Tasks) != len(want) {
+		t.Errorf("CollectTasks() returned %d tasks with dependencies %v, want %v", len(g.Tasks), g.Deps, want)
+	}
+}
+
+func TestParseResourceTypes(t *testing.T) {
+	got, err := parseResourceTypes("pods, events,,pods,secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"pods", "events", "secrets"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseResourceTypes() = %v, want %v", got, want)
+	}
+	if _, err := parseResourceTypes(" , "); err == nil {
+		t.Error("parseResourceTypes() without types didn't return an error")
+	}
+}
+
+func TestCollectTasksOutputs(t *testing.T) {
+	tgz, err := NewTgz(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tgz.Close()
+	definitions := func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+		tarFile.GetWriterToFile("definitions/projects/" + projects[0] + "/pods.json")
+		return []Task{func(context.Context) error { return nil }}, nil
+	}
+	none := func(ctx context.Context, projects []string, tarFile *Archive) ([]Task, error) {
+		return nil, nil
+	}
+	collectors := []*Collector{
+		{Name: "definitions", PerProject: true, Tasks: definitions},
+		{Name: "nothing", Tasks: none},
+	}
+	g, err := CollectTasks(context.Background(), collectors, []string{"core", "mbaas"}, tgz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TaskOutputs{
+		{Collector: "definitions", Project: "core", Tasks: 1, Files: []string{"definitions/projects/core/pods.json"}},
+		{Collector: "definitions", Project: "mbaas", Tasks: 1, Files: []string{"definitions/projects/mbaas/pods.json"}},
+	}
+	if !reflect.DeepEqual(g.Outputs, want) {
+		t.Errorf("CollectTasks() outputs = %+v, want %+v", g.Outputs, want)
+	}
+}
@@ -2,52 +2,242 @@ package main
 
 import (
 	"archive/tar"
+	"archive/zip"
+	"bufio"
 	"bytes"
-	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"io"
+	"log"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/feedhenry/fh-system-dump-tool/dumpformat"
 )
 
 type Archive struct {
+	// mu serializes writes to the archive, since tasks close their
+	// writers concurrently.
+	mu        sync.Mutex
 	tgzFile   io.Writer
 	tarWriter *tar.Writer
-	gzWriter  *gzip.Writer
+	// compressor compresses the tar stream into tgzFile.
+	compressor io.WriteCloser
+	// zipWriter, if not nil, replaces tarWriter and compressor for
+	// archives in the zip format, compressing each file with zipMethod.
+	zipWriter *zip.Writer
+	zipMethod uint16
+	// Redactor, if not nil, redacts the contents of files written with
+	// writers from GetWriterToFile.
+	Redactor *Redactor
+	// Keep, if not nil, selects files whose contents are kept in memory
+	// after being written, so that they can be used once the archive is
+	// complete.
+	Keep func(name string) bool
+	kept map[string][]byte
+	// manifest lists the files written to the archive.
+	manifest []dumpformat.ManifestEntry
+	// requested lists the files writers were requested for, in order.
+	requested []string
+	// FileMetadata, if true, adds a <file>.meta sidecar next to each file
+	// written with writers from GetWriterToFile, so that files extracted
+	// from the archive remain self-describing.
+	FileMetadata bool
+	// Cluster is the name of the cluster recorded in file metadata.
+	Cluster string
+	// MaxFileSize, if not zero, is the maximum size of the files written
+	// with writers from GetWriterToFile. What is written beyond it is
+	// dropped and replaced by a truncation marker.
+	MaxFileSize int64
+	// CompressDirs lists the top-level directories of the archive whose
+	// files written with writers from GetWriterToFile are compressed
+	// individually in the FileCompression format, with its extension
+	// appended to their names. Files selected by Keep are never
+	// compressed, so that they can be read back.
+	CompressDirs    map[string]bool
+	FileCompression string
+}
+
+// A FileMeta describes how a file of the dump was collected.
+type FileMeta struct {
+	File      string    `json:"file"`
+	Collected time.Time `json:"collected"`
+	// Command is the command whose output the file holds, if any.
+	Command []string `json:"command,omitempty"`
+	Cluster string   `json:"cluster,omitempty"`
+	Version string   `json:"toolVersion"`
 }
 
 type ArchiveWriter struct {
 	File    string
 	Archive *Archive
 	Writer  *bytes.Buffer
+	// command is the last command to write to the writer, recorded in
+	// the file metadata.
+	command []string
+	// dropped is the number of bytes written beyond the MaxFileSize of
+	// the archive.
+	dropped int64
+	// task and project identify the task whose commands write to the
+	// writer, recorded in the manifest.
+	task, project string
+	// redacted is true if what is written to the writer was already
+	// redacted, so that the Redactor of the archive is not applied to it.
+	redacted bool
 }
 
+// Write buffers p, up to the MaxFileSize of the archive. It never fails, so
+// that commands writing too much output still complete.
 func (a *ArchiveWriter) Write(p []byte) (n int, err error) {
-	return a.Writer.Write(p)
+	max := a.Archive.MaxFileSize
+	if max <= 0 || int64(a.Writer.Len()+len(p)) <= max {
+		return a.Writer.Write(p)
+	}
+	keep := max - int64(a.Writer.Len())
+	if keep < 0 {
+		keep = 0
+	}
+	a.Writer.Write(p[:keep])
+	a.dropped += int64(len(p)) - keep
+	return len(p), nil
+}
+
+// Reset discards what was written so far.
+func (a *ArchiveWriter) Reset() {
+	a.Writer.Reset()
+	a.dropped = 0
+}
+
+// setCommand records that the output of the command with args is written to
+// the writer.
+func (a *ArchiveWriter) setCommand(args []string) {
+	a.command = args
+}
+
+// setTask records that the named task of project writes to the writer.
+func (a *ArchiveWriter) setTask(name, project string) {
+	a.task, a.project = name, project
+}
+
+// setRedacted records that what is written to the writer is already redacted,
+// like tar archives whose files are redacted one by one.
+func (a *ArchiveWriter) setRedacted() {
+	a.redacted = true
 }
 
 func (a *ArchiveWriter) Close() error {
-	return a.Archive.AddFileByContent(a.Writer.Bytes(), a.File)
+	content := a.Writer.Bytes()
+	if a.Archive.Redactor != nil && !a.redacted {
+		content = a.Archive.Redactor.Redact(a.File, content)
+	}
+	if a.dropped > 0 {
+		log.Printf("Truncated %s: %s over the limit of %s", a.File, formatSize(a.dropped), formatSize(a.Archive.MaxFileSize))
+		content = append(content, truncationMarker(a.dropped, a.Archive.MaxFileSize)...)
+	}
+	name, content, err := a.Archive.compressFile(a.File, content)
+	if err != nil {
+		return err
+	}
+	entry := dumpformat.ManifestEntry{Task: a.task, Project: a.project, Truncated: a.dropped > 0}
+	if err := a.Archive.addFile(content, name, entry); err != nil {
+		return err
+	}
+	if !a.Archive.FileMetadata {
+		return nil
+	}
+	meta := FileMeta{File: name, Collected: time.Now().UTC(), Command: a.command, Cluster: a.Archive.Cluster, Version: version}
+	output, err := json.MarshalIndent(meta, "", "    ")
+	if err != nil {
+		return err
+	}
+	return a.Archive.AddFileByContent(output, name+".meta")
+}
+
+// compressFile returns the name and content of the file added to the archive
+// for the given file, compressed if it is in one of the CompressDirs.
+func (a *Archive) compressFile(file string, content []byte) (string, []byte, error) {
+	ext := compressedExtensions[a.FileCompression]
+	if ext == "" || !a.CompressDirs[strings.SplitN(file, "/", 2)[0]] || (a.Keep != nil && a.Keep(file)) {
+		return file, content, nil
+	}
+	var b bytes.Buffer
+	c, err := newCompressor(a.FileCompression, &b)
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := c.Write(content); err != nil {
+		c.Close()
+		return "", nil, err
+	}
+	if err := c.Close(); err != nil {
+		return "", nil, err
+	}
+	return file + ext, b.Bytes(), nil
+}
+
+// parseCompressDirs parses a comma-separated list of top-level directories of
+// the dump, as given to -compress-files.
+func parseCompressDirs(s string) map[string]bool {
+	dirs := make(map[string]bool)
+	for _, d := range strings.Split(s, ",") {
+		if d = strings.Trim(strings.TrimSpace(d), "/"); d != "" {
+			dirs[d] = true
+		}
+	}
+	return dirs
+}
+
+// compressDirs are the CompressDirs of dump archives, set with
+// -compress-files.
+var compressDirs map[string]bool
+
+// outputSizeLimit, if not zero, is the MaxFileSize of dump archives, set with
+// -max-output-size.
+var outputSizeLimit int64
+
+// truncationMarker returns the line ending files truncated to max bytes after
+// dropped more bytes were written to them.
+func truncationMarker(dropped, max int64) string {
+	return fmt.Sprintf("\n[truncated by fh-system-dump-tool: %d more bytes were dropped, over the limit of %s set with -max-output-size]\n", dropped, formatSize(max))
 }
 
 func NewTgz(file io.Writer) (*Archive, error) {
+	return NewArchive(file, compressionGzip)
+}
+
+// NewArchive returns an archive writing a tar stream to file, compressed in
+// the given format.
+func NewArchive(file io.Writer, compression string) (*Archive, error) {
 	tgz := Archive{}
 	var err error
 	tgz.tgzFile = file
+	tgz.compressor, err = newCompressor(compression, file)
 	if err != nil {
 		return nil, err
 	}
-
-	tgz.gzWriter = gzip.NewWriter(tgz.tgzFile)
-	tgz.tarWriter = tar.NewWriter(tgz.gzWriter)
+	tgz.tarWriter = tar.NewWriter(tgz.compressor)
 
 	return &tgz, nil
 }
 
 func (a *Archive) GetWriterToFile(file string) io.WriteCloser {
+	a.mu.Lock()
+	a.requested = append(a.requested, file)
+	a.mu.Unlock()
 	writer := ArchiveWriter{File: file, Archive: a, Writer: &bytes.Buffer{}}
 	return &writer
 }
 
 func (a *Archive) AddFileByContent(src []byte, dest string) error {
+	return a.addFile(src, dest, dumpformat.ManifestEntry{})
+}
+
+// addFile adds src to the archive as dest, recording it in the manifest with
+// the task and truncation of entry.
+func (a *Archive) addFile(src []byte, dest string, entry dumpformat.ManifestEntry) error {
 	header := &tar.Header{
 		Name:    dest,
 		Size:    int64(len(src)),
@@ -55,18 +245,169 @@ func (a *Archive) AddFileByContent(src []byte, dest string) error {
 		ModTime: time.Now(),
 	}
 
-	if err := a.tarWriter.WriteHeader(header); err != nil {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.Keep != nil && a.Keep(dest) {
+		if a.kept == nil {
+			a.kept = make(map[string][]byte)
+		}
+		a.kept[dest] = append([]byte(nil), src...)
+	}
+
+	var w io.Writer = a.tarWriter
+	if a.zipWriter != nil {
+		zipHeader := &zip.FileHeader{Name: dest, Method: a.zipMethod, Modified: header.ModTime}
+		zipHeader.SetMode(0775)
+		zw, err := a.zipWriter.CreateHeader(zipHeader)
+		if err != nil {
+			return err
+		}
+		w = zw
+	} else if err := a.tarWriter.WriteHeader(header); err != nil {
 		return err
 	}
 
-	if _, err := io.Copy(a.tarWriter, bytes.NewReader(src)); err != nil {
+	if _, err := io.Copy(w, bytes.NewReader(src)); err != nil {
 		return err
 	}
 
+	sum := sha256.Sum256(src)
+	entry.Name, entry.Size, entry.SHA256 = dest, int64(len(src)), hex.EncodeToString(sum[:])
+	a.manifest = append(a.manifest, entry)
+
 	return nil
 }
 
-func (a *Archive) Close() {
-	a.tarWriter.Close()
-	a.gzWriter.Close()
+// AddRedactedFile adds src to the archive as dest like AddFileByContent, after
+// applying the Redactor, if any. Collected content must be added with it or
+// with writers from GetWriterToFile, never left unredacted.
+func (a *Archive) AddRedactedFile(src []byte, dest string) error {
+	if a.Redactor != nil {
+		src = a.Redactor.Redact(dest, src)
+	}
+	return a.AddFileByContent(src, dest)
+}
+
+// RequestedFiles returns the files writers were requested for with
+// GetWriterToFile, in order, whether they were written yet or not.
+func (a *Archive) RequestedFiles() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.requested...)
+}
+
+// Manifest returns the list of files written to the archive, in order.
+func (a *Archive) Manifest() dumpformat.Manifest {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return dumpformat.Manifest{Files: append([]dumpformat.ManifestEntry{}, a.manifest...)}
+}
+
+// KeptFiles returns the contents of the files selected by Keep, keyed by
+// name.
+func (a *Archive) KeptFiles() map[string][]byte {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	files := make(map[string][]byte, len(a.kept))
+	for name, content := range a.kept {
+		files[name] = content
+	}
+	return files
+}
+
+func (a *Archive) Close() error {
+	if a.zipWriter != nil {
+		return a.zipWriter.Close()
+	}
+	if err := a.tarWriter.Close(); err != nil {
+		a.compressor.Close()
+		return err
+	}
+	return a.compressor.Close()
+}
+
+// An archiveReader iterates over the files of a dump archive.
+type archiveReader interface {
+	// Next returns the name of the next file and a reader of its
+	// content, or io.EOF after the last file.
+	Next() (string, io.Reader, error)
+	Close() error
+}
+
+// openArchive returns a reader of the dump archive read from r, detecting
+// whether it is a zip archive or a tar archive, compressed or not.
+func openArchive(r io.Reader) (archiveReader, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(len(zipMagic)); bytes.Equal(magic, zipMagic) {
+		return openZip(r, br)
+	}
+	zr, err := newDecompressor(br)
+	if err != nil {
+		return nil, err
+	}
+	return &tarArchiveReader{tarReader: tar.NewReader(zr), decompressor: zr}, nil
+}
+
+// A tarArchiveReader iterates over the files of a tar archive.
+type tarArchiveReader struct {
+	tarReader    *tar.Reader
+	decompressor io.Closer
+}
+
+func (t *tarArchiveReader) Next() (string, io.Reader, error) {
+	header, err := t.tarReader.Next()
+	if err != nil {
+		return "", nil, err
+	}
+	return header.Name, t.tarReader, nil
+}
+
+func (t *tarArchiveReader) Close() error {
+	return t.decompressor.Close()
+}
+
+// ReadTgz reads a dump archive, in any format, from r and returns the contents of all files
+// whose name satisfies match, keyed by name.
+func ReadTgz(r io.Reader, match func(name string) bool) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := WalkTgz(r, match, func(name string, content []byte) error {
+		files[name] = content
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// WalkTgz reads a dump archive, in any format, from r and calls fn, in order, with the name
+// and contents of each file whose name satisfies match. Only one file is held
+// in memory at a time.
+func WalkTgz(r io.Reader, match func(name string) bool, fn func(name string, content []byte) error) error {
+	archive, err := openArchive(r)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	for {
+		name, content, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !match(name) {
+			continue
+		}
+		var buf bytes.Buffer
+		if _, err := io.Copy(&buf, content); err != nil {
+			return err
+		}
+		if err := fn(name, buf.Bytes()); err != nil {
+			return err
+		}
+	}
 }
@@ -5,8 +5,14 @@ import (
 	"bufio"
 	"bytes"
 	"compress/gzip"
+	"context"
+	"encoding/json"
 	"io"
+	"io/ioutil"
+	"reflect"
 	"testing"
+
+	"github.com/feedhenry/fh-system-dump-tool/cmdrunner"
 )
 
 func TestWritingAFile(t *testing.T) {
@@ -56,3 +62,135 @@ func decompressAndListFiles(tgzFile io.Reader) (map[string]string, error) {
 
 	return ret, nil
 }
+
+func TestReadTgz(t *testing.T) {
+	var b bytes.Buffer
+	tgz, err := NewTgz(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"a.json": "a", "b.logs": "b"} {
+		if err := tgz.AddFileByContent([]byte(content), name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tgz.Close()
+
+	files, err := ReadTgz(&b, func(name string) bool { return name == "a.json" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || string(files["a.json"]) != "a" {
+		t.Errorf("ReadTgz() = %q, want only a.json", files)
+	}
+}
+
+func TestFileMetadata(t *testing.T) {
+	defer func(old cmdrunner.Runner) { runner = old }(runner)
+	cmd := ocCommand("get", "pods", "-o=json")
+	runner = cmdrunner.NewFakeRunner([]cmdrunner.Invocation{{Args: cmd.Args, Stdout: "{}"}})
+
+	var b bytes.Buffer
+	tgz, err := NewTgz(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tgz.FileMetadata, tgz.Cluster = true, "master.example.com"
+	tgz.Keep = func(string) bool { return true }
+	out := tgz.GetWriterToFile("definitions/projects/core/pods.json")
+	if err := runCmdCaptureOutput(context.Background(), cmd, out, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var meta FileMeta
+	if err := json.Unmarshal(tgz.KeptFiles()["definitions/projects/core/pods.json.meta"], &meta); err != nil {
+		t.Fatal(err)
+	}
+	if meta.File != "definitions/projects/core/pods.json" || meta.Cluster != "master.example.com" || !reflect.DeepEqual(meta.Command, cmd.Args) || meta.Collected.IsZero() {
+		t.Errorf("metadata = %+v, want the file, cluster, command and collection time", meta)
+	}
+}
+
+func TestMaxFileSize(t *testing.T) {
+	var b bytes.Buffer
+	tgz, err := NewTgz(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tgz.MaxFileSize = 10
+	tgz.Keep = func(string) bool { return true }
+	out := tgz.GetWriterToFile("events.json")
+	for _, s := range []string{"0123456", "789abc", "def"} {
+		if n, err := io.WriteString(out, s); n != len(s) || err != nil {
+			t.Errorf("Write(%q) = %d, %v, want %d, nil", s, n, err, len(s))
+		}
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(tgz.KeptFiles()["events.json"]), "0123456789"+truncationMarker(6, 10); got != want {
+		t.Errorf("content = %q, want %q", got, want)
+	}
+	if m := tgz.Manifest(); len(m.Files) != 1 || !m.Files[0].Truncated {
+		t.Errorf("Manifest() = %+v, want events.json marked as truncated", m)
+	}
+}
+
+func TestCompressDirs(t *testing.T) {
+	var b bytes.Buffer
+	tgz, err := NewTgz(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tgz.CompressDirs, tgz.FileCompression = parseCompressDirs(" logs/, nagios"), compressionGzip
+	for _, name := range []string{"logs/p/pod.log", "events.json", "definitions/projects/p/pods.json"} {
+		out := tgz.GetWriterToFile(name)
+		io.WriteString(out, "content of "+name)
+		if err := out.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tgz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ReadTgz(&b, func(string) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Errorf("files = %v, want 3 files", files)
+	}
+	for _, name := range []string{"events.json", "definitions/projects/p/pods.json"} {
+		if got, want := string(files[name]), "content of "+name; got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	r, err := newDecompressor(bytes.NewReader(files["logs/p/pod.log.gz"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if got, err := ioutil.ReadAll(r); string(got) != "content of logs/p/pod.log" || err != nil {
+		t.Errorf("logs/p/pod.log.gz = %q, %v, want the compressed log", got, err)
+	}
+}
+
+func TestAddRedactedFile(t *testing.T) {
+	var b bytes.Buffer
+	tgz, err := NewTgz(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tgz.Redactor, err = NewRedactor(defaultRedactionRules); err != nil {
+		t.Fatal(err)
+	}
+	tgz.Keep = func(string) bool { return true }
+	if err := tgz.AddRedactedFile([]byte("password=hunter2\n"), "errors.json"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(tgz.KeptFiles()["errors.json"]), "password=REDACTED\n"; got != want {
+		t.Errorf("content = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// slowestTasks is the number of slowest tasks listed at the end of a run.
+const slowestTasks = 5
+
+// Outcomes of tasks.
+const (
+	outcomeOK     = "ok"
+	outcomeFailed = "failed"
+	// outcomeSkipped is for tasks of projects deleted during the dump.
+	outcomeSkipped = "skipped"
+)
+
+// A TaskTiming records when a task ran and how it went.
+type TaskTiming struct {
+	Task      string        `json:"task"`
+	Project   string        `json:"project,omitempty"`
+	Collector string        `json:"collector,omitempty"`
+	Start     time.Time     `json:"start"`
+	Duration  time.Duration `json:"duration"`
+	Outcome   string        `json:"outcome"`
+	// Class and Error describe why failed tasks failed, Class as
+	// returned by ClassifyError.
+	Class string `json:"class,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// setOutcome records the outcome of a task that returned err.
+func (t *TaskTiming) setOutcome(err error) {
+	if err == nil {
+		t.Outcome = outcomeOK
+		return
+	}
+	if _, ok := err.(*ProjectDeletedError); ok {
+		t.Outcome, t.Error = outcomeSkipped, err.Error()
+		return
+	}
+	t.Outcome, t.Class, t.Error = outcomeFailed, ClassifyError(err), err.Error()
+}
+
+// A CollectorTiming sums up the tasks of a collector.
+type CollectorTiming struct {
+	Collector string `json:"collector"`
+	Tasks     int    `json:"tasks"`
+	Failed    int    `json:"failed"`
+	// Duration is the sum of the durations of the tasks, which may be
+	// longer than the run when tasks run in parallel.
+	Duration time.Duration `json:"duration"`
+}
+
+// A TaskSummary records how the tasks of a dump ran. It is written to
+// summary.json at the root of the archive.
+type TaskSummary struct {
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	// Collectors are sorted by decreasing duration.
+	Collectors []CollectorTiming `json:"collectors"`
+	Tasks      []TaskTiming      `json:"tasks"`
+	// Outputs lists the files the tasks of each collector were to write.
+	Outputs []TaskOutputs `json:"outputs,omitempty"`
+}
+
+// TaskOutputs lists the files requested by the tasks of a collector for a
+// project, or for all projects if Project is empty. The files are written
+// once the tasks complete, whether they succeed or not, so those of
+// collectors whose Tasks all succeeded must be in the dump.
+type TaskOutputs struct {
+	Collector string   `json:"collector"`
+	Project   string   `json:"project,omitempty"`
+	Tasks     int      `json:"tasks"`
+	Files     []string `json:"files"`
+}
+
+// NewTaskSummary returns the summary of a run of tasks that started at start,
+// took duration and whose tasks ran as in timings. Start times are in UTC.
+func NewTaskSummary(start time.Time, duration time.Duration, timings []TaskTiming) TaskSummary {
+	s := TaskSummary{Start: start.UTC(), Duration: duration, Collectors: []CollectorTiming{}, Tasks: []TaskTiming{}}
+	index := make(map[string]int)
+	for _, t := range timings {
+		if !t.Start.IsZero() {
+			t.Start = t.Start.UTC()
+		}
+		s.Tasks = append(s.Tasks, t)
+		i, ok := index[t.Collector]
+		if !ok {
+			i = len(s.Collectors)
+			index[t.Collector] = i
+			s.Collectors = append(s.Collectors, CollectorTiming{Collector: t.Collector})
+		}
+		c := &s.Collectors[i]
+		c.Tasks++
+		c.Duration += t.Duration
+		if t.Outcome == outcomeFailed {
+			c.Failed++
+		}
+	}
+	sort.SliceStable(s.Collectors, func(i, j int) bool { return s.Collectors[i].Duration > s.Collectors[j].Duration })
+	return s
+}
+
+// WriteTable writes the collectors of s and its slowest tasks as tables to w.
+func (s TaskSummary) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "COLLECTOR\tTASKS\tFAILED\tDURATION")
+	for _, c := range s.Collectors {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%v\n", orDash(c.Collector), c.Tasks, c.Failed, c.Duration.Round(time.Millisecond))
+	}
+	tasks := append([]TaskTiming(nil), s.Tasks...)
+	sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].Duration > tasks[j].Duration })
+	if len(tasks) > slowestTasks {
+		tasks = tasks[:slowestTasks]
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "SLOWEST TASKS\tPROJECT\tOUTCOME\tDURATION")
+	for _, t := range tasks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%v\n", orDash(t.Task), orDash(t.Project), t.Outcome, t.Duration.Round(time.Millisecond))
+	}
+	return tw.Flush()
+}
+
+// orDash returns s, or a dash if it is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// WriteTaskSummary writes s to summary.json in tarFile.
+func WriteTaskSummary(tarFile *Archive, s TaskSummary) error {
+	output, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return err
+	}
+	return tarFile.AddFileByContent(output, "summary.json")
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunTaskGraphTimings(t *testing.T) {
+	g := TaskGraph{
+		Tasks: []Task{
+			namedTask("fetch definitions", "core", func(context.Context) error { return nil }),
+			namedTask("build inventory", "", func(context.Context) error { return errors.New("no projects") }),
+		},
+		Collectors: []string{"definitions", "inventory"},
+	}
+	_, timings := RunTaskGraph(context.Background(), g, 2, 0)
+	want := []TaskTiming{
+		{Task: "fetch definitions", Project: "core", Collector: "definitions", Outcome: outcomeOK},
+		{Task: "build inventory", Collector: "inventory", Outcome: outcomeFailed, Class: classOther, Error: "build inventory: no projects"},
+	}
+	for i, w := range want {
+		got := timings[i]
+		got.Start, got.Duration = time.Time{}, 0
+		if got != w {
+			t.Errorf("timings[%d] = %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestTaskSummary(t *testing.T) {
+	timings := []TaskTiming{
+		{Task: "fetch logs of pod/a", Project: "core", Collector: "logs", Duration: 2 * time.Second, Outcome: outcomeOK},
+		{Task: "fetch definitions", Project: "core", Collector: "definitions", Duration: time.Second, Outcome: outcomeOK},
+		{Task: "fetch logs of pod/b", Project: "core", Collector: "logs", Duration: 3 * time.Second, Outcome: outcomeFailed},
+	}
+	s := NewTaskSummary(time.Now(), 4*time.Second, timings)
+	if len(s.Collectors) != 2 {
+		t.Fatalf("Collectors = %+v, want logs and definitions", s.Collectors)
+	}
+	if c := s.Collectors[0]; c.Collector != "logs" || c.Tasks != 2 || c.Failed != 1 || c.Duration != 5*time.Second {
+		t.Errorf("Collectors[0] = %+v, want logs with 2 tasks, 1 failed, taking 5s", c)
+	}
+
+	var b bytes.Buffer
+	if err := s.WriteTable(&b); err != nil {
+		t.Fatal(err)
+	}
+	table := b.String()
+	if !strings.Contains(table, "fetch logs of pod/b  core") || strings.Index(table, "pod/b") > strings.Index(table, "pod/a") {
+		t.Errorf("WriteTable() = %q, want the slowest tasks first", table)
+	}
+	local := time.FixedZone("CEST", 2*60*60)
+	s = NewTaskSummary(time.Date(2017, 6, 1, 12, 0, 0, 0, local), time.Second, []TaskTiming{{Start: time.Date(2017, 6, 1, 12, 0, 1, 0, local)}})
+	if s.Start.Location() != time.UTC || s.Tasks[0].Start.Location() != time.UTC || s.Tasks[0].Start.Hour() != 10 {
+		t.Errorf("NewTaskSummary() start times = %v and %v, want them in UTC", s.Start, s.Tasks[0].Start)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// A Change describes a difference between two dumps.
+type Change struct {
+	// Kind is one of the change kinds below.
+	Kind    string
+	Project string
+	Name    string
+	Message string
+}
+
+// Kinds of changes, in the order they are reported.
+const (
+	changeNewCritical      = "new critical"
+	changeNewIssue         = "new issue"
+	changeReplicasDropped  = "replicas dropped"
+	changeVersionChanged   = "version changed"
+	changeComponentRemoved = "component removed"
+	changeComponentAdded   = "component added"
+	changeIssueDowngraded  = "issue downgraded"
+	changeIssueResolved    = "issue resolved"
+)
+
+var changeKinds = []string{
+	changeNewCritical,
+	changeNewIssue,
+	changeReplicasDropped,
+	changeVersionChanged,
+	changeComponentRemoved,
+	changeComponentAdded,
+	changeIssueDowngraded,
+	changeIssueResolved,
+}
+
+// CompareWithPreviousDump compares the current dump with the previous dump in
+// the same directory, if any, and writes a report of the changes next to the
+// current dump archive.
+func CompareWithPreviousDump(current DumpSummary) error {
+	previousPath, err := FindPreviousDump(current.Path)
+	if err != nil {
+		return err
+	}
+	if previousPath == "" {
+		log.Println("No previous dump to compare with.")
+		return nil
+	}
+	previous, err := LoadDumpSummary(previousPath)
+	if err != nil {
+		return err
+	}
+
+	reportPath := trimArchiveExtension(current.Path) + ".changes.txt"
+	f, err := os.Create(reportPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := writeChangesReport(f, previous, current, CompareDumps(previous, current)); err != nil {
+		return err
+	}
+	log.Printf("Changes since the previous dump written to: %s\n", reportPath)
+	return nil
+}
+
+// CompareDumps returns the changes between the previous and current dumps:
+// issues detected, resolved or changing severity in analysis checks, and
+// components added, removed, changing images or losing available replicas. An
+// issue escalated from warning to critical is reported as a new critical.
+func CompareDumps(previous, current DumpSummary) []Change {
+	var changes []Change
+
+	issues := func(summary DumpSummary) map[[2]string]Result {
+		m := make(map[[2]string]Result)
+		for project, results := range summary.Results {
+			for _, r := range results {
+				if r.Status != StatusOK {
+					m[[2]string{project, r.CheckName}] = r
+				}
+			}
+		}
+		return m
+	}
+	previousIssues, currentIssues := issues(previous), issues(current)
+	for key, r := range currentIssues {
+		p, ok := previousIssues[key]
+		switch {
+		case ok && r.Status < p.Status:
+			changes = append(changes, Change{Kind: changeIssueDowngraded, Project: key[0], Name: key[1], Message: fmt.Sprintf("%s -> %s: %s", statusName(p.Status), statusName(r.Status), r.StatusMessage)})
+		case ok && r.Status == p.Status:
+		case r.Status >= StatusCritical:
+			message := r.StatusMessage
+			if ok {
+				message = fmt.Sprintf("escalated from %s: %s", statusName(p.Status), message)
+			}
+			changes = append(changes, Change{Kind: changeNewCritical, Project: key[0], Name: key[1], Message: message})
+		case !ok:
+			changes = append(changes, Change{Kind: changeNewIssue, Project: key[0], Name: key[1], Message: r.StatusMessage})
+		}
+	}
+	for key := range previousIssues {
+		if _, ok := currentIssues[key]; !ok {
+			changes = append(changes, Change{Kind: changeIssueResolved, Project: key[0], Name: key[1]})
+		}
+	}
+
+	components := func(summary DumpSummary) map[[2]string]Component {
+		m := make(map[[2]string]Component)
+		for _, c := range summary.Inventory.Components {
+			m[[2]string{c.Project, c.Name}] = c
+		}
+		return m
+	}
+	previousComponents, currentComponents := components(previous), components(current)
+	for key, c := range currentComponents {
+		p, ok := previousComponents[key]
+		if !ok {
+			changes = append(changes, Change{Kind: changeComponentAdded, Project: key[0], Name: key[1]})
+			continue
+		}
+		if was, is := strings.Join(p.Images, ", "), strings.Join(c.Images, ", "); was != is {
+			changes = append(changes, Change{Kind: changeVersionChanged, Project: key[0], Name: key[1], Message: fmt.Sprintf("%s -> %s", was, is)})
+		}
+		if c.AvailableReplicas < p.AvailableReplicas {
+			changes = append(changes, Change{Kind: changeReplicasDropped, Project: key[0], Name: key[1], Message: fmt.Sprintf("available replicas %d -> %d", p.AvailableReplicas, c.AvailableReplicas)})
+		}
+	}
+	for key := range previousComponents {
+		if _, ok := currentComponents[key]; !ok {
+			changes = append(changes, Change{Kind: changeComponentRemoved, Project: key[0], Name: key[1]})
+		}
+	}
+	return changes
+}
+
+// writeChangesReport writes a human readable report of changes to w, grouped
+// by kind, most relevant first.
+func writeChangesReport(w io.Writer, previous, current DumpSummary, changes []Change) error {
+	if _, err := fmt.Fprintf(w, "Changes from %s to %s\n", previous.Path, current.Path); err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		_, err := fmt.Fprintln(w, "\nNo changes detected.")
+		return err
+	}
+	for _, kind := range changeKinds {
+		var lines []string
+		for _, c := range changes {
+			if c.Kind != kind {
+				continue
+			}
+			line := fmt.Sprintf("  %s/%s", c.Project, c.Name)
+			if c.Message != "" {
+				line += ": " + c.Message
+			}
+			lines = append(lines, line)
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		sort.Strings(lines)
+		if _, err := fmt.Fprintf(w, "\n%s (%d):\n%s\n", strings.ToUpper(kind[:1])+kind[1:], len(lines), strings.Join(lines, "\n")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
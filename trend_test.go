@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCompareDumps(t *testing.T) {
+	previous := DumpSummary{
+		Path: "previous.tar.gz",
+		Inventory: Inventory{Components: []Component{
+			{Project: "core", Name: "fh-ngui", Images: []string{"fh-ngui:1"}, AvailableReplicas: 2},
+			{Project: "core", Name: "millicore", Images: []string{"millicore:1"}, AvailableReplicas: 1},
+			{Project: "core", Name: "gone", Images: []string{"gone:1"}},
+		}},
+		Results: map[string][]Result{
+			"core": {
+				{CheckName: "check a", Status: StatusWarning},
+				{CheckName: "check b", Status: StatusOK},
+				{CheckName: "check c", Status: StatusWarning},
+				{CheckName: "check d", Status: StatusCritical},
+				{CheckName: "check e", Status: StatusWarning},
+			},
+		},
+	}
+	current := DumpSummary{
+		Path: "current.tar.gz",
+		Inventory: Inventory{Components: []Component{
+			{Project: "core", Name: "fh-ngui", Images: []string{"fh-ngui:2"}, AvailableReplicas: 2},
+			{Project: "core", Name: "millicore", Images: []string{"millicore:1"}, AvailableReplicas: 0},
+			{Project: "core", Name: "new", Images: []string{"new:1"}},
+		}},
+		Results: map[string][]Result{
+			"core": {
+				{CheckName: "check a", Status: StatusOK},
+				{CheckName: "check b", Status: StatusWarning, StatusMessage: "b detected"},
+				{CheckName: "check c", Status: StatusCritical, StatusMessage: "c detected"},
+				{CheckName: "check d", Status: StatusWarning, StatusMessage: "d detected"},
+				{CheckName: "check e", Status: StatusWarning, StatusMessage: "e detected"},
+				{CheckName: "check f", Status: StatusCritical, StatusMessage: "f detected"},
+			},
+		},
+	}
+
+	changes := CompareDumps(previous, current)
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.Kind+" "+c.Project+"/"+c.Name)
+	}
+	sort.Strings(got)
+	want := []string{
+		"component added core/new",
+		"component removed core/gone",
+		"issue downgraded core/check d",
+		"issue resolved core/check a",
+		"new critical core/check c",
+		"new critical core/check f",
+		"new issue core/check b",
+		"replicas dropped core/millicore",
+		"version changed core/fh-ngui",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CompareDumps() = %v, want %v", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := writeChangesReport(&buf, previous, current, changes); err != nil {
+		t.Fatal(err)
+	}
+	report := buf.String()
+	for _, s := range []string{
+		"New critical (2):\n  core/check c: escalated from warning: c detected\n  core/check f: f detected",
+		"New issue (1):\n  core/check b: b detected",
+		"core/check d: critical -> warning: d detected",
+		"fh-ngui:1 -> fh-ngui:2",
+	} {
+		if !strings.Contains(report, s) {
+			t.Errorf("report doesn't include %q:\n%s", s, report)
+		}
+	}
+	if strings.Index(report, "New critical") > strings.Index(report, "New issue") || strings.Index(report, "New issue") > strings.Index(report, "Issue resolved") {
+		t.Errorf("new criticals and issues should be reported before resolved issues:\n%s", report)
+	}
+}
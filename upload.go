@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultUploadURL is the support case API of the Red Hat customer
+	// portal. Attachments are posted to <url>/<case>/attachments.
+	defaultUploadURL = "https://api.access.redhat.com/rs/cases"
+	// uploadRetries is the maximum number of retries of uploads failing
+	// with errors that may not happen again.
+	uploadRetries = 3
+	// uploadBackoff is the time to wait before the first retry of an
+	// upload. It doubles at each retry.
+	uploadBackoff = 10 * time.Second
+)
+
+// caseNumber matches the numbers of Red Hat support cases.
+var caseNumber = regexp.MustCompile(`^[0-9]+$`)
+
+// An uploader uploads the files of dumps to where they are collected.
+type uploader interface {
+	UploadFiles(paths []string) error
+}
+
+// An uploadStatusError is returned for uploads that the server rejected.
+type uploadStatusError struct {
+	file    string
+	status  string
+	code    int
+	message string
+}
+
+func (e *uploadStatusError) Error() string {
+	return fmt.Sprintf("upload of %s: %s: %s", e.file, e.status, e.message)
+}
+
+// retryable reports whether an upload that failed with err may succeed when
+// tried again. Requests rejected by the server, for bad credentials or an
+// unknown case, fail the same way every time, unless it was throttling.
+func retryable(err error) bool {
+	e, ok := err.(*uploadStatusError)
+	return !ok || e.code/100 == 5 || e.code == http.StatusTooManyRequests
+}
+
+// A caseUploader attaches files to a Red Hat support case. The credentials of
+// the customer portal are read from the REDHAT_USERNAME and REDHAT_PASSWORD
+// environment variables.
+type caseUploader struct {
+	url      string
+	caseID   string
+	username string
+	password string
+	client   *http.Client
+	// backoff is the time to wait before the first retry.
+	backoff time.Duration
+	// progress receives the progress of uploads.
+	progress io.Writer
+}
+
+// newCaseUploader returns an uploader of attachments to caseID with the
+// support case API at url.
+func newCaseUploader(url, caseID string) (*caseUploader, error) {
+	if !caseNumber.MatchString(caseID) {
+		return nil, fmt.Errorf("invalid support case number %q", caseID)
+	}
+	username, password := os.Getenv("REDHAT_USERNAME"), os.Getenv("REDHAT_PASSWORD")
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("uploading to a support case requires the REDHAT_USERNAME and REDHAT_PASSWORD environment variables")
+	}
+	return &caseUploader{url: strings.TrimSuffix(url, "/"), caseID: caseID, username: username, password: password,
+		client: &http.Client{}, backoff: uploadBackoff, progress: os.Stderr}, nil
+}
+
+// UploadFiles attaches the files at paths to the support case, in order. It
+// stops at the first file that could not be uploaded.
+func (u *caseUploader) UploadFiles(paths []string) error {
+	return uploadFiles(paths, u.backoff, u.upload, func(path string) {
+		log.Printf("Attached %s to support case %s\n", filepath.Base(path), u.caseID)
+	})
+}
+
+// uploadFiles calls upload with each of paths, in order, retrying each upload
+// up to uploadRetries times, waiting backoff before the first retry, and
+// calls done once it succeeded. It stops at the first file that could not be
+// uploaded.
+func uploadFiles(paths []string, backoff time.Duration, upload func(path string) error, done func(path string)) error {
+	for _, path := range paths {
+		err := upload(path)
+		for attempt := 0; err != nil && attempt < uploadRetries && retryable(err); attempt++ {
+			wait := backoff << uint(attempt)
+			log.Printf("Retrying the upload in %v after an error: %v\n", wait, err)
+			time.Sleep(wait)
+			err = upload(path)
+		}
+		if err != nil {
+			return err
+		}
+		done(path)
+	}
+	return nil
+}
+
+// upload posts the file at path as a multipart form, streaming it from disk.
+func (u *caseUploader) upload(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	form := multipart.NewWriter(pw)
+	go func() {
+		part, err := form.CreateFormFile("file", filepath.Base(path))
+		if err == nil {
+			_, err = io.Copy(part, newUploadProgress(f, u.progress, filepath.Base(path), fi.Size()))
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/%s/attachments", u.url, u.caseID), pr)
+	if err != nil {
+		pr.Close()
+		return err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.SetBasicAuth(u.username, u.password)
+	resp, err := u.client.Do(req)
+	if err != nil {
+		pr.Close()
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &uploadStatusError{file: filepath.Base(path), status: resp.Status, code: resp.StatusCode, message: strings.TrimSpace(string(msg))}
+	}
+	return nil
+}
+
+// An uploadProgress reads a file being uploaded, writing how much of it was
+// read every progressLogInterval and once it is all read.
+type uploadProgress struct {
+	r    io.Reader
+	w    io.Writer
+	file string
+	size int64
+	read int64
+	last time.Time
+}
+
+func newUploadProgress(r io.Reader, w io.Writer, file string, size int64) *uploadProgress {
+	return &uploadProgress{r: r, w: w, file: file, size: size, last: time.Now()}
+}
+
+func (p *uploadProgress) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	if now := time.Now(); now.Sub(p.last) >= progressLogInterval || (err == io.EOF && n == 0) {
+		p.last = now
+		percent := 100
+		if p.size > 0 {
+			percent = int(100 * p.read / p.size)
+		}
+		fmt.Fprintf(p.w, "Uploading %s: %d%% of %s\n", p.file, percent, formatSize(p.size))
+	}
+	return n, err
+}
+
+// uploadedFiles returns the files of the dump to upload: its
+// chunks and their manifest if it was split, the archive otherwise, and its
+// checksum file.
+func uploadedFiles(archive, checksum string, chunks []string) []string {
+	var files []string
+	if len(chunks) > 0 {
+		files = append(files, chunks...)
+		files = append(files, archive+".split.json")
+	} else {
+		files = append(files, archive)
+	}
+	if checksum != "" {
+		files = append(files, checksum)
+	}
+	return files
+}
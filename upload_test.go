@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNewCaseUploader(t *testing.T) {
+	os.Setenv("REDHAT_USERNAME", "customer")
+	os.Setenv("REDHAT_PASSWORD", "secret")
+	defer os.Unsetenv("REDHAT_USERNAME")
+	defer os.Unsetenv("REDHAT_PASSWORD")
+	if _, err := newCaseUploader(defaultUploadURL, "01234567"); err != nil {
+		t.Errorf("newCaseUploader() = %v", err)
+	}
+	for _, id := range []string{"", "../01234567"} {
+		if _, err := newCaseUploader(defaultUploadURL, id); err == nil {
+			t.Errorf("newCaseUploader(%q) didn't return an error", id)
+		}
+	}
+	os.Unsetenv("REDHAT_PASSWORD")
+	if _, err := newCaseUploader(defaultUploadURL, "01234567"); err == nil {
+		t.Error("newCaseUploader() without credentials didn't return an error")
+	}
+}
+
+func TestUploadFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dump.tar.gz")
+	if err := ioutil.WriteFile(path, []byte("dump"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	requests, status := 0, http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/rs/cases/01234567/attachments" {
+			t.Errorf("path = %s, want the attachments of the case", r.URL.Path)
+		}
+		if username, password, _ := r.BasicAuth(); username != "customer" || password != "secret" {
+			t.Errorf("credentials = %s:%s, want customer:secret", username, password)
+		}
+		f, header, err := r.FormFile("file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if content, _ := ioutil.ReadAll(f); header.Filename != "dump.tar.gz" || string(content) != "dump" {
+			t.Errorf("file = %s: %q, want dump.tar.gz: dump", header.Filename, content)
+		}
+		w.WriteHeader(status)
+		status = http.StatusCreated
+	}))
+	defer server.Close()
+
+	u := &caseUploader{url: server.URL + "/rs/cases", caseID: "01234567", username: "customer", password: "secret",
+		client: server.Client(), progress: ioutil.Discard}
+	if err := u.UploadFiles([]string{path}); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Errorf("%d requests, want a retry after the server error", requests)
+	}
+
+	requests, status = 0, http.StatusUnauthorized
+	if err := u.UploadFiles([]string{path}); err == nil {
+		t.Error("UploadFiles() with bad credentials didn't return an error")
+	}
+	if requests != 1 {
+		t.Errorf("%d requests, want no retry of rejected requests", requests)
+	}
+}
+
+func TestUploadedFiles(t *testing.T) {
+	if got, want := uploadedFiles("dump.tar.gz", "dump.tar.gz.sha256", nil), []string{"dump.tar.gz", "dump.tar.gz.sha256"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("uploadedFiles() = %v, want %v", got, want)
+	}
+	got := uploadedFiles("dump.tar.gz", "dump.tar.gz.sha256", []string{"dump.tar.gz.001", "dump.tar.gz.002"})
+	if want := []string{"dump.tar.gz.001", "dump.tar.gz.002", "dump.tar.gz.split.json", "dump.tar.gz.sha256"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("uploadedFiles() of a split dump = %v, want %v", got, want)
+	}
+}
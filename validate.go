@@ -0,0 +1,264 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/feedhenry/fh-system-dump-tool/dumpformat"
+)
+
+// A ValidationReport lists the problems found in a dump archive.
+type ValidationReport struct {
+	// Checked and Passed count the verifications made, and those that
+	// passed.
+	Checked, Passed int
+	Problems        []string
+}
+
+// Score returns the percentage of verifications that passed.
+func (r ValidationReport) Score() int {
+	if r.Checked == 0 {
+		return 0
+	}
+	return r.Passed * 100 / r.Checked
+}
+
+func (r *ValidationReport) check(ok bool, format string, a ...interface{}) bool {
+	r.Checked++
+	if ok {
+		r.Passed++
+	} else {
+		r.Problems = append(r.Problems, fmt.Sprintf(format, a...))
+	}
+	return ok
+}
+
+// ValidateDump reads the dump archive from r and verifies its metadata, its
+// manifest and checksums, that the definitions and analysis results of every
+// project dumped are present, unless their collectors did not run, and that
+// the tasks that succeeded wrote all their files. Files are hashed as they are read, not
+// kept in memory.
+func ValidateDump(r io.Reader) (ValidationReport, error) {
+	var report ValidationReport
+	var (
+		files                  = make(map[string]dumpformat.ManifestEntry)
+		metadataJSON, manifest []byte
+		summaryJSON            []byte
+	)
+	archive, err := openArchive(r)
+	if err == zip.ErrFormat {
+		// Zip archives are read from their end, truncated ones can't
+		// be opened at all.
+		report.check(false, "the archive is truncated or corrupt: %v", err)
+		err = nil
+		archive = &zipArchiveReader{}
+	}
+	if err != nil {
+		return report, err
+	}
+	defer archive.Close()
+	for {
+		name, content, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			var entry dumpformat.ManifestEntry
+			entry, err = readEntry(name, content, func(content []byte) {
+				switch name {
+				case "metadata.json":
+					metadataJSON = content
+				case "manifest.json":
+					manifest = content
+				case "summary.json":
+					summaryJSON = content
+				}
+			})
+			files[name] = entry
+		}
+		if err != nil {
+			// A truncated archive is a problem of the dump, not an
+			// error validating it.
+			report.check(false, "the archive is truncated or corrupt: %v", err)
+			break
+		}
+	}
+
+	// Dumps of layout version 0 predate metadata.json and manifest.json.
+	var metadata dumpformat.Metadata
+	if metadataJSON != nil && report.check(json.Unmarshal(metadataJSON, &metadata) == nil, "metadata.json is not valid") {
+		err := dumpformat.CheckLayoutVersion(metadata.LayoutVersion)
+		report.check(err == nil, "%v", err)
+	}
+
+	var m dumpformat.Manifest
+	if (metadataJSON != nil || manifest != nil) &&
+		report.check(manifest != nil, "manifest.json is missing") &&
+		report.check(json.Unmarshal(manifest, &m) == nil, "manifest.json is not valid") {
+		listed := map[string]bool{"manifest.json": true}
+		for _, want := range m.Files {
+			listed[want.Name] = true
+			got, ok := files[want.Name]
+			if report.check(ok, "%s is missing", want.Name) {
+				report.check(got.Size == want.Size && got.SHA256 == want.SHA256, "%s doesn't match its checksum", want.Name)
+			}
+		}
+		for name := range files {
+			report.check(listed[name], "%s is not listed in the manifest", name)
+		}
+		report.check(m.Interrupted == "", "the dump was interrupted: %s", m.Interrupted)
+	}
+
+	if metadataJSON == nil {
+		metadata.Projects = dumpedProjects(files)
+	}
+	// Dumps not recording the collectors run ran the definitions and
+	// checks collectors.
+	ran := func(collector string) bool {
+		return len(metadata.Collectors) == 0 || containsString(metadata.Collectors, collector)
+	}
+	for _, p := range metadata.Projects {
+		var expected []string
+		if ran("checks") {
+			expected = append(expected, "analysis")
+		}
+		if ran("definitions") {
+			expected = append(expected, metadata.Resources...)
+		}
+		for _, resource := range expected {
+			name := path.Join("definitions", "projects", p, resource+".json")
+			report.check(hasFile(files, name), "%s is missing", name)
+		}
+	}
+
+	var summary TaskSummary
+	if summaryJSON != nil && report.check(json.Unmarshal(summaryJSON, &summary) == nil, "summary.json is not valid") {
+		checkTaskOutputs(&report, summary, metadata.Refreshed, files)
+	}
+	return report, nil
+}
+
+// checkTaskOutputs verifies that the files requested by the collectors whose
+// tasks all succeeded, as recorded in summary, are in files. The files of
+// refreshed projects are left out, as other tasks collected them again.
+func checkTaskOutputs(report *ValidationReport, summary TaskSummary, refreshed []dumpformat.Refresh, files map[string]dumpformat.ManifestEntry) {
+	skip := make(map[string]bool)
+	for _, r := range refreshed {
+		skip[r.Project] = true
+	}
+	type group struct{ collector, project string }
+	succeeded := make(map[group]int)
+	for _, t := range summary.Tasks {
+		if t.Outcome == outcomeOK {
+			succeeded[group{t.Collector, t.Project}]++
+			if t.Project != "" {
+				// The tasks of collectors covering all projects
+				// may name the project they are about.
+				succeeded[group{t.Collector, ""}]++
+			}
+		}
+	}
+	for _, o := range summary.Outputs {
+		if skip[o.Project] || succeeded[group{o.Collector, o.Project}] != o.Tasks {
+			continue
+		}
+		for _, name := range o.Files {
+			report.check(hasFile(files, name), "%s is missing, though the tasks of the %s collector succeeded", name, o.Collector)
+		}
+	}
+}
+
+// dumpedProjects returns the projects with definitions in files, for dumps of
+// layout version 0, which do not list them.
+func dumpedProjects(files map[string]dumpformat.ManifestEntry) []string {
+	seen := make(map[string]bool)
+	var projects []string
+	for name := range files {
+		parts := strings.Split(name, "/")
+		if len(parts) == 4 && parts[0] == "definitions" && parts[1] == "projects" && !seen[parts[2]] {
+			seen[parts[2]] = true
+			projects = append(projects, parts[2])
+		}
+	}
+	sort.Strings(projects)
+	return projects
+}
+
+// readEntry hashes the content of the named archive file read from r. The
+// content of metadata.json, manifest.json and summary.json is also passed to
+// keep.
+func readEntry(name string, r io.Reader, keep func(content []byte)) (dumpformat.ManifestEntry, error) {
+	h := sha256.New()
+	if name == "metadata.json" || name == "manifest.json" || name == "summary.json" {
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			return dumpformat.ManifestEntry{}, err
+		}
+		keep(content)
+		r = bytes.NewReader(content)
+	}
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return dumpformat.ManifestEntry{}, err
+	}
+	return dumpformat.ManifestEntry{Name: name, Size: n, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// validateCommand verifies the completeness of dump archives, e.g. received
+// from a customer, printing the problems found and a completeness score.
+func validateCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: validate dump.tar.gz...")
+	}
+	incomplete := 0
+	for _, dump := range args {
+		f, err := os.Open(dump)
+		if err != nil {
+			return err
+		}
+		report, err := ValidateDump(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", dump, err)
+		}
+		if err := report.verifyChecksumFile(dump); err != nil {
+			return fmt.Errorf("%s: %v", dump, err)
+		}
+		writeValidationReport(os.Stdout, dump, report)
+		if len(report.Problems) > 0 {
+			incomplete++
+		}
+	}
+	if incomplete > 0 {
+		return fmt.Errorf("%d of %d dumps are incomplete", incomplete, len(args))
+	}
+	return nil
+}
+
+func writeValidationReport(w io.Writer, dump string, report ValidationReport) {
+	fmt.Fprintf(w, "%s: %d%% complete (%d of %d verifications passed)\n", dump, report.Score(), report.Passed, report.Checked)
+	for _, p := range report.Problems {
+		fmt.Fprintf(w, "  - %s\n", p)
+	}
+}
+
+// hasFile reports whether files has the named file, or the file compressed
+// individually as with -compress-files.
+func hasFile(files map[string]dumpformat.ManifestEntry, name string) bool {
+	for _, ext := range compressedExtensions {
+		if _, ok := files[name+ext]; ok {
+			return true
+		}
+	}
+	return false
+}
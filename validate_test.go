@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/feedhenry/fh-system-dump-tool/dumpformat"
+)
+
+func TestValidateDump(t *testing.T) {
+	dump := func(files map[string]string, corrupt, interrupted string) []byte {
+		var b bytes.Buffer
+		tgz, err := NewTgz(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteMetadata(tgz, dumpformat.Metadata{LayoutVersion: dumpformat.LayoutVersion, Projects: []string{"core"}, Resources: []string{"pods"}}); err != nil {
+			t.Fatal(err)
+		}
+		for name, content := range files {
+			if err := tgz.AddFileByContent([]byte(content), name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if corrupt != "" {
+			// Record the wrong checksum for the named file.
+			for i := range tgz.manifest {
+				if tgz.manifest[i].Name == corrupt {
+					tgz.manifest[i].SHA256 = "0"
+				}
+			}
+		}
+		if err := WriteManifest(tgz, interrupted, nil); err != nil {
+			t.Fatal(err)
+		}
+		tgz.Close()
+		return b.Bytes()
+	}
+	complete := map[string]string{
+		"definitions/projects/core/pods.json":     "{}",
+		"definitions/projects/core/analysis.json": "{}",
+	}
+
+	report, err := ValidateDump(bytes.NewReader(dump(complete, "", "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Score() != 100 || len(report.Problems) != 0 {
+		t.Errorf("complete dump: score %d, problems %v", report.Score(), report.Problems)
+	}
+
+	report, err = ValidateDump(bytes.NewReader(dump(map[string]string{"definitions/projects/core/pods.json": "{}"}, "definitions/projects/core/pods.json", "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems := strings.Join(report.Problems, "\n")
+	for _, want := range []string{"pods.json doesn't match its checksum", "analysis.json is missing"} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("problems = %q, want %q", problems, want)
+		}
+	}
+
+	report, err = ValidateDump(bytes.NewReader(dump(complete, "", "received interrupt")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Problems) != 1 || report.Problems[0] != "the dump was interrupted: received interrupt" {
+		t.Errorf("interrupted dump: problems %q, want it reported as interrupted", report.Problems)
+	}
+
+	full := dump(complete, "", "")
+	report, err = ValidateDump(bytes.NewReader(full[:len(full)/2]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Score() == 100 {
+		t.Errorf("truncated dump: score 100, want less")
+	}
+}
+
+func TestValidateDumpOfOlderLayouts(t *testing.T) {
+	dump := func(files map[string]string) []byte {
+		var b bytes.Buffer
+		tgz, err := NewTgz(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, content := range files {
+			if err := tgz.AddFileByContent([]byte(content), name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		tgz.Close()
+		return b.Bytes()
+	}
+
+	// Dumps of layout version 0 have no metadata.json and no manifest.
+	report, err := ValidateDump(bytes.NewReader(dump(map[string]string{
+		"definitions/projects/core/pods.json":      "{}",
+		"definitions/projects/core/analysis.json":  "{}",
+		"definitions/projects/mbaas/pods.json":     "{}",
+		"definitions/projects/mbaas/analysis.json": "{}",
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Score() != 100 || len(report.Problems) != 0 {
+		t.Errorf("layout version 0: score %d, problems %v", report.Score(), report.Problems)
+	}
+
+	report, err = ValidateDump(bytes.NewReader(dump(map[string]string{"definitions/projects/core/pods.json": "{}"})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "definitions/projects/core/analysis.json is missing"; len(report.Problems) != 1 || report.Problems[0] != want {
+		t.Errorf("layout version 0 without analysis: problems %q, want %q", report.Problems, want)
+	}
+
+	report, err = ValidateDump(bytes.NewReader(dump(map[string]string{
+		"metadata.json": fmt.Sprintf(`{"layoutVersion": %d}`, dumpformat.LayoutVersion+1),
+		"manifest.json": `{"files": [{"name": "metadata.json"}]}`,
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := strings.Join(report.Problems, "\n"); !strings.Contains(problems, "unsupported layout version") {
+		t.Errorf("newer layout version: problems %q, want an unsupported layout version", problems)
+	}
+}
+
+func TestValidateDumpTaskOutputs(t *testing.T) {
+	dump := func(metadata dumpformat.Metadata, summary TaskSummary, files ...string) []byte {
+		var b bytes.Buffer
+		tgz, err := NewTgz(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range files {
+			if err := tgz.AddFileByContent([]byte("{}"), name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := WriteTaskSummary(tgz, summary); err != nil {
+			t.Fatal(err)
+		}
+		metadata.LayoutVersion = dumpformat.LayoutVersion
+		if err := WriteMetadata(tgz, metadata); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteManifest(tgz, "", nil); err != nil {
+			t.Fatal(err)
+		}
+		tgz.Close()
+		return b.Bytes()
+	}
+	summary := TaskSummary{
+		Tasks: []TaskTiming{
+			{Task: "fetch logs", Project: "core", Collector: "logs", Outcome: outcomeOK},
+			{Task: "fetch logs", Project: "core", Collector: "logs", Outcome: outcomeOK},
+			{Task: "fetch logs", Project: "mbaas", Collector: "logs", Outcome: outcomeOK},
+			{Task: "fetch logs", Project: "mbaas", Collector: "logs", Outcome: outcomeFailed},
+			{Task: "build inventory", Collector: "inventory", Outcome: outcomeOK},
+		},
+		Outputs: []TaskOutputs{
+			{Collector: "logs", Project: "core", Tasks: 2, Files: []string{"logs/core/a.logs", "logs/core/b.logs"}},
+			{Collector: "logs", Project: "mbaas", Tasks: 2, Files: []string{"logs/mbaas/a.logs", "logs/mbaas/b.logs"}},
+			{Collector: "inventory", Tasks: 1, Files: []string{"inventory.json"}},
+		},
+	}
+	// Only logs and the inventory were collected, with -only.
+	metadata := dumpformat.Metadata{Projects: []string{"core", "mbaas"}, Resources: []string{"pods"}, Collectors: []string{"logs", "inventory"}}
+
+	report, err := ValidateDump(bytes.NewReader(dump(metadata, summary, "logs/core/a.logs", "logs/core/b.logs", "inventory.json")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Problems) != 0 {
+		t.Errorf("complete dump: problems %q, want none", report.Problems)
+	}
+
+	report, err = ValidateDump(bytes.NewReader(dump(metadata, summary, "logs/core/a.logs")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"logs/core/b.logs is missing, though the tasks of the logs collector succeeded",
+		"inventory.json is missing, though the tasks of the inventory collector succeeded",
+	}
+	if !reflect.DeepEqual(report.Problems, want) {
+		t.Errorf("incomplete dump: problems %q, want %q", report.Problems, want)
+	}
+
+	metadata.Refreshed = []dumpformat.Refresh{{Project: "core"}}
+	report, err = ValidateDump(bytes.NewReader(dump(metadata, summary, "inventory.json")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Problems) != 0 {
+		t.Errorf("refreshed dump: problems %q, want none", report.Problems)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// An sshHost is a jump host, given with -via, on which oc commands run instead
+// of locally.
+type sshHost struct {
+	// Destination is the host, prefixed with the user to log in as if
+	// any, as understood by ssh.
+	Destination string
+	Port        string
+}
+
+// viaHost, if not nil, is the host oc commands run on.
+var viaHost *sshHost
+
+// parseVia parses the argument of -via, an ssh:// URL with an optional user
+// and port, like ssh://user@bastion:2222.
+func parseVia(s string) (*sshHost, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "ssh" || u.Hostname() == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return nil, fmt.Errorf("%q is not an ssh://[user@]host[:port] URL", s)
+	}
+	h := &sshHost{Destination: u.Hostname(), Port: u.Port()}
+	if u.User != nil {
+		h.Destination = u.User.Username() + "@" + h.Destination
+	}
+	return h, nil
+}
+
+// command returns a command running name with args on h over ssh, with env,
+// a list of key=value pairs, added to its environment. The remote command is
+// quoted for the shell of the host. Connections are shared between commands
+// running at the same time, and ssh never prompts, so that it fails rather
+// than hangs without a working key.
+func (h *sshHost) command(env []string, name string, args ...string) *exec.Cmd {
+	sshArgs := []string{
+		"-o", "BatchMode=yes",
+		"-o", "ControlMaster=auto",
+		"-o", "ControlPath=" + filepath.Join(os.TempDir(), "fh-system-dump-tool-ssh-%C"),
+		"-o", "ControlPersist=60",
+	}
+	if h.Port != "" {
+		sshArgs = append(sshArgs, "-p", h.Port)
+	}
+	remote := append(append([]string(nil), env...), name)
+	for _, arg := range args {
+		remote = append(remote, shellQuote(arg))
+	}
+	sshArgs = append(sshArgs, h.Destination, "--", strings.Join(remote, " "))
+	return exec.Command("ssh", sshArgs...)
+}
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_=./:,@") == "" {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
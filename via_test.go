@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseVia(t *testing.T) {
+	tests := []struct {
+		via  string
+		want *sshHost
+	}{
+		{"ssh://bastion", &sshHost{Destination: "bastion"}},
+		{"ssh://admin@bastion.example.com:2222", &sshHost{Destination: "admin@bastion.example.com", Port: "2222"}},
+		{"http://bastion", nil},
+		{"ssh://", nil},
+		{"ssh://bastion/path", nil},
+	}
+	for _, tt := range tests {
+		got, err := parseVia(tt.via)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("parseVia(%q) didn't return an error", tt.via)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseVia(%q) = %+v, %v, want %+v", tt.via, got, err, tt.want)
+		}
+	}
+}
+
+func TestOcCommandVia(t *testing.T) {
+	defer func(old *sshHost, kc string) { viaHost, *kubeconfig = old, kc }(viaHost, *kubeconfig)
+	viaHost, *kubeconfig = &sshHost{Destination: "admin@bastion", Port: "2222"}, "/home/admin/my config"
+	args := ocCommand("get", "pods", "-o=jsonpath={.items[*].metadata.name}").Args
+	if args[0] != "ssh" {
+		t.Fatalf("ocCommand() with -via runs %q, want ssh", args[0])
+	}
+	want := []string{"-p", "2222", "admin@bastion", "--", "KUBECONFIG='/home/admin/my config' oc get pods '-o=jsonpath={.items[*].metadata.name}'"}
+	if got := args[len(args)-len(want):]; !reflect.DeepEqual(got, want) {
+		t.Errorf("ocCommand() with -via ends with %q, want %q", got, want)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	for s, want := range map[string]string{
+		"pods":       "pods",
+		"":           "''",
+		"a b":        "'a b'",
+		"it's":       `'it'\''s'`,
+		"--as=admin": "--as=admin",
+	} {
+		if got := shellQuote(s); got != want {
+			t.Errorf("shellQuote(%q) = %s, want %s", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// watchdogInterval is how often running commands are checked.
+	watchdogInterval = 10 * time.Second
+	// watchdogMinStall is the minimum time a command must run for before
+	// it is considered stalled, however fast similar commands were.
+	watchdogMinStall = time.Minute
+)
+
+// A watchdog tracks running commands, and reports those running for much
+// longer than similar commands took in the same run. It optionally kills
+// them, so that they can be retried.
+type watchdog struct {
+	// factor is how many times longer than the average duration of
+	// similar commands a command may run before it is reported. The
+	// watchdog is disabled if it is 0.
+	factor float64
+	// minStall is the minimum time a command may run for before it is
+	// reported.
+	minStall time.Duration
+	// kill enables killing stalled commands.
+	kill bool
+
+	mu      sync.Mutex
+	running map[*exec.Cmd]*watchedCmd
+	// total and count record the durations of completed commands, by
+	// commandKind.
+	total map[string]time.Duration
+	count map[string]int
+	// history records the durations of commands in previous runs, by
+	// commandKind, for kinds no command of completed yet in this run.
+	history map[string]CommandStats
+}
+
+type watchedCmd struct {
+	kind     string
+	start    time.Time
+	reported bool
+	killed   bool
+}
+
+// commandWatchdog watches all commands run by the dump tool.
+var commandWatchdog = &watchdog{minStall: watchdogMinStall}
+
+// commandKind groups commands expected to take a similar time: oc commands
+// with the same subcommand and, for get, resource type.
+func commandKind(args []string) string {
+	var words []string
+	for i := 1; i < len(args) && len(words) < 2; i++ {
+		switch a := args[i]; {
+		case a == "-n" || a == "--namespace" || a == "-l" || a == "-c":
+			i++
+		case strings.HasPrefix(a, "-"):
+		default:
+			words = append(words, a)
+			if a != "get" {
+				return strings.Join(words, " ")
+			}
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// start records that cmd started.
+func (w *watchdog) start(cmd *exec.Cmd) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.running == nil {
+		w.running = make(map[*exec.Cmd]*watchedCmd)
+		w.total = make(map[string]time.Duration)
+		w.count = make(map[string]int)
+	}
+	w.running[cmd] = &watchedCmd{kind: commandKind(cmd.Args), start: time.Now()}
+}
+
+// done records that cmd completed, and reports whether the watchdog killed
+// it.
+func (w *watchdog) done(cmd *exec.Cmd) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	c, ok := w.running[cmd]
+	if !ok {
+		return false
+	}
+	delete(w.running, cmd)
+	if !c.killed {
+		w.total[c.kind] += time.Since(c.start)
+		w.count[c.kind]++
+	}
+	return c.killed
+}
+
+// stallThreshold returns the time commands of kind may run before they are
+// reported. w.mu must be held.
+func (w *watchdog) stallThreshold(kind string) time.Duration {
+	threshold := w.minStall
+	var avg time.Duration
+	if n := w.count[kind]; n > 0 {
+		avg = w.total[kind] / time.Duration(n)
+	} else if h, ok := w.history[kind]; ok {
+		avg = h.Average
+	}
+	if stall := time.Duration(w.factor * float64(avg)); stall > threshold {
+		threshold = stall
+	}
+	return threshold
+}
+
+// setHistory sets the durations of commands in previous runs, by
+// commandKind.
+func (w *watchdog) setHistory(history map[string]CommandStats) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.history = history
+}
+
+// averages returns the number and average duration of the commands completed
+// in this run, by commandKind.
+func (w *watchdog) averages() map[string]CommandStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	averages := make(map[string]CommandStats, len(w.count))
+	for kind, n := range w.count {
+		averages[kind] = CommandStats{Count: n, Average: w.total[kind] / time.Duration(n)}
+	}
+	return averages
+}
+
+// check reports, and optionally kills, the commands that stalled at now.
+func (w *watchdog) check(now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for cmd, c := range w.running {
+		elapsed := now.Sub(c.start)
+		threshold := w.stallThreshold(c.kind)
+		if c.reported || elapsed < threshold {
+			continue
+		}
+		c.reported = true
+		log.Printf("Command stalled for %v (threshold %v): %q\n", elapsed.Round(time.Second), threshold.Round(time.Second), cmd.Args)
+		if w.kill && cmd.Process != nil {
+			if err := cmd.Process.Kill(); err != nil {
+				log.Printf("Killing stalled command %q: %v\n", cmd.Args, err)
+				continue
+			}
+			c.killed = true
+		}
+	}
+}
+
+// run checks running commands every interval until stop is closed.
+func (w *watchdog) run(interval time.Duration, stop <-chan struct{}) {
+	if w.factor <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			w.check(now)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// A resetter is an output that can be discarded, so that a command writing to
+// it can be retried.
+type resetter interface {
+	Reset()
+}
+
+// retryCommand returns a copy of cmd that can be run again.
+func retryCommand(cmd *exec.Cmd) *exec.Cmd {
+	retry := exec.Command(cmd.Path, cmd.Args[1:]...)
+	retry.Args = cmd.Args
+	retry.Env = cmd.Env
+	retry.Dir = cmd.Dir
+	return retry
+}
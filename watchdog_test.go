@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestCommandKind(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"oc", "--as=admin", "-n", "core", "get", "pods", "-o=json"}, "get pods"},
+		{[]string{"oc", "-n", "core", "logs", "dc/millicore", "-c", "millicore"}, "logs"},
+		{[]string{"oc", "get", "projects"}, "get projects"},
+	}
+	for _, tt := range tests {
+		if got := commandKind(tt.args); got != tt.want {
+			t.Errorf("commandKind(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestWatchdog(t *testing.T) {
+	w := &watchdog{factor: 2, minStall: 50 * time.Millisecond, kill: true}
+
+	fast := exec.Command("true")
+	if err := fast.Start(); err != nil {
+		t.Skip(err)
+	}
+	w.start(fast)
+	fast.Wait()
+	w.done(fast)
+	if got := w.stallThreshold("true"); got != w.minStall {
+		t.Errorf("stallThreshold() after a fast command = %v, want the minimum %v", got, w.minStall)
+	}
+
+	slow := exec.Command("sleep", "10")
+	if err := slow.Start(); err != nil {
+		t.Skip(err)
+	}
+	w.start(slow)
+	w.check(time.Now())
+	if w.running[slow].killed {
+		t.Fatal("watchdog killed a command before its threshold")
+	}
+	w.check(time.Now().Add(time.Second))
+	slow.Wait()
+	if !w.done(slow) {
+		t.Error("watchdog didn't kill the stalled command")
+	}
+}
+
+func TestWatchdogHistory(t *testing.T) {
+	w := &watchdog{factor: 2, minStall: time.Second}
+	w.setHistory(map[string]CommandStats{"logs": {Count: 3, Average: time.Minute}})
+	if got := w.stallThreshold("logs"); got != 2*time.Minute {
+		t.Errorf("stallThreshold() from history = %v, want %v", got, 2*time.Minute)
+	}
+	if got := w.stallThreshold("get pods"); got != w.minStall {
+		t.Errorf("stallThreshold() without history = %v, want the minimum %v", got, w.minStall)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// Formats of dump archives. Tar archives are compressed as a whole, zip
+// archives compress each file.
+const (
+	archiveFormatTar = "tar"
+	archiveFormatZip = "zip"
+)
+
+// zipExtension is the extension of dump archives in the zip format.
+const zipExtension = ".zip"
+
+// zipMagic prefixes zip archives.
+var zipMagic = []byte("PK\x03\x04")
+
+// zipMethods maps the compression formats usable in zip archives to the
+// methods compressing each file. Deflate is the algorithm behind gzip.
+var zipMethods = map[string]uint16{
+	compressionGzip: zip.Deflate,
+	compressionNone: zip.Store,
+}
+
+// checkArchiveFormat returns an error if format is not a known archive
+// format, or cannot be compressed in the given compression format.
+func checkArchiveFormat(format, compression string) error {
+	switch format {
+	case archiveFormatTar:
+		return nil
+	case archiveFormatZip:
+		if _, ok := zipMethods[compression]; !ok {
+			return fmt.Errorf("zip archives can only use gzip or no compression, not %s", compression)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown archive format %q, must be tar or zip", format)
+}
+
+// dumpExtension returns the extension of dump archives in the given archive
+// and compression formats.
+func dumpExtension(format, compression string) string {
+	if format == archiveFormatZip {
+		return zipExtension
+	}
+	return archiveExtensions[compression]
+}
+
+// dumpArchiveFormat returns the archive format of the dump archive name,
+// based on its extension.
+func dumpArchiveFormat(name string) string {
+	if archiveExtension(name) == zipExtension {
+		return archiveFormatZip
+	}
+	return archiveFormatTar
+}
+
+// newDumpArchive returns an archive writing to file in the given archive and
+// compression formats.
+func newDumpArchive(file io.Writer, format, compression string) (*Archive, error) {
+	if format == archiveFormatZip {
+		return NewZipArchive(file, compression)
+	}
+	return NewArchive(file, compression)
+}
+
+// NewZipArchive returns an archive writing a zip archive to file, with each
+// file compressed in the given format.
+func NewZipArchive(file io.Writer, compression string) (*Archive, error) {
+	method, ok := zipMethods[compression]
+	if !ok {
+		return nil, fmt.Errorf("zip archives can only use gzip or no compression, not %s", compression)
+	}
+	return &Archive{tgzFile: file, zipWriter: zip.NewWriter(file), zipMethod: method}, nil
+}
+
+// openZip returns a reader of the zip archive read from r, through br. Zip
+// archives are read from their end, so the archive is held in memory unless r
+// is a file.
+func openZip(r io.Reader, br *bufio.Reader) (archiveReader, error) {
+	var (
+		ra   io.ReaderAt
+		size int64
+	)
+	if f, ok := r.(*os.File); ok {
+		fi, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		ra, size = f, fi.Size()
+	} else {
+		content, err := ioutil.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		ra, size = bytes.NewReader(content), int64(len(content))
+	}
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return nil, err
+	}
+	return &zipArchiveReader{files: zr.File}, nil
+}
+
+// A zipArchiveReader iterates over the files of a zip archive.
+type zipArchiveReader struct {
+	files   []*zip.File
+	current io.ReadCloser
+}
+
+func (z *zipArchiveReader) Next() (string, io.Reader, error) {
+	z.Close()
+	if len(z.files) == 0 {
+		return "", nil, io.EOF
+	}
+	f := z.files[0]
+	z.files = z.files[1:]
+	rc, err := f.Open()
+	if err != nil {
+		return "", nil, err
+	}
+	z.current = rc
+	return f.Name, rc, nil
+}
+
+func (z *zipArchiveReader) Close() error {
+	if z.current == nil {
+		return nil
+	}
+	err := z.current.Close()
+	z.current = nil
+	return err
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestZipArchive(t *testing.T) {
+	for _, compression := range []string{compressionGzip, compressionNone} {
+		var b bytes.Buffer
+		archive, err := newDumpArchive(&b, archiveFormatZip, compression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, content := range map[string]string{
+			"metadata.json":                       `{"layoutVersion": 1}`,
+			"definitions/projects/core/pods.json": "{}",
+		} {
+			if err := archive.AddFileByContent([]byte(content), name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := archive.Close(); err != nil {
+			t.Fatalf("%s: Close(): %v", compression, err)
+		}
+		if !bytes.HasPrefix(b.Bytes(), zipMagic) {
+			t.Fatalf("%s: the archive is not a zip archive", compression)
+		}
+		files, err := ReadTgz(bytes.NewReader(b.Bytes()), func(string) bool { return true })
+		if err != nil {
+			t.Fatalf("%s: ReadTgz(): %v", compression, err)
+		}
+		if got := string(files["definitions/projects/core/pods.json"]); got != "{}" {
+			t.Errorf("%s: read back %q, want %q", compression, got, "{}")
+		}
+
+		// Truncated zip archives are reported, not failed on.
+		report, err := ValidateDump(bytes.NewReader(b.Bytes()[:b.Len()/2]))
+		if err != nil || len(report.Problems) == 0 {
+			t.Errorf("%s: ValidateDump() of a truncated archive = %+v, %v, want problems", compression, report, err)
+		}
+	}
+}
+
+func TestCheckArchiveFormat(t *testing.T) {
+	tests := []struct {
+		format, compression string
+		ok                  bool
+	}{
+		{archiveFormatTar, compressionZstd, true},
+		{archiveFormatZip, compressionGzip, true},
+		{archiveFormatZip, compressionNone, true},
+		{archiveFormatZip, compressionZstd, false},
+		{"rar", compressionGzip, false},
+	}
+	for _, tt := range tests {
+		if err := checkArchiveFormat(tt.format, tt.compression); (err == nil) != tt.ok {
+			t.Errorf("checkArchiveFormat(%q, %q) = %v, want ok=%v", tt.format, tt.compression, err, tt.ok)
+		}
+	}
+	if got := dumpArchiveFormat("rhmap-dumps/2016-09-01T12-00-00Z.zip"); got != archiveFormatZip {
+		t.Errorf("dumpArchiveFormat(.zip) = %q, want zip", got)
+	}
+	if got := trimArchiveExtension("2016-09-01T12-00-00Z.zip"); got != "2016-09-01T12-00-00Z" {
+		t.Errorf("trimArchiveExtension(.zip) = %q", got)
+	}
+}